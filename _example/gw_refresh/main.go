@@ -98,7 +98,7 @@ func main() {
 	gImpl := gw.NewGatewayImpl(model.BTCTestnet3, b, nil, st) // Set Wallet nil because we don't PutAnchor in this example.
 	g = gImpl
 
-	// Get the record and update its Note.
+	// Get the record and refresh it. The time is recorded in LastChecked, not in Note.
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancelFunc()
 	dom32 := util.MustDecodeHexString("456789abc0ef0123456089abcdef0023456789a0cdef0123406789abcde00123")
@@ -109,8 +109,7 @@ func main() {
 		return
 	}
 	fmt.Println(ar)
-	s := fmt.Sprintf("Updated at: %s", time.Now().Format(time.RFC3339))
-	if err := g.RefreshRecord(ctx, dom32, tx32, nil, &s); err != nil {
+	if err := g.RefreshRecord(ctx, dom32, tx32, nil, nil, nil); err != nil {
 		log.Println(err)
 		return
 	}
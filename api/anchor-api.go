@@ -3,18 +3,29 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/ebiiim/btcgw/api/anchor"
 	"github.com/ebiiim/btcgw/auth"
+	"github.com/ebiiim/btcgw/btc"
 	"github.com/ebiiim/btcgw/gw"
 	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+	"github.com/ebiiim/btcgw/util"
 
 	oapimiddleware "github.com/deepmap/oapi-codegen/pkg/chi-middleware"
 	"github.com/getkin/kin-openapi/openapi3filter"
@@ -60,15 +71,172 @@ func (g *GatewayService) GetAnchorsDomainsDomainDigestsDigest(w http.ResponseWri
 		return
 	}
 	ctx := r.Context()
-	ar, err := g.GetRecord(ctx, bdom, bdig)
+	reg, err := g.GetRegistration(ctx, bdom, bdig)
 	if err != nil {
 		log.Println(err)
 	}
-	if errors.Is(err, gw.ErrCouldNotGetRecord) {
+	switch {
+	case errors.Is(err, util.ErrNotFound):
 		sendGatewayServiceError(w, http.StatusNotFound, ErrDigestNotFound, ErrDigestNotFoundDesc)
 		return
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+		return
+	case err != nil:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrUnexpected, ErrUnexpectedDesc)
+		return
+	}
+	if reg.Record == nil {
+		writeRegistration(w, r, reg)
+		return
+	}
+	WriteJSON(w, http.StatusOK, convertAnchorRecord(reg.Record))
+}
+
+// writeRegistration sends the Registration with 202 and the location to get the progress.
+func writeRegistration(w http.ResponseWriter, r *http.Request, reg *gw.Registration) {
+	w.Header().Set("Location", r.URL.Path)
+	WriteJSON(w, http.StatusAccepted, convertRegistration(reg))
+}
+
+func (g *GatewayService) GetAnchorsDomainsDomainDigests(w http.ResponseWriter, r *http.Request, dom string, params anchor.GetAnchorsDomainsDomainDigestsParams) {
+	bdom, err := hex.DecodeString(dom)
+	if err != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
+		return
+	}
+	opts := &store.ListOptions{}
+	if params.Limit != nil {
+		opts.Limit = *params.Limit
+	}
+	if params.Cursor != nil {
+		opts.Cursor = *params.Cursor
+	}
+	if params.Since != nil {
+		opts.Since = time.Unix(int64(*params.Since), 0)
+	}
+	if params.Until != nil {
+		opts.Until = time.Unix(int64(*params.Until), 0)
+	}
+	if params.Status != nil {
+		for _, s := range *params.Status {
+			opts.Statuses = append(opts.Statuses, model.AnchorStatus(s))
+		}
+	}
+	if params.Meta != nil {
+		opts.Metadata = model.Metadata{}
+		for _, m := range *params.Meta {
+			// "key=value" or "key" (any value).
+			kv := strings.SplitN(m, "=", 2)
+			if len(kv) == 2 {
+				opts.Metadata[kv[0]] = kv[1]
+			} else {
+				opts.Metadata[kv[0]] = ""
+			}
+		}
+		if err := opts.Metadata.Validate(); err != nil {
+			sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidMetadata, ErrInvalidMetadataDesc)
+			return
+		}
+	}
+	ctx := r.Context()
+	ars, cursor, err := g.ListRecords(ctx, bdom, opts)
+	if err != nil {
+		log.Println(err)
+	}
+	switch {
+	case errors.Is(err, store.ErrInvalidCursor):
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidCursor, ErrInvalidCursorDesc)
+		return
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+		return
+	case err != nil:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrListFailed, ErrListFailedDesc)
+		return
+	}
+	WriteJSON(w, http.StatusOK, convertAnchorList(ars, cursor))
+}
+
+func (g *GatewayService) GetReportsSpend(w http.ResponseWriter, r *http.Request, params anchor.GetReportsSpendParams) {
+	opts := &store.ReportOptions{ByMonth: true, ByDomain: true, ByAPIKey: true}
+	if params.By != nil {
+		opts = &store.ReportOptions{}
+		for _, by := range *params.By {
+			switch by {
+			case "month":
+				opts.ByMonth = true
+			case "domain":
+				opts.ByDomain = true
+			case "apikey":
+				opts.ByAPIKey = true
+			default:
+				sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidReportParam, ErrInvalidReportParamDesc)
+				return
+			}
+		}
+	}
+	if params.Domain != nil {
+		bdom, err := hex.DecodeString(*params.Domain)
+		if err != nil || len(bdom) != 32 {
+			sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidReportParam, ErrInvalidReportParamDesc)
+			return
+		}
+		opts.BBc1DomainID = bdom
+	}
+	if params.Since != nil {
+		opts.Since = time.Unix(int64(*params.Since), 0)
+	}
+	if params.Until != nil {
+		opts.Until = time.Unix(int64(*params.Until), 0)
+	}
+	ctx := r.Context()
+	rows, err := g.SpendReport(ctx, opts)
+	if err != nil {
+		log.Println(err)
+	}
+	switch {
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+		return
+	case err != nil:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrReportFailed, ErrReportFailedDesc)
+		return
+	}
+	if params.Format != nil && *params.Format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.WriteHeader(http.StatusOK)
+		if err := store.WriteReportCSV(w, rows); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	WriteJSON(w, http.StatusOK, convertSpendReport(rows))
+}
+
+func (g *GatewayService) GetAnchorsBtctxTxid(w http.ResponseWriter, r *http.Request, txid string) {
+	btctx, err := hex.DecodeString(txid)
+	if err != nil || len(btctx) != 32 {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
+		return
+	}
+	ctx := r.Context()
+	ars, err := g.GetRecordsByBTCTransaction(ctx, btctx)
+	if err != nil {
+		log.Println(err)
+	}
+	switch {
+	case err == nil:
+		WriteJSON(w, http.StatusOK, convertAnchorList(ars, ""))
+	case errors.Is(err, btc.ErrInvalidTransactionID), errors.Is(err, btc.ErrInvalidOpReturn):
+		sendGatewayServiceError(w, http.StatusNotFound, ErrAnchorNotFound, ErrAnchorNotFoundDesc)
+	case gw.IsNodeUnavailable(err):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrNodeUnavailable, ErrNodeUnavailableDesc)
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+	default:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrUnexpected, ErrUnexpectedDesc)
 	}
-	WriteJSON(w, http.StatusOK, convertAnchorRecord(ar))
 }
 
 func (g *GatewayService) PatchAnchorsDomainsDomainDigestsDigest(w http.ResponseWriter, r *http.Request, dom string, dig string) {
@@ -78,57 +246,326 @@ func (g *GatewayService) PatchAnchorsDomainsDomainDigestsDigest(w http.ResponseW
 		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
 		return
 	}
+	// encoding/json replaces invalid UTF-8 with U+FFFD, so check the raw body.
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidRequestBody, ErrInvalidRequestBodyDesc)
+		return
+	}
+	if !utf8.Valid(b) {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidRecordUpdate, ErrInvalidRecordUpdateDesc)
+		return
+	}
+	var body anchor.PatchAnchorsDomainsDomainDigestsDigestJSONRequestBody
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&body); err != nil && err != io.EOF {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidRequestBody, ErrInvalidRequestBodyDesc)
+		return
+	}
+	if !validText(body.Bbc1name, maxBBc1DomainNameLen, "") || !validText(body.Note, maxNoteLen, "\n\t") {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidRecordUpdate, ErrInvalidRecordUpdateDesc)
+		return
+	}
+	meta := convertMetadataParam(body.Metadata)
+	if err := meta.Validate(); err != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidMetadata, ErrInvalidMetadataDesc)
+		return
+	}
 	ctx := r.Context()
-	note := fmt.Sprintf("Updated at %s", time.Now().Format(time.RFC3339))
-	err := g.RefreshRecord(ctx, bdom, bdig, nil, &note)
+	// The time of the update is recorded in the history.
+	ctx = gw.WithActor(ctx, requestActor(r))
+	err = g.RefreshRecord(ctx, bdom, bdig, body.Bbc1name, body.Note, meta)
 	if err != nil {
 		log.Println(err)
 	}
-	if errors.Is(err, gw.ErrCouldNotRefreshRecord) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, util.ErrNotFound):
 		sendGatewayServiceError(w, http.StatusNotFound, ErrDigestNotFound, ErrDigestNotFoundDesc)
+	case gw.IsNodeUnavailable(err):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrNodeUnavailable, ErrNodeUnavailableDesc)
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+	default:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrUnexpected, ErrUnexpectedDesc)
+	}
+}
+
+func (g *GatewayService) GetAnchorsDomainsDomainDigestsDigestHistory(w http.ResponseWriter, r *http.Request, dom string, dig string) {
+	bdom, err1 := hex.DecodeString(dom)
+	bdig, err2 := hex.DecodeString(dig)
+	if err1 != nil || err2 != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+	ctx := r.Context()
+	es, err := g.GetHistory(ctx, bdom, bdig)
+	if err != nil {
+		log.Println(err)
+	}
+	switch {
+	case err == nil:
+		WriteJSON(w, http.StatusOK, convertHistory(es))
+	case errors.Is(err, util.ErrNotFound):
+		sendGatewayServiceError(w, http.StatusNotFound, ErrDigestNotFound, ErrDigestNotFoundDesc)
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+	default:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrUnexpected, ErrUnexpectedDesc)
+	}
 }
 
-func (g *GatewayService) PostAnchorsDomainsDomainDigestsDigest(w http.ResponseWriter, r *http.Request, dom string, dig string) {
+// Maximum lengths in characters of AnchorRecordUpdate.
+const (
+	maxBBc1DomainNameLen = 255
+	maxNoteLen           = 1024
+)
+
+// validText checks that s is valid UTF-8, at most max characters,
+// and has no control characters except ones in allowed. nil is valid.
+func validText(s *string, max int, allowed string) bool {
+	if s == nil {
+		return true
+	}
+	if !utf8.ValidString(*s) || utf8.RuneCountInString(*s) > max {
+		return false
+	}
+	for _, c := range *s {
+		if unicode.IsControl(c) && !strings.ContainsRune(allowed, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// requestActor returns the actor of changes made by r for the history.
+// It is the ID of the API key that authenticated r, or the client IP if r has no key
+// (r.RemoteAddr is the client IP if middleware.RealIP is used).
+func requestActor(r *http.Request) string {
+	if id := auth.KeyID(r.Header.Get("X-Api-Key")); id != "" {
+		return "apikey:" + id
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "api:" + host
+}
+
+func (g *GatewayService) PostAnchorsDomainsDomainDigestsDigest(w http.ResponseWriter, r *http.Request, dom string, dig string, params anchor.PostAnchorsDomainsDomainDigestsDigestParams) {
 	bdom, err1 := hex.DecodeString(dom)
 	bdig, err2 := hex.DecodeString(dig)
 	if err1 != nil || err2 != nil {
 		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
 		return
 	}
+	idemKey := ""
+	if params.IdempotencyKey != nil {
+		idemKey = *params.IdempotencyKey
+	}
+	var body anchor.PostAnchorsDomainsDomainDigestsDigestJSONRequestBody
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&body); err != nil && err != io.EOF {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidRequestBody, ErrInvalidRequestBodyDesc)
+		return
+	}
+	meta := convertMetadataParam(body.Metadata)
+	if err := meta.Validate(); err != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidMetadata, ErrInvalidMetadataDesc)
+		return
+	}
+	// Only the ID of the API key is stored for spend reports.
+	keyID := auth.KeyID(r.Header.Get("X-Api-Key"))
 	ctx := r.Context()
-	_, err := g.GetRecord(ctx, bdom, bdig)
-	if err == nil {
-		sendGatewayServiceError(w, http.StatusInternalServerError, ErrDigestAlreadyExists, ErrDigestAlreadyExistsDesc)
+	if params.Async != nil && *params.Async {
+		reg, err := g.Enqueue(ctx, bdom, bdig, idemKey, keyID, meta)
+		if err != nil {
+			log.Println(err)
+		}
+		switch {
+		case err == nil:
+			writeRegistration(w, r, reg)
+		case errors.Is(err, gw.ErrRecordAlreadyExists), errors.Is(err, util.ErrAlreadyExists):
+			sendGatewayServiceError(w, http.StatusConflict, ErrDigestAlreadyExists, ErrDigestAlreadyExistsDesc)
+		case errors.Is(err, gw.ErrIdempotencyKeyMismatch):
+			sendGatewayServiceError(w, http.StatusConflict, ErrIdempotencyKeyMismatch, ErrIdempotencyKeyMismatchDesc)
+		case errors.Is(err, util.ErrUnavailable):
+			sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+		default:
+			sendGatewayServiceError(w, http.StatusInternalServerError, ErrRegisterFailed, ErrRegisterFailedDesc)
+		}
 		return
 	}
-	btctx, err := g.RegisterTransaction(ctx, bdom, bdig)
+	ar, err := g.Register(ctx, bdom, bdig, idemKey, keyID, meta)
 	if err != nil {
 		log.Println(err)
 	}
-	if errors.Is(err, gw.ErrCouldNotPutAnchor) {
+	switch {
+	case err == nil:
+		WriteJSON(w, http.StatusOK, convertAnchorRecord(ar))
+	case errors.Is(err, gw.ErrRegistrationFailed):
+		sendGatewayServiceError(w, http.StatusConflict, ErrRegistrationFailed, ErrRegistrationFailedDesc)
+	case errors.Is(err, gw.ErrRecordAlreadyExists), errors.Is(err, util.ErrAlreadyExists):
+		sendGatewayServiceError(w, http.StatusConflict, ErrDigestAlreadyExists, ErrDigestAlreadyExistsDesc)
+	case errors.Is(err, gw.ErrIdempotencyKeyMismatch):
+		sendGatewayServiceError(w, http.StatusConflict, ErrIdempotencyKeyMismatch, ErrIdempotencyKeyMismatchDesc)
+	case errors.Is(err, gw.ErrNetworkNotAllowed):
+		sendGatewayServiceError(w, http.StatusForbidden, ErrNetworkNotAllowed, ErrNetworkNotAllowedDesc)
+	case errors.Is(err, gw.ErrDailyQuotaExceeded):
+		sendGatewayServiceError(w, http.StatusTooManyRequests, ErrDailyQuotaExceeded, ErrDailyQuotaExceededDesc)
+	case errors.Is(err, gw.ErrMonthlyQuotaExceeded):
+		sendGatewayServiceError(w, http.StatusTooManyRequests, ErrMonthlyQuotaExceeded, ErrMonthlyQuotaExceededDesc)
+	case errors.Is(err, gw.ErrBudgetExceeded):
+		sendGatewayServiceError(w, http.StatusPaymentRequired, ErrBudgetExceeded, ErrBudgetExceededDesc)
+	case errors.Is(err, gw.ErrGatewayQuotaExceeded):
+		sendGatewayServiceError(w, http.StatusTooManyRequests, ErrGatewayQuotaExceeded, ErrGatewayQuotaExceededDesc)
+	case errors.Is(err, gw.ErrGatewayBudgetExceeded):
+		sendGatewayServiceError(w, http.StatusPaymentRequired, ErrGatewayBudgetExceeded, ErrGatewayBudgetExceededDesc)
+	case gw.IsMempoolRejection(err):
+		code, desc := mempoolRejectionError(err)
+		sendGatewayServiceError(w, http.StatusUnprocessableEntity, code, desc)
+	case gw.IsNodeUnavailable(err):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrNodeUnavailable, ErrNodeUnavailableDesc)
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+	default:
 		sendGatewayServiceError(w, http.StatusInternalServerError, ErrRegisterFailed, ErrRegisterFailedDesc)
+	}
+}
+
+// mempoolRejectionError returns the error code and its description for the mempool rejection.
+func mempoolRejectionError(err error) (error, string) {
+	switch {
+	case errors.Is(err, btc.ErrTxFeeTooLow):
+		return ErrTxFeeTooLow, ErrTxFeeTooLowDesc
+	case errors.Is(err, btc.ErrTxDust):
+		return ErrTxDust, ErrTxDustDesc
+	case errors.Is(err, btc.ErrTxNonStandard):
+		return ErrTxNonStandard, ErrTxNonStandardDesc
+	case errors.Is(err, btc.ErrTxMissingInputs):
+		return ErrTxMissingInputs, ErrTxMissingInputsDesc
+	case errors.Is(err, btc.ErrTxTooLongMempoolChain):
+		return ErrTxTooLongMempoolChain, ErrTxTooLongMempoolChainDesc
+	default:
+		return ErrTxRejected, ErrTxRejectedDesc
+	}
+}
+
+func (g *GatewayService) GetDomains(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ds, err := g.ListDomains(ctx)
+	if err != nil {
+		log.Println(err)
+	}
+	switch {
+	case err == nil:
+		l := anchor.DomainList{
+			Domains: make([]anchor.Domain, len(ds)),
+		}
+		for i, d := range ds {
+			l.Domains[i] = convertDomain(d)
+		}
+		WriteJSON(w, http.StatusOK, l)
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+	default:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrUnexpected, ErrUnexpectedDesc)
+	}
+}
+
+func (g *GatewayService) GetDomainsDomain(w http.ResponseWriter, r *http.Request, dom string) {
+	bdom, err := hex.DecodeString(dom)
+	if err != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
 		return
 	}
-	err = g.StoreRecord(ctx, btctx)
+	ctx := r.Context()
+	d, err := g.GetDomain(ctx, bdom)
 	if err != nil {
 		log.Println(err)
 	}
-	if errors.Is(err, gw.ErrCouldNotStoreRecord) {
-		sendGatewayServiceError(w, http.StatusInternalServerError, ErrRegisterFailed, ErrRegisterFailedDesc)
+	switch {
+	case err == nil:
+		WriteJSON(w, http.StatusOK, convertDomain(d))
+	case errors.Is(err, util.ErrNotFound):
+		sendGatewayServiceError(w, http.StatusNotFound, ErrDomainNotFound, ErrDomainNotFoundDesc)
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+	default:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrUnexpected, ErrUnexpectedDesc)
+	}
+}
+
+func (g *GatewayService) PutDomainsDomain(w http.ResponseWriter, r *http.Request, dom string) {
+	bdom, err := hex.DecodeString(dom)
+	if err != nil || len(bdom) != 32 {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
 		return
 	}
-	ar, err := g.GetRecord(ctx, bdom, bdig)
+	var body anchor.PutDomainsDomainJSONRequestBody
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&body); err != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidRequestBody, ErrInvalidRequestBodyDesc)
+		return
+	}
+	md, err := convertDomainSettings(bdom, anchor.DomainSettings(body))
+	if err != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidDomain, ErrInvalidDomainDesc)
+		return
+	}
+	ctx := r.Context()
+	err = g.PutDomain(ctx, md)
 	if err != nil {
 		log.Println(err)
 	}
-	if errors.Is(err, gw.ErrCouldNotGetRecord) {
-		sendGatewayServiceError(w, http.StatusInternalServerError, ErrRegisterFailed, ErrRegisterFailedDesc)
+	switch {
+	case err == nil:
+		WriteJSON(w, http.StatusOK, convertDomain(md))
+	case errors.Is(err, model.ErrInvalidDomain):
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidDomain, ErrInvalidDomainDesc)
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+	default:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrUnexpected, ErrUnexpectedDesc)
+	}
+}
+
+func (g *GatewayService) DeleteDomainsDomain(w http.ResponseWriter, r *http.Request, dom string) {
+	bdom, err := hex.DecodeString(dom)
+	if err != nil {
+		sendGatewayServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
 		return
 	}
-	WriteJSON(w, http.StatusOK, convertAnchorRecord(ar))
+	ctx := r.Context()
+	err = g.DeleteDomain(ctx, bdom)
+	if err != nil {
+		log.Println(err)
+	}
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, util.ErrNotFound):
+		sendGatewayServiceError(w, http.StatusNotFound, ErrDomainNotFound, ErrDomainNotFoundDesc)
+	case errors.Is(err, util.ErrUnavailable):
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrStoreUnavailable, ErrStoreUnavailableDesc)
+	default:
+		sendGatewayServiceError(w, http.StatusInternalServerError, ErrUnexpected, ErrUnexpectedDesc)
+	}
+}
+
+func (g *GatewayService) GetInfo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	info, err := g.Info(ctx)
+	if err != nil {
+		log.Println(err)
+		sendGatewayServiceError(w, http.StatusServiceUnavailable, ErrNodeUnavailable, ErrNodeUnavailableDesc)
+		return
+	}
+	WriteJSON(w, http.StatusOK, convertInfo(info))
 }
 
 // OAPIValidator sets up OpenAPI validator and must be set as a middleware.
@@ -149,7 +586,11 @@ func (g *GatewayService) OAPIValidator() func(next http.Handler) http.Handler {
 		if h == nil {
 			return errors.New("X-API-KEY not found")
 		}
-		if !g.AuthFunc(ctx, h[0], input.RequestValidationInput.PathParams) {
+		scopes := make([]auth.Scope, len(input.Scopes))
+		for i, s := range input.Scopes {
+			scopes[i] = auth.Scope(s)
+		}
+		if !g.AuthFunc(ctx, h[0], scopes, input.RequestValidationInput.PathParams) {
 			return errors.New("auth failed")
 		}
 		return nil
@@ -176,6 +617,19 @@ func convertAnchor(a *model.Anchor) anchor.Anchor {
 	}
 }
 
+func convertInfo(info *gw.Info) anchor.Info {
+	features := info.CoreFeatures.Names()
+	if features == nil {
+		features = []string{}
+	}
+	return anchor.Info{
+		Chain:          info.BTCNet.String(),
+		CoreVersion:    info.CoreVersion.String(),
+		MinCoreVersion: info.MinCoreVersion.String(),
+		CoreFeatures:   features,
+	}
+}
+
 func convertAnchorRecord(ar *model.AnchorRecord) anchor.AnchorRecord {
 	var name *string = nil
 	if ar.BBc1DomainName != "" {
@@ -185,12 +639,265 @@ func convertAnchorRecord(ar *model.AnchorRecord) anchor.AnchorRecord {
 	if ar.Note != "" {
 		note = &(ar.Note)
 	}
+	status := string(ar.Status)
+	var reason *string = nil
+	if ar.StatusReason != "" {
+		reason = &(ar.StatusReason)
+	}
+	var lastChecked *int = nil
+	if !ar.LastChecked.IsZero() {
+		t := int(ar.LastChecked.Unix())
+		lastChecked = &t
+	}
+	var meta *anchor.Metadata = nil
+	if len(ar.Metadata) != 0 {
+		meta = &anchor.Metadata{AdditionalProperties: ar.Metadata}
+	}
+	var fee, input, change *int64 = nil, nil, nil
+	if ar.Fee != 0 {
+		v := int64(ar.Fee)
+		fee = &v
+	}
+	if ar.InputAmount != 0 {
+		v := int64(ar.InputAmount)
+		input = &v
+	}
+	if ar.ChangeAmount != 0 {
+		v := int64(ar.ChangeAmount)
+		change = &v
+	}
+	var vsize *int = nil
+	if ar.VSize != 0 {
+		v := int(ar.VSize)
+		vsize = &v
+	}
 	return anchor.AnchorRecord{
 		Anchor:        convertAnchor(ar.Anchor),
 		Bbc1name:      name,
 		Btctx:         hex.EncodeToString(ar.BTCTransactionID),
 		Confirmations: int(ar.Confirmations),
 		Note:          note,
+		Metadata:      meta,
+		Fee:           fee,
+		InputAmount:   input,
+		ChangeAmount:  change,
+		Vsize:         vsize,
+		Status:        status,
+		StatusReason:  reason,
+		LastChecked:   lastChecked,
 		Time:          int(ar.TransactionTime.Unix()),
 	}
 }
+
+// convertMetadataParam returns nil if m is not given, or non-nil Metadata even if m is empty.
+func convertMetadataParam(m *anchor.Metadata) model.Metadata {
+	if m == nil {
+		return nil
+	}
+	meta := model.Metadata{}
+	for k, v := range m.AdditionalProperties {
+		meta[k] = v
+	}
+	return meta
+}
+
+// convertDomainSettings returns the Domain of domID with the settings s.
+func convertDomainSettings(domID []byte, s anchor.DomainSettings) (*model.Domain, error) {
+	d := &model.Domain{
+		BBc1DomainID: domID,
+	}
+	if s.Name != nil {
+		d.Name = *s.Name
+	}
+	if s.Owner != nil {
+		d.Owner = *s.Owner
+	}
+	if s.Networks != nil {
+		for _, name := range *s.Networks {
+			n, err := model.ParseBTCNet(name)
+			if err != nil {
+				return nil, err
+			}
+			d.AllowedNetworks = append(d.AllowedNetworks, n)
+		}
+	}
+	if s.DailyQuota != nil {
+		if *s.DailyQuota < 0 {
+			return nil, fmt.Errorf("negative daily_quota %d", *s.DailyQuota)
+		}
+		d.DailyQuota = uint(*s.DailyQuota)
+	}
+	if s.MonthlyQuota != nil {
+		if *s.MonthlyQuota < 0 {
+			return nil, fmt.Errorf("negative monthly_quota %d", *s.MonthlyQuota)
+		}
+		d.MonthlyQuota = uint(*s.MonthlyQuota)
+	}
+	if s.DailyBudget != nil {
+		d.DailyBudget = model.Amount(*s.DailyBudget)
+	}
+	if s.MonthlyBudget != nil {
+		d.MonthlyBudget = model.Amount(*s.MonthlyBudget)
+	}
+	if s.FeeTier != nil {
+		d.FeeTier = model.FeeTier(*s.FeeTier)
+	}
+	if s.PublicKey != nil {
+		pub, err := hex.DecodeString(*s.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public_key (%v)", err)
+		}
+		d.PublicKey = pub
+	}
+	if s.KeyType != nil {
+		d.KeyType = model.KeyType(*s.KeyType)
+	}
+	return d, nil
+}
+
+func convertDomain(d *model.Domain) anchor.Domain {
+	var name *string = nil
+	if d.Name != "" {
+		name = &(d.Name)
+	}
+	var owner *string = nil
+	if d.Owner != "" {
+		owner = &(d.Owner)
+	}
+	var networks *[]string = nil
+	if len(d.AllowedNetworks) != 0 {
+		ns := make([]string, len(d.AllowedNetworks))
+		for i, n := range d.AllowedNetworks {
+			ns[i] = n.String()
+		}
+		networks = &ns
+	}
+	var quota *int = nil
+	if d.DailyQuota != 0 {
+		q := int(d.DailyQuota)
+		quota = &q
+	}
+	var monthlyQuota *int = nil
+	if d.MonthlyQuota != 0 {
+		q := int(d.MonthlyQuota)
+		monthlyQuota = &q
+	}
+	var dailyBudget *int64 = nil
+	if d.DailyBudget != 0 {
+		b := int64(d.DailyBudget)
+		dailyBudget = &b
+	}
+	var monthlyBudget *int64 = nil
+	if d.MonthlyBudget != 0 {
+		b := int64(d.MonthlyBudget)
+		monthlyBudget = &b
+	}
+	var tier *string = nil
+	if d.FeeTier != model.FeeDefault {
+		t := string(d.FeeTier)
+		tier = &t
+	}
+	var pub *string = nil
+	if len(d.PublicKey) != 0 {
+		p := hex.EncodeToString(d.PublicKey)
+		pub = &p
+	}
+	var keyType *string = nil
+	if d.KeyType != model.KeyNone {
+		k := string(d.KeyType)
+		keyType = &k
+	}
+	return anchor.Domain{
+		DomainSettings: anchor.DomainSettings{
+			Name:          name,
+			Owner:         owner,
+			Networks:      networks,
+			DailyQuota:    quota,
+			MonthlyQuota:  monthlyQuota,
+			DailyBudget:   dailyBudget,
+			MonthlyBudget: monthlyBudget,
+			FeeTier:       tier,
+			PublicKey:     pub,
+			KeyType:       keyType,
+		},
+		Domain:    hex.EncodeToString(d.BBc1DomainID),
+		CreatedAt: int(d.CreatedAt.Unix()),
+		UpdatedAt: int(d.UpdatedAt.Unix()),
+	}
+}
+
+func convertAnchorList(ars []*model.AnchorRecord, cursor string) anchor.AnchorList {
+	l := anchor.AnchorList{
+		Anchors: make([]anchor.AnchorRecord, len(ars)),
+	}
+	for i, ar := range ars {
+		l.Anchors[i] = convertAnchorRecord(ar)
+	}
+	if cursor != "" {
+		l.NextCursor = &cursor
+	}
+	return l
+}
+
+func convertSpendReport(rows []*store.SpendRow) anchor.SpendReport {
+	rep := anchor.SpendReport{
+		Rows: make([]anchor.SpendRow, len(rows)),
+	}
+	for i, row := range rows {
+		r := anchor.SpendRow{
+			Anchors:  int(row.Anchors),
+			Unpriced: int(row.Unpriced),
+			Fee:      int64(row.Fee),
+			Vsize:    int(row.VSize),
+		}
+		if row.Month != "" {
+			month := row.Month
+			r.Month = &month
+		}
+		if row.BBc1DomainID != nil {
+			dom := hex.EncodeToString(row.BBc1DomainID)
+			r.Domain = &dom
+		}
+		if row.APIKeyID != "" {
+			key := row.APIKeyID
+			r.Apikey = &key
+		}
+		rep.Rows[i] = r
+	}
+	return rep
+}
+
+func convertHistory(es []*gw.HistoryEntry) anchor.HistoryList {
+	l := anchor.HistoryList{
+		Entries: make([]anchor.HistoryEntry, len(es)),
+	}
+	for i, e := range es {
+		l.Entries[i] = anchor.HistoryEntry{
+			Time:  int(e.Time.Unix()),
+			Actor: e.Actor,
+			Field: e.Field,
+			Old:   e.Old,
+			New:   e.New,
+		}
+	}
+	return l
+}
+
+func convertRegistration(reg *gw.Registration) anchor.Registration {
+	var btctx *string = nil
+	if reg.BTCTransactionID != nil {
+		s := hex.EncodeToString(reg.BTCTransactionID)
+		btctx = &s
+	}
+	var reason *string = nil
+	if reg.FailReason != "" {
+		reason = &(reg.FailReason)
+	}
+	return anchor.Registration{
+		Domain: hex.EncodeToString(reg.BBc1DomainID),
+		Digest: hex.EncodeToString(reg.BBc1TransactionID),
+		Status: string(reg.Status),
+		Btctx:  btctx,
+		Reason: reason,
+	}
+}
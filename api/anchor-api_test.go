@@ -0,0 +1,259 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ebiiim/btcgw/api"
+	"github.com/ebiiim/btcgw/auth"
+	"github.com/ebiiim/btcgw/gw"
+	"github.com/ebiiim/btcgw/model"
+
+	"github.com/go-chi/chi"
+)
+
+const (
+	testDom  = "456789abc0ef0123456089abcdef0023456789a0cdef0123406789abcde00123"
+	testDom2 = "56789abcd0f0123456709abcdef0103456789ab0def0123450789abcdef01234"
+	testDig  = "6789abcde0f0123456789abcdef0123456789abcdef0123456789abcdef01234"
+)
+
+// refreshCall is the arguments of fakeGateway.RefreshRecord.
+type refreshCall struct {
+	domID, txID []byte
+	name, note  *string
+	meta        model.Metadata
+}
+
+// fakeGateway is a gw.Gateway that records RefreshRecord calls.
+// Other methods are not implemented and panic.
+type fakeGateway struct {
+	gw.Gateway
+
+	mu        sync.Mutex
+	refreshes []*refreshCall
+}
+
+func (g *fakeGateway) RefreshRecord(ctx context.Context, domID, txID []byte, pBBc1domName, pNote *string, meta model.Metadata) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshes = append(g.refreshes, &refreshCall{domID, txID, pBBc1domName, pNote, meta})
+	return nil
+}
+
+func (g *fakeGateway) lastRefresh() *refreshCall {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.refreshes) == 0 {
+		return nil
+	}
+	return g.refreshes[len(g.refreshes)-1]
+}
+
+// newTestAuth returns a BoltAuth in a temporary directory.
+func newTestAuth(t *testing.T) *auth.BoltAuth {
+	t.Helper()
+	a := auth.MustNewBoltAuth(t.TempDir() + "/auth.db")
+	t.Cleanup(func() {
+		if err := a.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return a
+}
+
+// keyGenerator is implemented by BoltAuth and DocstoreAuth.
+type keyGenerator interface {
+	Generate(ctx context.Context, domID string, isGlobalAdmin bool, note string, ttl time.Duration, scopes []auth.Scope) (*auth.APIKey, error)
+}
+
+// mustGenerate returns a new key for domID, or a global admin key if domID is "".
+func mustGenerate(t *testing.T, a keyGenerator, domID string, scopes ...auth.Scope) string {
+	t.Helper()
+	k, err := a.Generate(context.Background(), domID, domID == "", "test", 0, scopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k.Key
+}
+
+func serve(h http.Handler, method, path, apiKey, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		r.Header.Set("X-Api-Key", apiKey)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// newTestHandler returns the handler of s without OAPIValidator, to test validations in handlers.
+func newTestHandler(s *api.GatewayService) http.Handler {
+	r := chi.NewRouter()
+	api.AnchorHandlerFromMux(s, r)
+	return r
+}
+
+func TestGatewayService_PatchAnchorsDomainsDomainDigestsDigest(t *testing.T) {
+	t.Parallel()
+	ptr := func(s string) *string { return &s }
+	cases := []struct {
+		name     string
+		body     string
+		wantCode int
+		// wantName and wantNote are the values given to RefreshRecord if wantCode is 204.
+		wantName, wantNote *string
+	}{
+		{"empty_body", "", http.StatusNoContent, nil, nil},
+		{"empty_object", "{}", http.StatusNoContent, nil, nil},
+		{"set", `{"bbc1name":"example.com","note":"line1\nline2\tend"}`, http.StatusNoContent, ptr("example.com"), ptr("line1\nline2\tend")},
+		{"remove", `{"bbc1name":"","note":""}`, http.StatusNoContent, ptr(""), ptr("")},
+		{"max_chars", `{"bbc1name":"` + strings.Repeat("あ", 255) + `"}`, http.StatusNoContent, ptr(strings.Repeat("あ", 255)), nil},
+		{"name_too_long", `{"bbc1name":"` + strings.Repeat("a", 256) + `"}`, http.StatusBadRequest, nil, nil},
+		{"note_too_long", `{"note":"` + strings.Repeat("a", 1025) + `"}`, http.StatusBadRequest, nil, nil},
+		{"invalid_utf8", "{\"note\":\"\xff\xfe\"}", http.StatusBadRequest, nil, nil},
+		{"control_in_name", `{"bbc1name":"a\tb"}`, http.StatusBadRequest, nil, nil},
+		{"control_in_note", `{"note":"a\u0000b"}`, http.StatusBadRequest, nil, nil},
+		{"escape_in_note", `{"note":"a\u001bb"}`, http.StatusBadRequest, nil, nil},
+		{"unknown_field", `{"notes":"a"}`, http.StatusBadRequest, nil, nil},
+		{"not_json", `note`, http.StatusBadRequest, nil, nil},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			g := &fakeGateway{}
+			h := newTestHandler(api.NewGatewayService(g, nil))
+			w := serve(h, http.MethodPatch, "/anchors/domains/"+testDom+"/digests/"+testDig, "", c.body)
+			if w.Code != c.wantCode {
+				t.Fatalf("got %d want %d (%s)", w.Code, c.wantCode, w.Body)
+			}
+			rc := g.lastRefresh()
+			if c.wantCode != http.StatusNoContent {
+				if rc != nil {
+					t.Errorf("RefreshRecord is called")
+				}
+				return
+			}
+			if rc == nil {
+				t.Fatal("RefreshRecord is not called")
+			}
+			if !equalPtr(rc.name, c.wantName) || !equalPtr(rc.note, c.wantNote) {
+				t.Errorf("got name=%v note=%v", rc.name, rc.note)
+			}
+		})
+	}
+}
+
+func equalPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestGatewayService_PatchAnchorsDomainsDomainDigestsDigest_Auth(t *testing.T) {
+	t.Parallel()
+	a := newTestAuth(t)
+	g := &fakeGateway{}
+	s := api.NewGatewayService(g, a)
+	h := chi.NewRouter()
+	h.Use(s.OAPIValidator())
+	api.AnchorHandlerFromMux(s, h)
+
+	cases := []struct {
+		name     string
+		apiKey   string
+		wantCode int
+	}{
+		{"no_key", "", http.StatusUnauthorized},
+		{"wrong_key", mustGenerate(t, a, testDom) + "x", http.StatusUnauthorized},
+		{"another_domain", mustGenerate(t, a, testDom2), http.StatusUnauthorized},
+		{"domain_key", mustGenerate(t, a, testDom), http.StatusNoContent},
+		{"global_admin", mustGenerate(t, a, ""), http.StatusNoContent},
+	}
+	for _, c := range cases {
+		w := serve(h, http.MethodPatch, "/anchors/domains/"+testDom+"/digests/"+testDig, c.apiKey, `{"note":"a"}`)
+		if w.Code != c.wantCode {
+			t.Errorf("%s: got %d want %d (%s)", c.name, w.Code, c.wantCode, w.Body)
+		}
+	}
+	if n := len(g.refreshes); n != 2 {
+		t.Errorf("RefreshRecord: got %d calls want 2", n)
+	}
+}
+
+func TestGatewayService_OAPIValidator(t *testing.T) {
+	t.Parallel()
+	a := newTestAuth(t)
+	s := api.NewGatewayService(&fakeGateway{}, a)
+	// Requests that pass OAPIValidator get 204.
+	h := s.OAPIValidator()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	const (
+		pathDom = "/anchors/domains/" + testDom + "/digests"
+		pathDig = pathDom + "/" + testDig
+	)
+	cases := []struct {
+		method, path, body string
+		scope              auth.Scope
+		// hasDomain is true if the path has the domain, so keys of the domain are allowed.
+		hasDomain bool
+	}{
+		{http.MethodGet, pathDom, "", auth.ScopeAnchorsRead, true},
+		{http.MethodGet, pathDig, "", auth.ScopeAnchorsRead, true},
+		{http.MethodGet, pathDig + "/history", "", auth.ScopeAnchorsRead, true},
+		{http.MethodPost, pathDig, "{}", auth.ScopeAnchorsRegister, true},
+		{http.MethodPatch, pathDig, "{}", auth.ScopeAnchorsRefresh, true},
+		{http.MethodGet, "/anchors/btctx/" + testDig, "", auth.ScopeAnchorsRead, false},
+		{http.MethodGet, "/domains", "", auth.ScopeWalletAdmin, false},
+		{http.MethodGet, "/domains/" + testDom, "", auth.ScopeWalletAdmin, true},
+		{http.MethodPut, "/domains/" + testDom, "{}", auth.ScopeWalletAdmin, true},
+		{http.MethodDelete, "/domains/" + testDom, "", auth.ScopeWalletAdmin, true},
+		{http.MethodGet, "/reports/spend", "", auth.ScopeWalletAdmin, false},
+	}
+	for _, c := range cases {
+		var others []auth.Scope
+		for _, sc := range auth.AllScopes {
+			if sc != c.scope {
+				others = append(others, sc)
+			}
+		}
+		wantDomain := http.StatusUnauthorized
+		if c.hasDomain {
+			wantDomain = http.StatusNoContent
+		}
+		for _, k := range []struct {
+			name     string
+			apiKey   string
+			wantCode int
+		}{
+			{"no_key", "", http.StatusUnauthorized},
+			{"global_without_scope", mustGenerate(t, a, "", others...), http.StatusUnauthorized},
+			{"global_with_scope", mustGenerate(t, a, "", c.scope), http.StatusNoContent},
+			{"domain_without_scope", mustGenerate(t, a, testDom, others...), http.StatusUnauthorized},
+			{"domain_with_scope", mustGenerate(t, a, testDom, c.scope), wantDomain},
+			{"another_domain", mustGenerate(t, a, testDom2, c.scope), http.StatusUnauthorized},
+		} {
+			w := serve(h, c.method, c.path, k.apiKey, c.body)
+			if w.Code != k.wantCode {
+				t.Errorf("%s %s %s: got %d want %d (%s)", c.method, c.path, k.name, w.Code, k.wantCode, w.Body)
+			}
+		}
+	}
+
+	// No API key is required.
+	if w := serve(h, http.MethodGet, "/info", "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("/info: got %d want %d", w.Code, http.StatusNoContent)
+	}
+}
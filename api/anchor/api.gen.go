@@ -8,6 +8,7 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -15,6 +16,7 @@ import (
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -40,6 +42,14 @@ type Anchor struct {
 	Version int `json:"version"`
 }
 
+// AnchorList defines model for AnchorList.
+type AnchorList struct {
+	Anchors []AnchorRecord `json:"anchors"`
+
+	// Cursor to get the next page. Not set if this is the last page.
+	NextCursor *string `json:"next_cursor,omitempty"`
+}
+
 // AnchorRecord defines model for AnchorRecord.
 type AnchorRecord struct {
 	Anchor Anchor `json:"anchor"`
@@ -50,14 +60,123 @@ type AnchorRecord struct {
 	// Bitcoin transaction ID in hexadecimal string.
 	Btctx string `json:"btctx"`
 
+	// Total output amount in satoshis returned as change. Not set if unknown.
+	ChangeAmount *int64 `json:"change_amount,omitempty"`
+
 	// Comfirmations of the Bitcoin transaction.
 	Confirmations int `json:"confirmations"`
 
+	// Fee in satoshis paid by the Bitcoin transaction. Not set if unknown.
+	Fee *int64 `json:"fee,omitempty"`
+
+	// Total input amount in satoshis of the Bitcoin transaction. Not set if unknown.
+	InputAmount *int64 `json:"input_amount,omitempty"`
+
+	// Timestamp when the confirmations were checked.
+	LastChecked *int `json:"last_checked,omitempty"`
+
+	// Key/value data that is not embedded in the Bitcoin transaction.
+	// At most 16 keys of 1 to 64 characters in `[A-Za-z0-9_.-]`,
+	// and values of at most 512 characters in UTF-8 without control characters.
+	Metadata *Metadata `json:"metadata,omitempty"`
+
 	// Arbitrary string that is not embedded in the Bitcoin transaction.
 	Note *string `json:"note,omitempty"`
 
+	// Status of the anchor. `pending` `broadcast` `confirmed` `final` `failed` `reorged`
+	// `final` means the Bitcoin transaction has enough confirmations (6 by default, configurable by the gateway).
+	Status string `json:"status"`
+
+	// Why the anchor is `failed` or `reorged`.
+	StatusReason *string `json:"status_reason,omitempty"`
+
 	// Timestamp in Bitcoin block chain.
 	Time int `json:"time"`
+
+	// Virtual size in vbytes of the Bitcoin transaction. Not set if unknown.
+	Vsize *int `json:"vsize,omitempty"`
+}
+
+// AnchorRecordUpdate defines model for AnchorRecordUpdate.
+type AnchorRecordUpdate struct {
+
+	// BBc-1 domain name to set. Not changed if not given, and removed if empty.
+	// Must be valid UTF-8 without control characters.
+	Bbc1name *string `json:"bbc1name,omitempty"`
+
+	// Key/value data that is not embedded in the Bitcoin transaction.
+	// At most 16 keys of 1 to 64 characters in `[A-Za-z0-9_.-]`,
+	// and values of at most 512 characters in UTF-8 without control characters.
+	Metadata *Metadata `json:"metadata,omitempty"`
+
+	// Note to set. Not changed if not given, and removed if empty.
+	// Must be valid UTF-8 without control characters except line feeds and tabs.
+	Note *string `json:"note,omitempty"`
+}
+
+// AnchorRegistration defines model for AnchorRegistration.
+type AnchorRegistration struct {
+
+	// Key/value data that is not embedded in the Bitcoin transaction.
+	// At most 16 keys of 1 to 64 characters in `[A-Za-z0-9_.-]`,
+	// and values of at most 512 characters in UTF-8 without control characters.
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// Domain defines model for Domain.
+type Domain struct {
+	// Embedded struct due to allOf(#/components/schemas/DomainSettings)
+	DomainSettings `yaml:",inline"`
+	// Embedded fields due to inline allOf schema
+
+	// Time the domain was created (Unix time).
+	CreatedAt int `json:"created_at"`
+
+	// BBc-1 domain ID in hexadecimal string.
+	Domain string `json:"domain"`
+
+	// Time the domain was updated (Unix time).
+	UpdatedAt int `json:"updated_at"`
+}
+
+// DomainList defines model for DomainList.
+type DomainList struct {
+	Domains []Domain `json:"domains"`
+}
+
+// DomainSettings defines model for DomainSettings.
+type DomainSettings struct {
+
+	// Maximum fee in satoshis spent per day (UTC). Unlimited if not given or 0.
+	DailyBudget *int64 `json:"daily_budget,omitempty"`
+
+	// Maximum number of anchors per day (UTC). Unlimited if not given or 0.
+	DailyQuota *int `json:"daily_quota,omitempty"`
+
+	// Fee of anchor transactions. The default fee of the gateway is used if not given.
+	FeeTier *string `json:"fee_tier,omitempty"`
+
+	// Type of public_key, ECDSA secp256k1 or P-256 as used by BBc-1.
+	KeyType *string `json:"key_type,omitempty"`
+
+	// Maximum fee in satoshis spent per month (UTC). Unlimited if not given or 0.
+	MonthlyBudget *int64 `json:"monthly_budget,omitempty"`
+
+	// Maximum number of anchors per month (UTC). Unlimited if not given or 0.
+	MonthlyQuota *int `json:"monthly_quota,omitempty"`
+
+	// Display name of the domain. Must be valid UTF-8 without control characters.
+	Name *string `json:"name,omitempty"`
+
+	// Bitcoin networks allowed to anchor. All networks are allowed if not given or empty.
+	Networks *[]string `json:"networks,omitempty"`
+
+	// Contact of the owner. Must be valid UTF-8 without control characters.
+	Owner *string `json:"owner,omitempty"`
+
+	// Public key of the domain in SEC 1 form (compressed or uncompressed) in hexadecimal string.
+	// The owner of the domain proves the ownership with it to get API Keys. Requires key_type.
+	PublicKey *string `json:"public_key,omitempty"`
 }
 
 // Error defines model for Error.
@@ -70,17 +189,264 @@ type Error struct {
 	ErrorDescription *string `json:"error_description,omitempty"`
 }
 
+// HistoryEntry defines model for HistoryEntry.
+type HistoryEntry struct {
+
+	// Who made the change. `system` for workers and the tracker, `apikey:<API key ID>` for requests, or `api:<client IP>` for requests without API keys.
+	Actor string `json:"actor"`
+
+	// Changed field of the AnchorRecord.
+	// `btctx` `confirmations` `status` `status_reason` `last_checked` `bbc1name` `note` `metadata`
+	Field string `json:"field"`
+
+	// New value in string. Times are in RFC 3339, and metadata is in JSON. Empty if not set.
+	New string `json:"new"`
+
+	// Old value in string. Times are in RFC 3339, and metadata is in JSON. Empty if not set.
+	Old string `json:"old"`
+
+	// Time of the change (Unix time).
+	Time int `json:"time"`
+}
+
+// HistoryList defines model for HistoryList.
+type HistoryList struct {
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// Info defines model for Info.
+type Info struct {
+
+	// Target Bitcoin network. `Mainnet` `Testnet3` `Testnet4`(unsupported)
+	Chain string `json:"chain"`
+
+	// Version dependent features of Bitcoin Core detected.
+	CoreFeatures []string `json:"core_features"`
+
+	// Version of Bitcoin Core (bitcoin-cli) detected.
+	CoreVersion string `json:"core_version"`
+
+	// The oldest version of Bitcoin Core supported.
+	MinCoreVersion string `json:"min_core_version"`
+}
+
+// Metadata defines model for Metadata.
+type Metadata struct {
+	AdditionalProperties map[string]string `json:"-"`
+}
+
+// Registration defines model for Registration.
+type Registration struct {
+
+	// Bitcoin transaction ID in hexadecimal string, set after the anchor transaction is sent.
+	Btctx *string `json:"btctx,omitempty"`
+
+	// BBc-1 digest in hexadecimal string.
+	Digest string `json:"digest"`
+
+	// BBc-1 domain ID in hexadecimal string.
+	Domain string `json:"domain"`
+
+	// Reason of the failure, set if `status` is `failed`.
+	Reason *string `json:"reason,omitempty"`
+
+	// Status of the registration. `queued` `pending` `broadcast` `confirmed` `final` `failed` `reorged`
+	Status string `json:"status"`
+}
+
+// SpendReport defines model for SpendReport.
+type SpendReport struct {
+	Rows []SpendRow `json:"rows"`
+}
+
+// SpendRow defines model for SpendRow.
+type SpendRow struct {
+
+	// Number of anchors.
+	Anchors int `json:"anchors"`
+
+	// ID of the API key that registered the anchors. Set if grouped by API key and known.
+	Apikey *string `json:"apikey,omitempty"`
+
+	// BBc-1 domain ID in hexadecimal string. Set if grouped by domain.
+	Domain *string `json:"domain,omitempty"`
+
+	// Total fee in satoshis.
+	Fee int64 `json:"fee"`
+
+	// Month in UTC. Set if grouped by month.
+	Month *string `json:"month,omitempty"`
+
+	// Number of anchors whose cost is unknown, e.g. stored from existing Bitcoin transactions.
+	Unpriced int `json:"unpriced"`
+
+	// Total virtual size in vbytes.
+	Vsize int `json:"vsize"`
+}
+
+// Accepted defines model for Accepted.
+type Accepted Registration
+
 // BadRequest defines model for BadRequest.
 type BadRequest Error
 
+// BudgetExceeded defines model for BudgetExceeded.
+type BudgetExceeded Error
+
+// Conflict defines model for Conflict.
+type Conflict Error
+
+// DomainPolicy defines model for DomainPolicy.
+type DomainPolicy Error
+
 // ErrAnchorNotFound defines model for ErrAnchorNotFound.
 type ErrAnchorNotFound Error
 
+// ErrDomainNotFound defines model for ErrDomainNotFound.
+type ErrDomainNotFound Error
+
 // InternalServerError defines model for InternalServerError.
 type InternalServerError Error
 
+// ServiceUnavailable defines model for ServiceUnavailable.
+type ServiceUnavailable Error
+
+// TransactionRejected defines model for TransactionRejected.
+type TransactionRejected Error
+
+// Unauthorized defines model for Unauthorized.
+type Unauthorized Error
+
+// GetAnchorsDomainsDomainDigestsParams defines parameters for GetAnchorsDomainsDomainDigests.
+type GetAnchorsDomainsDomainDigestsParams struct {
+
+	// Maximum number of anchors in a page.
+	Limit *int `json:"limit,omitempty"`
+
+	// `next_cursor` of the previous page.
+	Cursor *string `json:"cursor,omitempty"`
+
+	// Lists anchors whose timestamp is later than or equal to this (Unix time).
+	Since *int `json:"since,omitempty"`
+
+	// Lists anchors whose timestamp is earlier than this (Unix time).
+	Until *int `json:"until,omitempty"`
+
+	// Lists anchors in one of the statuses.
+	Status *[]string `json:"status,omitempty"`
+
+	// Lists anchors that have all the metadata. `key=value` matches the value, and `key` matches any value.
+	Meta *[]string `json:"meta,omitempty"`
+}
+
+// PatchAnchorsDomainsDomainDigestsDigestJSONBody defines parameters for PatchAnchorsDomainsDomainDigestsDigest.
+type PatchAnchorsDomainsDomainDigestsDigestJSONBody AnchorRecordUpdate
+
+// PostAnchorsDomainsDomainDigestsDigestJSONBody defines parameters for PostAnchorsDomainsDomainDigestsDigest.
+type PostAnchorsDomainsDomainDigestsDigestJSONBody AnchorRegistration
+
+// PostAnchorsDomainsDomainDigestsDigestParams defines parameters for PostAnchorsDomainsDomainDigestsDigest.
+type PostAnchorsDomainsDomainDigestsDigestParams struct {
+
+	// Registers asynchronously if true.
+	Async *bool `json:"async,omitempty"`
+
+	// Arbitrary unique string given by the client, e.g. UUID.
+	IdempotencyKey *string `json:"Idempotency-Key,omitempty"`
+}
+
+// PutDomainsDomainJSONBody defines parameters for PutDomainsDomain.
+type PutDomainsDomainJSONBody DomainSettings
+
+// GetReportsSpendParams defines parameters for GetReportsSpend.
+type GetReportsSpendParams struct {
+
+	// Groups the totals by these. All anchors are totaled into one row if empty.
+	By *[]string `json:"by,omitempty"`
+
+	// Totals anchors of the BBc-1 domain ID in hexadecimal string.
+	Domain *string `json:"domain,omitempty"`
+
+	// Totals anchors whose timestamp is later than or equal to this (Unix time).
+	Since *int `json:"since,omitempty"`
+
+	// Totals anchors whose timestamp is earlier than this (Unix time).
+	Until *int `json:"until,omitempty"`
+
+	// Returns the report in CSV with a header line if `csv`.
+	Format *string `json:"format,omitempty"`
+}
+
+// PatchAnchorsDomainsDomainDigestsDigestJSONRequestBody defines body for PatchAnchorsDomainsDomainDigestsDigest for application/json ContentType.
+type PatchAnchorsDomainsDomainDigestsDigestJSONRequestBody PatchAnchorsDomainsDomainDigestsDigestJSONBody
+
+// PostAnchorsDomainsDomainDigestsDigestJSONRequestBody defines body for PostAnchorsDomainsDomainDigestsDigest for application/json ContentType.
+type PostAnchorsDomainsDomainDigestsDigestJSONRequestBody PostAnchorsDomainsDomainDigestsDigestJSONBody
+
+// PutDomainsDomainJSONRequestBody defines body for PutDomainsDomain for application/json ContentType.
+type PutDomainsDomainJSONRequestBody PutDomainsDomainJSONBody
+
+// Getter for additional properties for Metadata. Returns the specified
+// element and whether it was found
+func (a Metadata) Get(fieldName string) (value string, found bool) {
+	if a.AdditionalProperties != nil {
+		value, found = a.AdditionalProperties[fieldName]
+	}
+	return
+}
+
+// Setter for additional properties for Metadata
+func (a *Metadata) Set(fieldName string, value string) {
+	if a.AdditionalProperties == nil {
+		a.AdditionalProperties = make(map[string]string)
+	}
+	a.AdditionalProperties[fieldName] = value
+}
+
+// Override default JSON handling for Metadata to handle AdditionalProperties
+func (a *Metadata) UnmarshalJSON(b []byte) error {
+	object := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &object)
+	if err != nil {
+		return err
+	}
+
+	if len(object) != 0 {
+		a.AdditionalProperties = make(map[string]string)
+		for fieldName, fieldBuf := range object {
+			var fieldVal string
+			err := json.Unmarshal(fieldBuf, &fieldVal)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("error unmarshaling field %s", fieldName))
+			}
+			a.AdditionalProperties[fieldName] = fieldVal
+		}
+	}
+	return nil
+}
+
+// Override default JSON handling for Metadata to handle AdditionalProperties
+func (a Metadata) MarshalJSON() ([]byte, error) {
+	var err error
+	object := make(map[string]json.RawMessage)
+
+	for fieldName, field := range a.AdditionalProperties {
+		object[fieldName], err = json.Marshal(field)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("error marshaling '%s'", fieldName))
+		}
+	}
+	return json.Marshal(object)
+}
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Gets anchors embedded in the Bitcoin transaction specified by ID.
+	// (GET /anchors/btctx/{txid})
+	GetAnchorsBtctxTxid(w http.ResponseWriter, r *http.Request, txid string)
+	// Lists anchors of the BBc-1 domain specified by ID.
+	// (GET /anchors/domains/{domain}/digests)
+	GetAnchorsDomainsDomainDigests(w http.ResponseWriter, r *http.Request, domain string, params GetAnchorsDomainsDomainDigestsParams)
 	// Gets the anchor specified by BBc-1 domain ID and BBc-1 digest.
 	// (GET /anchors/domains/{domain}/digests/{digest})
 	GetAnchorsDomainsDomainDigestsDigest(w http.ResponseWriter, r *http.Request, domain string, digest string)
@@ -89,7 +455,28 @@ type ServerInterface interface {
 	PatchAnchorsDomainsDomainDigestsDigest(w http.ResponseWriter, r *http.Request, domain string, digest string)
 	// Registers an anchor with specified BBc-1 domain ID and BBc-1 digest.
 	// (POST /anchors/domains/{domain}/digests/{digest})
-	PostAnchorsDomainsDomainDigestsDigest(w http.ResponseWriter, r *http.Request, domain string, digest string)
+	PostAnchorsDomainsDomainDigestsDigest(w http.ResponseWriter, r *http.Request, domain string, digest string, params PostAnchorsDomainsDomainDigestsDigestParams)
+	// Gets the change history of the anchor specified by BBc-1 domain ID and BBc-1 digest.
+	// (GET /anchors/domains/{domain}/digests/{digest}/history)
+	GetAnchorsDomainsDomainDigestsDigestHistory(w http.ResponseWriter, r *http.Request, domain string, digest string)
+	// Lists the settings of all BBc-1 domains.
+	// (GET /domains)
+	GetDomains(w http.ResponseWriter, r *http.Request)
+	// Deletes the settings of the BBc-1 domain specified by ID.
+	// (DELETE /domains/{domain})
+	DeleteDomainsDomain(w http.ResponseWriter, r *http.Request, domain string)
+	// Gets the settings of the BBc-1 domain specified by ID.
+	// (GET /domains/{domain})
+	GetDomainsDomain(w http.ResponseWriter, r *http.Request, domain string)
+	// Creates or replaces the settings of the BBc-1 domain specified by ID.
+	// (PUT /domains/{domain})
+	PutDomainsDomain(w http.ResponseWriter, r *http.Request, domain string)
+	// Gets information about the gateway and the Bitcoin node behind it.
+	// (GET /info)
+	GetInfo(w http.ResponseWriter, r *http.Request)
+	// Totals the cost of anchors per month, BBc-1 domain and API key.
+	// (GET /reports/spend)
+	GetReportsSpend(w http.ResponseWriter, r *http.Request, params GetReportsSpendParams)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -100,6 +487,131 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
 
+// GetAnchorsBtctxTxid operation middleware
+func (siw *ServerInterfaceWrapper) GetAnchorsBtctxTxid(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "txid" -------------
+	var txid string
+
+	err = runtime.BindStyledParameter("simple", false, "txid", chi.URLParam(r, "txid"), &txid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter txid: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"anchors:read"})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAnchorsBtctxTxid(w, r, txid)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetAnchorsDomainsDomainDigests operation middleware
+func (siw *ServerInterfaceWrapper) GetAnchorsDomainsDomainDigests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain string
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter domain: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"anchors:read"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAnchorsDomainsDomainDigestsParams
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter limit: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+	if paramValue := r.URL.Query().Get("cursor"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", r.URL.Query(), &params.Cursor)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter cursor: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "since" -------------
+	if paramValue := r.URL.Query().Get("since"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "since", r.URL.Query(), &params.Since)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter since: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "until" -------------
+	if paramValue := r.URL.Query().Get("until"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "until", r.URL.Query(), &params.Until)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter until: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+	if paramValue := r.URL.Query().Get("status"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "status", r.URL.Query(), &params.Status)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter status: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "meta" -------------
+	if paramValue := r.URL.Query().Get("meta"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "meta", r.URL.Query(), &params.Meta)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter meta: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAnchorsDomainsDomainDigests(w, r, domain, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // GetAnchorsDomainsDomainDigestsDigest operation middleware
 func (siw *ServerInterfaceWrapper) GetAnchorsDomainsDomainDigestsDigest(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -124,6 +636,8 @@ func (siw *ServerInterfaceWrapper) GetAnchorsDomainsDomainDigestsDigest(w http.R
 		return
 	}
 
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"anchors:read"})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetAnchorsDomainsDomainDigestsDigest(w, r, domain, digest)
 	}
@@ -159,6 +673,8 @@ func (siw *ServerInterfaceWrapper) PatchAnchorsDomainsDomainDigestsDigest(w http
 		return
 	}
 
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"anchors:refresh"})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.PatchAnchorsDomainsDomainDigestsDigest(w, r, domain, digest)
 	}
@@ -194,10 +710,275 @@ func (siw *ServerInterfaceWrapper) PostAnchorsDomainsDomainDigestsDigest(w http.
 		return
 	}
 
-	ctx = context.WithValue(ctx, ApiKeyScopes, []string{""})
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"anchors:register"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostAnchorsDomainsDomainDigestsDigestParams
+
+	// ------------- Optional query parameter "async" -------------
+	if paramValue := r.URL.Query().Get("async"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "async", r.URL.Query(), &params.Async)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter async: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey string
+		n := len(valueList)
+		if n != 1 {
+			http.Error(w, fmt.Sprintf("Expected one value for Idempotency-Key, got %d", n), http.StatusBadRequest)
+			return
+		}
+
+		err = runtime.BindStyledParameter("simple", false, "Idempotency-Key", valueList[0], &IdempotencyKey)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid format for parameter Idempotency-Key: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAnchorsDomainsDomainDigestsDigest(w, r, domain, digest, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetAnchorsDomainsDomainDigestsDigestHistory operation middleware
+func (siw *ServerInterfaceWrapper) GetAnchorsDomainsDomainDigestsDigestHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain string
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter domain: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Path parameter "digest" -------------
+	var digest string
+
+	err = runtime.BindStyledParameter("simple", false, "digest", chi.URLParam(r, "digest"), &digest)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter digest: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"anchors:read"})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAnchorsDomainsDomainDigestsDigestHistory(w, r, domain, digest)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetDomains operation middleware
+func (siw *ServerInterfaceWrapper) GetDomains(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"wallet:admin"})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetDomains(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteDomainsDomain operation middleware
+func (siw *ServerInterfaceWrapper) DeleteDomainsDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain string
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter domain: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"wallet:admin"})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteDomainsDomain(w, r, domain)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetDomainsDomain operation middleware
+func (siw *ServerInterfaceWrapper) GetDomainsDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain string
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter domain: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"wallet:admin"})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetDomainsDomain(w, r, domain)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PutDomainsDomain operation middleware
+func (siw *ServerInterfaceWrapper) PutDomainsDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain string
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter domain: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"wallet:admin"})
 
 	var handler = func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.PostAnchorsDomainsDomainDigestsDigest(w, r, domain, digest)
+		siw.Handler.PutDomainsDomain(w, r, domain)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetInfo operation middleware
+func (siw *ServerInterfaceWrapper) GetInfo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetInfo(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetReportsSpend operation middleware
+func (siw *ServerInterfaceWrapper) GetReportsSpend(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"wallet:admin"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetReportsSpendParams
+
+	// ------------- Optional query parameter "by" -------------
+	if paramValue := r.URL.Query().Get("by"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "by", r.URL.Query(), &params.By)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter by: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "domain" -------------
+	if paramValue := r.URL.Query().Get("domain"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "domain", r.URL.Query(), &params.Domain)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter domain: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "since" -------------
+	if paramValue := r.URL.Query().Get("since"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "since", r.URL.Query(), &params.Since)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter since: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "until" -------------
+	if paramValue := r.URL.Query().Get("until"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "until", r.URL.Query(), &params.Until)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter until: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "format" -------------
+	if paramValue := r.URL.Query().Get("format"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "format", r.URL.Query(), &params.Format)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter format: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetReportsSpend(w, r, params)
 	}
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -244,6 +1025,12 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		HandlerMiddlewares: options.Middlewares,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/anchors/btctx/{txid}", wrapper.GetAnchorsBtctxTxid)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/anchors/domains/{domain}/digests", wrapper.GetAnchorsDomainsDomainDigests)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/anchors/domains/{domain}/digests/{digest}", wrapper.GetAnchorsDomainsDomainDigestsDigest)
 	})
@@ -253,6 +1040,27 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/anchors/domains/{domain}/digests/{digest}", wrapper.PostAnchorsDomainsDomainDigestsDigest)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/anchors/domains/{domain}/digests/{digest}/history", wrapper.GetAnchorsDomainsDomainDigestsDigestHistory)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/domains", wrapper.GetDomains)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/domains/{domain}", wrapper.DeleteDomainsDomain)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/domains/{domain}", wrapper.GetDomainsDomain)
+	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/domains/{domain}", wrapper.PutDomainsDomain)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/info", wrapper.GetInfo)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/reports/spend", wrapper.GetReportsSpend)
+	})
 
 	return r
 }
@@ -260,29 +1068,107 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/9RXXXPbthL9Kxjc+5A7w0ikrO83O/ZNPUlaj+POtJPxWCCwkpCQAAssbasZ/vcOAIoS",
-	"LaZ2naTTPgnEx+7B4pzd1WfKdV5oBQotnX+mBmyhlQX/ccLEJfxWgkX3xbVCUH7IiiKTnKHUqv/RauXm",
-	"LF9DztzovwaWdE7/09+Z7odV2z8zRhtaVVVEBVhuZOGM0Dk9V7csk4KY4JAY4CBvQUTEAJZGWcIU8ad7",
-	"tIromTHHiq+1+VHj/3WpxPdHGPwRpZEsnccvIDtXCEax7D2YWzDB2uPY4J7lRQZ+GI7QFPnqbj4vFdwX",
-	"wBHETViJwo6bh+jIbifxO8iaWaI5L41xcSwyYBaIA8I4ktJ6uF/9bOG2tcedt47YNN48uUI43agwugCD",
-	"MpCOr5n0IWk7umJmBUhOJHItFVGAd9p86pHFOyaVAlyQxRVYVIBHu+Fw8aJUtiwKbRDE/1zotnGm9Tka",
-	"UdwUbsKikWrlYiLkquZ8G8PJCX+ZkLBKpCJruGcCuMxZRsLpXsvFaDyZzljKRbyMk8HRcDSexP4blnES",
-	"+2+3Hvtvtx7X++vvTmw674xPjc2vkvPTp8Db+ufx1v84bvzHgy2+uMETb+8Dsfvugocyh47HkzlYZHlB",
-	"IE9BCBAOH66BBBa0cCXjZDAczsaDaWNfKoQVGOfgFoyVuiMAtTzr9R5ZJAuyGIxGixfonqu0QLTKNi0S",
-	"JIceqoi6FCQNCDr/0LiLal7WN2zeoSHLdWNKpx+Bo8MaIF0C10YcEp01Avgz5dUyqSKapjxRrCu8rbd3",
-	"WwiuGRJpfbJ6GPKthNAwZRl3Ntq8WOsV3Giz6nrfFDnedyA4tPlEEo5ngykkfDycTEWyHI1AJGwkpmNI",
-	"WDoYjsczlgyXk8kknU5maZoORnwyHI+GR9MkTpezZNwFkmu1lCb3GdYegn2l890y0csnRWU6OOqio9LY",
-	"8SDHJpVomNnUV/7K94As0+ROm0w8R3JSNS7STPNPxDO5S3KzZPyoIGrWbpnQ6KEd8y41NKWwLQPYTrfx",
-	"+92EawHkTuKaFAaW8p4s6qq4aIeonsX7G6XxxpfnrlB1FM6Hft+BtWwFBLV/nNJCOzvRqz2KN71A79Db",
-	"g7hta7e7UUd4XG0EXhqJm/dO+SE2x4V8Axs3cimfroEJcEZCGqC/vDy+OH/55uzXnXcWTvhKLdVSb1sP",
-	"xl09qyKaSQ7KesLUVi7eHr86u/nhp7enZ5eBT+gvGrLKydUr8poh3LEN3cu+NO4lvdht1wUoVkg6p0e9",
-	"uBfTiBYM1x5+P5DF9kNqsv3PYVD1Q9J0E35Qud0r8CXXccOz6FzQOX0NGDKgPQ02ws9pOB9+aNRuWwdx",
-	"3NFxbZ7VDbZSeEf/8xrQeqKEqxJbcg7WLsss2xCmRNMH7YpdMOabr2FA2gWguVF/rwv3R44eP3LYHVcR",
-	"HT3FWVf36slZ5jkzm64bF8DlUoIg6YY87EJcBPa7Jq8TtrJOEXVlu64cYwzLAcG4hWd1NjQKEnHc2wmk",
-	"KdI7JaIpYb/r/Z7tUBX99Q7yCxfZEf3xi3z7trO69qrm60OBXrjpZ0h0eJh63zfa+deKo8ZiXfkoC8EQ",
-	"vFQsMiybVuNbCkfbjqR5oe0/O2tewkpaDIiJO58Bgv07kmdySLufFStxrY38HYLhr+NCXcV9ItvW7w/X",
-	"TkH7NHH3B+P/Htd08F3OjhPPIkQV0T4r5CfY2D43wHxvuj8pwEXaTXqoDnpIuaXJ6JxSB7O22vQHzT+Q",
-	"3czFOfFdxnX1RwAAAP//7dyUR0ASAAA=",
+	"H4sIAAAAAAAC/+x9eXPcNpb4V0Hx9/vDrqJa7FNH1VStLCkZbRLHJcuT3bVc3SD52I0xCTAAqFbHpe++",
+	"hYNnow9dmWQn+cctEgQeHt59IN+8iGU5o0Cl8E6/eRxEzqgA/cdZFEEuIVa/I0YlUKl+4jxPSYQlYfTw",
+	"n4JR9UxEC8iw+vX/OSTeqff/DuuJD81bcXgNcyIk1596Dw8PvheDiDjJ9YNT72YBCNNowTgiAoVA6Bxx",
+	"/Q1wiBEWKxotOKOsEOkKMY4WWKAEkxRiH3GQBacCyQWg5kI9z/cWgGPgelM/MgO6+t1e/dP1j0gyNAep",
+	"58g5m3MQQn1fb0+ucvBOPSE5oXO1hwffe4fja/i1ACFfDFOXnDPuQtEVvcMpiRE3CyIOEZC75v4xRfrr",
+	"nqdAK+I5yMv7CCDe6yDhHmd5CvqnhuHUC2U0X56ehnqqKZRz+WbAdP0IY5ZhQvXhFAJiVOSISIHMBChh",
+	"vDxkyTEVOFKfogRA9NCHFLAAJPkK4bmahFB9GBTuJcqBExajN59uzt/q3T0TlwrWBACxRK/hAMps1tCU",
+	"Bd8OtntU4xeA5ljCEq8ch3BLZ278zd6MgsFbVL61M0ydo26p2u05o0lKIvmsU4zJHISc4pQDjldTuCdC",
+	"ig1neaHHIjsWmbEvhXiL7fbkvpPvl0QuEKZMLoCjqxiynEmg0ergB1j5t9SeAW/wvPlEPe0Md0oMx2E5",
+	"0TSrTovUs06/wmqaEZFhGS3qEU1opmbBmTnGC005H1hKotXzjhKTdDX9tWASP4Mr9SzlaejJHFwoWcY4",
+	"Z8uX5b3WiRGBKFPElrIlxChc6eMTICWhc/F0tqMgl4x/nVImp3ZyxVTDmvVceJy9GQ1O6iEZo3Kxa1DJ",
+	"wq5B5ugvOT/TiH7P5HesoPHr6wuznkZtolbcoCcuOTd0+QjItlCmnkqjXC+6Sb6Yw6xgewm66s65Yb9X",
+	"VAKnOP0I/A64me05Oy4o3OcQSYin5o17x2cU1SORHqEZkkVRwbmSSblhPQUIjiQqXkTelru1K9arOXGj",
+	"cEIi+ETxHSYpDs2Gn4waymKYFo3J3Kh5R2TE9MnFUAmD8huHSEqxBP5Skqi1uBUuMZZYSMbXodmAt5va",
+	"cLiGf+ojfhbi5P00AZhKxqYpW26R7A7DZYkF4hYIFEKECwFa3Ct7hwgkGUMpW76wMt8MgZHmmdKaLEW5",
+	"Vn6lTG8if6sobyOk1rXyfhoXQrYeUEanQmIaYx63XmRECELnU0LzoqnS5b2dmM6nFs5ptMCEtoaUO7K6",
+	"/BPFhVwwTn575lkXzYk2yI4PV+grrNTp2T0gK9hLnZmUlKtl4EucrXtNYnyQjmP1yy+/TM8KuQAq1b6h",
+	"rbzW/aYKOuNragpSv3LOcuCSGB9UH8G6r3aDuTLGK9IxSr6HZj9hQikoWrgBISnIYf1zNHtTUFHkOeMS",
+	"4reeXx+IZ7/z/C6kvmdswXUY3r2LDvrIvEXKsoJ7HENEMpwi83WvtcR4cnR8gsMoDpKgPxiOxpOjQP8N",
+	"SdAPhiP7PojBvg/sePu3EzZ91Bth02/R1cU+4JXrR0G5/iSo1g8GJXxBBU9Q7gcC9bcLPEkycBweyUBI",
+	"nOUIshDiGOLSzTNU0IKrP+kPRqOTyeC4mp9QCXPgaoE74MLpzJuZkH3fQ7P+DM0G4/HsjVTHpcQho+mq",
+	"RQT99RUefE952oQr/v5cLedburQ7rM6hIpYv1VQsVBJDwWpA+pEIuU7mRoDqn0RCJnZxrJnrGiLGY++h",
+	"Wgxzjlfqb+UvT6OCC8bXcXOunzdjHca9xnPoofdMIgESESWcidDKYgEoxcKOeGWa7mC8RMxmhFokbEDp",
+	"fohUs4Vh1Kc4gx3MpIYgucCyNAu6NFzKpIYybONsweYwZXzuYphQRvLeAcH6nHty9eRkcAz9aDI6Oo77",
+	"yXgMcR+P4+MJ9HE4GE0mJ7g/So6OjsLjo5MwDAfj6Gg0GY+Gx/0gTE76ExeQ0QLTOUxxxgrqkIs3TOIU",
+	"sULmhURmkIJUYMmEoiij4XU8D5mpWlRX0K+ULds46/fHx5PByPcSxjMsDYdORt46w/pexGhC1CjCqHAQ",
+	"P8vq110TZNOZHQ+GrqUScFDLdwCt7eaYVAaQa52dew+CINhr59qk2XEshG44FZY8HcL+ZO/TUXJkGi0g",
+	"+gqxA8ZKMywXYNipdZxoCRyQ/XxdTYyH4/7EtWoGEit7fpc0+Kkcp0Qok47TPeMhkRzzleW2Z4oCSFOG",
+	"loynsYvRhMSycNDwR/28HbfsoVkONCZ0rgxWznAcYW0TWwSCMoMTQnGq/jUBKTTjwPhcm7PluwwwFZug",
+	"184qUFbMF52DeTNRNB5DgotU+ublvODKXSqJ38ZH3vZuaQsJeuHN259ywMKl4n9ZrBr7V2dQ7Yvxemtt",
+	"jLehjjnLc2U5c5ahIZIMBU8xYwitkBWmLPqKtHXgMmNO3PR5J8hvjhX+QbgslGAnv2mZcheuJDyPVYfj",
+	"nUaOVZylMqpsnLZgrahzl2b+lMfWG8BxTNTHOP3Q0NQJTgX4HeX9KGXM1I7N1o0+idX2FUPOyR1QH2Ea",
+	"Iw4ZuzNvIMvlqndLfyqERCEgk1H5dPPdwbEOHLNC6gAMZ6makONIAhddsrW/ehHLPN/L8P2PQOdy4Z0O",
+	"xmMHDb2cCHrP5O+2aZ0BySVKCdW5kljoeSUO1/Bhzrx29lOI58CRKEKxEhKyXhtL/WAwWkPTwxZaaqQO",
+	"H0dLj8e8C4yLytPCafpz4p1+3j6bGf/Rxq+9B3/Ns+WAVbwQS7doaca7VTDFjkdvPlFyjxRPvn2Eo/QH",
+	"dxSLPH4UMuz4rcgYDyfB4HinuKtct8aBtABaF3BfKnpwe3Nmyv29OTPXuh/nhNQtcTvk9jgOMRkQk3pc",
+	"x/9P+J5kRWZChw2LUeRAdVIWxXhlM0PoE01JRmRHGimFHLQOaBCY/xxGY0aoWs87DZyEXGdrNoNKiywE",
+	"rhSl9R+fCmZfg7gdIh2TJMDdrkAFQ1NPix7SKTljLjWT0NZIQsRm6ZrgacioguOzJzKcKqOJKuypH6mK",
+	"iHlfGrDX79bYTeUszcM1ZlvlGpa8CFMSqeSmjy7PLz6eIQFRPhhPvvYVlj4cDMYTVKYSwxXScqQFYDnc",
+	"8z31ow1a8+26qrTZtqcTpJ7hsWc9Cp5Kk6304GOp8kmwDoI9CNNtQF0Qkad4ZWynVlq1hx5pET3HHrIh",
+	"W7E52FGOqOLbklW+zlmaNt5zqMZ00WbMniagn70yMqxIspLPJd3WgeBqWPVz5H1x7KQbe2NL6hIG5zal",
+	"Z1GuRz0P4zjOCP2PR+K9Zux1ED/odzri3863E4o+Xp6jvkoxZOiNUmEchGJ9xlFB67/fbjAgbulNuefO",
+	"1DlndyBqlIgFyTUGEJFliFKlIX6Aleiha6MOBSolWNcKDUbRCYajST8eDSYYT44mR8ejydEYh9Gwj3E0",
+	"6kfjYTA6GmN8POzH8TgBPBqFR30YhBgnYYyP8NF41E+SKI77STyIR+MQ4mCI8SAaHCc4GoX4OB4MkiSZ",
+	"9Cej+CSaxONRgqPh0dHJCUwG3l5WbZVybmtiKB+3D0aPRhGLwaAm55CQ+yox1fFxm1mwOgO/RgmOJNOa",
+	"5AIh8Fx7G+qACgHt4Hwz59nM4++K6pY5crUjpznzdyIk46tLKvlqHUs4ki4s/bJgKMOxMRbLyOLMeB8z",
+	"nR1T8gK49WEWoFRy9BW4j2Y4J19hdXpbBMEwKtNeVxf6bzAf25I74evwAs6JHR2lBKhEVx9cgytmtnN2",
+	"WdgsW8bOSzPadVwJgdQRMju3zp9+XfJW0/+2CVR5X8eCjAs/QzPjwlc/bKhlhmbNMJ2KJ1l3fIZmlEn1",
+	"T+lUzTr81w0SOOT+0uHSwlJJwMLocyMykI6vaOFOKLr+7hwNh8MT49eWqyMi1Mv//Pjz+x66VKK+VADK",
+	"OW4Hwl3AMBdGf07j1wZm/LgoU3mshqZfwumxIR3DSCVtGXyYI9rClG6fB6jk9udePk+Lw3d5PuXkLqiu",
+	"aML+dXnjhpGwnidhHKYJYFlwcNg5/zDJRBRDDjQGKlE5Vp13CeI544BikLrsoGPIzEGGOMU0AsVq5eyM",
+	"T5c4TUGah0rGxtOG89Eye3ZaM3oTG9Os5R66AL8JzV8HUUreusH37oLeoN9zBlwzQqfbF9bmRBqDkOhu",
+	"AwzVia0vG7iW7dBcmeFtweEArXvOLhr9qRGCcvvl35qW27g/cOX6Wwj4AVaHRkqpiR+dhrilZxJlTEjU",
+	"n2i9pNDXR5KhyagZ/yMUzT6fHfwPPvgtODiZ9g6+zPxbqsSeXlx/hu1M4/6g8+kjY6rfvJhFleS6YyQC",
+	"z/cEK3ikHgHPPd8reOqdegspc3F6eNiwfg/tJ+Kwr2kI3zcR3J84zqUbVewEoZ+fjPV1MB4nEvimGnMi",
+	"kAAqXz9t+1cdy5PDk5tSUNf6eameVfap4OCXCZjKvGqkptqwXnJ+zoo0fs/kh0KelYmXJyYCm/XUPTT7",
+	"tYBCW2/PyAu2oDUT7hSc3VqYrZmijwq4a1CCep0BOVvub1CYmdhypzGhZ90MC1tuLc/pGK7dkE47SuOK",
+	"yhiTf32qq4vKerfuh5bpjT6IWoCIHvpoaGzOWZGbEFz5mRLO65k/L8RHx/1JmBwnQT9KAL88GzpgKksR",
+	"X5tBnZUYpuKhEyV0hdH2qFvQUTqHg6weG0137tq//qy9/UEw6B8EA2cWhOacRBDvQWdouWC6TlxotW9z",
+	"vT6C3ryHdNmyzWrr1hVVqOBQW21kBI/ITBvU3jnz020MHwV7JpyF10CAOdFy+XVmVTIRooITufqo+N82",
+	"LObkBxdrlb43WuA7QCJiOQjjuwGOFkhxumlC4WV4SS7KcWpfRApULleOyYDK01s6s8CfcsBKcNZ/GrZt",
+	"PUo4CNUjpGA51dG7GZoZW93+2bulKtCFsBAsIjrFZbqfUIsFmxFPVcaoQw7VNgRitBFi82+pYM23pT02",
+	"+2YGPMxKSzHHcmFpaHZo4T7UZtDhN3lP4oeZxtrs0HwoZn6JDoPfRHd0pXZha94pgWILhb0yKO3918HZ",
+	"h6uDHy7/u+YDbE5PVwYT69TZPgj10344J3JRhNreg5AQkh3qcNeaYbhpnKpBIhFQAY1Jz3IcLeBg0Av2",
+	"necwTFl4qHZ5+OPV+eX7j5fGhZeax81hvbs5R9+bfI7XKFX1gp5ye1T4IQeKc+KdesOecUnUAWhSdmJf",
+	"vXBmRK4bva+W+ZshIBOyqgNDyHqFRkL8/GF6fXnz6fr9lsISE8mgunfALKDCSo0qU23kULgDrqMdt7SK",
+	"1GJaaaYOefiI9KCHMJqnLMQp0iygB2qab7OWX7UxlISqi5EoK3WM5RyWuJil6kTQM4+CPgKVIHCs00Of",
+	"BDSo38J6WPLKobFq1AP9QzOPkIBjQ+0Vn13F3qn3PVirTrxT53hzT2J9yhxnIHXh/OfnuBcldyl01Lwl",
+	"zSq1iJW8gGYvwGv6GQ9f/HYX+SAIXqzNrVFC7ehWKLmgRfq6DWIUBJumrmA9bLRx60/6uz9p9X/oj0av",
+	"39J3s4lFRR2CL5vjaZky29CzNN4HMa5mOf3tcPe3jmaypvLW9F+q7c9ekxO9L4qURJFlmK8MK4nK+tkj",
+	"xIFEDhFJiDHEri50VgLPFcuV3SZfFCg7OX2n1G3RG2I8BlsGZSbo3dKrBK0JSwt5iSpf5ywRkQgLNCvH",
+	"OWv0t8oZUwhi/7mwO9glcvYx792ypnL29pE2L2/5P/jdvWxOtitVULUw6K38WgBf1XvRqffWbQ+2QMM7",
+	"HQc6oGSS7eNW6t3ZNdKFqn36Vs/mHO4IK8RWoMw3W++gWFtNCUjR8RRkXa0qTPOk8i9NjvxXZcTrHB8R",
+	"3eyCCyZBaAQtkPaqQnsCoIB5SkpQ9wWvoJKkm8HbnCLZDh6hiNEqF2NiGiA2AWHet6BYqzWwgRnP96rA",
+	"TF1na7wgWxttAjOazXRgZo9ChF3b0dEF7RApk8y0Z5oodU97KH/TAd4Z0hcbWJdIPzKekxpSv8R0ZV42",
+	"TP4OQtTs7kP5bEO8f6sDvI9IUfwBDA4jVhRlvKTp0bmdpmFq/NlUd5vwSi+jqXReSldXVvlerlLztEzD",
+	"WxWhV8ZTCEBrb+dnuQC+JAI23jSkpiDy0VcXPV6fX5SB1T3ofvUMui/7C9cpXxtjDXSJIopAiKRI05Wt",
+	"PHejWZPuIBjsJr/q9qnf337f/tH6LR5/QkO6eXZN3uuaguosmwkiJ2f+G5mXu5NlGzZSs+zujbxCV+0X",
+	"dUYyWrgEog5LWg3f7o8q66SEq+Hslp6lQrehiEaFkG9LhKyVUNUJ6cC4rsq0jk/IYtWP0hjBIU9xZAFZ",
+	"LlhamyRmNkxNPae2w0xvi0BEOVimCkrYWI8SNLVvuDCVJi4x+0GhZD9BqwXLOxavXti2aDVJmWsROlJ9",
+	"5Mj7VeK295d4fJZ41LTflZDXZfGeZLbTZCMXvIz0ZMJpqbSvx6ouHTPJ5c7NZjqmYNoEbumZ0r9cAWUJ",
+	"t74QTeBMB+tbGppxMleeRsciMnHN2odWRg2eN4KtWAf+c3Op32CEFqzgolHu0LrhS51bCipBdEtNSERb",
+	"RRpwLQsrkAbBoAa4jYYsg5hgCdbMaPd/Ngwu1YqJo68qFUdjW35c3uiIIkxRWPURq4P7/vJGz6XugFQl",
+	"L+UtkTNkEhca5IaosjOsCTQNlK7okGXRbKcWc10GMbGXrbdVxV7bnYuupamsWl5sjC7o0e6Qh+0Usook",
+	"ZCwFTF0qsW6JLij5tYCyM9ogx3YDmiJZm1v69OnqooKomxnq0LW3QUUmQTQIj+FgiEfxwSjqw8FJeIQP",
+	"BvEkURHtAJ8MHeXwGaHl3/0NoevXE/Tty08f/oXm+7WTL/+4VvwOV3i0DySdq1D1Z3soltZ1jfqjk90f",
+	"VVd1qg8GewDnujtMf3vyeAj/sLrWiKh1ZVuJLlrKci38a836JLX6qHDBoTUSd4YNImtoOgrt/bIgNSFc",
+	"yB46s4PV2KxIJclTMGX6Qnv/mCJQ1c9aeepCBP3SKisLkFImOM+Bxgcq0W8FqFpa2sJw/eWHs5vzvxsV",
+	"aI1ftUADgHZF/1NDDrZo23vFiFuzyHxLyE1hoDH0LzP41aIEloZKenwF6/ev2MEfPHagRakuWxSH0ULV",
+	"K9E5eKffWs9TTLLuM91R33kYQwprDzmT6yOFxGn5rNFSv0E+7yw36blEnpV1rynRGjcF7BBo9cjenzYP",
+	"0Cxnc+cBuvc6qyNqsrnYqM67atxgx5KTt/XKa8rMraMEav+UOEjiQk/X0oDe7xqR2Wnq7qd7Orc5/7no",
+	"xByCcN4A/ui0kV8KjE28v+mUX1oC7Mf9f5HPs8mnMl1egHb+rxonKh9QSLfYrNCmy4wV1ZvLF5rRvM6N",
+	"/Lrgs/lWMoTL9kpdlqaiavZvMXPWZA5tYxmjIBBTlaSzxmUvzm8GJ86IWuFg7ZcP63RveHp46J7mw7+F",
+	"TPkTSYZzbZAKxHg72fRsQaGsk7JmfZO60Y3Kr0gRev59Lrgnor7Lfi3Mp2bpPfto2rJYYcbmFBEOWSFb",
+	"Vx2VcfwWjCEsCI1Vcm8TurluGhOHIgcaN/D+eL8A6S6eqrv/0825hslcklo9rhqIbmnVT6JeiQVbUoQ1",
+	"9ghHVxfC1wJPbUkPkgvIBKR3IDaEXUz7m9DtZ7sC/d+r3iJzUpJJnAobYBdgLsUpi2wwtwN0LkQykzJl",
+	"y/oqwA0ZgXDlTgd8ti1QjbuubSeb6xadLWMfXbZ2Y/a5rXxoW2eoa5MVWP+qUEBnT797iWZ/eDQJgn1q",
+	"IHdD+go1mqPxeHK8H3xNbWdEgiKF84//KBunTH7J3GCp8qaRuJttgsqIKTcDeFoa1xec2T8jceeg6lct",
+	"Smz2zCpRKOFeHio4WmSsWdC3LWCG/Xx7in7ZZ+cnAL7usbulti/Rfy75+91GU3/gB77ptBwcBbd0+/9P",
+	"b5s109j4XyaNVbCWP03tjpDO2+X8TkMUjUtd6NauGhK1KaOBTEOc9/ClGls3zpXX59dPzF1d6pLM/x0A",
+	"5OghkF1yAAA=",
 }
 
 // GetSwagger returns the Swagger specification corresponding to the generated code
@@ -3,35 +3,57 @@
 package api
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/ebiiim/btcgw/api/apikey"
 	"github.com/ebiiim/btcgw/auth"
+	"github.com/ebiiim/btcgw/gw"
 
 	oapimiddleware "github.com/deepmap/oapi-codegen/pkg/chi-middleware"
+	"github.com/getkin/kin-openapi/openapi3filter"
 )
 
 // export
 
 var APIKeyHandlerFromMux = apikey.HandlerFromMux
 
+// APIKeyService requires an admin key (with ScopeKeysAdmin) to create, list and delete API keys.
+// The bootstrap token is accepted as a global admin key if it is not empty.
 type APIKeyService struct {
-	d *auth.DocstoreAuth
+	d         auth.KeyManager
+	bootstrap auth.BootstrapToken
+
+	// Challenges and Domains are optional, and enable owners of BBc-1 domains
+	// to get API keys by proving the ownership with the public keys of the domains.
+	Challenges *auth.DocstoreChallenges
+	Domains    gw.Domains
 }
 
 var _ apikey.ServerInterface = (*APIKeyService)(nil)
 
-func NewAPIKeyService(d *auth.DocstoreAuth) *APIKeyService {
+func NewAPIKeyService(d auth.KeyManager, bootstrap auth.BootstrapToken) *APIKeyService {
 	a := &APIKeyService{
-		d: d,
+		d:         d,
+		bootstrap: bootstrap,
 	}
 	return a
 }
 
+// admin returns the admin key of the request, or nil if it is not an admin key.
+func (a *APIKeyService) admin(ctx context.Context, r *http.Request) (*auth.APIKey, error) {
+	apiKey := r.Header.Get("X-Api-Key")
+	if k := a.bootstrap.Admin(apiKey); k != nil {
+		return k, nil
+	}
+	return a.d.Admin(ctx, apiKey)
+}
+
 func sendAPIKeyServiceError(w http.ResponseWriter, code int, err error, desc string) {
 	var pDesc *string = nil
 	if desc != "" {
@@ -45,22 +67,222 @@ func sendAPIKeyServiceError(w http.ResponseWriter, code int, err error, desc str
 }
 
 func (a *APIKeyService) PostApikeysCreate(w http.ResponseWriter, r *http.Request) {
-	var rdom apikey.BBc1Domain
+	var rdom apikey.APIKeyCreation
 	if err := json.NewDecoder(r.Body).Decode(&rdom); err != nil {
 		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidRequestBody, ErrInvalidRequestBodyDesc)
 		return
 	}
-	if _, err := hex.DecodeString(rdom.Domain); err != nil {
+	global := rdom.GlobalAdmin != nil && *rdom.GlobalAdmin
+	var domID string
+	if !global {
+		if rdom.Domain == nil {
+			sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidAPIKeyCreation, ErrInvalidAPIKeyCreationDesc)
+			return
+		}
+		if b, err := hex.DecodeString(*rdom.Domain); err != nil || len(b) != 32 {
+			sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidAPIKeyCreation, ErrInvalidAPIKeyCreationDesc)
+			return
+		}
+		domID = *rdom.Domain
+	}
+	scopes, ok := parseScopes(rdom.Scopes)
+	if !ok {
+		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidAPIKeyCreation, ErrInvalidAPIKeyCreationDesc)
+		return
+	}
+	var ttl time.Duration
+	if rdom.Ttl != nil {
+		ttl = time.Duration(*rdom.Ttl) * time.Second
+	}
+	ctx := r.Context()
+	admin, err := a.admin(ctx, r)
+	switch {
+	case err != nil:
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyCreationFailed, ErrAPIKeyCreationFailedDesc)
+		return
+	case admin == nil:
+		sendAPIKeyServiceError(w, http.StatusUnauthorized, ErrUnauthorized, ErrUnauthorizedDesc)
+		return
+	}
+	// Admins cannot create keys stronger than themselves.
+	want := &auth.APIKey{ScopeRegisterAll: global, Scopes: scopes}
+	if !admin.Manages(domID) || !admin.HasScopes(want.AllowedScopes()...) {
+		sendAPIKeyServiceError(w, http.StatusForbidden, ErrAPIKeyForbidden, ErrAPIKeyForbiddenDesc)
+		return
+	}
+	note := fmt.Sprintf("Created by API at: %s (admin: %s)", time.Now().Format(time.RFC3339), admin.ID)
+	k, err := a.d.Generate(ctx, domID, global, note, ttl, scopes)
+	if err != nil {
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyCreationFailed, ErrAPIKeyCreationFailedDesc)
+		return
+	}
+	WriteJSON(w, http.StatusOK, convertAPIKey(k))
+}
+
+func (a *APIKeyService) PostApikeysChallenge(w http.ResponseWriter, r *http.Request) {
+	if a.Challenges == nil || a.Domains == nil {
+		sendAPIKeyServiceError(w, http.StatusNotImplemented, ErrOwnershipProofDisabled, ErrOwnershipProofDisabledDesc)
+		return
+	}
+	var rreq apikey.APIKeyChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&rreq); err != nil {
+		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidRequestBody, ErrInvalidRequestBodyDesc)
+		return
+	}
+	bdom, err := hex.DecodeString(rreq.Domain)
+	if err != nil || len(bdom) != 32 {
 		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidParam, ErrInvalidParamDesc)
 		return
 	}
 	ctx := r.Context()
-	k, err := a.d.Generate(ctx, rdom.Domain, false, fmt.Sprintf("Created by API at: %s", time.Now().Format(time.RFC3339)))
+	d, err := a.Domains.Get(ctx, bdom)
+	switch {
+	case errors.Is(err, gw.ErrDomainNotFound):
+		sendAPIKeyServiceError(w, http.StatusNotFound, ErrDomainKeyNotFound, ErrDomainKeyNotFoundDesc)
+		return
+	case err != nil:
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrChallengeFailed, ErrChallengeFailedDesc)
+		return
+	case len(d.PublicKey) == 0:
+		sendAPIKeyServiceError(w, http.StatusNotFound, ErrDomainKeyNotFound, ErrDomainKeyNotFoundDesc)
+		return
+	}
+	ch, err := a.Challenges.Issue(ctx, rreq.Domain)
+	switch {
+	case errors.Is(err, auth.ErrTooManyChallenges):
+		sendAPIKeyServiceError(w, http.StatusTooManyRequests, ErrTooManyChallenges, ErrTooManyChallengesDesc)
+		return
+	case err != nil:
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrChallengeFailed, ErrChallengeFailedDesc)
+		return
+	}
+	WriteJSON(w, http.StatusOK, apikey.APIKeyChallenge{
+		Domain:    ch.DomainID,
+		Nonce:     ch.Nonce,
+		Digest:    hex.EncodeToString(ch.Digest()),
+		ExpiresAt: int(ch.ExpiresAt.Unix()),
+	})
+}
+
+func (a *APIKeyService) PostApikeysClaim(w http.ResponseWriter, r *http.Request) {
+	if a.Challenges == nil || a.Domains == nil {
+		sendAPIKeyServiceError(w, http.StatusNotImplemented, ErrOwnershipProofDisabled, ErrOwnershipProofDisabledDesc)
+		return
+	}
+	var rclm apikey.APIKeyClaim
+	if err := json.NewDecoder(r.Body).Decode(&rclm); err != nil {
+		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidRequestBody, ErrInvalidRequestBodyDesc)
+		return
+	}
+	bdom, err := hex.DecodeString(rclm.Domain)
+	if err != nil || len(bdom) != 32 {
+		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidAPIKeyClaim, ErrInvalidAPIKeyClaimDesc)
+		return
+	}
+	sig, err := hex.DecodeString(rclm.Signature)
+	if err != nil {
+		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidAPIKeyClaim, ErrInvalidAPIKeyClaimDesc)
+		return
+	}
+	scopes, ok := parseScopes(rclm.Scopes)
+	// Domain settings are managed by the gateway, not by the owners.
+	if !ok || (&auth.APIKey{Scopes: scopes}).HasScopes(auth.ScopeWalletAdmin) {
+		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidAPIKeyClaim, ErrInvalidAPIKeyClaimDesc)
+		return
+	}
+	var ttl time.Duration
+	if rclm.Ttl != nil {
+		ttl = time.Duration(*rclm.Ttl) * time.Second
+	}
+	ctx := r.Context()
+	d, err := a.Domains.Get(ctx, bdom)
+	switch {
+	case errors.Is(err, gw.ErrDomainNotFound):
+		sendAPIKeyServiceError(w, http.StatusNotFound, ErrDomainKeyNotFound, ErrDomainKeyNotFoundDesc)
+		return
+	case err != nil:
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyCreationFailed, ErrAPIKeyCreationFailedDesc)
+		return
+	case len(d.PublicKey) == 0:
+		sendAPIKeyServiceError(w, http.StatusNotFound, ErrDomainKeyNotFound, ErrDomainKeyNotFoundDesc)
+		return
+	}
+	// Consumes the challenge before verifying, so that each challenge can be tried only once.
+	ch, err := a.Challenges.Consume(ctx, rclm.Nonce, rclm.Domain)
+	switch {
+	case errors.Is(err, auth.ErrChallengeNotFound):
+		sendAPIKeyServiceError(w, http.StatusForbidden, ErrOwnershipNotProven, ErrOwnershipNotProvenDesc)
+		return
+	case err != nil:
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyCreationFailed, ErrAPIKeyCreationFailedDesc)
+		return
+	}
+	if err := auth.VerifySignature(d.KeyType, d.PublicKey, ch.Digest(), sig); err != nil {
+		sendAPIKeyServiceError(w, http.StatusForbidden, ErrOwnershipNotProven, ErrOwnershipNotProvenDesc)
+		return
+	}
+	note := fmt.Sprintf("Claimed by the domain owner at: %s", time.Now().Format(time.RFC3339))
+	k, err := a.d.Generate(ctx, rclm.Domain, false, note, ttl, scopes)
 	if err != nil {
 		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyCreationFailed, ErrAPIKeyCreationFailedDesc)
 		return
 	}
-	WriteJSON(w, http.StatusOK, &apikey.APIKey{Key: k.Key})
+	WriteJSON(w, http.StatusOK, convertAPIKey(k))
+}
+
+func (a *APIKeyService) PostApikeysRotate(w http.ResponseWriter, r *http.Request) {
+	var rrot apikey.APIKeyRotation
+	if err := json.NewDecoder(r.Body).Decode(&rrot); err != nil {
+		sendAPIKeyServiceError(w, http.StatusBadRequest, ErrInvalidRequestBody, ErrInvalidRequestBodyDesc)
+		return
+	}
+	grace := 24 * time.Hour
+	if rrot.Grace != nil {
+		grace = time.Duration(*rrot.Grace) * time.Second
+	}
+	ctx := r.Context()
+	k, err := a.d.Rotate(ctx, rrot.Key, grace)
+	switch {
+	case err == nil:
+		WriteJSON(w, http.StatusOK, convertAPIKey(k))
+	case errors.Is(err, auth.ErrKeyNotFound):
+		sendAPIKeyServiceError(w, http.StatusNotFound, ErrAPIKeyNotFound, ErrAPIKeyNotFoundDesc)
+	case errors.Is(err, auth.ErrKeyAlreadyRotated):
+		sendAPIKeyServiceError(w, http.StatusConflict, ErrAPIKeyAlreadyRotated, ErrAPIKeyAlreadyRotatedDesc)
+	default:
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyRotationFailed, ErrAPIKeyRotationFailedDesc)
+	}
+}
+
+func (a *APIKeyService) GetApikeysStale(w http.ResponseWriter, r *http.Request, params apikey.GetApikeysStaleParams) {
+	unused := 90 * 24 * time.Hour
+	if params.Unused != nil {
+		unused = time.Duration(*params.Unused) * time.Second
+	}
+	ctx := r.Context()
+	admin, err := a.admin(ctx, r)
+	switch {
+	case err != nil:
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyListFailed, ErrAPIKeyListFailedDesc)
+		return
+	case admin == nil:
+		sendAPIKeyServiceError(w, http.StatusUnauthorized, ErrUnauthorized, ErrUnauthorizedDesc)
+		return
+	}
+	ks, err := a.d.ListStale(ctx, time.Now().Add(-unused))
+	if err != nil {
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyListFailed, ErrAPIKeyListFailedDesc)
+		return
+	}
+	l := apikey.APIKeyList{
+		Keys: make([]apikey.APIKeyInfo, 0, len(ks)),
+	}
+	for _, k := range ks {
+		if admin.Manages(k.DomainID) {
+			l.Keys = append(l.Keys, convertAPIKeyInfo(k))
+		}
+	}
+	WriteJSON(w, http.StatusOK, l)
 }
 
 func (a *APIKeyService) PostApikeysDelete(w http.ResponseWriter, r *http.Request) {
@@ -70,6 +292,24 @@ func (a *APIKeyService) PostApikeysDelete(w http.ResponseWriter, r *http.Request
 		return
 	}
 	ctx := r.Context()
+	admin, err := a.admin(ctx, r)
+	switch {
+	case err != nil:
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyDeletionFailed, ErrAPIKeyDeletionFailedDesc)
+		return
+	case admin == nil:
+		sendAPIKeyServiceError(w, http.StatusUnauthorized, ErrUnauthorized, ErrUnauthorizedDesc)
+		return
+	}
+	k, err := a.d.Lookup(ctx, rkey.Key)
+	if err != nil {
+		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyDeletionFailed, ErrAPIKeyDeletionFailedDesc)
+		return
+	}
+	if k != nil && !admin.Manages(k.DomainID) {
+		sendAPIKeyServiceError(w, http.StatusForbidden, ErrAPIKeyForbidden, ErrAPIKeyForbiddenDesc)
+		return
+	}
 	if err := a.d.Delete(ctx, rkey.Key); err != nil {
 		sendAPIKeyServiceError(w, http.StatusInternalServerError, ErrAPIKeyDeletionFailed, ErrAPIKeyDeletionFailedDesc)
 		return
@@ -87,12 +327,90 @@ func (a *APIKeyService) OAPIValidator() func(next http.Handler) http.Handler {
 	swagger.Servers = nil
 
 	validatorOpts := &oapimiddleware.Options{}
+	validatorOpts.Options.AuthenticationFunc = func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+		r := input.RequestValidationInput.Request
+		if r.Header.Get("X-Api-Key") == "" {
+			return errors.New("X-API-KEY not found")
+		}
+		scopes := make([]auth.Scope, len(input.Scopes))
+		for i, s := range input.Scopes {
+			scopes[i] = auth.Scope(s)
+		}
+		// The domain is checked by the handlers as it is in the request body.
+		k, err := a.admin(ctx, r)
+		if err != nil || k == nil || !k.HasScopes(scopes...) {
+			return errors.New("auth failed")
+		}
+		return nil
+	}
 	return oapimiddleware.OapiRequestValidatorWithOptions(swagger, validatorOpts)
 }
 
 func (a *APIKeyService) Close() error {
 	if err := a.d.Close(); err != nil {
-		return fmt.Errorf("%w (DocstoreAuth: %v)", ErrCouldNotClose, err)
+		return fmt.Errorf("%w (KeyManager: %v)", ErrCouldNotClose, err)
 	}
 	return nil
 }
+
+// parseScopes returns the Scopes of ss, nil if ss is nil. Returns false if any of them is unknown.
+func parseScopes(ss *[]string) ([]auth.Scope, bool) {
+	if ss == nil {
+		return nil, true
+	}
+	var scopes []auth.Scope
+	for _, v := range *ss {
+		sc, err := auth.ParseScopes(v)
+		if err != nil || len(sc) != 1 {
+			return nil, false
+		}
+		scopes = append(scopes, sc...)
+	}
+	return scopes, true
+}
+
+// unixOrNil returns nil if t is zero.
+func unixOrNil(t time.Time) *int {
+	if t.IsZero() {
+		return nil
+	}
+	u := int(t.Unix())
+	return &u
+}
+
+func convertAPIKey(k *auth.APIKey) *apikey.APIKey {
+	id := k.ID
+	return &apikey.APIKey{
+		Key:       k.Key,
+		Id:        &id,
+		ExpiresAt: unixOrNil(k.ExpiresAt),
+	}
+}
+
+func convertAPIKeyInfo(k *auth.APIKey) apikey.APIKeyInfo {
+	scopes := make([]string, 0, len(k.AllowedScopes()))
+	for _, s := range k.AllowedScopes() {
+		scopes = append(scopes, string(s))
+	}
+	info := apikey.APIKeyInfo{
+		Id:          k.ID,
+		GlobalAdmin: k.ScopeRegisterAll,
+		Scopes:      scopes,
+		CreatedAt:   unixOrNil(k.CreatedAt),
+		ExpiresAt:   unixOrNil(k.ExpiresAt),
+		LastUsed:    unixOrNil(k.LastUsed),
+	}
+	if k.DomainID != "" {
+		dom := k.DomainID
+		info.Domain = &dom
+	}
+	if k.Note != "" {
+		note := k.Note
+		info.Note = &note
+	}
+	if k.SuccessorID != "" {
+		succ := k.SuccessorID
+		info.Successor = &succ
+	}
+	return info
+}
@@ -0,0 +1,310 @@
+package api_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ebiiim/btcgw/api"
+	"github.com/ebiiim/btcgw/api/apikey"
+	"github.com/ebiiim/btcgw/auth"
+	"github.com/ebiiim/btcgw/gw"
+	"github.com/ebiiim/btcgw/model"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/go-chi/chi"
+	_ "gocloud.dev/docstore/memdocstore"
+)
+
+const testDom3 = "789abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456"
+
+// Do not parallelize APIKeyService tests because memdocstore is NOT thread-safe.
+
+// newTestAPIKeyService returns an APIKeyService with in-memory collections named by name,
+// and its handler without OAPIValidator.
+func newTestAPIKeyService(t *testing.T, name string) (*api.APIKeyService, *auth.DocstoreAuth, http.Handler) {
+	t.Helper()
+	d := auth.MustNewDocstoreAuth("mem://" + name + "_apikeys/id")
+	s := api.NewAPIKeyService(d, "")
+	s.Challenges = auth.MustNewDocstoreChallenges("mem://" + name + "_challenges/nonce")
+	s.Domains = gw.NewBoltDomains(t.TempDir() + "/domains.db")
+	t.Cleanup(func() {
+		for _, c := range []interface{ Close() error }{s, s.Challenges, s.Domains} {
+			if err := c.Close(); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	r := chi.NewRouter()
+	api.APIKeyHandlerFromMux(s, r)
+	return s, d, r
+}
+
+func mustPutDomain(t *testing.T, s *api.APIKeyService, dom string, keyType model.KeyType, pub []byte) {
+	t.Helper()
+	d := &model.Domain{BBc1DomainID: mustDecodeHex(t, dom), KeyType: keyType, PublicKey: pub}
+	if err := s.Domains.Put(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// mustChallenge gets a challenge of dom and returns the nonce and the digest to sign.
+func mustChallenge(t *testing.T, h http.Handler, dom string) (string, []byte) {
+	t.Helper()
+	w := serve(h, http.MethodPost, "/apikeys/challenge", "", mustJSON(t, apikey.APIKeyChallengeRequest{Domain: dom}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("challenge: got %d (%s)", w.Code, w.Body)
+	}
+	var ch apikey.APIKeyChallenge
+	if err := json.Unmarshal(w.Body.Bytes(), &ch); err != nil {
+		t.Fatal(err)
+	}
+	return ch.Nonce, mustDecodeHex(t, ch.Digest)
+}
+
+// signer signs digests as bbclib signs, r and s in 32 bytes each.
+type signer func(digest []byte) []byte
+
+func newSecp256k1Signer(t *testing.T) (signer, []byte) {
+	t.Helper()
+	k, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SignCompact returns the recovery code followed by r and s.
+	sign := func(digest []byte) []byte { return secp256k1ecdsa.SignCompact(k, digest, false)[1:] }
+	return sign, k.PubKey().SerializeCompressed()
+}
+
+func newP256Signer(t *testing.T) (signer, []byte) {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(digest []byte) []byte {
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	}
+	return sign, elliptic.Marshal(elliptic.P256(), k.X, k.Y)
+}
+
+func claim(t *testing.T, h http.Handler, dom, nonce string, sig []byte, scopes ...string) int {
+	t.Helper()
+	c := apikey.APIKeyClaim{Domain: dom, Nonce: nonce, Signature: hex.EncodeToString(sig)}
+	if scopes != nil {
+		c.Scopes = &scopes
+	}
+	w := serve(h, http.MethodPost, "/apikeys/claim", "", mustJSON(t, c))
+	return w.Code
+}
+
+func TestAPIKeyService_PostApikeysClaim(t *testing.T) {
+	s, d, h := newTestAPIKeyService(t, "api_test_claim")
+	ctx := context.Background()
+	sign1, pub1 := newSecp256k1Signer(t)
+	sign2, pub2 := newP256Signer(t)
+	mustPutDomain(t, s, testDom, model.KeySecp256k1, pub1)
+	mustPutDomain(t, s, testDom2, model.KeyP256, pub2)
+	mustPutDomain(t, s, testDom3, model.KeyNone, nil)
+
+	for _, c := range []struct {
+		name string
+		dom  string
+		sign signer
+	}{{"secp256k1", testDom, sign1}, {"p256", testDom2, sign2}} {
+		nonce, digest := mustChallenge(t, h, c.dom)
+		sig := c.sign(digest)
+		cl := apikey.APIKeyClaim{Domain: c.dom, Nonce: nonce, Signature: hex.EncodeToString(sig)}
+		w := serve(h, http.MethodPost, "/apikeys/claim", "", mustJSON(t, cl))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: got %d (%s)", c.name, w.Code, w.Body)
+		}
+		var k apikey.APIKey
+		if err := json.Unmarshal(w.Body.Bytes(), &k); err != nil {
+			t.Fatal(err)
+		}
+		// A domain key with the default scopes.
+		if ok, err := d.Do(ctx, k.Key, c.dom, auth.DefaultScopes...); err != nil || !ok {
+			t.Errorf("%s: the key is not allowed for the domain (%v)", c.name, err)
+		}
+		if ok, _ := d.Do(ctx, k.Key, ""); ok {
+			t.Errorf("%s: the key is allowed for all domains", c.name)
+		}
+		// The nonce cannot be used again.
+		if got := claim(t, h, c.dom, nonce, sig); got != http.StatusForbidden {
+			t.Errorf("%s reused: got %d want %d", c.name, got, http.StatusForbidden)
+		}
+	}
+
+	// The nonce of another domain.
+	nonce, _ := mustChallenge(t, h, testDom)
+	other := (&auth.Challenge{Nonce: nonce, DomainID: testDom2}).Digest()
+	if got := claim(t, h, testDom2, nonce, sign2(other)); got != http.StatusForbidden {
+		t.Errorf("another domain: got %d want %d", got, http.StatusForbidden)
+	}
+
+	// Signed with another key.
+	nonce, digest := mustChallenge(t, h, testDom)
+	if got := claim(t, h, testDom, nonce, sign2(digest)); got != http.StatusForbidden {
+		t.Errorf("bad signature: got %d want %d", got, http.StatusForbidden)
+	}
+	// The challenge has been consumed by the failed claim.
+	if got := claim(t, h, testDom, nonce, sign1(digest)); got != http.StatusForbidden {
+		t.Errorf("after bad signature: got %d want %d", got, http.StatusForbidden)
+	}
+
+	nonce, digest = mustChallenge(t, h, testDom)
+	if got := claim(t, h, testDom, nonce, sign1(digest), string(auth.ScopeAnchorsRead), string(auth.ScopeWalletAdmin)); got != http.StatusBadRequest {
+		t.Errorf("wallet:admin: got %d want %d", got, http.StatusBadRequest)
+	}
+	if got := claim(t, h, testDom, nonce, sign1(digest), "unknown"); got != http.StatusBadRequest {
+		t.Errorf("unknown scope: got %d want %d", got, http.StatusBadRequest)
+	}
+	// Still usable as rejected before consumed.
+	if got := claim(t, h, testDom, nonce, sign1(digest), string(auth.ScopeAnchorsRead)); got != http.StatusOK {
+		t.Errorf("anchors:read: got %d want %d", got, http.StatusOK)
+	}
+
+	for _, dom := range []string{testDom3, testDig} {
+		if got := claim(t, h, dom, nonce, sign1(digest)); got != http.StatusNotFound {
+			t.Errorf("no public key %s: got %d want %d", dom, got, http.StatusNotFound)
+		}
+		w := serve(h, http.MethodPost, "/apikeys/challenge", "", mustJSON(t, apikey.APIKeyChallengeRequest{Domain: dom}))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("challenge %s: got %d want %d", dom, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestAPIKeyService_PostApikeysCreate(t *testing.T) {
+	_, d, h := newTestAPIKeyService(t, "api_test_create")
+	global := mustGenerate(t, d, "")
+	domAdmin := mustGenerate(t, d, testDom, auth.ScopeKeysAdmin, auth.ScopeAnchorsRead)
+	domKey := mustGenerate(t, d, testDom)
+
+	cases := []struct {
+		name     string
+		apiKey   string
+		body     string
+		wantCode int
+	}{
+		{"no_key", "", `{"domain":"` + testDom + `"}`, http.StatusUnauthorized},
+		{"not_admin", domKey, `{"domain":"` + testDom + `"}`, http.StatusUnauthorized},
+		{"global_domain_key", global, `{"domain":"` + testDom2 + `"}`, http.StatusOK},
+		{"global_global_key", global, `{"global_admin":true}`, http.StatusOK},
+		{"domain_own_domain", domAdmin, `{"domain":"` + testDom + `","scopes":["anchors:read"]}`, http.StatusOK},
+		{"domain_keys_admin", domAdmin, `{"domain":"` + testDom + `","scopes":["keys:admin","anchors:read"]}`, http.StatusOK},
+		{"domain_another_domain", domAdmin, `{"domain":"` + testDom2 + `","scopes":["anchors:read"]}`, http.StatusForbidden},
+		{"domain_global_key", domAdmin, `{"global_admin":true,"scopes":["anchors:read"]}`, http.StatusForbidden},
+		{"domain_scope_lacked", domAdmin, `{"domain":"` + testDom + `","scopes":["anchors:register"]}`, http.StatusForbidden},
+		{"domain_default_scopes", domAdmin, `{"domain":"` + testDom + `"}`, http.StatusForbidden},
+		{"invalid_scope", global, `{"domain":"` + testDom + `","scopes":["unknown"]}`, http.StatusBadRequest},
+		{"no_domain", global, `{}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		w := serve(h, http.MethodPost, "/apikeys/create", c.apiKey, c.body)
+		if w.Code != c.wantCode {
+			t.Errorf("%s: got %d want %d (%s)", c.name, w.Code, c.wantCode, w.Body)
+		}
+	}
+}
+
+func TestAPIKeyService_GetApikeysStale(t *testing.T) {
+	_, d, h := newTestAPIKeyService(t, "api_test_stale")
+	global := mustGenerate(t, d, "")
+	domAdmin := mustGenerate(t, d, testDom, auth.ScopeKeysAdmin)
+	mustGenerate(t, d, testDom2)
+
+	for _, c := range []struct {
+		name     string
+		apiKey   string
+		wantCode int
+		wantKeys int
+	}{
+		{"no_key", "", http.StatusUnauthorized, 0},
+		{"global", global, http.StatusOK, 3},
+		// Only keys of the domain.
+		{"domain", domAdmin, http.StatusOK, 1},
+	} {
+		w := serve(h, http.MethodGet, "/apikeys/stale?unused=0", c.apiKey, "")
+		if w.Code != c.wantCode {
+			t.Errorf("%s: got %d want %d (%s)", c.name, w.Code, c.wantCode, w.Body)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var l apikey.APIKeyList
+		if err := json.Unmarshal(w.Body.Bytes(), &l); err != nil {
+			t.Fatal(err)
+		}
+		if len(l.Keys) != c.wantKeys {
+			t.Errorf("%s: got %d keys want %d", c.name, len(l.Keys), c.wantKeys)
+		}
+	}
+}
+
+func TestAPIKeyService_PostApikeysDelete(t *testing.T) {
+	_, d, h := newTestAPIKeyService(t, "api_test_delete")
+	ctx := context.Background()
+	domAdmin := mustGenerate(t, d, testDom, auth.ScopeKeysAdmin)
+	key1 := mustGenerate(t, d, testDom)
+	key2 := mustGenerate(t, d, testDom2)
+
+	for _, c := range []struct {
+		name     string
+		apiKey   string
+		key      string
+		wantCode int
+		// wantDeleted is whether key has been deleted after the request.
+		wantDeleted bool
+	}{
+		{"no_key", "", key1, http.StatusUnauthorized, false},
+		{"not_admin", key1, key1, http.StatusUnauthorized, false},
+		{"another_domain", domAdmin, key2, http.StatusForbidden, false},
+		{"own_domain", domAdmin, key1, http.StatusNoContent, true},
+	} {
+		w := serve(h, http.MethodPost, "/apikeys/delete", c.apiKey, mustJSON(t, apikey.APIKey{Key: c.key}))
+		if w.Code != c.wantCode {
+			t.Errorf("%s: got %d want %d (%s)", c.name, w.Code, c.wantCode, w.Body)
+		}
+		k, err := d.Lookup(ctx, c.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (k == nil) != c.wantDeleted {
+			t.Errorf("%s: got %+v want deleted %v", c.name, k, c.wantDeleted)
+		}
+	}
+}
@@ -6,29 +6,137 @@ package apikey
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi"
 )
 
+const (
+	ApiKeyScopes = "ApiKey.Scopes"
+)
+
 // APIKey defines model for APIKey.
 type APIKey struct {
 
-	// API key (must be kept secret).
+	// Timestamp when the API key expires. Not set if it does not expire.
+	ExpiresAt *int `json:"expires_at,omitempty"`
+
+	// ID of the API key that is not secret.
+	Id *string `json:"id,omitempty"`
+
+	// API key (must be kept secret) in `<id>.<secret>`. Only the ID and the hash of the secret are stored,
+	// so the key cannot be shown again.
 	Key string `json:"key"`
 }
 
-// BBc1Domain defines model for BBc1Domain.
-type BBc1Domain struct {
+// APIKeyChallenge defines model for APIKeyChallenge.
+type APIKeyChallenge struct {
+
+	// SHA-256 of "btcgw-apikey-challenge:<domain>:<nonce>" in hexadecimal string, to sign as bbclib signs digests.
+	Digest string `json:"digest"`
+
+	// BBc-1 domain ID in hexadecimal string.
+	Domain string `json:"domain"`
+
+	// Timestamp when the challenge expires.
+	ExpiresAt int `json:"expires_at"`
+
+	// Nonce of the challenge.
+	Nonce string `json:"nonce"`
+}
+
+// APIKeyChallengeRequest defines model for APIKeyChallengeRequest.
+type APIKeyChallengeRequest struct {
 
 	// BBc-1 domain ID in hexadecimal string.
 	Domain string `json:"domain"`
 }
 
+// APIKeyClaim defines model for APIKeyClaim.
+type APIKeyClaim struct {
+
+	// BBc-1 domain ID in hexadecimal string.
+	Domain string `json:"domain"`
+
+	// Nonce of the challenge.
+	Nonce string `json:"nonce"`
+
+	// Scopes of the API key except wallet:admin. Defaults to anchors:read, anchors:register and anchors:refresh.
+	Scopes *[]string `json:"scopes,omitempty"`
+
+	// ECDSA signature of the digest in 64 bytes (r and s) in hexadecimal string, as bbclib signs.
+	Signature string `json:"signature"`
+
+	// Lifetime of the API key in seconds. It does not expire if not given or 0.
+	Ttl *int `json:"ttl,omitempty"`
+}
+
+// APIKeyCreation defines model for APIKeyCreation.
+type APIKeyCreation struct {
+
+	// BBc-1 domain ID in hexadecimal string. Required unless global_admin is true.
+	Domain *string `json:"domain,omitempty"`
+
+	// Creates a key allowed for all domains. Only global admins can create it.
+	GlobalAdmin *bool `json:"global_admin,omitempty"`
+
+	// Scopes of the API key. Defaults to anchors:read, anchors:register and anchors:refresh, or all scopes for global admins.
+	Scopes *[]string `json:"scopes,omitempty"`
+
+	// Lifetime of the API key in seconds. It does not expire if not given or 0.
+	Ttl *int `json:"ttl,omitempty"`
+}
+
+// APIKeyInfo defines model for APIKeyInfo.
+type APIKeyInfo struct {
+
+	// Timestamp when the API key was created. Not set if unknown.
+	CreatedAt *int `json:"created_at,omitempty"`
+
+	// BBc-1 domain ID that the API key is associated with.
+	Domain *string `json:"domain,omitempty"`
+
+	// Timestamp when the API key expires. Not set if it does not expire.
+	ExpiresAt *int `json:"expires_at,omitempty"`
+
+	// Whether the API key is allowed for all domains.
+	GlobalAdmin bool `json:"global_admin"`
+
+	// ID of the API key.
+	Id string `json:"id"`
+
+	// Timestamp when the API key was used last (updated at most hourly). Not set if never used.
+	LastUsed *int    `json:"last_used,omitempty"`
+	Note     *string `json:"note,omitempty"`
+
+	// Scopes of the API key.
+	Scopes []string `json:"scopes"`
+
+	// ID of the API key issued by rotation.
+	Successor *string `json:"successor,omitempty"`
+}
+
+// APIKeyList defines model for APIKeyList.
+type APIKeyList struct {
+	Keys []APIKeyInfo `json:"keys"`
+}
+
+// APIKeyRotation defines model for APIKeyRotation.
+type APIKeyRotation struct {
+
+	// Seconds the API key stays valid after the rotation.
+	Grace *int `json:"grace,omitempty"`
+
+	// API key to rotate.
+	Key string `json:"key"`
+}
+
 // Error defines model for Error.
 type Error struct {
 
@@ -45,26 +153,66 @@ type BadRequest Error
 // InternalServerError defines model for InternalServerError.
 type InternalServerError Error
 
+// Unauthorized defines model for Unauthorized.
+type Unauthorized Error
+
+// PostApikeysChallengeJSONBody defines parameters for PostApikeysChallenge.
+type PostApikeysChallengeJSONBody APIKeyChallengeRequest
+
+// PostApikeysClaimJSONBody defines parameters for PostApikeysClaim.
+type PostApikeysClaimJSONBody APIKeyClaim
+
 // PostApikeysCreateJSONBody defines parameters for PostApikeysCreate.
-type PostApikeysCreateJSONBody BBc1Domain
+type PostApikeysCreateJSONBody APIKeyCreation
 
 // PostApikeysDeleteJSONBody defines parameters for PostApikeysDelete.
 type PostApikeysDeleteJSONBody APIKey
 
+// PostApikeysRotateJSONBody defines parameters for PostApikeysRotate.
+type PostApikeysRotateJSONBody APIKeyRotation
+
+// GetApikeysStaleParams defines parameters for GetApikeysStale.
+type GetApikeysStaleParams struct {
+
+	// Lists keys not used for this period in seconds.
+	Unused *int `json:"unused,omitempty"`
+}
+
+// PostApikeysChallengeJSONRequestBody defines body for PostApikeysChallenge for application/json ContentType.
+type PostApikeysChallengeJSONRequestBody PostApikeysChallengeJSONBody
+
+// PostApikeysClaimJSONRequestBody defines body for PostApikeysClaim for application/json ContentType.
+type PostApikeysClaimJSONRequestBody PostApikeysClaimJSONBody
+
 // PostApikeysCreateJSONRequestBody defines body for PostApikeysCreate for application/json ContentType.
 type PostApikeysCreateJSONRequestBody PostApikeysCreateJSONBody
 
 // PostApikeysDeleteJSONRequestBody defines body for PostApikeysDelete for application/json ContentType.
 type PostApikeysDeleteJSONRequestBody PostApikeysDeleteJSONBody
 
+// PostApikeysRotateJSONRequestBody defines body for PostApikeysRotate for application/json ContentType.
+type PostApikeysRotateJSONRequestBody PostApikeysRotateJSONBody
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Issues a challenge to prove the ownership of the BBc-1 domain.
+	// (POST /apikeys/challenge)
+	PostApikeysChallenge(w http.ResponseWriter, r *http.Request)
+	// Creates an API Key of the BBc-1 domain for the owner who signed the challenge.
+	// (POST /apikeys/claim)
+	PostApikeysClaim(w http.ResponseWriter, r *http.Request)
 	// Creates an API Key associated with the BBc-1 domain specified by ID.
 	// (POST /apikeys/create)
 	PostApikeysCreate(w http.ResponseWriter, r *http.Request)
 	// Deletes the specified API Key.
 	// (POST /apikeys/delete)
 	PostApikeysDelete(w http.ResponseWriter, r *http.Request)
+	// Issues the successor of the specified API Key.
+	// (POST /apikeys/rotate)
+	PostApikeysRotate(w http.ResponseWriter, r *http.Request)
+	// Lists API Keys that have expired or not been used recently.
+	// (GET /apikeys/stale)
+	GetApikeysStale(w http.ResponseWriter, r *http.Request, params GetApikeysStaleParams)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -75,10 +223,42 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
 
+// PostApikeysChallenge operation middleware
+func (siw *ServerInterfaceWrapper) PostApikeysChallenge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApikeysChallenge(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PostApikeysClaim operation middleware
+func (siw *ServerInterfaceWrapper) PostApikeysClaim(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApikeysClaim(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // PostApikeysCreate operation middleware
 func (siw *ServerInterfaceWrapper) PostApikeysCreate(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"keys:admin"})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.PostApikeysCreate(w, r)
 	}
@@ -94,6 +274,8 @@ func (siw *ServerInterfaceWrapper) PostApikeysCreate(w http.ResponseWriter, r *h
 func (siw *ServerInterfaceWrapper) PostApikeysDelete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"keys:admin"})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.PostApikeysDelete(w, r)
 	}
@@ -105,6 +287,54 @@ func (siw *ServerInterfaceWrapper) PostApikeysDelete(w http.ResponseWriter, r *h
 	handler(w, r.WithContext(ctx))
 }
 
+// PostApikeysRotate operation middleware
+func (siw *ServerInterfaceWrapper) PostApikeysRotate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostApikeysRotate(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetApikeysStale operation middleware
+func (siw *ServerInterfaceWrapper) GetApikeysStale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{"keys:admin"})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetApikeysStaleParams
+
+	// ------------- Optional query parameter "unused" -------------
+	if paramValue := r.URL.Query().Get("unused"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "unused", r.URL.Query(), &params.Unused)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter unused: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetApikeysStale(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // Handler creates http.Handler with routing matching OpenAPI spec.
 func Handler(si ServerInterface) http.Handler {
 	return HandlerWithOptions(si, ChiServerOptions{})
@@ -142,12 +372,24 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		HandlerMiddlewares: options.Middlewares,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/apikeys/challenge", wrapper.PostApikeysChallenge)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/apikeys/claim", wrapper.PostApikeysClaim)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/apikeys/create", wrapper.PostApikeysCreate)
 	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/apikeys/delete", wrapper.PostApikeysDelete)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/apikeys/rotate", wrapper.PostApikeysRotate)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/apikeys/stale", wrapper.GetApikeysStale)
+	})
 
 	return r
 }
@@ -155,23 +397,57 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8RW32/bNhD+VwhuDxugWHRi54ff7CTYjHRY0PRhQxGkNHmO2UgkR1KJhUD/+3CkJNuN",
-	"i2Tdir2R1PHu++4+3umZClNao0EHTyfP1IG3RnuImxmX7+GvCnzAnTA6gI5Lbm2hBA/K6PyzNxrPvFhB",
-	"yXH1o4MlndAf8o3rPH31+aVzxtGmaTIqwQunLDqhEzrXj7xQkrgUkDgQoB5BZsRBqJz2hGsSbw9ok9G5",
-	"DuA0L27APYJLXl/HCGte2gLiMl2hiyDunyaTSsPagggg79KXLFnc7aKcarKxJNGCrLgnRojKOURrC+Ae",
-	"CALhIpDKR7j/OjmJbRtxE21PbvposYTT6/kV1LiyzlhwQaXSPqTDL9hdz8kD1OSnsvKBLIA8gA3Eg3AQ",
-	"fh5gSrr80dPTszPOFwshpARYLhkbDg8Pj45Go/H4+PjkhGY01BYtfXBK30dcWFvlQNLJxwjgtjcyi88g",
-	"AmZqNhPDC1NypV+Clv35Lu7ZTBwMSfpK5hdEabKCNZcgVMkLkhDs4h+Nj09Oz/hCMFiy4eHRaHzM4l7C",
-	"krG4x+8s7vE7a+0lMNy/yq/Fuo9ir9ZddtAd75KL1kQYCeRJhRWxDpZqTT61wv20y6s9Des7bcLd0lRa",
-	"voS6V9tfxv0NvOf3QIIhYQWk8uB2Q31wXHsu0JxoE0gMNng1Md3zQkZ70oPyBVE5FeoblHHKzdSqVsZY",
-	"f7oCLgGdaF7i5T8Optfzg6vLPzfReboRH5PSS9N1By6wOzQZLZQA7WMvaL1cv5ueX979+vu7i8v3mKSg",
-	"QiSa5DX7cE5+4QGeeE0z+gjOpzyxwXDA0NxY0NwqOqFHAzZgNKOWh1WEn3MtVsb5PKnC589p0eRS3YMP",
-	"eBAXTZQFd7yEAM7Tycdv0jrNUp4QwCZLrSS3yxFcBdvd6fs+kK9wicz/EZF4441EOh6SdTxOWMdjyDoe",
-	"C9bxGLOTnifu9xC5bTKac6seoPa5cMBDlJE1aVDio45zZy5RVcaHabI9T6YJN/gwM7L+zybrVuNsdt8c",
-	"5iYebI32Q8b2RK6/KXI7Y/bMrUgY+wPeKiCAJ74SArxfVkVRE65lP8KwySRPcWKOEsB9cXsi+dYPSpPR",
-	"8Vuu7PtxiE2nKkvu6g40xJmKA/EKasK9N0JxnPmxByPWnYfoLQi1VCDJoibzi9gF+T2+X9r6oLfNtmok",
-	"YDrepJqLZPp9VLNdu9cUM3o5JG76ahLjtqfA/1rBlLEkqU1l2kJ8pTZx7qDD1HQrV9AJpc1tb9yPiWns",
-	"5RTbWXfSumlum78DAAD//6Rl4ttQCwAA",
+	"H4sIAAAAAAAC/+xb63PbuBH/VzBsP9xNZYl6W/rmPObqyTXNxO6knbPHAsGliDMJ8ADQtprR/97BixRF",
+	"yZKTOOfr3CeLJB77/O1iF/4cEJ4XnAFTMph/DgTIgjMJ5uEVjj/CbyVIpZ8IZwqY+YmLIqMEK8pZ71fJ",
+	"mX4nSQo51r/+KiAJ5sFfevXSPftV9t4KwUWwXq87QQySCFroRYJ5cM7ucEZjJOyGSAABegdxBwlQpWAS",
+	"YYbM7G6w7gTnTIFgOLsAcQfCrnqYRnjAeZGB+WmnBJEiy/v5vGTwUABREN/YLx074qZJ5RlD9UhkRqAU",
+	"S8QJKYXQ1BYZYAlIE4KJQqU05H61cCy3bsd6t52y+RfDpUq5oP+F+CuFsrHQHoF8OEe3sEJUopxKSdkS",
+	"cYEYVwhnGb+HGCVcIJUCinmOKfsW0ti9J7UG1A06QQo4BmFM+NOnTzdnpUqBKc03NC1VrQoI5oFUgrKl",
+	"3mpdUWdmn304fwcr/asQvAChqHUMeCioAHmDjVCb1F3SHKTCeYHuU2CGdU+wm9ZF77lCEhSiCaIKxRyk",
+	"EZn9rjmodNKfDMLBYDzrTzqeWsoULEFoQdK4vf/5G8STxrYqxQpRu4UEIkA1tgjCsN8fDIbD0Wg8nkym",
+	"06CzLZhOcAur9lZ+gx/yUioUAbqFwm/xI6IMLa7KMBwSGpu/0LWPdoB9teiif7JsZeg9f4Mwi83PFMvU",
+	"s2GHIywAScW12V8xyc0nvTnBTDMWAZIpv2cIL7WZXbFHWeyens5mGEcRIXEMkCSHZbDuBBqbqNBO9YsR",
+	"yHU1iEe/AlFaUNZkXqc4y4AtoW07MV06QG0K8+LvZyeD8URzfWUd8AQX9BZWJ8QvNrfys55kfvtXjDMC",
+	"9s1VoCWfwgOOgdAcZ8hy0EGKI0mXDGGJoohkNDKPElmK5LbMpjhMxiSCEelHQzLA/XgKs6QfRn08IMN4",
+	"BONkEk77p4PZEI+iMZnEUzhNZpUgd5mRJb3N/KtX5KTvIELbwU4OmlY7Gk+mpzMckRCSsD8YjsaT0DzH",
+	"kIThYOi+hyR230M3PoZQP+8i74l+XSmm8uym6/YHo3E42O26RmPtXd7r197yq/Uf99dtWz5ou04LnoiO",
+	"t8mGAI4w7o3kYMvGX7KadwvjEXYzTPM/GI/f2b500OQFyPZ+F+b9dkSCB6Ijxb3eXs1xnFPWRW8gwWWm",
+	"pMYpzEjKhZwLwHFn42lJpQJhwkT9MhEg0wYLvwSbCwSdYHsFrW6qIJc7soCKOywEXhnm6JJhVYod8nz7",
+	"+s3FGaoGeEatQ2ndT0YoWimQ6AdLtvxxHzxvwXJTJ8NolvRJGA9ghMfRhEzjU5glOIz6ZBAPYZSMw0l/",
+	"Ojgdzja/h7gfPYrVR3/fpXOlsrZAfqYJKJrDtsop04Gcs1h20Xkr59GZkH5a0jtgOpsLG+xPp9NJGIad",
+	"IKeM5mUezMM2ph4CuVqLj/i6AGwZ+bbujj46ylDJMpASLTMe4ezGmD6iEilRwvODwuaulhXjcsE8wZmE",
+	"7TzbCAMkwkaBm/k8zjLHsnT5m10ZmZWlzskQMbMRbSabbiNHWcR5Bpg9GUC+Fiw6yDFhdzU8NTh4TjR5",
+	"eW6zxxnOWcLbjmD1Gj/17HOPpTOJuHH+Kdkt4/esnTmNZnsOPcd6ojn2NGQpEZaSE6qJQPdUpS8uo3y2",
+	"k2Lb8zcp+ZSCSkG0pLXH5Y9y6KPOpk8/iGZYqptSQvxk69OTkJ6OfiiL2NgAVijnUqGUlyJb/dgQN4M7",
+	"EGbSjqx+ON6X1dsSQ82TBdEYRStDDFZzNAgH/ZNwcBKOLgfD+Wg870/+Fs7mYfjVidUTYWvzlcHFJ6ZF",
+	"JSEgJRdt6lqKRlTK0opBcGVibFP32ynnwbydxsGWWVfC2h/ff6a7jiu3sDJ/K9YfK0ttYGNLJu0awWPE",
+	"fHSCaBO0FNgn7y5An05GGsi3bMBGhoacpcIriWwtFSfKOfVOmbs1HwsOBwo/ituVofs7VVuqwm9TfuBf",
+	"byXr+jUiPAaD/qgQkNAHtHDlzkWTC/dWPdwwrm4SXrJ4J8K3q6Lb+/4DpMRL0OLSyigliOZWlwIziYke",
+	"buDdbNY9KBVfqdYc7RCPdlEgpaBqdaGN18rmrKDvHtGpRCm+A5cYdUzyBJikSIvXmBByFFizs+OQTmKV",
+	"RH47PyYHpuZXbLEJRAu02AaixisDRAu00LTYo+ECLTZPiovuFXsHq1YwRxg1UgAsoIpgXOeoOoxVbEjE",
+	"2UZd2tYUN77qFXmp0OKzHbBeIGonFFilHQTdZRcteo7uXqSIeuh9Vg80Xi+M1BY9O1EuOl4cVr7b0dSU",
+	"3XRUdnXroBMwnGtF/vvk7MP5ybu3/6ktAVvtmUI1dfmZ6zXon27ikqq0jLqE5z2IKKV5zxhz0AlKkemN",
+	"lCrkvNfbN07HWUqASdhY9KzAJIWTQTc8dp1elPGop7ns/Xz++u37i7d6ZUWVMXqrrFeXr9FPWME9XgWd",
+	"4A6EtOYYdvvdUA/nBTBcUH0G7oZmb60AY8o7pR/MP6879Scn5Z5XY89VO48c1vtsf6wNyGCBc1CmtfDL",
+	"Fx0EvaY1C7Weq6Nq7dz6PLjZKHnegtgeXqoixtGM+DriMYx4PuLQ8zENPR/90PMRhZ6PcTit+NTPOxi5",
+	"fpJKeymViovVn6r9w6jWNERkjzTaK3xXO+UyBcTvGYiqJGfVZ1seC8vOwgYO/bkQ9A4r21LiCSrKKKPk",
+	"xj014oQGd6JrwqbtqsPmO1jVC9W1wAgSLmBPp+CKXdZE+f6ZD4g+otVUdK/YmR9NMLNBGqOM5lTHP1bm",
+	"kWVVw2W9nY/gZlMdBkGauBhDBvokbmJPFfXO42AefOBSnVk5110sq3aQ6hWPV9/sMsCedsJ6vd42s3Wn",
+	"eTNhEIbPRcWujvNH12bXqnxdF83XnWAUhvvWrwjubdyjMFNGz3+dYsO4XOd3w7bcpQXGnYHZo6MmbTD7",
+	"rqRpKhTnKMds1bZcf7nB5+eK8xs98qYetDBkj49Rwq4bI2tzis1zrINAcK6PqBLhDW9VHBWC31knNnAi",
+	"U1p4SNiMCyZbx0sdPgKHCcH1ugFaVR9pL2DV2EElugNBE9qGgi1AqnrmNdUaICKwBQ+T+XJGoHvFmmn0",
+	"RufckHYYDgwDzwoFZoffxf93WarvBiA9SwOmRK7mkZRZtjKSFxvQYFf6clwYfh/nqw2FSn9tplPHCFv0",
+	"MiVytW2T94Lr5uXLRrFvBAdV+6MO8jv8vrrfZJON+9ResoC43WE9AA9mu/348NEfuzGzTQrNb6WliHMl",
+	"lcAFUvwWWH13RtxRAuYuTnWa1AfoN+6M3OrXaFrq47JKgYoG0jjQWArMlD/9+0r7CsXcaElnJ4fAxLL7",
+	"rGji3PfLAWX1BweU/uEpjcuC3xOFKhv2ru1rNcrkA7peVpWkmim8tkvuLLCuQX0D13fVK3MC9GUyW8W1",
+	"ITO4Xl8fQIjtmlQLLmQBxIb1aIXO3xzGBZuqPxkXtCnFLSe3i1n/3pBs5ePdx1z2jaXkOV32OFcd7WiH",
+	"VG7k75+6GuqfrvMiXcfakqshVy7hPOCwU9iuw4Fk2ren7DFDv8G5Z9n4R+aa7u4w3qKj0Uzxcd70ZlAB",
+	"gvK4ERIj12aBuLqD+p43deUNGzmCnCPZ2+K0Bn2zOVUSsuRAGP1oBfGcPlm1qV5MXu4pOjaMVpbw4o/s",
+	"le5ljWE+0XUJuuNh9n0J0gTgTPdvVigCYN7Un+H83dBYlcg+HSOkwva/GpawAyFMB6nu8ZuqGEmB3NrI",
+	"bIMi8fmaQQl0Wd1Gt1U0rSJz6bx7xY4OwxIei787vP0n8M5+YTjqPF6s1i1uaRfX9BnmLHZR6WBr81aR",
+	"r/b+VoJY1eXekumJwWZ5t+pFH3et6PrZoUFzeqhaZ4zA24xEXMQg6tzrSyP9942VVqMVD+a0Zeq/G0d2",
+	"G4CAWX0LIMBU9oiTuOaI64Jtt0r+nzsippHgWqf6p4CCC21bBbBYvzMqEneecdvpDNbXlSjrjqjpNAXr",
+	"Tv3GCXl9vf7fAPGwn9FaNwAA",
 }
 
 // GetSwagger returns the Swagger specification corresponding to the generated code
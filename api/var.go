@@ -25,20 +25,137 @@ var (
 	ErrInvalidParam     = errors.New("btcgw::invalid_param")
 	ErrInvalidParamDesc = "Parameter should be a 32 bytes binary in hexadecimal string."
 
+	ErrInvalidCursor     = errors.New("btcgw::invalid_cursor")
+	ErrInvalidCursorDesc = "Cursor should be next_cursor of the previous page."
+
+	ErrListFailed     = errors.New("btcgw::list_failed")
+	ErrListFailedDesc = "Could not list anchors. There may be a system error."
+
+	ErrInvalidReportParam     = errors.New("btcgw::invalid_report_param")
+	ErrInvalidReportParamDesc = "domain should be a 32 bytes binary in hexadecimal string, and by should be month, domain or apikey."
+
+	ErrReportFailed     = errors.New("btcgw::report_failed")
+	ErrReportFailedDesc = "Could not total the spend. There may be a system error."
+
+	ErrInvalidRecordUpdate     = errors.New("btcgw::invalid_record_update")
+	ErrInvalidRecordUpdateDesc = "bbc1name should be at most 255 characters and note should be at most 1024 characters, in UTF-8 without control characters."
+
+	ErrInvalidMetadata     = errors.New("btcgw::invalid_metadata")
+	ErrInvalidMetadataDesc = "metadata should have at most 16 keys of [A-Za-z0-9_.-] up to 64 characters, and values up to 512 characters in UTF-8 without control characters."
+
+	ErrInvalidDomain     = errors.New("btcgw::invalid_domain")
+	ErrInvalidDomainDesc = "name and owner should be at most 255 characters in UTF-8 without control characters, and public_key should be a public key in SEC 1 form in hexadecimal string with key_type."
+
+	ErrDomainNotFound     = errors.New("btcgw::domain_not_found")
+	ErrDomainNotFoundDesc = "Domain not found."
+
+	ErrNetworkNotAllowed     = errors.New("btcgw::network_not_allowed")
+	ErrNetworkNotAllowedDesc = "The domain is not allowed to anchor to the Bitcoin network of the gateway."
+
+	ErrDailyQuotaExceeded     = errors.New("btcgw::daily_quota_exceeded")
+	ErrDailyQuotaExceededDesc = "The domain has used up its daily anchor quota. Please try again tomorrow (UTC)."
+
+	ErrMonthlyQuotaExceeded     = errors.New("btcgw::monthly_quota_exceeded")
+	ErrMonthlyQuotaExceededDesc = "The domain has used up its monthly anchor quota. Please try again next month (UTC)."
+
+	ErrBudgetExceeded     = errors.New("btcgw::budget_exceeded")
+	ErrBudgetExceededDesc = "The domain has used up its budget for anchor transaction fees. Please try again in the next period (UTC)."
+
+	ErrGatewayQuotaExceeded     = errors.New("btcgw::gateway_quota_exceeded")
+	ErrGatewayQuotaExceededDesc = "The gateway has used up its anchor quota. Please try again in the next period (UTC)."
+
+	ErrGatewayBudgetExceeded     = errors.New("btcgw::gateway_budget_exceeded")
+	ErrGatewayBudgetExceededDesc = "The gateway has used up its budget for anchor transaction fees. Please try again in the next period (UTC)."
+
 	ErrDigestNotFound     = errors.New("btcgw::digest_not_found")
 	ErrDigestNotFoundDesc = "Digest not found."
 
+	ErrAnchorNotFound     = errors.New("btcgw::anchor_not_found")
+	ErrAnchorNotFoundDesc = "Bitcoin transaction not found or it has no anchor."
+
+	ErrUnexpected     = errors.New("btcgw::unexpected_error")
+	ErrUnexpectedDesc = "An unexpected error has occurred, please contact us."
+
 	ErrRegisterFailed     = errors.New("btcgw::register_failed")
 	ErrRegisterFailedDesc = "Could not register. There may be a system error."
 
 	ErrDigestAlreadyExists     = errors.New("btcgw::digest_already_exists")
 	ErrDigestAlreadyExistsDesc = "Digest already exists."
 
+	ErrIdempotencyKeyMismatch     = errors.New("btcgw::idempotency_key_mismatch")
+	ErrIdempotencyKeyMismatchDesc = "Digest is registered with another Idempotency-Key."
+
+	ErrRegistrationFailed     = errors.New("btcgw::registration_failed")
+	ErrRegistrationFailedDesc = "The registration with the Idempotency-Key has failed. Please try again with another Idempotency-Key."
+
+	ErrNodeUnavailable     = errors.New("btcgw::node_unavailable")
+	ErrNodeUnavailableDesc = "Bitcoin node is not available. Please try again later."
+
+	ErrStoreUnavailable     = errors.New("btcgw::store_unavailable")
+	ErrStoreUnavailableDesc = "Database is not available. Please try again later."
+
+	ErrTxRejected     = errors.New("btcgw::tx_rejected")
+	ErrTxRejectedDesc = "The anchor transaction was rejected by the Bitcoin node."
+
+	ErrTxFeeTooLow     = errors.New("btcgw::tx_fee_too_low")
+	ErrTxFeeTooLowDesc = "The anchor transaction was rejected because its fee is too low."
+
+	ErrTxDust     = errors.New("btcgw::tx_dust")
+	ErrTxDustDesc = "The anchor transaction was rejected because it has a dust output."
+
+	ErrTxNonStandard     = errors.New("btcgw::tx_non_standard")
+	ErrTxNonStandardDesc = "The anchor transaction was rejected because it is non-standard."
+
+	ErrTxMissingInputs     = errors.New("btcgw::tx_missing_inputs")
+	ErrTxMissingInputsDesc = "The anchor transaction was rejected because its inputs are missing or already spent."
+
+	ErrTxTooLongMempoolChain     = errors.New("btcgw::tx_too_long_mempool_chain")
+	ErrTxTooLongMempoolChainDesc = "The anchor transaction was rejected because there are too many unconfirmed transactions. Please try again later."
+
 	ErrAPIKeyCreationFailed     = errors.New("btcgw::apikey_creation_failed")
 	ErrAPIKeyCreationFailedDesc = "Could not create API Key. There may be a system error."
 
 	ErrAPIKeyDeletionFailed     = errors.New("btcgw::apikey_deletion_failed")
 	ErrAPIKeyDeletionFailedDesc = "Could not delete API Key. There may be a system error."
 
+	ErrAPIKeyRotationFailed     = errors.New("btcgw::apikey_rotation_failed")
+	ErrAPIKeyRotationFailedDesc = "Could not rotate API Key. There may be a system error."
+
+	ErrAPIKeyNotFound     = errors.New("btcgw::apikey_not_found")
+	ErrAPIKeyNotFoundDesc = "API Key not found or it has expired."
+
+	ErrAPIKeyAlreadyRotated     = errors.New("btcgw::apikey_already_rotated")
+	ErrAPIKeyAlreadyRotatedDesc = "API Key has already been rotated. Please use the successor."
+
+	ErrAPIKeyListFailed     = errors.New("btcgw::apikey_list_failed")
+	ErrAPIKeyListFailedDesc = "Could not list API Keys. There may be a system error."
+
+	ErrInvalidAPIKeyCreation     = errors.New("btcgw::invalid_apikey_creation")
+	ErrInvalidAPIKeyCreationDesc = "domain should be a 32 bytes binary in hexadecimal string unless global_admin is true, and scopes should be known ones."
+
+	ErrUnauthorized     = errors.New("btcgw::unauthorized")
+	ErrUnauthorizedDesc = "API key is missing or not an admin key."
+
+	ErrAPIKeyForbidden     = errors.New("btcgw::apikey_forbidden")
+	ErrAPIKeyForbiddenDesc = "The admin key is not allowed to manage API Keys of the domain or to grant the scopes."
+
+	ErrOwnershipProofDisabled     = errors.New("btcgw::ownership_proof_disabled")
+	ErrOwnershipProofDisabledDesc = "Proof of domain ownership is not enabled on this server. Please contact the administrator."
+
+	ErrInvalidAPIKeyClaim     = errors.New("btcgw::invalid_apikey_claim")
+	ErrInvalidAPIKeyClaimDesc = "domain should be a 32 bytes binary, nonce and signature should be hexadecimal strings, and scopes should be known ones except wallet:admin."
+
+	ErrDomainKeyNotFound     = errors.New("btcgw::domain_key_not_found")
+	ErrDomainKeyNotFoundDesc = "Domain not found or it has no public key."
+
+	ErrChallengeFailed     = errors.New("btcgw::challenge_failed")
+	ErrChallengeFailedDesc = "Could not issue a challenge. There may be a system error."
+
+	ErrTooManyChallenges     = errors.New("btcgw::too_many_challenges")
+	ErrTooManyChallengesDesc = "The domain has too many open challenges. Please claim with one of them or retry after they expire."
+
+	ErrOwnershipNotProven     = errors.New("btcgw::ownership_not_proven")
+	ErrOwnershipNotProvenDesc = "The challenge is invalid, expired or already used, or the signature is wrong. Please get a new challenge."
+
 	ErrCouldNotClose = errors.New("ErrCouldNotClose")
 )
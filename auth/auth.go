@@ -8,24 +8,58 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/gob"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/ebiiim/btcgw/util"
 
 	"github.com/google/uuid"
 	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
 )
 
+func init() {
+	// memdocstore saves time and slice fields of APIKey with gob.
+	gob.Register(time.Time{})
+	gob.Register([]interface{}{})
+}
+
 type Authenticator interface {
-	AuthFunc(ctx context.Context, apiKey string, params map[string]string) bool
+	// AuthFunc returns whether apiKey has all the scopes,
+	// and is allowed for the BBc-1 domain in params (path parameters) if any.
+	AuthFunc(ctx context.Context, apiKey string, scopes []Scope, params map[string]string) bool
 	io.Closer
 }
 
 var _ Authenticator = (*SpecialAuth)(nil)
 var _ Authenticator = (*DocstoreAuth)(nil)
+var _ Authenticator = (*BoltAuth)(nil)
+
+// KeyManager manages APIKeys, and is used by APIKeyService.
+type KeyManager interface {
+	// Admin returns the APIKey of apiKey if it has not expired and has ScopeKeysAdmin, otherwise nil.
+	Admin(ctx context.Context, apiKey string) (*APIKey, error)
+	// Lookup returns the APIKey of apiKey even if it has expired, or nil if not found or the secret is wrong.
+	Lookup(ctx context.Context, apiKey string) (*APIKey, error)
+	Generate(ctx context.Context, domID string, isGlobalAdmin bool, note string, ttl time.Duration, scopes []Scope) (*APIKey, error)
+	Rotate(ctx context.Context, apiKey string, grace time.Duration) (*APIKey, error)
+	ListStale(ctx context.Context, since time.Time) ([]*APIKey, error)
+	Delete(ctx context.Context, apiKey string) error
+	io.Closer
+}
+
+var _ KeyManager = (*DocstoreAuth)(nil)
+var _ KeyManager = (*BoltAuth)(nil)
 
 const (
 	paramPathDomainID      = "domain"
@@ -36,7 +70,7 @@ const (
 type SpecialAuth struct{}
 
 // AuthFunc returns (apiKey == "12345").
-func (*SpecialAuth) AuthFunc(_ context.Context, apiKey string, _ map[string]string) bool {
+func (*SpecialAuth) AuthFunc(_ context.Context, apiKey string, _ []Scope, _ map[string]string) bool {
 	return apiKey == "12345"
 }
 
@@ -50,10 +84,18 @@ func (*SpecialAuth) Close() error {
 // NextKeyFunc generates next key.
 type NextKeyFunc func() string
 
-// NextKeyFn generates next key and is used by DocstoreAuth.
+// NextKeyFn generates the secret of next key and is used by DocstoreAuth.
 // Default is UUID Version 4.
 var NextKeyFn = UUIDNextKey
 
+// NextKeyIDFn generates the ID of next key and is used by DocstoreAuth.
+// Default is 8 random bytes in hex.
+var NextKeyIDFn = RandomKeyID
+
+// LastUsedInterval throttles updating APIKey.LastUsed,
+// so successful authentication writes to the datastore at most once per interval for each key.
+var LastUsedInterval = time.Hour
+
 // UUIDNextKey returns an UUID Version 4, without hyphens.
 func UUIDNextKey() string {
 	k, err := uuid.NewRandom()
@@ -67,13 +109,136 @@ func UUIDNextKey() string {
 	return hex.EncodeToString(kb)
 }
 
+// RandomKeyID returns 8 random bytes in hex.
+func RandomKeyID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// keySep separates the ID and the secret of an API key.
+const keySep = "."
+
+// KeyID returns the ID of apiKey that is safe to store and show, e.g. in spend reports.
+// API keys are "<ID>.<secret>". Keys issued before that have no ID, so their ID is
+// the first 16 hex digits of the SHA-256 of the whole key. Returns "" if apiKey is "".
+func KeyID(apiKey string) string {
+	id, _ := splitKey(apiKey)
+	return id
+}
+
+// splitKey returns the ID and the secret of apiKey.
+// The secret of keys without ID is the whole key.
+func splitKey(apiKey string) (id, secret string) {
+	if apiKey == "" {
+		return "", ""
+	}
+	if i := strings.Index(apiKey, keySep); i >= 0 {
+		return apiKey[:i], apiKey[i+len(keySep):]
+	}
+	h := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(h[:8]), apiKey
+}
+
+// hashSecret returns the SHA-256 of salt and secret in hex.
+// A fast hash is enough as secrets are random and long.
+func hashSecret(salt, secret string) string {
+	h := sha256.Sum256([]byte(salt + secret))
+	return hex.EncodeToString(h[:])
+}
+
+// newAPIKey returns an APIKey with a new salt and the hash of secret.
+func newAPIKey(id, secret string) (*APIKey, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	salt := hex.EncodeToString(b)
+	return &APIKey{
+		ID:   id,
+		Salt: salt,
+		Hash: hashSecret(salt, secret),
+	}, nil
+}
+
+// verify checks whether secret is the one of k.
+func (k *APIKey) verify(secret string) bool {
+	if k.Hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSecret(k.Salt, secret)), []byte(k.Hash)) == 1
+}
+
 // APIKey contains API key and scope.
+// Only the ID and the salted hash of the secret are stored.
 type APIKey struct {
-	Key                 string `docstore:"key"`
+	ID string `docstore:"id"`
+	// Key is "<ID>.<secret>". It is set only by Generate and never stored.
+	Key                 string `docstore:"-" json:"-"`
+	Salt                string `docstore:"salt"`
+	Hash                string `docstore:"hash"`
 	ScopeRegisterAll    bool   `docstore:"scope_register_all"`    // globalAdmin: You can do whatever you want.
 	ScopeRegisterDomain bool   `docstore:"scope_register_domain"` // domainAdmin: DomainID will be checked.
 	DomainID            string `docstore:"domid"`
 	Note                string `docstore:"note"`
+	// Scopes limits the operations. See DefaultScopes if empty.
+	Scopes []Scope `docstore:"scopes"`
+
+	// CreatedAt is zero for keys migrated from plaintext.
+	CreatedAt time.Time `docstore:"created_at"`
+	// ExpiresAt is zero if the key does not expire.
+	ExpiresAt time.Time `docstore:"expires_at"`
+	// LastUsed is updated on successful authentication, at most once per LastUsedInterval.
+	LastUsed time.Time `docstore:"last_used"`
+	// SuccessorID is the ID of the key issued by Rotate. The key expires after the grace period.
+	SuccessorID string `docstore:"successor"`
+}
+
+// expired returns whether k has expired at now.
+func (k *APIKey) expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// usedAt returns when k is used last, or created if never used.
+func (k *APIKey) usedAt() time.Time {
+	if k.LastUsed.IsZero() {
+		return k.CreatedAt
+	}
+	return k.LastUsed
+}
+
+// stale returns whether k has expired or not been used since the given time.
+func (k *APIKey) stale(since, now time.Time) bool {
+	return k.expired(now) || k.usedAt().Before(since)
+}
+
+// touch returns whether k.LastUsed should be updated to now.
+func (k *APIKey) touch(now time.Time) bool {
+	return now.Sub(k.LastUsed) >= LastUsedInterval
+}
+
+// successor returns a new key with the same scopes and lifetime as k.
+func (k *APIKey) successor(now time.Time) (*APIKey, error) {
+	var ttl time.Duration
+	if !k.ExpiresAt.IsZero() && !k.CreatedAt.IsZero() {
+		ttl = k.ExpiresAt.Sub(k.CreatedAt)
+	}
+	s, err := generateKey(k.DomainID, k.ScopeRegisterAll, k.Note, ttl, k.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	s.ScopeRegisterDomain = k.ScopeRegisterDomain
+	return s, nil
+}
+
+// retire sets k.SuccessorID and shortens k.ExpiresAt to the end of the grace period.
+func (k *APIKey) retire(successorID string, now time.Time, grace time.Duration) {
+	k.SuccessorID = successorID
+	if end := now.Add(grace); k.ExpiresAt.IsZero() || end.Before(k.ExpiresAt) {
+		k.ExpiresAt = end
+	}
 }
 
 // Errors
@@ -84,8 +249,46 @@ var (
 	ErrCouldNotDeleteKey     = errors.New("ErrCouldNotDeleteKey")
 	ErrKeyNotFound           = errors.New("ErrKeyNotFound")
 	ErrCouldNotGenerateKey   = errors.New("ErrCouldNotGenerateKey")
+	ErrCouldNotMigrateKeys   = errors.New("ErrCouldNotMigrateKeys")
+	ErrCouldNotRotateKey     = errors.New("ErrCouldNotRotateKey")
+	ErrCouldNotListKeys      = errors.New("ErrCouldNotListKeys")
+	ErrKeyAlreadyRotated     = errors.New("ErrKeyAlreadyRotated")
 )
 
+// legacyAPIKey is an APIKey stored before hashing, with the plaintext key as the ID.
+// It is used to read APIKeys in both formats.
+type legacyAPIKey struct {
+	Key string `docstore:"key"`
+	APIKey
+}
+
+// plaintext returns the key of l if l is stored in plaintext, otherwise "".
+// Stores that keep the ID field separately, e.g. MongoDB, put the old key in ID.
+func (l *legacyAPIKey) plaintext() string {
+	if l.Hash != "" {
+		return ""
+	}
+	if l.Key != "" {
+		return l.Key
+	}
+	return l.ID
+}
+
+// migrate returns the hashed APIKey of the plaintext one.
+func (l *legacyAPIKey) migrate() (*APIKey, error) {
+	id, secret := splitKey(l.plaintext())
+	k, err := newAPIKey(id, secret)
+	if err != nil {
+		return nil, err
+	}
+	k.ScopeRegisterAll = l.ScopeRegisterAll
+	k.ScopeRegisterDomain = l.ScopeRegisterDomain
+	k.DomainID = l.DomainID
+	k.Note = l.Note
+	k.Scopes = l.Scopes
+	return k, nil
+}
+
 // DocstoreAuth provides AuthFunc and is backed by docstore.
 type DocstoreAuth struct {
 	conn string
@@ -94,66 +297,267 @@ type DocstoreAuth struct {
 }
 
 // AuthFunc handles authentication.
-func (a *DocstoreAuth) AuthFunc(ctx context.Context, apiKey string, params map[string]string) bool {
+func (a *DocstoreAuth) AuthFunc(ctx context.Context, apiKey string, scopes []Scope, params map[string]string) bool {
 	// TODO: check error and put logs if unexpected error
-	b, _ := a.Do(ctx, apiKey, params[paramPathDomainID])
+	b, _ := a.Do(ctx, apiKey, params[paramPathDomainID], scopes...)
 	return b
 }
 
-// Do authenticates apiKey.
-func (a *DocstoreAuth) Do(ctx context.Context, apiKey string, domainID string) (bool, error) {
+// Do authenticates apiKey, and checks that it has all the scopes and is allowed for domainID.
+// Expired keys are not allowed.
+func (a *DocstoreAuth) Do(ctx context.Context, apiKey string, domainID string, scopes ...Scope) (bool, error) {
+	k, err := a.get(ctx, apiKey)
+	if err != nil {
+		return false, util.Wrap(ErrCouldNotAuthenticate, err)
+	}
+	now := time.Now()
+	if k == nil || k.expired(now) || !k.allows(domainID) || !k.HasScopes(scopes...) {
+		return false, nil
+	}
+	a.touch(ctx, k, now)
+	return true, nil
+}
+
+// Admin returns the APIKey of apiKey if it has not expired and has ScopeKeysAdmin, otherwise nil.
+// See APIKey.Manages for the keys that it can manage.
+func (a *DocstoreAuth) Admin(ctx context.Context, apiKey string) (*APIKey, error) {
+	k, err := a.get(ctx, apiKey)
+	if err != nil {
+		return nil, util.Wrap(ErrCouldNotAuthenticate, err)
+	}
+	now := time.Now()
+	if k == nil || k.expired(now) || !k.HasScopes(ScopeKeysAdmin) {
+		return nil, nil
+	}
+	a.touch(ctx, k, now)
+	return k, nil
+}
+
+// Lookup returns the APIKey of apiKey even if it has expired, or nil if not found or the secret is wrong.
+// It does not authenticate apiKey, e.g. it is used to check the domain of a key to delete.
+func (a *DocstoreAuth) Lookup(ctx context.Context, apiKey string) (*APIKey, error) {
+	k, err := a.get(ctx, apiKey)
+	if err != nil {
+		return nil, util.Wrap(ErrCouldNotAuthenticate, err)
+	}
+	return k, nil
+}
+
+// touch updates k.LastUsed to now if LastUsedInterval has passed.
+func (a *DocstoreAuth) touch(ctx context.Context, k *APIKey, now time.Time) {
+	if k.touch(now) {
+		// LastUsed is informational, so failing to update it does not fail authentication.
+		_ = a.coll.Update(ctx, k, docstore.Mods{"last_used": now})
+	}
+}
+
+// get returns the APIKey of apiKey, or nil if not found or the secret is wrong.
+func (a *DocstoreAuth) get(ctx context.Context, apiKey string) (*APIKey, error) {
+	id, secret := splitKey(apiKey)
 	k := &APIKey{
-		Key: apiKey,
+		ID: id,
 	}
 	if err := a.coll.Get(ctx, k); err != nil {
 		// NotFound: not empty but not found in docstore
 		// InvalidArgument: empty string
-		if strings.Contains(err.Error(), "code=NotFound") || strings.Contains(err.Error(), "code=InvalidArgument") {
-			return false, nil
+		if c := gcerrors.Code(err); c == gcerrors.NotFound || c == gcerrors.InvalidArgument {
+			return nil, nil
 		}
-		return false, fmt.Errorf("%w (%v)", ErrCouldNotAuthenticate, err)
+		return nil, util.DocstoreError(err)
+	}
+	if !k.verify(secret) {
+		return nil, nil
 	}
+	return k, nil
+}
+
+// allows checks whether k is allowed for domainID.
+// Only global admin keys are allowed for operations without domains, i.e. domainID is "".
+func (k *APIKey) allows(domainID string) bool {
 	if k.ScopeRegisterAll {
-		return true, nil
+		return true
 	}
 	if k.ScopeRegisterDomain && (k.DomainID == domainID) {
-		return true, nil
+		return true
 	}
-	return false, nil
+	return false
 }
 
-// Generate generates a new APIKey and inserts it into datastore.
-func (a *DocstoreAuth) Generate(ctx context.Context, domID string, isGlobalAdmin bool, note string) (*APIKey, error) {
-	key := NextKeyFn()
-	if key == "" {
-		return nil, fmt.Errorf(`%w (NextKeyFn error)`, ErrCouldNotGenerateKey)
+// Manages returns whether the admin key k can manage API keys of domID.
+// Global admins manage all keys, and domain admins manage keys of their domain.
+// Global keys, i.e. domID is "", are managed only by global admins.
+func (k *APIKey) Manages(domID string) bool {
+	return k.allows(domID)
+}
+
+// generateKey returns a new APIKey with Key set. ttl is 0 if the key does not expire.
+// scopes is nil for DefaultScopes, or AllScopes if isGlobalAdmin.
+func generateKey(domID string, isGlobalAdmin bool, note string, ttl time.Duration, scopes []Scope) (*APIKey, error) {
+	for _, s := range scopes {
+		if !s.valid() {
+			return nil, fmt.Errorf("%w (%v %q)", ErrCouldNotGenerateKey, ErrInvalidScope, s)
+		}
 	}
-	k := &APIKey{
-		Key:                 key,
-		ScopeRegisterDomain: !isGlobalAdmin,
-		ScopeRegisterAll:    isGlobalAdmin,
-		DomainID:            domID,
-		Note:                note,
+	id, secret := NextKeyIDFn(), NextKeyFn()
+	if id == "" || secret == "" {
+		return nil, fmt.Errorf(`%w (NextKeyIDFn or NextKeyFn error)`, ErrCouldNotGenerateKey)
 	}
-	if err := a.coll.Create(ctx, k); err != nil {
+	k, err := newAPIKey(id, secret)
+	if err != nil {
 		return nil, fmt.Errorf("%w (%v)", ErrCouldNotGenerateKey, err)
 	}
+	k.Key = id + keySep + secret
+	k.ScopeRegisterDomain = !isGlobalAdmin
+	k.ScopeRegisterAll = isGlobalAdmin
+	k.DomainID = domID
+	k.Note = note
+	k.Scopes = scopes
+	k.CreatedAt = time.Now()
+	if ttl > 0 {
+		k.ExpiresAt = k.CreatedAt.Add(ttl)
+	}
+	return k, nil
+}
+
+// Generate generates a new APIKey and inserts it into datastore.
+// The key expires after ttl, or never if ttl is 0. See APIKey.Scopes for scopes.
+// The returned APIKey.Key is not stored and cannot be shown again.
+func (a *DocstoreAuth) Generate(ctx context.Context, domID string, isGlobalAdmin bool, note string, ttl time.Duration, scopes []Scope) (*APIKey, error) {
+	k, err := generateKey(domID, isGlobalAdmin, note, ttl, scopes)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.coll.Create(ctx, k); err != nil {
+		return nil, util.Wrap(ErrCouldNotGenerateKey, util.DocstoreError(err))
+	}
 	return k, nil
 }
 
 // Delete deletes the APIKey specified by apiKey from datastore.
-// No errors returned when the APIKey does not exist.
+// No errors returned when the APIKey does not exist or the secret is wrong.
 func (a *DocstoreAuth) Delete(ctx context.Context, apiKey string) error {
-	k := &APIKey{
-		Key: apiKey,
+	k, err := a.get(ctx, apiKey)
+	if err != nil {
+		return util.Wrap(ErrCouldNotDeleteKey, err)
+	}
+	if k == nil {
+		return nil
 	}
-	err := a.coll.Delete(ctx, k)
-	if err != nil && !strings.Contains(err.Error(), "code=InvalidArgument") {
-		return fmt.Errorf("%w (%v)", ErrCouldNotDeleteKey, err)
+	err = a.coll.Delete(ctx, k)
+	if err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		return util.Wrap(ErrCouldNotDeleteKey, util.DocstoreError(err))
 	}
 	return nil
 }
 
+// Rotate issues the successor of apiKey with the same scopes and lifetime,
+// and apiKey stays valid for grace. Returns ErrKeyNotFound if apiKey is not valid,
+// or ErrKeyAlreadyRotated if apiKey already has the successor.
+// The returned APIKey.Key is not stored and cannot be shown again.
+func (a *DocstoreAuth) Rotate(ctx context.Context, apiKey string, grace time.Duration) (*APIKey, error) {
+	k, err := a.get(ctx, apiKey)
+	if err != nil {
+		return nil, util.Wrap(ErrCouldNotRotateKey, err)
+	}
+	now := time.Now()
+	if k == nil || k.expired(now) {
+		return nil, util.Wrap(ErrCouldNotRotateKey, ErrKeyNotFound)
+	}
+	if k.SuccessorID != "" {
+		return nil, util.Wrap(ErrCouldNotRotateKey, ErrKeyAlreadyRotated)
+	}
+	s, err := k.successor(now)
+	if err != nil {
+		return nil, util.Wrap(ErrCouldNotRotateKey, err)
+	}
+	if err := a.coll.Create(ctx, s); err != nil {
+		return nil, util.Wrap(ErrCouldNotRotateKey, util.DocstoreError(err))
+	}
+	k.retire(s.ID, now, grace)
+	mods := docstore.Mods{"successor": k.SuccessorID, "expires_at": k.ExpiresAt}
+	if err := a.coll.Update(ctx, k, mods); err != nil {
+		return nil, util.Wrap(ErrCouldNotRotateKey, util.DocstoreError(err))
+	}
+	return s, nil
+}
+
+// ListStale returns APIKeys that have expired or not been used since the given time, ordered by ID.
+// Keys never used are checked by APIKey.CreatedAt.
+func (a *DocstoreAuth) ListStale(ctx context.Context, since time.Time) ([]*APIKey, error) {
+	now := time.Now()
+	var ks []*APIKey
+	iter := a.coll.Query().Get(ctx)
+	defer iter.Stop()
+	for {
+		k := &APIKey{}
+		err := iter.Next(ctx, k)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, util.Wrap(ErrCouldNotListKeys, util.DocstoreError(err))
+		}
+		if k.Hash != "" && k.stale(since, now) {
+			ks = append(ks, k)
+		}
+	}
+	sortKeys(ks)
+	return ks, nil
+}
+
+// Revoke deletes the APIKey specified by id, e.g. one of ListStale.
+// Unlike Delete, the secret is not required. No errors returned when the APIKey does not exist.
+func (a *DocstoreAuth) Revoke(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+	err := a.coll.Delete(ctx, &APIKey{ID: id})
+	if err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		return util.Wrap(ErrCouldNotDeleteKey, util.DocstoreError(err))
+	}
+	return nil
+}
+
+func sortKeys(ks []*APIKey) {
+	sort.Slice(ks, func(i, j int) bool { return ks[i].ID < ks[j].ID })
+}
+
+// Migrate replaces APIKeys stored in plaintext with hashed ones, and returns the number of them.
+// The keys are still valid, and their IDs are KeyID of them.
+// The collection must use "id" as the ID field, and it is safe to run Migrate again.
+func (a *DocstoreAuth) Migrate(ctx context.Context) (int, error) {
+	var ls []*legacyAPIKey
+	iter := a.coll.Query().Get(ctx)
+	defer iter.Stop()
+	for {
+		var l legacyAPIKey
+		err := iter.Next(ctx, &l)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, util.Wrap(ErrCouldNotMigrateKeys, util.DocstoreError(err))
+		}
+		if l.plaintext() != "" {
+			ls = append(ls, &l)
+		}
+	}
+	for i, l := range ls {
+		k, err := l.migrate()
+		if err != nil {
+			return i, fmt.Errorf("%w (%v)", ErrCouldNotMigrateKeys, err)
+		}
+		// Put not Create, as the hashed one exists if the last Migrate failed before Delete.
+		if err := a.coll.Put(ctx, k); err != nil {
+			return i, util.Wrap(ErrCouldNotMigrateKeys, util.DocstoreError(err))
+		}
+		if err := a.coll.Delete(ctx, &APIKey{ID: l.plaintext()}); err != nil {
+			return i, util.Wrap(ErrCouldNotMigrateKeys, util.DocstoreError(err))
+		}
+	}
+	return len(ls), nil
+}
+
 // MustNewDocstoreAuth initializes an DocstoreAuth,
 // panics if failed to access datastore.
 func MustNewDocstoreAuth(conn string) *DocstoreAuth {
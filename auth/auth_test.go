@@ -2,13 +2,25 @@ package auth_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/ebiiim/btcgw/auth"
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
 
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"go.etcd.io/bbolt"
 	_ "gocloud.dev/docstore/memdocstore"
 )
 
@@ -19,9 +31,85 @@ func TestUUIDNextKey(t *testing.T) {
 	}
 }
 
+func TestKeyID(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"normal", "0123456789abcdef.abc", "0123456789abcdef"},
+		{"no_id", "abc", "ba7816bf8f01cfea"},
+		{"empty", "", ""},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := auth.KeyID(c.key); got != c.want {
+				t.Errorf("got %q want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		s       string
+		want    []auth.Scope
+		wantErr error
+	}{
+		{"one", "anchors:read", []auth.Scope{auth.ScopeAnchorsRead}, nil},
+		{"many", "anchors:read, keys:admin,wallet:admin", []auth.Scope{auth.ScopeAnchorsRead, auth.ScopeKeysAdmin, auth.ScopeWalletAdmin}, nil},
+		{"empty", "", nil, nil},
+		{"unknown", "anchors:read,anchors:write", nil, auth.ErrInvalidScope},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := auth.ParseScopes(c.s)
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("got %v but want %v", err, c.wantErr)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v but want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAPIKey_HasScopes(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name   string
+		key    *auth.APIKey
+		scopes []auth.Scope
+		want   bool
+	}{
+		{"granted", &auth.APIKey{Scopes: []auth.Scope{auth.ScopeAnchorsRead, auth.ScopeKeysAdmin}}, []auth.Scope{auth.ScopeKeysAdmin, auth.ScopeAnchorsRead}, true},
+		{"not_granted", &auth.APIKey{Scopes: []auth.Scope{auth.ScopeAnchorsRead}}, []auth.Scope{auth.ScopeAnchorsRead, auth.ScopeAnchorsRegister}, false},
+		{"none_required", &auth.APIKey{Scopes: []auth.Scope{auth.ScopeAnchorsRead}}, nil, true},
+		{"default_domain", &auth.APIKey{ScopeRegisterDomain: true}, []auth.Scope{auth.ScopeAnchorsRefresh}, true},
+		{"default_domain_admin", &auth.APIKey{ScopeRegisterDomain: true}, []auth.Scope{auth.ScopeWalletAdmin}, false},
+		{"default_global", &auth.APIKey{ScopeRegisterAll: true}, []auth.Scope{auth.ScopeWalletAdmin, auth.ScopeKeysAdmin}, true},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := c.key.HasScopes(c.scopes...); got != c.want {
+				t.Errorf("got %v but want %v", got, c.want)
+			}
+		})
+	}
+}
+
 func TestSpecialAuth(t *testing.T) {
 	a := &auth.SpecialAuth{}
-	if !(a.AuthFunc(nil, "12345", nil)) && a.AuthFunc(nil, "1234", nil) {
+	if !(a.AuthFunc(nil, "12345", nil, nil)) && a.AuthFunc(nil, "1234", nil, nil) {
 		t.Error("AuthFunc")
 		t.Skip()
 	}
@@ -39,36 +127,69 @@ func dummyNextKey(key string) auth.NextKeyFunc {
 	return func() string { return key }
 }
 
+// setNextKey makes the next key "<id>.<secret>".
+func setNextKey(id, secret string) {
+	auth.NextKeyIDFn = dummyNextKey(id)
+	auth.NextKeyFn = dummyNextKey(secret)
+}
+
+// sameKey compares a and b except the salt, the hash and the creation time.
+func sameKey(a, b *auth.APIKey) bool {
+	a2, b2 := *a, *b
+	a2.Salt, a2.Hash, b2.Salt, b2.Hash = "", "", "", ""
+	a2.CreatedAt, b2.CreatedAt = time.Time{}, time.Time{}
+	return reflect.DeepEqual(a2, b2)
+}
+
 // Assumes tests will be run from package root.
 var (
+	// testdb1 has key1, key2 and key3 stored in plaintext, so it is migrated first.
 	testdb1 = "testdata/apikeys1.db"
-	conn1   = "mem://auth_test_do/key?filename=" + testdb1
-	conn2   = "mem://auth_test/key"
+	conn2   = "mem://auth_test/id"
 )
 
+// copyTestDB returns the conn to a copy of testdb1, as memdocstore saves it on Close.
+func copyTestDB(t *testing.T, name string) string {
+	b, err := ioutil.ReadFile(testdb1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "apikeys.db")
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return "mem://" + name + "/id?filename=" + path
+}
+
 var (
 	key1    = "0123456789abcdef0123456789abcdef"
 	key2    = "123456789abcdef0123456789abcdef0"
 	key3    = "23456789abcdef0123456789abcdef01"
+	id1     = "0011223344556677"
+	id2     = "1122334455667788"
+	id3     = "2233445566778899"
 	dom1    = "0123456780abcdef0120456789abc0ef"
 	dom2    = "1234567890bcdef0123056789abcd0f0"
 	dom3    = "23456789a0cdef0123406789abcde001"
 	apikey1 = &auth.APIKey{
-		Key:                 key1,
+		ID:                  id1,
+		Key:                 id1 + "." + key1,
 		ScopeRegisterAll:    true,
 		ScopeRegisterDomain: false,
 		DomainID:            dom1,
 		Note:                "globalAdmin",
 	}
 	apikey2 = &auth.APIKey{
-		Key:                 key2,
+		ID:                  id2,
+		Key:                 id2 + "." + key2,
 		ScopeRegisterAll:    false,
 		ScopeRegisterDomain: true,
 		DomainID:            dom2,
 		Note:                "",
 	}
 	apikey3 = &auth.APIKey{
-		Key:                 key3,
+		ID:                  id3,
+		Key:                 id3 + "." + key3,
 		ScopeRegisterAll:    false,
 		ScopeRegisterDomain: true,
 		DomainID:            dom3,
@@ -76,7 +197,45 @@ var (
 	}
 )
 
+func TestDocstoreAuth_Migrate(t *testing.T) {
+	conn := copyTestDB(t, "auth_test_migrate")
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+
+	a := auth.MustNewDocstoreAuth(conn)
+	defer a.Close()
+	if got, _ := a.Do(ctx, key1, dom1); got {
+		t.Error("keys stored in plaintext should not be authenticated")
+	}
+	n, err := a.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("got %d but want %d", n, 3)
+	}
+	if n, err := a.Migrate(ctx); err != nil || n != 0 {
+		t.Errorf("second Migrate: got (%d, %v) but want (0, nil)", n, err)
+	}
+	if got, _ := a.Do(ctx, key1, dom1); !got {
+		t.Error("migrated key should be authenticated")
+	}
+	if got, _ := a.Do(ctx, auth.KeyID(key1)+"."+key1, dom1); !got {
+		t.Error("migrated key should be authenticated with its ID")
+	}
+	if got, _ := a.Do(ctx, auth.KeyID(key1), dom1); got {
+		t.Error("ID only should not be authenticated")
+	}
+}
+
 func TestDocstoreAuth_Do(t *testing.T) {
+	conn1 := copyTestDB(t, "auth_test_do")
+	a := auth.MustNewDocstoreAuth(conn1)
+	if _, err := a.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
 	cases := []struct {
 		name      string
 		conn      string
@@ -90,8 +249,10 @@ func TestDocstoreAuth_Do(t *testing.T) {
 		{"user_self", conn1, key2, dom2, true},
 		{"user_other_1", conn1, key2, dom3, false},
 		{"user_other_2", conn1, key2, dom1, false},
+		{"wrong_secret", conn1, auth.KeyID(key1) + "." + key2, dom1, false},
 		{"notfound_1", conn1, "12121212121212121212121212121212", dom1, false},
 		{"notfound_2", conn1, "1", dom1, false},
+		{"notfound_3", conn1, id1 + "." + key1, dom1, false},
 		{"empty", conn1, "", dom1, false},
 	}
 	for _, c := range cases {
@@ -119,15 +280,16 @@ func TestDocstoreAuth_Generate(t *testing.T) {
 	cases := []struct {
 		name    string
 		conn    string
+		id      string
 		key     string
 		dom     string
 		isAdmin bool
 		note    string
 		want    *auth.APIKey
 	}{
-		{"admin", conn2, key1, dom1, true, "globalAdmin", apikey1},
-		{"user1", conn2, key2, dom2, false, "", apikey2},
-		{"user2", conn2, key3, dom3, false, "", apikey3},
+		{"admin", conn2, id1, key1, dom1, true, "globalAdmin", apikey1},
+		{"user1", conn2, id2, key2, dom2, false, "", apikey2},
+		{"user2", conn2, id3, key3, dom3, false, "", apikey3},
 	}
 	for _, c := range cases {
 		c := c
@@ -137,15 +299,18 @@ func TestDocstoreAuth_Generate(t *testing.T) {
 			ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancelFunc()
 
-			auth.NextKeyFn = dummyNextKey(c.key)
-			got, err := a.Generate(ctx, c.dom, c.isAdmin, c.note)
+			setNextKey(c.id, c.key)
+			got, err := a.Generate(ctx, c.dom, c.isAdmin, c.note, 0, nil)
 			if err != nil {
 				t.Error(err)
 				t.Skip()
 			}
-			if !reflect.DeepEqual(got, c.want) {
+			if !sameKey(got, c.want) {
 				t.Errorf("got %+v but want %+v", got, c.want)
 			}
+			if ok, err := a.Do(ctx, got.Key, c.dom); err != nil || !ok {
+				t.Errorf("Do: got (%v, %v) but want (true, nil)", ok, err)
+			}
 		})
 	}
 }
@@ -154,13 +319,14 @@ func TestDocstoreAuth_Generate_Error(t *testing.T) {
 	cases := []struct {
 		name    string
 		conn    string
+		id      string
 		key     string
 		dom     string
 		isAdmin bool
 		note    string
 		want    error
 	}{
-		{"dup", conn2, key1, dom1, true, "globalAdmin", auth.ErrCouldNotGenerateKey},
+		{"dup", conn2, id1, key1, dom1, true, "globalAdmin", auth.ErrCouldNotGenerateKey},
 	}
 	for _, c := range cases {
 		c := c
@@ -170,14 +336,14 @@ func TestDocstoreAuth_Generate_Error(t *testing.T) {
 			ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancelFunc()
 
-			auth.NextKeyFn = dummyNextKey(c.key)
-			_, err := a.Generate(ctx, c.dom, c.isAdmin, c.note)
+			setNextKey(c.id, c.key)
+			_, err := a.Generate(ctx, c.dom, c.isAdmin, c.note, 0, nil)
 			if err != nil {
 				t.Error(err)
 				t.Skip()
 			}
-			_, err = a.Generate(ctx, c.dom, c.isAdmin, c.note)
-			if !errors.Is(err, c.want) {
+			_, err = a.Generate(ctx, c.dom, c.isAdmin, c.note, 0, nil)
+			if !errors.Is(err, c.want) || !errors.Is(err, util.ErrAlreadyExists) {
 				t.Errorf("got %+v but want %+v", err, c.want)
 			}
 		})
@@ -188,14 +354,15 @@ func TestDocstoreAuth_Delete(t *testing.T) {
 	cases := []struct {
 		name    string
 		conn    string
+		id      string
 		key     string
 		dom     string
 		isAdmin bool
 		note    string
 	}{
-		{"admin", conn2, key1, dom1, true, "globalAdmin"},
-		{"user1", conn2, key2, dom2, false, ""},
-		{"user2", conn2, key3, dom3, false, ""},
+		{"admin", conn2, id1, key1, dom1, true, "globalAdmin"},
+		{"user1", conn2, id2, key2, dom2, false, ""},
+		{"user2", conn2, id3, key3, dom3, false, ""},
 	}
 	for _, c := range cases {
 		c := c
@@ -205,12 +372,21 @@ func TestDocstoreAuth_Delete(t *testing.T) {
 			ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancelFunc()
 
-			auth.NextKeyFn = dummyNextKey(c.key)
-			got, err := a.Generate(ctx, c.dom, c.isAdmin, c.note)
+			setNextKey(c.id, c.key)
+			got, err := a.Generate(ctx, c.dom, c.isAdmin, c.note, 0, nil)
 			if err != nil {
 				t.Error(err)
 				t.Skip()
 			}
+			// The ID only or a wrong secret does not delete it.
+			for _, k := range []string{got.ID, got.ID + ".x"} {
+				if err := a.Delete(ctx, k); err != nil {
+					t.Error(err)
+				}
+			}
+			if ok, _ := a.Do(ctx, got.Key, c.dom); !ok {
+				t.Error("deleted by a wrong key")
+			}
 			err = a.Delete(ctx, got.Key)
 			if err != nil {
 				t.Error(err)
@@ -230,6 +406,7 @@ func TestDocstoreAuth_Delete_NotFound(t *testing.T) {
 		key  string
 	}{
 		{"not_found", conn2, "12345"},
+		{"not_found_id", conn2, id1 + ".12345"},
 		{"empty", conn2, ""},
 	}
 	for _, c := range cases {
@@ -251,3 +428,535 @@ func TestDocstoreAuth_Delete_NotFound(t *testing.T) {
 		})
 	}
 }
+
+// keyLifecycle is implemented by DocstoreAuth and BoltAuth.
+type keyLifecycle interface {
+	Do(ctx context.Context, apiKey string, domainID string, scopes ...auth.Scope) (bool, error)
+	Generate(ctx context.Context, domID string, isGlobalAdmin bool, note string, ttl time.Duration, scopes []auth.Scope) (*auth.APIKey, error)
+	Rotate(ctx context.Context, apiKey string, grace time.Duration) (*auth.APIKey, error)
+	ListStale(ctx context.Context, since time.Time) ([]*auth.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+}
+
+// testKeyLifecycle tests expiry, rotation, stale keys and revocation of a.
+func testKeyLifecycle(t *testing.T, a keyLifecycle) {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	auth.NextKeyIDFn, auth.NextKeyFn = auth.RandomKeyID, auth.UUIDNextKey
+
+	old, err := a.Generate(ctx, dom1, false, "old", time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := old.ExpiresAt.Sub(old.CreatedAt); d != time.Hour {
+		t.Errorf("ttl: got %v but want %v", d, time.Hour)
+	}
+	unused, err := a.Generate(ctx, dom2, false, "unused", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now()
+	if ok, _ := a.Do(ctx, old.Key, dom1); !ok {
+		t.Error("old key should be authenticated")
+	}
+
+	// The old key expires immediately with no grace period.
+	succ, err := a.Rotate(ctx, old.Key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if succ.Key == old.Key || succ.DomainID != dom1 || succ.ScopeRegisterDomain != true || succ.ExpiresAt.Sub(succ.CreatedAt) != time.Hour {
+		t.Errorf("successor: got %+v", succ)
+	}
+	if ok, _ := a.Do(ctx, old.Key, dom1); ok {
+		t.Error("rotated key should expire after the grace period")
+	}
+	if ok, _ := a.Do(ctx, succ.Key, dom1); !ok {
+		t.Error("successor should be authenticated")
+	}
+	if _, err := a.Rotate(ctx, old.Key, 0); !errors.Is(err, auth.ErrKeyNotFound) {
+		t.Errorf("rotate expired: got %v but want %v", err, auth.ErrKeyNotFound)
+	}
+	if _, err := a.Rotate(ctx, succ.Key, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Rotate(ctx, succ.Key, time.Hour); !errors.Is(err, auth.ErrKeyAlreadyRotated) {
+		t.Errorf("rotate twice: got %v but want %v", err, auth.ErrKeyAlreadyRotated)
+	}
+
+	// old has expired, unused is not used since, and succ and its successor are used or new.
+	ks, err := a.ListStale(ctx, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, k := range ks {
+		got[k.ID] = true
+	}
+	if want := map[string]bool{old.ID: true, unused.ID: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stale: got %v but want %v", got, want)
+	}
+	for _, k := range ks {
+		if k.ID == old.ID && k.SuccessorID != succ.ID {
+			t.Errorf("successor ID: got %q but want %q", k.SuccessorID, succ.ID)
+		}
+	}
+
+	if err := a.Revoke(ctx, unused.ID); err != nil {
+		t.Error(err)
+	}
+	if ok, _ := a.Do(ctx, unused.Key, dom2); ok {
+		t.Error("revoked key should not be authenticated")
+	}
+}
+
+func TestDocstoreAuth_Lifecycle(t *testing.T) {
+	a := auth.MustNewDocstoreAuth("mem://auth_test_lifecycle/id")
+	defer a.Close()
+	testKeyLifecycle(t, a)
+}
+
+func TestDocstoreAuth_LastUsed(t *testing.T) {
+	a := auth.MustNewDocstoreAuth("mem://auth_test_lastused/id")
+	defer a.Close()
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	setNextKey(id1, key1)
+
+	k, err := a.Generate(ctx, dom1, false, "", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now()
+	if ks, _ := a.ListStale(ctx, since); len(ks) != 1 {
+		t.Errorf("never used: got %d stale keys but want 1", len(ks))
+	}
+	if ok, _ := a.Do(ctx, k.Key, dom1); !ok {
+		t.Fatal("should be authenticated")
+	}
+	ks, _ := a.ListStale(ctx, since)
+	if len(ks) != 0 {
+		t.Errorf("used: got %d stale keys but want 0", len(ks))
+	}
+	// Throttled until LastUsedInterval passes.
+	ks, _ = a.ListStale(ctx, time.Now().Add(time.Minute))
+	if len(ks) != 1 {
+		t.Fatalf("got %d stale keys but want 1", len(ks))
+	}
+	used := ks[0].LastUsed
+	a.Do(ctx, k.Key, dom1)
+	ks, _ = a.ListStale(ctx, time.Now().Add(time.Minute))
+	if len(ks) != 1 || !ks[0].LastUsed.Equal(used) {
+		t.Errorf("LastUsed should not be updated within LastUsedInterval")
+	}
+}
+
+func TestDocstoreAuth_Scopes(t *testing.T) {
+	a := auth.MustNewDocstoreAuth("mem://auth_test_scopes/id")
+	defer a.Close()
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+
+	setNextKey(id1, key1)
+	reader, err := a.Generate(ctx, dom1, false, "", 0, []auth.Scope{auth.ScopeAnchorsRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setNextKey(id2, key2)
+	admin, err := a.Generate(ctx, "", true, "", 0, []auth.Scope{auth.ScopeWalletAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Generate(ctx, dom1, false, "", 0, []auth.Scope{"anchors:write"}); !errors.Is(err, auth.ErrCouldNotGenerateKey) {
+		t.Errorf("got %v but want %v", err, auth.ErrCouldNotGenerateKey)
+	}
+
+	cases := []struct {
+		name   string
+		key    string
+		dom    string
+		scopes []auth.Scope
+		want   bool
+	}{
+		{"reader_read", reader.Key, dom1, []auth.Scope{auth.ScopeAnchorsRead}, true},
+		{"reader_register", reader.Key, dom1, []auth.Scope{auth.ScopeAnchorsRegister}, false},
+		{"reader_other_domain", reader.Key, dom2, []auth.Scope{auth.ScopeAnchorsRead}, false},
+		{"reader_no_domain", reader.Key, "", []auth.Scope{auth.ScopeAnchorsRead}, false},
+		{"admin_wallet", admin.Key, "", []auth.Scope{auth.ScopeWalletAdmin}, true},
+		{"admin_read", admin.Key, dom1, []auth.Scope{auth.ScopeAnchorsRead}, false},
+	}
+	for _, c := range cases {
+		if got, err := a.Do(ctx, c.key, c.dom, c.scopes...); err != nil || got != c.want {
+			t.Errorf("%s: got (%v, %v) but want (%v, nil)", c.name, got, err, c.want)
+		}
+	}
+}
+
+// testKeyAdmin tests Admin and Lookup of a.
+func testKeyAdmin(t *testing.T, a auth.KeyManager) {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+
+	setNextKey(id1, key1)
+	domAdmin, err := a.Generate(ctx, dom1, false, "", 0, []auth.Scope{auth.ScopeKeysAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setNextKey(id2, key2)
+	user, err := a.Generate(ctx, dom1, false, "", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setNextKey(id3, key3)
+	admin, err := a.Generate(ctx, "", true, "", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		key     string
+		wantID  string
+		manages map[string]bool
+	}{
+		{"domain_admin", domAdmin.Key, id1, map[string]bool{dom1: true, dom2: false, "": false}},
+		{"global_admin", admin.Key, id3, map[string]bool{dom1: true, dom2: true, "": true}},
+		{"no_scope", user.Key, "", nil},
+		{"wrong_secret", id1 + ".x", "", nil},
+		{"empty", "", "", nil},
+	}
+	for _, c := range cases {
+		k, err := a.Admin(ctx, c.key)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if (k == nil && c.wantID != "") || (k != nil && k.ID != c.wantID) {
+			t.Errorf("%s: got %+v but want ID %q", c.name, k, c.wantID)
+			continue
+		}
+		for dom, want := range c.manages {
+			if got := k.Manages(dom); got != want {
+				t.Errorf("%s: Manages(%q) got %v but want %v", c.name, dom, got, want)
+			}
+		}
+	}
+
+	k, err := a.Lookup(ctx, user.Key)
+	if err != nil || k == nil || k.DomainID != dom1 {
+		t.Errorf("Lookup: got (%+v, %v) but want the key of %s", k, err, dom1)
+	}
+}
+
+func TestDocstoreAuth_Admin(t *testing.T) {
+	a := auth.MustNewDocstoreAuth("mem://auth_test_admin/id")
+	defer a.Close()
+	testKeyAdmin(t, a)
+}
+
+func TestReadBootstrapToken(t *testing.T) {
+	t.Parallel()
+	const token = "0123456789abcdef0123456789abcdef"
+	dir := t.TempDir()
+	cases := []struct {
+		name    string
+		content string
+		want    auth.BootstrapToken
+		wantErr error
+	}{
+		{"normal", token, token, nil},
+		{"trim", " " + token + "\n", token, nil},
+		{"short", token[:31], "", auth.ErrInvalidBootstrapToken},
+		{"no_file", "", "", auth.ErrInvalidBootstrapToken},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(dir, c.name)
+			if c.content != "" {
+				if err := ioutil.WriteFile(path, []byte(c.content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := auth.ReadBootstrapToken(path)
+			if got != c.want || !errors.Is(err, c.wantErr) {
+				t.Errorf("got (%q, %v) but want (%q, %v)", got, err, c.want, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestBootstrapToken_Admin(t *testing.T) {
+	t.Parallel()
+	const token = "0123456789abcdef0123456789abcdef"
+	cases := []struct {
+		name   string
+		token  auth.BootstrapToken
+		apiKey string
+		want   bool
+	}{
+		{"match", token, token, true},
+		{"mismatch", token, token[1:], false},
+		{"empty_token", "", "", false},
+	}
+	for _, c := range cases {
+		k := c.token.Admin(c.apiKey)
+		if (k != nil) != c.want {
+			t.Errorf("%s: got %+v but want %v", c.name, k, c.want)
+			continue
+		}
+		if k != nil && (k.ID != auth.BootstrapKeyID || !k.Manages("") || !k.HasScopes(auth.AllScopes...)) {
+			t.Errorf("%s: got %+v but want a global admin", c.name, k)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+	digest := sha256.Sum256([]byte("hello"))
+	other := sha256.Sum256([]byte("world"))
+
+	k1, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SignCompact returns the recovery code followed by r and s.
+	sig1 := secp256k1ecdsa.SignCompact(k1, digest[:], false)[1:]
+
+	k2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, k2, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2 := make([]byte, 64)
+	r.FillBytes(sig2[:32])
+	s.FillBytes(sig2[32:])
+	pub2 := elliptic.Marshal(elliptic.P256(), k2.X, k2.Y)
+
+	cases := []struct {
+		name    string
+		keyType model.KeyType
+		pub     []byte
+		digest  []byte
+		sig     []byte
+		want    error
+	}{
+		{"secp256k1_uncompressed", model.KeySecp256k1, k1.PubKey().SerializeUncompressed(), digest[:], sig1, nil},
+		{"secp256k1_compressed", model.KeySecp256k1, k1.PubKey().SerializeCompressed(), digest[:], sig1, nil},
+		{"secp256k1_other_digest", model.KeySecp256k1, k1.PubKey().SerializeCompressed(), other[:], sig1, auth.ErrInvalidSignature},
+		{"secp256k1_short_sig", model.KeySecp256k1, k1.PubKey().SerializeCompressed(), digest[:], sig1[1:], auth.ErrInvalidSignature},
+		{"p256_uncompressed", model.KeyP256, pub2, digest[:], sig2, nil},
+		{"p256_compressed", model.KeyP256, elliptic.MarshalCompressed(elliptic.P256(), k2.X, k2.Y), digest[:], sig2, nil},
+		{"p256_other_digest", model.KeyP256, pub2, other[:], sig2, auth.ErrInvalidSignature},
+		{"wrong_key_type", model.KeySecp256k1, pub2, digest[:], sig2, auth.ErrInvalidSignature},
+		{"no_key_type", model.KeyNone, pub2, digest[:], sig2, auth.ErrInvalidSignature},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := auth.VerifySignature(c.keyType, c.pub, c.digest, c.sig); !errors.Is(got, c.want) {
+				t.Errorf("got %v but want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDocstoreChallenges(t *testing.T) {
+	c := auth.MustNewDocstoreChallenges("mem://auth_test_challenges/nonce")
+	defer c.Close()
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+
+	ch, err := c.Issue(ctx, dom1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.Nonce) != 32 || len(ch.Digest()) != 32 || !ch.ExpiresAt.After(time.Now()) {
+		t.Fatalf("got %+v", ch)
+	}
+	got, err := c.Consume(ctx, ch.Nonce, dom1)
+	if err != nil || got.DomainID != dom1 || string(got.Digest()) != string(ch.Digest()) {
+		t.Errorf("got (%+v, %v) but want %+v", got, err, ch)
+	}
+	if _, err := c.Consume(ctx, ch.Nonce, dom1); !errors.Is(err, auth.ErrChallengeNotFound) {
+		t.Errorf("used: got %v but want %v", err, auth.ErrChallengeNotFound)
+	}
+
+	// Challenges of other domains are consumed too.
+	ch2, err := c.Issue(ctx, dom2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dom := range []string{dom1, dom2} {
+		if _, err := c.Consume(ctx, ch2.Nonce, dom); !errors.Is(err, auth.ErrChallengeNotFound) {
+			t.Errorf("other domain: got %v but want %v", err, auth.ErrChallengeNotFound)
+		}
+	}
+
+	orig := auth.ChallengeTTL
+	auth.ChallengeTTL = 0
+	defer func() { auth.ChallengeTTL = orig }()
+	ch3, err := c.Issue(ctx, dom1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Consume(ctx, ch3.Nonce, dom1); !errors.Is(err, auth.ErrChallengeNotFound) {
+		t.Errorf("expired: got %v but want %v", err, auth.ErrChallengeNotFound)
+	}
+	if _, err := c.Consume(ctx, "", dom1); !errors.Is(err, auth.ErrChallengeNotFound) {
+		t.Errorf("empty: got %v but want %v", err, auth.ErrChallengeNotFound)
+	}
+}
+
+func TestDocstoreChallenges_Purge(t *testing.T) {
+	c := auth.MustNewDocstoreChallenges("mem://auth_test_challenges_purge/nonce")
+	defer c.Close()
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+
+	origMax := auth.MaxOpenChallenges
+	auth.MaxOpenChallenges = 2
+	defer func() { auth.MaxOpenChallenges = origMax }()
+	for i := 0; i < 2; i++ {
+		if _, err := c.Issue(ctx, dom1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Issue(ctx, dom1); !errors.Is(err, auth.ErrTooManyChallenges) {
+		t.Errorf("got %v but want %v", err, auth.ErrTooManyChallenges)
+	}
+	// Other domains are not limited.
+	if _, err := c.Issue(ctx, dom2); err != nil {
+		t.Errorf("other domain: %v", err)
+	}
+	if n, err := c.Purge(ctx); err != nil || n != 0 {
+		t.Errorf("got (%d, %v) but want 0", n, err)
+	}
+
+	// Expired ones are purged by Issue, so they do not count toward the limit.
+	origTTL := auth.ChallengeTTL
+	auth.ChallengeTTL = 0
+	defer func() { auth.ChallengeTTL = origTTL }()
+	c2 := auth.MustNewDocstoreChallenges("mem://auth_test_challenges_purge2/nonce")
+	defer c2.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := c2.Issue(ctx, dom1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := c2.Purge(ctx); err != nil || n != 1 {
+		t.Errorf("got (%d, %v) but want 1", n, err)
+	}
+	if n, err := c2.Purge(ctx); err != nil || n != 0 {
+		t.Errorf("purged: got (%d, %v) but want 0", n, err)
+	}
+}
+
+func TestBoltAuth_Lifecycle(t *testing.T) {
+	a := auth.MustNewBoltAuth(t.TempDir() + "/apikeys.db")
+	defer a.Close()
+	testKeyLifecycle(t, a)
+}
+
+func TestBoltAuth_Admin(t *testing.T) {
+	a := auth.MustNewBoltAuth(t.TempDir() + "/apikeys.db")
+	defer a.Close()
+	testKeyAdmin(t, a)
+}
+
+func TestBoltAuth(t *testing.T) {
+	a := auth.MustNewBoltAuth(t.TempDir() + "/apikeys.db")
+	defer a.Close()
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+
+	for _, c := range []struct {
+		id, key string
+		want    *auth.APIKey
+	}{{id1, key1, apikey1}, {id2, key2, apikey2}} {
+		setNextKey(c.id, c.key)
+		got, err := a.Generate(ctx, c.want.DomainID, c.want.ScopeRegisterAll, c.want.Note, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameKey(got, c.want) {
+			t.Errorf("got %+v but want %+v", got, c.want)
+		}
+	}
+	if _, err := a.Generate(ctx, dom1, true, "", 0, nil); !errors.Is(err, auth.ErrCouldNotGenerateKey) || !errors.Is(err, util.ErrAlreadyExists) {
+		t.Errorf("dup: got %v but want %v", err, auth.ErrCouldNotGenerateKey)
+	}
+
+	cases := []struct {
+		name      string
+		key       string
+		trydomain string
+		want      bool
+	}{
+		{"admin_other", apikey1.Key, dom2, true},
+		{"user_self", apikey2.Key, dom2, true},
+		{"user_other", apikey2.Key, dom1, false},
+		{"wrong_secret", id1 + "." + key2, dom1, false},
+		{"id_only", id1, dom1, false},
+		{"notfound", apikey3.Key, dom3, false},
+		{"empty", "", dom1, false},
+	}
+	for _, c := range cases {
+		got, err := a.Do(ctx, c.key, c.trydomain)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: got %v but want %v", c.name, got, c.want)
+		}
+	}
+
+	if err := a.Delete(ctx, apikey2.Key); err != nil {
+		t.Error(err)
+	}
+	if err := a.Delete(ctx, apikey2.Key); err != nil {
+		t.Error(err)
+	}
+	if got, _ := a.Do(ctx, apikey2.Key, dom2); got {
+		t.Error("deleted key should not be authenticated")
+	}
+}
+
+func TestBoltAuth_Migrate(t *testing.T) {
+	path := t.TempDir() + "/apikeys.db"
+	// Stores apikey1 in plaintext like before hashing.
+	db, err := util.OpenBolt(path, []byte("apikeys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := json.Marshal(map[string]interface{}{"Key": key1, "ScopeRegisterAll": true, "DomainID": dom1})
+	err = db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte("apikeys")).Put([]byte(key1), v)
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := auth.MustNewBoltAuth(path)
+	defer a.Close()
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	if got, _ := a.Do(ctx, key1, dom2); got {
+		t.Error("keys stored in plaintext should not be authenticated")
+	}
+	if n, err := a.Migrate(ctx); err != nil || n != 1 {
+		t.Errorf("got (%d, %v) but want (1, nil)", n, err)
+	}
+	if n, err := a.Migrate(ctx); err != nil || n != 0 {
+		t.Errorf("second Migrate: got (%d, %v) but want (0, nil)", n, err)
+	}
+	if got, _ := a.Do(ctx, key1, dom2); !got {
+		t.Error("migrated key should be authenticated")
+	}
+}
@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ebiiim/btcgw/util"
+
+	"go.etcd.io/bbolt"
+)
+
+// boltAPIKeys contains APIKeys in JSON, with APIKey.ID as the key.
+var boltAPIKeys = []byte("apikeys")
+
+// BoltAuth provides AuthFunc and is backed by an embedded bbolt database file (see util.OpenBolt).
+type BoltAuth struct {
+	path string
+	db   *bbolt.DB
+	once sync.Once
+}
+
+// AuthFunc handles authentication.
+func (a *BoltAuth) AuthFunc(ctx context.Context, apiKey string, scopes []Scope, params map[string]string) bool {
+	b, _ := a.Do(ctx, apiKey, params[paramPathDomainID], scopes...)
+	return b
+}
+
+// Do authenticates apiKey, and checks that it has all the scopes and is allowed for domainID.
+// Expired keys are not allowed.
+func (a *BoltAuth) Do(ctx context.Context, apiKey string, domainID string, scopes ...Scope) (bool, error) {
+	k, err := a.get(apiKey)
+	if err != nil {
+		return false, fmt.Errorf("%w (%v)", ErrCouldNotAuthenticate, err)
+	}
+	now := time.Now()
+	if k == nil || k.expired(now) || !k.allows(domainID) || !k.HasScopes(scopes...) {
+		return false, nil
+	}
+	if k.touch(now) {
+		// LastUsed is informational, so failing to update it does not fail authentication.
+		_ = a.update(k.ID, func(k *APIKey) { k.LastUsed = now })
+	}
+	return true, nil
+}
+
+// Admin returns the APIKey of apiKey if it has not expired and has ScopeKeysAdmin, otherwise nil.
+// See APIKey.Manages for the keys that it can manage.
+func (a *BoltAuth) Admin(ctx context.Context, apiKey string) (*APIKey, error) {
+	k, err := a.get(apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotAuthenticate, err)
+	}
+	now := time.Now()
+	if k == nil || k.expired(now) || !k.HasScopes(ScopeKeysAdmin) {
+		return nil, nil
+	}
+	if k.touch(now) {
+		// LastUsed is informational, so failing to update it does not fail authentication.
+		_ = a.update(k.ID, func(k *APIKey) { k.LastUsed = now })
+	}
+	return k, nil
+}
+
+// Lookup returns the APIKey of apiKey even if it has expired, or nil if not found or the secret is wrong.
+// It does not authenticate apiKey, e.g. it is used to check the domain of a key to delete.
+func (a *BoltAuth) Lookup(ctx context.Context, apiKey string) (*APIKey, error) {
+	k, err := a.get(apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotAuthenticate, err)
+	}
+	return k, nil
+}
+
+// update reads the APIKey of id, applies f and writes it in a transaction.
+// Does nothing if the APIKey does not exist.
+func (a *BoltAuth) update(id string, f func(k *APIKey)) error {
+	return a.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(boltAPIKeys)
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		k := &APIKey{}
+		if err := json.Unmarshal(v, k); err != nil {
+			return err
+		}
+		f(k)
+		v, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), v)
+	})
+}
+
+// get returns the APIKey of apiKey, or nil if not found or the secret is wrong.
+func (a *BoltAuth) get(apiKey string) (*APIKey, error) {
+	id, secret := splitKey(apiKey)
+	if id == "" {
+		return nil, nil
+	}
+	var k *APIKey
+	err := a.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(boltAPIKeys).Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		k = &APIKey{}
+		return json.Unmarshal(v, k)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if k == nil || !k.verify(secret) {
+		return nil, nil
+	}
+	return k, nil
+}
+
+// Generate generates a new APIKey and inserts it into the database.
+// The key expires after ttl, or never if ttl is 0. See APIKey.Scopes for scopes.
+// The returned APIKey.Key is not stored and cannot be shown again.
+func (a *BoltAuth) Generate(ctx context.Context, domID string, isGlobalAdmin bool, note string, ttl time.Duration, scopes []Scope) (*APIKey, error) {
+	k, err := generateKey(domID, isGlobalAdmin, note, ttl, scopes)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.create(k); err != nil {
+		return nil, util.Wrap(ErrCouldNotGenerateKey, err)
+	}
+	return k, nil
+}
+
+// create inserts k. Returns util.ErrAlreadyExists if k.ID exists.
+func (a *BoltAuth) create(k *APIKey) error {
+	v, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return a.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(boltAPIKeys)
+		if b.Get([]byte(k.ID)) != nil {
+			return util.ErrAlreadyExists
+		}
+		return b.Put([]byte(k.ID), v)
+	})
+}
+
+// Delete deletes the APIKey specified by apiKey from the database.
+// No errors returned when the APIKey does not exist or the secret is wrong.
+func (a *BoltAuth) Delete(ctx context.Context, apiKey string) error {
+	k, err := a.get(apiKey)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotDeleteKey, err)
+	}
+	if k == nil {
+		return nil
+	}
+	err = a.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltAPIKeys).Delete([]byte(k.ID))
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotDeleteKey, err)
+	}
+	return nil
+}
+
+// Rotate issues the successor of apiKey with the same scopes and lifetime,
+// and apiKey stays valid for grace. Returns ErrKeyNotFound if apiKey is not valid,
+// or ErrKeyAlreadyRotated if apiKey already has the successor.
+// The returned APIKey.Key is not stored and cannot be shown again.
+func (a *BoltAuth) Rotate(ctx context.Context, apiKey string, grace time.Duration) (*APIKey, error) {
+	k, err := a.get(apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotRotateKey, err)
+	}
+	now := time.Now()
+	if k == nil || k.expired(now) {
+		return nil, util.Wrap(ErrCouldNotRotateKey, ErrKeyNotFound)
+	}
+	if k.SuccessorID != "" {
+		return nil, util.Wrap(ErrCouldNotRotateKey, ErrKeyAlreadyRotated)
+	}
+	s, err := k.successor(now)
+	if err != nil {
+		return nil, util.Wrap(ErrCouldNotRotateKey, err)
+	}
+	if err := a.create(s); err != nil {
+		return nil, util.Wrap(ErrCouldNotRotateKey, err)
+	}
+	if err := a.update(k.ID, func(k *APIKey) { k.retire(s.ID, now, grace) }); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotRotateKey, err)
+	}
+	return s, nil
+}
+
+// ListStale returns APIKeys that have expired or not been used since the given time, ordered by ID.
+// Keys never used are checked by APIKey.CreatedAt.
+func (a *BoltAuth) ListStale(ctx context.Context, since time.Time) ([]*APIKey, error) {
+	now := time.Now()
+	var ks []*APIKey
+	err := a.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltAPIKeys).ForEach(func(_, v []byte) error {
+			k := &APIKey{}
+			if err := json.Unmarshal(v, k); err != nil {
+				return err
+			}
+			if k.Hash != "" && k.stale(since, now) {
+				ks = append(ks, k)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotListKeys, err)
+	}
+	sortKeys(ks)
+	return ks, nil
+}
+
+// Revoke deletes the APIKey specified by id, e.g. one of ListStale.
+// Unlike Delete, the secret is not required. No errors returned when the APIKey does not exist.
+func (a *BoltAuth) Revoke(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+	err := a.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltAPIKeys).Delete([]byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotDeleteKey, err)
+	}
+	return nil
+}
+
+// Migrate replaces APIKeys stored in plaintext with hashed ones, and returns the number of them.
+// The keys are still valid, and their IDs are KeyID of them.
+func (a *BoltAuth) Migrate(ctx context.Context) (int, error) {
+	n := 0
+	err := a.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(boltAPIKeys)
+		var ls []*legacyAPIKey
+		err := b.ForEach(func(_, v []byte) error {
+			l := &legacyAPIKey{}
+			if err := json.Unmarshal(v, l); err != nil {
+				return err
+			}
+			if l.plaintext() != "" {
+				ls = append(ls, l)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Keys are not modified in ForEach.
+		for _, l := range ls {
+			k, err := l.migrate()
+			if err != nil {
+				return err
+			}
+			v, err := json.Marshal(k)
+			if err != nil {
+				return err
+			}
+			if err := b.Delete([]byte(l.plaintext())); err != nil {
+				return err
+			}
+			if err := b.Put([]byte(k.ID), v); err != nil {
+				return err
+			}
+		}
+		n = len(ls)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w (%v)", ErrCouldNotMigrateKeys, err)
+	}
+	return n, nil
+}
+
+// MustNewBoltAuth initializes a BoltAuth,
+// panics if failed to open the database file.
+func MustNewBoltAuth(path string) *BoltAuth {
+	a := &BoltAuth{
+		path: path,
+		db:   nil,
+	}
+	if err := a.Open(); err != nil {
+		panic(fmt.Sprintf("%v path=%s", err, path))
+	}
+	return a
+}
+
+func (a *BoltAuth) open() error {
+	db, err := util.OpenBolt(a.path, boltAPIKeys)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenKeyStore, err)
+	}
+	a.db = db
+	return nil
+}
+
+// Open opens a.db once.
+func (a *BoltAuth) Open() error {
+	var oErr error
+	a.once.Do(func() { oErr = a.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the BoltAuth.
+func (a *BoltAuth) Close() error {
+	if err := a.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseKeyStore, err)
+	}
+	if err := a.db.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseKeyStore, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// MinBootstrapTokenLen is the minimum length of BootstrapToken.
+const MinBootstrapTokenLen = 32
+
+// BootstrapKeyID is the ID of the APIKey returned by BootstrapToken.Admin.
+const BootstrapKeyID = "bootstrap"
+
+// Errors
+var (
+	ErrInvalidBootstrapToken = errors.New("ErrInvalidBootstrapToken")
+)
+
+// BootstrapToken is a secret read from a file, and is accepted as a global admin key
+// by the API key service, so that the first admin keys can be issued without one.
+// Remove the file and restart the service after that.
+type BootstrapToken string
+
+// ReadBootstrapToken reads a BootstrapToken from the file.
+// Leading and trailing spaces are ignored, and it must be at least MinBootstrapTokenLen characters.
+func ReadBootstrapToken(path string) (BootstrapToken, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%w (%v)", ErrInvalidBootstrapToken, err)
+	}
+	t := strings.TrimSpace(string(b))
+	if len(t) < MinBootstrapTokenLen {
+		return "", fmt.Errorf("%w (%d characters, at least %d)", ErrInvalidBootstrapToken, len(t), MinBootstrapTokenLen)
+	}
+	return BootstrapToken(t), nil
+}
+
+// Admin returns a global admin APIKey with AllScopes if apiKey is t, otherwise nil.
+// Empty t matches nothing.
+func (t BootstrapToken) Admin(apiKey string) *APIKey {
+	if t == "" || subtle.ConstantTimeCompare([]byte(t), []byte(apiKey)) != 1 {
+		return nil
+	}
+	return &APIKey{
+		ID:               BootstrapKeyID,
+		ScopeRegisterAll: true,
+		Note:             "Bootstrap token",
+	}
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"gocloud.dev/docstore"
+	"gocloud.dev/gcerrors"
+)
+
+// ChallengeTTL is the lifetime of Challenges.
+var ChallengeTTL = 5 * time.Minute
+
+// MaxOpenChallenges is the maximum number of unexpired Challenges of a domain.
+// Issuing challenges requires no API key, so this limits the documents anyone can create.
+var MaxOpenChallenges = 10
+
+// challengePrefix is prepended to the message of Challenges,
+// so that signatures for other purposes cannot be used.
+const challengePrefix = "btcgw-apikey-challenge:"
+
+// Errors
+var (
+	ErrCouldNotIssueChallenge = errors.New("ErrCouldNotIssueChallenge")
+	ErrTooManyChallenges      = errors.New("ErrTooManyChallenges")
+	ErrCouldNotPurgeChallenge = errors.New("ErrCouldNotPurgeChallenge")
+	ErrChallengeNotFound      = errors.New("ErrChallengeNotFound")
+	ErrInvalidSignature       = errors.New("ErrInvalidSignature")
+)
+
+// Challenge is a nonce that the owner of a BBc-1 domain signs to prove the ownership.
+type Challenge struct {
+	Nonce     string    `docstore:"nonce"`
+	DomainID  string    `docstore:"domid"`
+	ExpiresAt time.Time `docstore:"expires_at"`
+
+	// DocstoreRevision makes Consume fail if the Challenge has been consumed concurrently.
+	DocstoreRevision interface{}
+}
+
+// Digest returns the SHA-256 of "btcgw-apikey-challenge:<domain ID>:<nonce>".
+// The owner signs it with the key of the domain, as bbclib signs digests.
+func (c *Challenge) Digest() []byte {
+	h := sha256.Sum256([]byte(challengePrefix + c.DomainID + ":" + c.Nonce))
+	return h[:]
+}
+
+// VerifySignature verifies the ECDSA signature of digest with pub, the public key of keyType in SEC 1 form.
+// sig is r and s in 32 bytes each, as bbclib signs.
+// Returns ErrInvalidSignature if the signature or the key is invalid.
+func VerifySignature(keyType model.KeyType, pub, digest, sig []byte) error {
+	if len(sig) != 64 {
+		return fmt.Errorf("%w (signature: %d bytes)", ErrInvalidSignature, len(sig))
+	}
+	switch keyType {
+	case model.KeySecp256k1:
+		pk, err := secp256k1.ParsePubKey(pub)
+		if err != nil {
+			return fmt.Errorf("%w (%v)", ErrInvalidSignature, err)
+		}
+		var r, s secp256k1.ModNScalar
+		if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) {
+			return fmt.Errorf("%w (r or s overflows)", ErrInvalidSignature)
+		}
+		if !secp256k1ecdsa.NewSignature(&r, &s).Verify(digest, pk) {
+			return ErrInvalidSignature
+		}
+	case model.KeyP256:
+		c := elliptic.P256()
+		var x, y *big.Int
+		if len(pub) == 33 {
+			x, y = elliptic.UnmarshalCompressed(c, pub)
+		} else {
+			x, y = elliptic.Unmarshal(c, pub)
+		}
+		if x == nil {
+			return fmt.Errorf("%w (invalid public key)", ErrInvalidSignature)
+		}
+		pk := &ecdsa.PublicKey{Curve: c, X: x, Y: y}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pk, digest, r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("%w (key type %q)", ErrInvalidSignature, keyType)
+	}
+	return nil
+}
+
+// DocstoreChallenges stores Challenges and is backed by docstore.
+// The collection must use "nonce" as the ID field.
+type DocstoreChallenges struct {
+	conn string
+	coll *docstore.Collection
+	once sync.Once
+}
+
+// MustNewDocstoreChallenges initializes an DocstoreChallenges,
+// panics if failed to access datastore.
+func MustNewDocstoreChallenges(conn string) *DocstoreChallenges {
+	c := &DocstoreChallenges{
+		conn: conn,
+		coll: nil,
+	}
+	if err := c.Open(); err != nil {
+		panic(fmt.Sprintf("%v conn=%s", err, conn))
+	}
+	return c
+}
+
+func (c *DocstoreChallenges) open() error {
+	coll, err := docstore.OpenCollection(context.Background(), c.conn)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenKeyStore, err)
+	}
+	c.coll = coll
+	return nil
+}
+
+// Open opens c.coll once.
+func (c *DocstoreChallenges) Open() error {
+	var oErr error
+	c.once.Do(func() { oErr = c.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the DocstoreChallenges.
+func (c *DocstoreChallenges) Close() error {
+	if err := c.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseKeyStore, err)
+	}
+	if err := c.coll.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseKeyStore, err)
+	}
+	return nil
+}
+
+// Issue creates a Challenge of domID that expires after ChallengeTTL.
+// Expired Challenges are purged first, as they are deleted only when consumed otherwise.
+// Returns ErrTooManyChallenges if domID has MaxOpenChallenges unexpired ones.
+// Concurrent calls may exceed the limit slightly.
+func (c *DocstoreChallenges) Issue(ctx context.Context, domID string) (*Challenge, error) {
+	if _, err := c.Purge(ctx); err != nil {
+		return nil, util.Wrap(ErrCouldNotIssueChallenge, err)
+	}
+	n, err := c.count(ctx, domID)
+	if err != nil {
+		return nil, util.Wrap(ErrCouldNotIssueChallenge, err)
+	}
+	if n >= MaxOpenChallenges {
+		return nil, fmt.Errorf("%w (domain=%s)", ErrTooManyChallenges, domID)
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotIssueChallenge, err)
+	}
+	ch := &Challenge{
+		Nonce:     hex.EncodeToString(b),
+		DomainID:  domID,
+		ExpiresAt: time.Now().Add(ChallengeTTL),
+	}
+	if err := c.coll.Create(ctx, ch); err != nil {
+		return nil, util.Wrap(ErrCouldNotIssueChallenge, util.DocstoreError(err))
+	}
+	return ch, nil
+}
+
+// count returns the number of Challenges of domID.
+func (c *DocstoreChallenges) count(ctx context.Context, domID string) (int, error) {
+	iter := c.coll.Query().Where("domid", "=", domID).Get(ctx, "nonce")
+	defer iter.Stop()
+	n := 0
+	for {
+		var ch Challenge
+		err := iter.Next(ctx, &ch)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return 0, util.DocstoreError(err)
+		}
+		n++
+	}
+}
+
+// Purge deletes expired Challenges and returns the number of them.
+func (c *DocstoreChallenges) Purge(ctx context.Context) (int, error) {
+	iter := c.coll.Query().Where("expires_at", "<=", time.Now()).Get(ctx, "nonce")
+	defer iter.Stop()
+	al := c.coll.Actions()
+	n := 0
+	for {
+		ch := &Challenge{}
+		err := iter.Next(ctx, ch)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, util.Wrap(ErrCouldNotPurgeChallenge, util.DocstoreError(err))
+		}
+		// Without the revision, as deleting the ones consumed concurrently does nothing.
+		al.Delete(&Challenge{Nonce: ch.Nonce})
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err := al.Do(ctx); err != nil {
+		return 0, util.Wrap(ErrCouldNotPurgeChallenge, util.DocstoreError(err))
+	}
+	return n, nil
+}
+
+// Consume deletes the Challenge of nonce and returns it, so that it can be used only once.
+// Returns ErrChallengeNotFound if it is not found, already consumed, expired, or not of domID.
+func (c *DocstoreChallenges) Consume(ctx context.Context, nonce, domID string) (*Challenge, error) {
+	ch := &Challenge{Nonce: nonce}
+	if err := c.coll.Get(ctx, ch); err != nil {
+		if code := gcerrors.Code(err); code == gcerrors.NotFound || code == gcerrors.InvalidArgument {
+			return nil, ErrChallengeNotFound
+		}
+		return nil, util.DocstoreError(err)
+	}
+	// Deletes the Challenge of another domain too, as the nonce has been disclosed.
+	if err := c.coll.Delete(ctx, ch); err != nil {
+		if code := gcerrors.Code(err); code == gcerrors.NotFound || code == gcerrors.FailedPrecondition {
+			return nil, ErrChallengeNotFound
+		}
+		return nil, util.DocstoreError(err)
+	}
+	if ch.DomainID != domID || !time.Now().Before(ch.ExpiresAt) {
+		return nil, ErrChallengeNotFound
+	}
+	return ch, nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Scope is an operation that an APIKey is allowed to do.
+// The domain restriction of the APIKey is applied on top of it.
+type Scope string
+
+// Scopes
+const (
+	ScopeAnchorsRead     Scope = "anchors:read"     // gets and lists anchors
+	ScopeAnchorsRegister Scope = "anchors:register" // registers digests
+	ScopeAnchorsRefresh  Scope = "anchors:refresh"  // refreshes anchors and updates their optional data
+	ScopeKeysAdmin       Scope = "keys:admin"       // manages API keys
+	ScopeWalletAdmin     Scope = "wallet:admin"     // manages domain policies and fee spending
+)
+
+// AllScopes contains all known Scopes.
+var AllScopes = []Scope{ScopeAnchorsRead, ScopeAnchorsRegister, ScopeAnchorsRefresh, ScopeKeysAdmin, ScopeWalletAdmin}
+
+// DefaultScopes are given to APIKeys without Scopes, e.g. ones issued by old versions.
+// Old global admin keys have AllScopes.
+var DefaultScopes = []Scope{ScopeAnchorsRead, ScopeAnchorsRegister, ScopeAnchorsRefresh}
+
+// Errors
+var (
+	ErrInvalidScope = errors.New("ErrInvalidScope")
+)
+
+// ParseScopes parses comma separated Scopes, e.g. "anchors:read,anchors:register".
+// Returns nil for "".
+func ParseScopes(s string) ([]Scope, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var ss []Scope
+	for _, v := range strings.Split(s, ",") {
+		sc := Scope(strings.TrimSpace(v))
+		if !sc.valid() {
+			return nil, fmt.Errorf("%w (%q)", ErrInvalidScope, sc)
+		}
+		ss = append(ss, sc)
+	}
+	return ss, nil
+}
+
+func (s Scope) valid() bool {
+	for _, v := range AllScopes {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedScopes returns the Scopes of k, or the defaults if k.Scopes is empty.
+func (k *APIKey) AllowedScopes() []Scope {
+	if len(k.Scopes) != 0 {
+		return k.Scopes
+	}
+	if k.ScopeRegisterAll {
+		return AllScopes
+	}
+	return DefaultScopes
+}
+
+// HasScopes returns whether k has all the given Scopes.
+func (k *APIKey) HasScopes(scopes ...Scope) bool {
+	ks := k.AllowedScopes()
+	for _, s := range scopes {
+		found := false
+		for _, v := range ks {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
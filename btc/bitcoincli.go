@@ -9,8 +9,8 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ebiiim/btcgw/model"
@@ -23,10 +23,13 @@ type cliCmd string
 const (
 	cmdPing                         = "ping"
 	cmdGetBalance                   = "getbalance"
+	cmdGetBalances                  = "getbalances"
 	cmdGetTransaction               = "gettransaction"
 	cmdCreateRawTransaction         = "createrawtransaction"
 	cmdSignRawTransactionWithWallet = "signrawtransactionwithwallet"
 	cmdSendRawTransaction           = "sendrawtransaction"
+	cmdTestMempoolAccept            = "testmempoolaccept"
+	cmdGetMempoolEntry              = "getmempoolentry"
 	cmdDecodeRawTransaction         = "decoderawtransaction"
 	cmdOptionVersion                = "--version"
 )
@@ -51,6 +54,7 @@ const (
 	exitWalletNotLoaded = 18
 	exitTxDecodeFailed  = 22
 	exitTxAlreadySpent  = 25
+	exitTxRejected      = 26
 	exitTxAlreadyExists = 27
 )
 
@@ -74,12 +78,14 @@ var (
 	ErrTxAlreadyExists      = errors.New("ErrTxAlreadyExists")
 	ErrNotEnoughBalance     = errors.New("ErrNotEnoughBalance")
 	ErrNotEnoughConfirm     = errors.New("ErrNotEnoughConfirm")
+	ErrTxNotInMempool       = errors.New("ErrTxNotInMempool")
 )
 
 // BitcoinCLI contains parameters for bitcoin-cli.
 // Parameters are read only so this struct does not have state.
 //
 // Excepts: xBTCAddr and xTransactionID are mutable. This is under consideration.
+// coreVersion is also mutable but it is only a cache of the result of Ping.
 type BitcoinCLI struct {
 	binPath     string
 	btcNet      model.BTCNet
@@ -94,6 +100,10 @@ type BitcoinCLI struct {
 
 	cmdproxyEnabled bool
 	cmdproxyClient  *cmdproxy.Client
+
+	// Set by Ping and used to switch version dependent behaviors.
+	mu          sync.RWMutex
+	coreVersion *CoreVersion
 }
 
 // MustNewBitcoinCLIWithCmdProxy initializes a BitcoinCLI with remote bitcoin-cli via cmdproxy.
@@ -234,6 +244,8 @@ func (b *BitcoinCLI) run(ctx context.Context, args []string) (*bytes.Buffer, *by
 			return &stdout, &stderr, ErrTxDecodeFailed
 		case exitTxAlreadySpent:
 			return &stdout, &stderr, ErrTxAlreadySpent
+		case exitTxRejected:
+			return &stdout, &stderr, ErrTxRejected
 		case exitTxAlreadyExists:
 			return &stdout, &stderr, ErrTxAlreadyExists
 		default:
@@ -243,11 +255,6 @@ func (b *BitcoinCLI) run(ctx context.Context, args []string) (*bytes.Buffer, *by
 	return &stdout, &stderr, nil
 }
 
-var supportedCLIVersions = map[string]struct{}{
-	"Bitcoin Core RPC client version v0.20.1": {},
-	"Bitcoin Core RPC client version v0.21.0": {},
-}
-
 func (b *BitcoinCLI) checkCLIVersion(ctx context.Context) error {
 	if dryRun {
 		return nil
@@ -256,17 +263,43 @@ func (b *BitcoinCLI) checkCLIVersion(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("%w (stdout=%s, stderr=$%s)", ErrUnsupportedVersion, stdout.String(), stderr.String())
 	}
-	stdout = removeCRLF(stdout)
-	stderr = removeCRLF(stderr)
-	if stderr.String() != "" {
-		return fmt.Errorf("%w (stdout=%s, stderr=$%s)", ErrUnsupportedVersion, stdout.String(), stderr.String())
+	if s := removeCRLF(stderr).String(); s != "" {
+		return fmt.Errorf("%w (stdout=%s, stderr=$%s)", ErrUnsupportedVersion, stdout.String(), s)
+	}
+	v, err := ParseCoreVersion(stdout.String())
+	if err != nil {
+		return err
 	}
-	if _, ok := supportedCLIVersions[stdout.String()]; !ok {
-		return fmt.Errorf("%w (%s)", ErrUnsupportedVersion, stdout.String())
+	if !v.Supported() {
+		return fmt.Errorf("%w (%s < %s)", ErrUnsupportedVersion, v, MinSupportedCoreVersion)
 	}
+	b.mu.Lock()
+	b.coreVersion = &v
+	b.mu.Unlock()
 	return nil
 }
 
+// CoreVersion returns the bitcoin-cli version detected by the last successful Ping.
+// Returns false if it is not detected yet.
+func (b *BitcoinCLI) CoreVersion() (CoreVersion, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.coreVersion == nil {
+		return CoreVersion{}, false
+	}
+	return *b.coreVersion, true
+}
+
+// features returns CoreFeatures of the detected version,
+// or all disabled if the version is not detected yet.
+func (b *BitcoinCLI) features() CoreFeatures {
+	v, ok := b.CoreVersion()
+	if !ok {
+		return CoreFeatures{}
+	}
+	return v.Features()
+}
+
 // Ping checks bitcoin-cli version and pings bitcoind.
 //
 // Possible errors: ErrUnsupportedVersion|ErrPingFailed|ErrUnexpectedExitCode|ErrFailedToExec
@@ -286,30 +319,85 @@ func (b *BitcoinCLI) Ping(ctx context.Context) error {
 	return nil
 }
 
-// GetBalance returns balance of the default wallet.
+// GetBalance returns trusted balance of the default wallet.
+// Uses `getbalances` if available, otherwise `getbalance`.
 //
-// Possible errors: ErrWalletNotLoaded|ErrUnexpectedExitCode|ErrFailedToExec
-func (b *BitcoinCLI) GetBalance(ctx context.Context) (string, error) {
-	stdout, stderr, err := b.run(ctx, []string{cmdGetBalance})
+// Possible errors: ErrFailedToDecode|ErrWalletNotLoaded|ErrUnexpectedExitCode|ErrFailedToExec
+func (b *BitcoinCLI) GetBalance(ctx context.Context) (model.Amount, error) {
+	cmd := cmdGetBalance
+	if b.features().GetBalances {
+		cmd = cmdGetBalances
+	}
+	stdout, stderr, err := b.run(ctx, []string{cmd})
 	if err != nil {
 		if errors.Is(err, ErrDryRun) {
-			return "", err
+			return 0, err
 		}
-		return "", fmt.Errorf("%w (stdout=%s, stderr=%s)", err, stdout.String(), stderr.String())
+		return 0, fmt.Errorf("%w (stdout=%s, stderr=%s)", err, stdout.String(), stderr.String())
 	}
-	stdout = removeCRLF(stdout)
-	s := stdout.String()
+	return parseBalance(removeCRLF(stdout))
+}
+
+// parseBalance parses the output of `getbalance` or `getbalances`.
+//
+// Possible errors: ErrFailedToDecode
+func parseBalance(stdout *bytes.Buffer) (model.Amount, error) {
+	s := strings.TrimSpace(stdout.String())
 	if len(s) == 0 {
-		return "", fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
+		return 0, fmt.Errorf("%w (empty)", ErrFailedToDecode)
+	}
+	if !strings.HasPrefix(s, "{") {
+		// getbalance: 0.01158624
+		return parseAmount(s)
+	}
+	// getbalances: { "mine": { "trusted": 0.01158624, ... }, ... }
+	var val map[string]interface{}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		return 0, fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
+	}
+	mine, ok := val["mine"].(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("%w (root->mine)", ErrFailedToDecode)
+	}
+	trusted, ok := mine["trusted"].(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("%w (root->mine->trusted)", ErrFailedToDecode)
+	}
+	return parseAmount(trusted.String())
+}
+
+// parseAmount parses a BTC amount printed by bitcoin-cli.
+//
+// Possible errors: ErrFailedToDecode
+func parseAmount(s string) (model.Amount, error) {
+	a, err := model.ParseBTC(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
 	}
-	return s, nil
+	return a, nil
 }
 
 // GetTransaction returns a transaction in JSON.
 //
 // Possible errors: ErrInvalidTransactionID|ErrWalletNotLoaded|ErrUnexpectedExitCode|ErrFailedToExec
 func (b *BitcoinCLI) GetTransaction(ctx context.Context, txid []byte) (*bytes.Buffer, error) {
-	stdout, stderr, err := b.run(ctx, []string{cmdGetTransaction, hex.EncodeToString(txid)})
+	return b.getTransaction(ctx, []string{cmdGetTransaction, hex.EncodeToString(txid)})
+}
+
+// GetTransactionDecoded returns a transaction in JSON,
+// including the decoded transaction in the "decoded" field.
+// Needs CoreFeatures.DecodedTransaction.
+//
+// Possible errors: ErrInvalidTransactionID|ErrWalletNotLoaded|ErrUnexpectedExitCode|ErrFailedToExec
+func (b *BitcoinCLI) GetTransactionDecoded(ctx context.Context, txid []byte) (*bytes.Buffer, error) {
+	// gettransaction "txid" ( include_watchonly verbose )
+	return b.getTransaction(ctx, []string{cmdGetTransaction, hex.EncodeToString(txid), "true", "true"})
+}
+
+func (b *BitcoinCLI) getTransaction(ctx context.Context, args []string) (*bytes.Buffer, error) {
+	stdout, stderr, err := b.run(ctx, args)
 	if err != nil {
 		if errors.Is(err, ErrDryRun) {
 			return nil, err
@@ -319,42 +407,44 @@ func (b *BitcoinCLI) GetTransaction(ctx context.Context, txid []byte) (*bytes.Bu
 	return stdout, nil
 }
 
-// Possible errors: ErrFailedToDecode|ErrNotEnoughBalance
-func calcFee(bal string, feeSatoshi uint) (string, error) {
-	f64Bal, err := strconv.ParseFloat(bal, 64)
-	if err != nil {
-		return "", fmt.Errorf("%w (%+v)", ErrFailedToDecode, err)
+// calcFee returns the change, that is bal minus fee.
+//
+// Possible errors: ErrInvalidFee|ErrNotEnoughBalance
+func calcFee(bal, fee model.Amount) (model.Amount, error) {
+	if fee < 0 {
+		return 0, fmt.Errorf("%w (%s)", ErrInvalidFee, fee)
 	}
-	var rate float64 = 100_000_000
-	f64Bal = float64(int(f64Bal*rate)-int(feeSatoshi)) / rate
-	if f64Bal < 0 {
-		return "", fmt.Errorf("%w (%.8f)", ErrNotEnoughBalance, f64Bal)
+	change := bal - fee
+	if change < 0 {
+		return 0, fmt.Errorf("%w (%s)", ErrNotEnoughBalance, change)
 	}
-	return fmt.Sprintf("%.8f", f64Bal), err
+	return change, nil
 }
 
 // ParseTransactionReceived returns vout number and received amount of the given Bitcoin address.
 // Only counts the first received amount of the given address.
 // Returns error if no received.
-func (*BitcoinCLI) ParseTransactionReceived(txJSON *bytes.Buffer, recvAddr string) (int, string, error) {
+func (*BitcoinCLI) ParseTransactionReceived(txJSON *bytes.Buffer, recvAddr string) (int, model.Amount, error) {
 	var val map[string]interface{}
-	if err := json.NewDecoder(txJSON).Decode(&val); err != nil {
-		return 0, "", fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
+	dec := json.NewDecoder(txJSON)
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		return 0, 0, fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
 	}
 	// Parse { ..., "details": [ { "address": "abc", "category": "receive", "amount": 0.123 } ] }
 	details, ok := val["details"].([]interface{})
 	if !ok {
-		return 0, "", fmt.Errorf("%w (root->details)", ErrFailedToDecode)
+		return 0, 0, fmt.Errorf("%w (root->details)", ErrFailedToDecode)
 	}
 	for idx, d := range details {
 		dd, ok := d.(map[string]interface{})
 		if !ok {
-			return 0, "", fmt.Errorf("%w (root->details[%d])", ErrFailedToDecode, idx)
+			return 0, 0, fmt.Errorf("%w (root->details[%d])", ErrFailedToDecode, idx)
 		}
 		// category == receive ? pass : continue
 		cat, ok := dd["category"].(string)
 		if !ok {
-			return 0, "", fmt.Errorf("%w (root->details[%d]->category)", ErrFailedToDecode, idx)
+			return 0, 0, fmt.Errorf("%w (root->details[%d]->category)", ErrFailedToDecode, idx)
 		}
 		if cat != "receive" {
 			continue
@@ -362,24 +452,32 @@ func (*BitcoinCLI) ParseTransactionReceived(txJSON *bytes.Buffer, recvAddr strin
 		// address == ${recvAddr} ? pass : continue
 		addr, ok := dd["address"].(string)
 		if !ok {
-			return 0, "", fmt.Errorf("%w (root->details[%d]->address)", ErrFailedToDecode, idx)
+			return 0, 0, fmt.Errorf("%w (root->details[%d]->address)", ErrFailedToDecode, idx)
 		}
 		if addr != recvAddr {
 			continue
 		}
-		// amount is float ? return : ErrFailedToDecode
-		amo, ok := dd["amount"].(float64)
+		// amount is BTC amount ? return : ErrFailedToDecode
+		jamo, ok := dd["amount"].(json.Number)
 		if !ok {
-			return 0, "", fmt.Errorf("%w (root->details[%d]->amount)", ErrFailedToDecode, idx)
+			return 0, 0, fmt.Errorf("%w (root->details[%d]->amount)", ErrFailedToDecode, idx)
+		}
+		amo, err := parseAmount(jamo.String())
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w (root->details[%d]->amount)", err, idx)
 		}
 		// vout is int ? return : ErrFailedToDecode
-		fvout, ok := dd["vout"].(float64)
+		jvout, ok := dd["vout"].(json.Number)
 		if !ok {
-			return 0, "", fmt.Errorf("%w (root->details[%d]->vout)", ErrFailedToDecode, idx)
+			return 0, 0, fmt.Errorf("%w (root->details[%d]->vout)", ErrFailedToDecode, idx)
 		}
-		return int(fvout), fmt.Sprintf("%.8f", amo), nil
+		vout, err := jvout.Int64()
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w (root->details[%d]->vout)", ErrFailedToDecode, idx)
+		}
+		return int(vout), amo, nil
 	}
-	return 0, "", fmt.Errorf("%w (not found)", ErrFailedToDecode)
+	return 0, 0, fmt.Errorf("%w (not found)", ErrFailedToDecode)
 }
 
 // CreateRawTransactionForAnchor creates a raw transaction with one vout and one OP_RETURN.
@@ -388,20 +486,20 @@ func (*BitcoinCLI) ParseTransactionReceived(txJSON *bytes.Buffer, recvAddr strin
 //   - fromTxid sets UTXO.
 //   - balance sets balance of fromTxid.
 //   - toAddr sets destination Bitcoin address.
-//   - fee sets transaction fee in Satoshi.
+//   - fee sets transaction fee.
 //     - Info: BTC 0.0002 = Satoshi 20,000
 //   - data sets OP_RETURN data. Up to 80 bytes.
 //
 // Possible errors: ErrInvalidFee|ErrExitCode1|ErrUnexpectedExitCode|ErrFailedToExec
-func (b *BitcoinCLI) CreateRawTransactionForAnchor(ctx context.Context, fromTxid []byte, vout int, balance string, toAddr string, fee uint, data []byte) ([]byte, error) {
-	sFee, err := calcFee(balance, fee)
+func (b *BitcoinCLI) CreateRawTransactionForAnchor(ctx context.Context, fromTxid []byte, vout int, balance model.Amount, toAddr string, fee model.Amount, data []byte) ([]byte, error) {
+	change, err := calcFee(balance, fee)
 	if err != nil {
 		return nil, fmt.Errorf("%w (%v)", ErrInvalidFee, err)
 	}
 	argFmt0 := `[{"txid": "%s", "vout": %d}]`
 	argFmt1 := `[{"%s": %s}, {"data": "%s"}]`
 	arg0 := fmt.Sprintf(argFmt0, hex.EncodeToString(fromTxid), vout)
-	arg1 := fmt.Sprintf(argFmt1, toAddr, sFee, hex.EncodeToString(data))
+	arg1 := fmt.Sprintf(argFmt1, toAddr, change, hex.EncodeToString(data))
 	stdout, stderr, err := b.run(ctx, []string{cmdCreateRawTransaction, arg0, arg1})
 	if err != nil {
 		if errors.Is(err, ErrDryRun) {
@@ -458,15 +556,73 @@ func (*BitcoinCLI) ParseSignRawTransactionWithWallet(stdout io.Reader) ([]byte,
 	return bs, nil
 }
 
+// TestMempoolAccept checks whether the given signed raw transaction would be accepted by the mempool
+// without sending it, and returns JSON.
+//
+// Possible errors: ErrUnexpectedExitCode|ErrFailedToExec
+func (b *BitcoinCLI) TestMempoolAccept(ctx context.Context, signedRawTx []byte) (*bytes.Buffer, error) {
+	stdout, stderr, err := b.run(ctx, []string{cmdTestMempoolAccept, fmt.Sprintf(`["%s"]`, hex.EncodeToString(signedRawTx))})
+	if err != nil {
+		if errors.Is(err, ErrDryRun) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w (stdout=%s, stderr=%s)", err, stdout.String(), stderr.String())
+	}
+	stdout = removeCRLF(stdout)
+	return stdout, nil
+}
+
+// ParseTestMempoolAccept parses the response from b.TestMempoolAccept.
+// Returns nil if the transaction is allowed, ErrTxAlreadyExists if it has already been sent,
+// otherwise one of the mempool rejections.
+//
+// Possible errors: ErrFailedToDecode|ErrTxAlreadyExists|ErrTxRejected|ErrTxFeeTooLow|ErrTxDust|ErrTxNonStandard|ErrTxMissingInputs|ErrTxTooLongMempoolChain
+func (*BitcoinCLI) ParseTestMempoolAccept(stdout io.Reader) error {
+	// Parse [ { "txid": "1234", "allowed": false, "reject-reason": "min relay fee not met" } ]
+	var val []struct {
+		Allowed      *bool  `json:"allowed"`
+		RejectReason string `json:"reject-reason"`
+	}
+	if err := json.NewDecoder(stdout).Decode(&val); err != nil {
+		return fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
+	}
+	if len(val) != 1 {
+		return fmt.Errorf("%w (len(root)=%d)", ErrFailedToDecode, len(val))
+	}
+	if val[0].Allowed == nil {
+		return fmt.Errorf("%w (root[0]->allowed)", ErrFailedToDecode)
+	}
+	if !*val[0].Allowed {
+		return rejectReasonError(val[0].RejectReason)
+	}
+	return nil
+}
+
+// cliErrorMessage extracts the message from stderr of bitcoin-cli.
+// e.g. "error code: -26\nerror message:\nmin relay fee not met" -> "min relay fee not met"
+func cliErrorMessage(stderr *bytes.Buffer) string {
+	if stderr == nil {
+		return ""
+	}
+	s := stderr.String()
+	if i := strings.Index(s, "error message:"); i >= 0 {
+		s = s[i+len("error message:"):]
+	}
+	return strings.TrimSpace(s)
+}
+
 // SendRawTransaction sends the given signed raw transaction and returns transaction ID.
 //
-// Possible errors: ErrTxAlreadySpent|ErrTxAlreadyExists|ErrUnexpectedExitCode|ErrFailedToExec
+// Possible errors: ErrTxAlreadySpent|ErrTxAlreadyExists|mempool rejections|ErrUnexpectedExitCode|ErrFailedToExec
 func (b *BitcoinCLI) SendRawTransaction(ctx context.Context, signedRawTx []byte) ([]byte, error) {
 	stdout, stderr, err := b.run(ctx, []string{cmdSendRawTransaction, hex.EncodeToString(signedRawTx)})
 	if err != nil {
 		if errors.Is(err, ErrDryRun) {
 			return nil, err
 		}
+		if errors.Is(err, ErrTxRejected) {
+			return nil, rejectReasonError(cliErrorMessage(stderr))
+		}
 		return nil, fmt.Errorf("%w (stdout=%s, stderr=%s)", err, stdout.String(), stderr.String())
 	}
 	stdout = removeCRLF(stdout)
@@ -477,15 +633,30 @@ func (b *BitcoinCLI) SendRawTransaction(ctx context.Context, signedRawTx []byte)
 	return bs, nil
 }
 
-// Transaction fee in Satoshi.
+// Transaction fee.
 const (
-	feeNormal = 20_000
-	feeLarge  = 30_000
-	feeSmall  = 10_000
+	feeNormal = 20_000 * model.Satoshi
+	feeLarge  = 30_000 * model.Satoshi
+	feeSmall  = 10_000 * model.Satoshi
 )
 
 // txFee sets fee.
-var txFee uint = feeNormal
+var txFee = feeNormal
+
+// FeeOf returns the fee of the model.FeeTier t.
+// The default fee is returned for model.FeeDefault and unknown tiers.
+func FeeOf(t model.FeeTier) model.Amount {
+	switch t {
+	case model.FeeSmall:
+		return feeSmall
+	case model.FeeNormal:
+		return feeNormal
+	case model.FeeLarge:
+		return feeLarge
+	default:
+		return txFee
+	}
+}
 
 // XSetUTXO sets b.xTransactionID and b.xBTCAddr.
 // As b.PutAnchor does not update b.xTransactionID after sending the transaction,
@@ -509,66 +680,142 @@ const (
 )
 
 // PutAnchor anchors the given Anchor by sending a Bitcoin transaction and returns its transaction ID.
+// This is same as calling b.CreateAnchorTransaction and b.BroadcastTransaction,
+// and then setting the next UTXO by b.XSetUTXO.
+//
+// Possible errors: mempool rejections (see IsMempoolRejection) and errors from the methods used.
 func (b *BitcoinCLI) PutAnchor(ctx context.Context, a *model.Anchor) ([]byte, error) {
+	signedTx, err := b.CreateAnchorTransaction(ctx, a)
+	if err != nil {
+		return nil, fmt.Errorf("%w (PutAnchor)", err)
+	}
+	sentTxid, err := b.BroadcastTransaction(ctx, signedTx)
+	if err != nil {
+		return nil, fmt.Errorf("%w (PutAnchor)", err)
+	}
+	// Set the next UTXO, the caller can get it by calling b.XGetUTXO.
+	b.XSetUTXO(sentTxid, b.xBTCAddr)
+	return sentTxid, nil
+}
+
+// CreateAnchorTransaction creates and signs a Bitcoin transaction that anchors the given Anchor,
+// and returns the signed raw transaction. The transaction is NOT sent.
+// The UTXO set by b.XSetUTXO is used as the input.
+func (b *BitcoinCLI) CreateAnchorTransaction(ctx context.Context, a *model.Anchor) ([]byte, error) {
+	return b.CreateAnchorTransactionWithFee(ctx, a, txFee)
+}
+
+// CreateAnchorTransactionWithFee is CreateAnchorTransaction with the given fee, e.g. FeeOf the domain.
+func (b *BitcoinCLI) CreateAnchorTransactionWithFee(ctx context.Context, a *model.Anchor, fee model.Amount) ([]byte, error) {
 	// Check the given Anchor.
 	if a.BTCNet != b.btcNet {
-		return nil, fmt.Errorf("%w (Anchor: %s, BitcoinCLI: %s) (PutAnchor)", ErrInconsistentBTCNet, a.BTCNet, b.btcNet)
+		return nil, fmt.Errorf("%w (Anchor: %s, BitcoinCLI: %s) (CreateAnchorTransaction)", ErrInconsistentBTCNet, a.BTCNet, b.btcNet)
 	}
 
 	// Check the bitcoind.
 	err := b.Ping(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("%w (PutAnchor)", err)
+		return nil, fmt.Errorf("%w (CreateAnchorTransaction)", err)
 	}
 
 	// Get UTXO balance and check confirmations.
 	fromTx, err := b.GetTransaction(ctx, b.xTransactionID)
 	if err != nil {
-		return nil, fmt.Errorf("%w (PutAnchor)", err)
+		return nil, fmt.Errorf("%w (CreateAnchorTransaction)", err)
 	}
 	var bufR, bufC bytes.Buffer
 	w := io.MultiWriter(&bufR, &bufC)
 	io.Copy(w, fromTx)
 	vout, balance, err := b.ParseTransactionReceived(&bufR, b.xBTCAddr)
 	if err != nil {
-		return nil, fmt.Errorf("%w (PutAnchor)", err)
+		return nil, fmt.Errorf("%w (CreateAnchorTransaction)", err)
 	}
 	confs, err := b.ParseTransactionConfirmations(&bufC)
 	if err != nil {
-		return nil, fmt.Errorf("%w (GetAnchor)", err)
+		return nil, fmt.Errorf("%w (CreateAnchorTransaction)", err)
 	}
 	if confs < leastConfirmationNeeded {
-		return nil, fmt.Errorf("%w (confirmations=%d) (GetAnchor)", ErrNotEnoughConfirm, confs)
+		return nil, fmt.Errorf("%w (confirmations=%d) (CreateAnchorTransaction)", ErrNotEnoughConfirm, confs)
 	}
 
 	// Encode OP_RETURN.
 	tmp := model.EncodeOpReturn(a)
 	opRet := tmp[:]
 
-	// Create, sign, and send the anchor transaction.
-	rawTx, err := b.CreateRawTransactionForAnchor(ctx, b.xTransactionID, vout, balance, b.xBTCAddr, txFee, opRet)
+	// Create and sign the anchor transaction.
+	rawTx, err := b.CreateRawTransactionForAnchor(ctx, b.xTransactionID, vout, balance, b.xBTCAddr, fee, opRet)
 	if err != nil {
-		return nil, fmt.Errorf("%w (PutAnchor)", err)
+		return nil, fmt.Errorf("%w (CreateAnchorTransaction)", err)
 	}
 	signedTxReader, err := b.SignRawTransactionWithWallet(ctx, rawTx)
 	if err != nil {
-		return nil, fmt.Errorf("%w (PutAnchor)", err)
+		return nil, fmt.Errorf("%w (CreateAnchorTransaction)", err)
 	}
 	signedTx, err := b.ParseSignRawTransactionWithWallet(signedTxReader)
 	if err != nil {
-		return nil, fmt.Errorf("%w (PutAnchor)", err)
+		return nil, fmt.Errorf("%w (CreateAnchorTransaction)", err)
+	}
+	return signedTx, nil
+}
+
+// BroadcastTransaction sends the given signed raw transaction and returns its transaction ID.
+// The transaction is checked by `testmempoolaccept` before sending
+// so that rejections are reported as typed errors.
+// This can also be used to rebroadcast a transaction that has been dropped from the mempool.
+//
+// Possible errors: ErrTxAlreadyExists if the transaction is already in the mempool or the block chain,
+// mempool rejections (see IsMempoolRejection) and errors from the methods used.
+func (b *BitcoinCLI) BroadcastTransaction(ctx context.Context, signedTx []byte) ([]byte, error) {
+	acceptReader, err := b.TestMempoolAccept(ctx, signedTx)
+	if err != nil {
+		return nil, fmt.Errorf("%w (BroadcastTransaction)", err)
+	}
+	if err := b.ParseTestMempoolAccept(acceptReader); err != nil {
+		return nil, fmt.Errorf("%w (BroadcastTransaction)", err)
 	}
 	sentTxid, err := b.SendRawTransaction(ctx, signedTx)
 	if err != nil {
-		return nil, fmt.Errorf("%w (PutAnchor)", err)
+		return nil, fmt.Errorf("%w (BroadcastTransaction)", err)
 	}
-	// Set the next UTXO, the caller can get it by calling b.XGetUTXO.
-	b.XSetUTXO(sentTxid, b.xBTCAddr)
 	return sentTxid, nil
 }
 
+// GetMempoolEntry returns JSON of the given transaction in the mempool of bitcoind.
+// Unlike b.GetTransaction, this does not depend on the wallet.
+//
+// Possible errors: ErrTxNotInMempool|ErrUnexpectedExitCode|ErrFailedToExec
+func (b *BitcoinCLI) GetMempoolEntry(ctx context.Context, txid []byte) (*bytes.Buffer, error) {
+	stdout, stderr, err := b.run(ctx, []string{cmdGetMempoolEntry, hex.EncodeToString(txid)})
+	if err != nil {
+		if errors.Is(err, ErrDryRun) {
+			return nil, err
+		}
+		if errors.Is(err, ErrInvalidTransactionID) {
+			return nil, fmt.Errorf("%w (txid=%s)", ErrTxNotInMempool, hex.EncodeToString(txid))
+		}
+		return nil, fmt.Errorf("%w (stdout=%s, stderr=%s)", err, stdout.String(), stderr.String())
+	}
+	stdout = removeCRLF(stdout)
+	return stdout, nil
+}
+
 // ParseTransactionConfirmations returns confirmations of the given transaction.
-func (*BitcoinCLI) ParseTransactionConfirmations(txJSON *bytes.Buffer) (uint, error) {
+// Returns 0 if the wallet reports negative confirmations. See ParseWalletTransactionDepth.
+func (b *BitcoinCLI) ParseTransactionConfirmations(txJSON *bytes.Buffer) (uint, error) {
+	depth, err := b.ParseWalletTransactionDepth(txJSON)
+	if err != nil {
+		return 0, err
+	}
+	if depth < 0 {
+		return 0, nil
+	}
+	return uint(depth), nil
+}
+
+// ParseWalletTransactionDepth returns confirmations of the given transaction as it is.
+// The wallet reports negative confirmations if the transaction conflicts with a confirmed transaction,
+// e.g. -3 means a conflicting transaction has 3 confirmations.
+func (*BitcoinCLI) ParseWalletTransactionDepth(txJSON *bytes.Buffer) (int, error) {
 	var val map[string]interface{}
 	if err := json.NewDecoder(txJSON).Decode(&val); err != nil {
 		return 0, fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
@@ -578,7 +825,7 @@ func (*BitcoinCLI) ParseTransactionConfirmations(txJSON *bytes.Buffer) (uint, er
 	if !ok {
 		return 0, fmt.Errorf("%w (root->confirmations)", ErrFailedToDecode)
 	}
-	return uint(confs), nil
+	return int(confs), nil
 }
 
 // ParseTransactionTime returns time of the given transaction.
@@ -595,6 +842,30 @@ func (*BitcoinCLI) ParseTransactionTime(txJSON *bytes.Buffer) (time.Time, error)
 	return time.Unix(int64(unixT), 0), nil
 }
 
+// ParseTransactionFee returns the fee paid by the wallet for the given transaction as a positive Amount.
+// Returns ErrFailedToDecode if the wallet did not send it.
+func (*BitcoinCLI) ParseTransactionFee(txJSON *bytes.Buffer) (model.Amount, error) {
+	var val map[string]interface{}
+	dec := json.NewDecoder(txJSON)
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		return 0, fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
+	}
+	// Parse { ..., "fee": -0.00010000, ... }
+	jfee, ok := val["fee"].(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("%w (root->fee)", ErrFailedToDecode)
+	}
+	fee, err := parseAmount(jfee.String())
+	if err != nil {
+		return 0, fmt.Errorf("%w (root->fee)", err)
+	}
+	if fee < 0 {
+		fee = -fee
+	}
+	return fee, nil
+}
+
 // ParseTransactionRawHex returns raw data of the given transaction.
 func (*BitcoinCLI) ParseTransactionRawHex(txJSON *bytes.Buffer) ([]byte, error) {
 	var val map[string]interface{}
@@ -614,6 +885,21 @@ func (*BitcoinCLI) ParseTransactionRawHex(txJSON *bytes.Buffer) ([]byte, error)
 
 }
 
+// ParseTransactionDecoded returns the decoded transaction in JSON,
+// that is same as the output of b.DecodeRawTransaction.
+func (*BitcoinCLI) ParseTransactionDecoded(txJSON *bytes.Buffer) (*bytes.Buffer, error) {
+	var val map[string]json.RawMessage
+	if err := json.NewDecoder(txJSON).Decode(&val); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
+	}
+	// Parse { ..., "decoded": { "txid": "12345", ... } }
+	decoded, ok := val["decoded"]
+	if !ok || len(decoded) == 0 || decoded[0] != '{' {
+		return nil, fmt.Errorf("%w (root->decoded)", ErrFailedToDecode)
+	}
+	return bytes.NewBuffer(decoded), nil
+}
+
 // DecodeRawTransaction returns a raw transaction in JSON.
 //
 // Possible errors: ErrTxDecodeFailed|ErrWalletNotLoaded|ErrUnexpectedExitCode|ErrFailedToExec
@@ -667,6 +953,64 @@ func (*BitcoinCLI) ParseRawTransactionOpReturn(rawTxJSON *bytes.Buffer) ([]byte,
 	return nil, fmt.Errorf("%w (not found)", ErrFailedToDecode)
 }
 
+// ParseRawTransactionSize returns the virtual size in vbytes and the total output amount of the given raw transaction.
+func (*BitcoinCLI) ParseRawTransactionSize(rawTxJSON *bytes.Buffer) (uint, model.Amount, error) {
+	var val map[string]interface{}
+	dec := json.NewDecoder(rawTxJSON)
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		return 0, 0, fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
+	}
+	// Parse { ..., "vsize": 135, "vout": [ { "value": 0.01158624, ... } ] }
+	jvsize, ok := val["vsize"].(json.Number)
+	if !ok {
+		return 0, 0, fmt.Errorf("%w (root->vsize)", ErrFailedToDecode)
+	}
+	vsize, err := jvsize.Int64()
+	if err != nil || vsize < 0 {
+		return 0, 0, fmt.Errorf("%w (root->vsize)", ErrFailedToDecode)
+	}
+	vouts, ok := val["vout"].([]interface{})
+	if !ok {
+		return 0, 0, fmt.Errorf("%w (root->vout)", ErrFailedToDecode)
+	}
+	var total model.Amount
+	for idx, vout := range vouts {
+		o, ok := vout.(map[string]interface{})
+		if !ok {
+			return 0, 0, fmt.Errorf("%w (root->vout[%d])", ErrFailedToDecode, idx)
+		}
+		jval, ok := o["value"].(json.Number)
+		if !ok {
+			return 0, 0, fmt.Errorf("%w (root->vout[%d]->value)", ErrFailedToDecode, idx)
+		}
+		v, err := parseAmount(jval.String())
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w (root->vout[%d]->value)", err, idx)
+		}
+		total += v
+	}
+	return uint(vsize), total, nil
+}
+
+// ParseRawTransactionID returns the transaction ID of the given raw transaction.
+func (*BitcoinCLI) ParseRawTransactionID(rawTxJSON *bytes.Buffer) ([]byte, error) {
+	var val map[string]interface{}
+	if err := json.NewDecoder(rawTxJSON).Decode(&val); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrFailedToDecode, err)
+	}
+	// Parse { "txid": "12345", ... }
+	txidStr, ok := val["txid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w (root->txid)", ErrFailedToDecode)
+	}
+	txid, err := hex.DecodeString(txidStr)
+	if err != nil || len(txid) != 32 {
+		return nil, fmt.Errorf("%w (root->txid)", ErrFailedToDecode)
+	}
+	return txid, nil
+}
+
 // GetAnchor returns an AnchorRecord by searching the given Bitcoin transaction ID and parsing its data.
 func (b *BitcoinCLI) GetAnchor(ctx context.Context, btctx []byte) (*model.AnchorRecord, error) {
 	// Check the bitcoind.
@@ -676,12 +1020,19 @@ func (b *BitcoinCLI) GetAnchor(ctx context.Context, btctx []byte) (*model.Anchor
 	}
 
 	// Parse the given transaction and get data.
-	tx, err := b.GetTransaction(ctx, btctx)
+	// Newer versions return the decoded transaction so no need to call decoderawtransaction.
+	decoded := b.features().DecodedTransaction
+	var tx *bytes.Buffer
+	if decoded {
+		tx, err = b.GetTransactionDecoded(ctx, btctx)
+	} else {
+		tx, err = b.GetTransaction(ctx, btctx)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("%w (GetAnchor)", err)
 	}
-	var bufT, bufC, bufH bytes.Buffer
-	w := io.MultiWriter(&bufT, &bufC, &bufH)
+	var bufT, bufC, bufH, bufF bytes.Buffer
+	w := io.MultiWriter(&bufT, &bufC, &bufH, &bufF)
 	io.Copy(w, tx)
 	tts, err := b.ParseTransactionTime(&bufT)
 	if err != nil {
@@ -691,15 +1042,25 @@ func (b *BitcoinCLI) GetAnchor(ctx context.Context, btctx []byte) (*model.Anchor
 	if err != nil {
 		return nil, fmt.Errorf("%w (GetAnchor)", err)
 	}
-	tHex, err := b.ParseTransactionRawHex(&bufH)
-	if err != nil {
-		return nil, fmt.Errorf("%w (GetAnchor)", err)
-	}
-	rawTx, err := b.DecodeRawTransaction(ctx, tHex)
-	if err != nil {
-		return nil, fmt.Errorf("%w (GetAnchor)", err)
+	var rawTx *bytes.Buffer
+	if decoded {
+		rawTx, err = b.ParseTransactionDecoded(&bufH)
+		if err != nil {
+			return nil, fmt.Errorf("%w (GetAnchor)", err)
+		}
+	} else {
+		tHex, err := b.ParseTransactionRawHex(&bufH)
+		if err != nil {
+			return nil, fmt.Errorf("%w (GetAnchor)", err)
+		}
+		rawTx, err = b.DecodeRawTransaction(ctx, tHex)
+		if err != nil {
+			return nil, fmt.Errorf("%w (GetAnchor)", err)
+		}
 	}
-	opRetSlice, err := b.ParseRawTransactionOpReturn(rawTx)
+	var bufO, bufS bytes.Buffer
+	io.Copy(io.MultiWriter(&bufO, &bufS), rawTx)
+	opRetSlice, err := b.ParseRawTransactionOpReturn(&bufO)
 	if err != nil {
 		return nil, fmt.Errorf("%w (GetAnchor)", err)
 	}
@@ -722,6 +1083,16 @@ func (b *BitcoinCLI) GetAnchor(ctx context.Context, btctx []byte) (*model.Anchor
 		BTCTransactionID: btctx,
 		TransactionTime:  tts,
 		Confirmations:    tcs,
+		Status:           model.StatusOfConfirmations(tcs),
+	}
+	// The cost is optional as the wallet knows the fee only if it has sent the transaction.
+	if fee, err := b.ParseTransactionFee(&bufF); err == nil {
+		if vsize, change, err := b.ParseRawTransactionSize(&bufS); err == nil {
+			r.Fee = fee
+			r.InputAmount = change + fee
+			r.ChangeAmount = change
+			r.VSize = vsize
+		}
 	}
 	return &r, nil
 }
@@ -41,6 +41,11 @@ func TestPing(t *testing.T) {
 	if err := b.Ping(ctx); err != nil {
 		t.Error(err)
 	}
+	v, ok := b.CoreVersion()
+	if !ok {
+		t.Error("version not detected")
+	}
+	t.Log(v, v.Features().Names())
 }
 
 func TestGetBalance(t *testing.T) {
@@ -57,8 +62,8 @@ const (
 	lTxid1     = "c7ace9d33c00b870e183f7dc929d3887efe257317a0d24810b2ee91fd08c6535"
 	lTxid2     = txid1
 	lRecvAddr1 = recvAddr1
-	lUnspent1  = "0.01168624"
-	lFee1      = 10000
+	lUnspent1  = model.Amount(1168624)
+	lFee1      = model.Amount(10000)
 	lOpRet1    = opRet1
 	lRawTx1    = rawTx1
 	lSignedTx1 = signedRawTx1
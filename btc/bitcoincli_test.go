@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,7 +25,7 @@ const (
 
 	txid1        = "57511f74c3836c0d4d62a6183fa54e600372e1aed5b5be2f78ef5b766a314a5d"
 	recvAddr1    = "tb1qhexc7d0fzex7lrzw3l0j2dmvhgegt02ckfdzjr"
-	recvAmount1  = "0.01158624"
+	recvAmount1  = model.Amount(1158624)
 	opRet1       = "7468697320697320612070656e0a" // "this is a pen"
 	rawTx1       = "020000000135658cd01fe92e0b81240d7a3157e2ef87389d92dcf783e170b8003cd3e9acc70000000000ffffffff02e0ad110000000000160014be4d8f35e9164def8c4e8fdf25376cba3285bd580000000000000000106a0e7468697320697320612070656e0a00000000"
 	signedOut1   = `{"hex": "0200000000010135658cd01fe92e0b81240d7a3157e2ef87389d92dcf783e170b8003cd3e9acc70000000000ffffffff02e0ad110000000000160014be4d8f35e9164def8c4e8fdf25376cba3285bd580000000000000000106a0e7468697320697320612070656e0a0247304402207081f817c5cfe5579c44b770ce13fe8b4aff04a241a666e2ad8a6cdf2f88286e02202176b0ae03924adb869b4c17ae3ef1bee12ed0a0798e7673bfeeeb290d954eb501210201f52ea462e04534e2e5f9be72a4bddd6e5fe7a001bc8bdba8a8dad392222d5300000000", "complete": true}`
@@ -38,26 +39,28 @@ const (
 func TestCalcFee(t *testing.T) {
 	t.Parallel()
 	cases := []struct {
-		name       string
-		bal        string
-		feeSatoshi uint
-		result     string
+		name   string
+		bal    model.Amount
+		fee    model.Amount
+		result string
 	}{
 		{"normal", recvAmount1, 20_000, "0.01138624"},
 		{"big_fee", recvAmount1, 123_456, "0.01035168"},
-		{"big_amo", "12345.12345678", 20_000, "12345.12325678"},
-		{"just", "0.00020000", 20_000, "0.00000000"},
+		{"big_amo", 1234512345678, 20_000, "12345.12325678"},
+		{"just", 20_000, 20_000, "0.00000000"},
+		{"one_satoshi", 20_001, 20_000, "0.00000001"},
+		{"max", model.MaxAmount, 20_000, "20999999.99980000"},
 	}
 	for _, c := range cases {
 		c := c
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
-			bal, err := btc.CalcFee(c.bal, c.feeSatoshi)
+			bal, err := btc.CalcFee(c.bal, c.fee)
 			if err != nil {
 				t.Error(err)
 				t.Skip()
 			}
-			if bal != c.result {
+			if bal.String() != c.result {
 				t.Errorf("got %s but want %s", bal, c.result)
 			}
 		})
@@ -67,20 +70,20 @@ func TestCalcFee(t *testing.T) {
 func TestCalcFee_Error(t *testing.T) {
 	t.Parallel()
 	cases := []struct {
-		name       string
-		bal        string
-		feeSatoshi uint
-		wantErr    error
+		name    string
+		bal     model.Amount
+		fee     model.Amount
+		wantErr error
 	}{
-		{"minus", "0.00020000", 20_001, btc.ErrNotEnoughBalance},
-		{"zero", "0.00000000", 1, btc.ErrNotEnoughBalance},
-		{"invalid", "12345.1a345678", 20_000, btc.ErrFailedToDecode},
+		{"minus", 20_000, 20_001, btc.ErrNotEnoughBalance},
+		{"zero", 0, 1, btc.ErrNotEnoughBalance},
+		{"negative_fee", 20_000, -1, btc.ErrInvalidFee},
 	}
 	for _, c := range cases {
 		c := c
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
-			_, err := btc.CalcFee(c.bal, c.feeSatoshi)
+			_, err := btc.CalcFee(c.bal, c.fee)
 			if !errors.Is(err, c.wantErr) {
 				t.Errorf("got %+v but want %+v", err, c.wantErr)
 			}
@@ -88,6 +91,30 @@ func TestCalcFee_Error(t *testing.T) {
 	}
 }
 
+func TestFeeOf(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		tier model.FeeTier
+		want model.Amount
+	}{
+		{"default", model.FeeDefault, 20_000},
+		{"small", model.FeeSmall, 10_000},
+		{"normal", model.FeeNormal, 20_000},
+		{"large", model.FeeLarge, 30_000},
+		{"unknown", "huge", 20_000},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := btc.FeeOf(c.tier); got != c.want {
+				t.Errorf("got %v but want %v", got, c.want)
+			}
+		})
+	}
+}
+
 func TestNewBitcoinCLI(t *testing.T) {
 	t.Parallel()
 	cases := []struct {
@@ -281,9 +308,9 @@ func TestBitcoinCLI_ParseTransactionReceived(t *testing.T) {
 		getTxOutput  string
 		targetAddr   string
 		wantVout     int
-		wantReceived string
+		wantReceived model.Amount
 	}{
-		{"confirmed", getTx1, recvAddr1, 0, "0.01158624"},
+		{"confirmed", getTx1, recvAddr1, 0, 1158624},
 	}
 	for _, c := range cases {
 		c := c
@@ -314,14 +341,14 @@ func TestBitcoinCLI_CreateRawTransactionForAnchor_DryRun(t *testing.T) {
 		name        string
 		txid        string
 		vout        int
-		bal         string
+		bal         model.Amount
 		toAddr      string
-		fee         uint
+		fee         model.Amount
 		data        string
-		recvAmo     string
+		recvAmo     model.Amount
 		fullcommand string
 	}{
-		{"normal", txid1, 0, "0.01168624", recvAddr1, 10000, opRet1, recvAmount1, fmt.Sprintf(`%s -chain=test createrawtransaction [{"txid": "%s", "vout": 0}] [{"%s": %s}, {"data": "%s"}]`, path1, txid1, recvAddr1, recvAmount1, opRet1)},
+		{"normal", txid1, 0, 1168624, recvAddr1, 10000, opRet1, recvAmount1, fmt.Sprintf(`%s -chain=test createrawtransaction [{"txid": "%s", "vout": 0}] [{"%s": %s}, {"data": "%s"}]`, path1, txid1, recvAddr1, recvAmount1, opRet1)},
 	}
 	for _, c := range cases {
 		c := c
@@ -465,6 +492,7 @@ func TestBitcoinCLI_ParseTransactionConfirmations(t *testing.T) {
 		wantConfs uint
 	}{
 		{"normal", getTx1, 27320},
+		{"conflicted", `{"confirmations": -3}`, 0},
 	}
 	for _, c := range cases {
 		c := c
@@ -594,3 +622,345 @@ func TestBitcoinCLI_ParseRawTransactionOpReturn(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBalance(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name   string
+		output string
+		want   model.Amount
+	}{
+		{"getbalance", "0.01158624\n", 1158624},
+		{"getbalances", `{"mine": {"trusted": 0.01158624, "untrusted_pending": 0.00000000, "immature": 0.00000000}}`, 1158624},
+		{"getbalances_watchonly", `{"mine": {"trusted": 12345.12345678, "untrusted_pending": 0.00100000, "immature": 0.00000000, "used": 0.00000000}, "watchonly": {"trusted": 1.00000000, "untrusted_pending": 0.00000000, "immature": 0.00000000}}`, 1234512345678},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := btc.ParseBalance(bytes.NewBufferString(c.output))
+			if err != nil {
+				t.Error(err)
+				t.Skip()
+			}
+			if got != c.want {
+				t.Errorf("got %+v but want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseBalance_Error(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name   string
+		output string
+	}{
+		{"empty", ""},
+		{"no_mine", `{"watchonly": {"trusted": 1.00000000}}`},
+		{"no_trusted", `{"mine": {"untrusted_pending": 0.00000000}}`},
+		{"broken", `{"mine": `},
+		{"float_error", "0.1 + 0.2"},
+		{"too_precise", `{"mine": {"trusted": 0.000000001}}`},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := btc.ParseBalance(bytes.NewBufferString(c.output))
+			if !errors.Is(err, btc.ErrFailedToDecode) {
+				t.Errorf("got %+v but want %+v", err, btc.ErrFailedToDecode)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseTransactionDecoded(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name  string
+		txOut string
+		want  []byte
+	}{
+		{"normal", strings.TrimSuffix(getTx1, "}") + `, "decoded": ` + decRawTx1 + "}", util.MustDecodeHexString(opRet1)},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			buf := bytes.NewBufferString(c.txOut)
+			b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+			decoded, err := b.ParseTransactionDecoded(buf)
+			if err != nil {
+				t.Errorf("failed to parse %+v", err)
+				t.Skip()
+			}
+			got, err := b.ParseRawTransactionOpReturn(decoded)
+			if err != nil {
+				t.Errorf("failed to parse %+v", err)
+				t.Skip()
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %+v but want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseTransactionDecoded_Error(t *testing.T) {
+	t.Parallel()
+	b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+	if _, err := b.ParseTransactionDecoded(bytes.NewBufferString(getTx1)); !errors.Is(err, btc.ErrFailedToDecode) {
+		t.Errorf("got %+v but want %+v", err, btc.ErrFailedToDecode)
+	}
+}
+
+func TestBitcoinCLI_TestMempoolAccept_DryRun(t *testing.T) {
+	btc.DryRun(true)
+
+	t.Parallel()
+	cases := []struct {
+		name        string
+		signedRawTx string
+		fullcommand string
+	}{
+		{"normal", signedRawTx1, fmt.Sprintf(`%s -chain=test testmempoolaccept ["%s"]`, path1, signedRawTx1)},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			b := btc.NewBitcoinCLI(path1, model.BTCTestnet3, "", "", "", "")
+			ctx := context.Background()
+			bSignedRawTx, _ := hex.DecodeString(c.signedRawTx)
+			_, err := b.TestMempoolAccept(ctx, bSignedRawTx)
+			if !errors.Is(err, btc.ErrDryRun) {
+				t.Errorf("unexpected err %+v", err)
+				t.Skip()
+			}
+			if err.Error() != c.fullcommand {
+				t.Errorf("got %+v but want %+v", err.Error(), c.fullcommand)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseTestMempoolAccept(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name   string
+		output string
+	}{
+		{"allowed", `[{"txid": "57511f74c3836c0d4d62a6183fa54e600372e1aed5b5be2f78ef5b766a314a5d", "allowed": true, "vsize": 135, "fees": {"base": 0.00010000}}]`},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			buf := bytes.NewBufferString(c.output)
+			b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+			if err := b.ParseTestMempoolAccept(buf); err != nil {
+				t.Errorf("got %+v but want nil", err)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseTestMempoolAccept_Error(t *testing.T) {
+	t.Parallel()
+	reject := func(reason string) string {
+		return fmt.Sprintf(`[{"txid": "57511f74c3836c0d4d62a6183fa54e600372e1aed5b5be2f78ef5b766a314a5d", "allowed": false, "reject-reason": "%s"}]`, reason)
+	}
+	cases := []struct {
+		name      string
+		output    string
+		wantErr   error
+		rejection bool
+	}{
+		{"min_relay_fee", reject("min relay fee not met, 100 < 141"), btc.ErrTxFeeTooLow, true},
+		{"min_relay_fee_with_code", reject("66: min relay fee not met"), btc.ErrTxFeeTooLow, true},
+		{"mempool_min_fee", reject("mempool min fee not met"), btc.ErrTxFeeTooLow, true},
+		{"dust", reject("dust"), btc.ErrTxDust, true},
+		{"dust_with_code", reject("64: dust"), btc.ErrTxDust, true},
+		{"scriptpubkey", reject("scriptpubkey"), btc.ErrTxNonStandard, true},
+		{"multi_op_return", reject("multi-op-return"), btc.ErrTxNonStandard, true},
+		{"missing_inputs", reject("missing-inputs"), btc.ErrTxMissingInputs, true},
+		{"missingorspent", reject("bad-txns-inputs-missingorspent"), btc.ErrTxMissingInputs, true},
+		{"too_long_mempool_chain", reject("too-long-mempool-chain, too many unconfirmed ancestors [limit: 25]"), btc.ErrTxTooLongMempoolChain, true},
+		{"unknown", reject("bad-txns-vout-negative"), btc.ErrTxRejected, true},
+		{"already_in_mempool", reject("txn-already-in-mempool"), btc.ErrTxAlreadyExists, false},
+		{"already_known", reject("txn-already-known"), btc.ErrTxAlreadyExists, false},
+		{"same_nonwitness_data", reject("txn-same-nonwitness-data-in-mempool"), btc.ErrTxAlreadyExists, false},
+		{"invalid_json_1", `{"allowed": true}`, btc.ErrFailedToDecode, false},
+		{"invalid_json_2", `[{"txid": "57511f74c3836c0d4d62a6183fa54e600372e1aed5b5be2f78ef5b766a314a5d"}]`, btc.ErrFailedToDecode, false},
+		{"invalid_json_3", `[]`, btc.ErrFailedToDecode, false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			buf := bytes.NewBufferString(c.output)
+			b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+			err := b.ParseTestMempoolAccept(buf)
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("got %+v but want %+v", err, c.wantErr)
+			}
+			if btc.IsMempoolRejection(err) != c.rejection {
+				t.Errorf("IsMempoolRejection: got %v but want %v", !c.rejection, c.rejection)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseWalletTransactionDepth(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name      string
+		txOut     string
+		wantDepth int
+	}{
+		{"normal", getTx1, 27320},
+		{"unconfirmed", `{"confirmations": 0}`, 0},
+		{"conflicted", `{"confirmations": -3}`, -3},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			buf := bytes.NewBufferString(c.txOut)
+			b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+			depth, err := b.ParseWalletTransactionDepth(buf)
+			if err != nil {
+				t.Errorf("failed to parse %+v", err)
+				t.Skip()
+			}
+			if depth != c.wantDepth {
+				t.Errorf("got %+v but want %+v", depth, c.wantDepth)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_GetMempoolEntry_DryRun(t *testing.T) {
+	btc.DryRun(true)
+
+	t.Parallel()
+	cases := []struct {
+		name        string
+		txid        string
+		fullcommand string
+	}{
+		{"normal", txid1, fmt.Sprintf("%s -chain=test getmempoolentry %s", path1, txid1)},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			b := btc.NewBitcoinCLI(path1, model.BTCTestnet3, "", "", "", "")
+			ctx := context.Background()
+			bTxid, _ := hex.DecodeString(c.txid)
+			_, err := b.GetMempoolEntry(ctx, bTxid)
+			if !errors.Is(err, btc.ErrDryRun) {
+				t.Errorf("unexpected err %+v", err)
+				t.Skip()
+			}
+			if err.Error() != c.fullcommand {
+				t.Errorf("got %+v but want %+v", err.Error(), c.fullcommand)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseRawTransactionID(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name     string
+		rawTx    string
+		wantTxid string
+	}{
+		{"normal", decRawTx1, txid1},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			buf := bytes.NewBufferString(c.rawTx)
+			b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+			txid, err := b.ParseRawTransactionID(buf)
+			if err != nil {
+				t.Errorf("failed to parse %+v", err)
+				t.Skip()
+			}
+			if hex.EncodeToString(txid) != c.wantTxid {
+				t.Errorf("got %x but want %s", txid, c.wantTxid)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseRawTransactionID_Error(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name  string
+		rawTx string
+	}{
+		{"no_txid", `{"hash": "58f472dca084218182d6fce7d42d3e4ac56323e4f9b93966c2b5e9f9096e8db0"}`},
+		{"not_hex", `{"txid": "hello"}`},
+		{"short", `{"txid": "57511f74"}`},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			buf := bytes.NewBufferString(c.rawTx)
+			b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+			if _, err := b.ParseRawTransactionID(buf); !errors.Is(err, btc.ErrFailedToDecode) {
+				t.Errorf("got %+v but want %+v", err, btc.ErrFailedToDecode)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseTransactionFee(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		txOut   string
+		want    model.Amount
+		wantErr bool
+	}{
+		{"normal", getTx1, 10000, false},
+		{"received", `{"amount": 0.01158624, "confirmations": 1}`, 0, true},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			buf := bytes.NewBufferString(c.txOut)
+			b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+			got, err := b.ParseTransactionFee(buf)
+			if (err != nil) != c.wantErr {
+				t.Errorf("unexpected err %+v", err)
+				t.Skip()
+			}
+			if got != c.want {
+				t.Errorf("got %s but want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestBitcoinCLI_ParseRawTransactionSize(t *testing.T) {
+	t.Parallel()
+	b := btc.NewBitcoinCLI("", 0, "", "", "", "")
+	vsize, out, err := b.ParseRawTransactionSize(bytes.NewBufferString(decRawTx1))
+	if err != nil {
+		t.Fatalf("failed to parse %+v", err)
+	}
+	if vsize != 135 || out != 1158624 {
+		t.Errorf("got (%d, %s) but want (135, 0.01158624)", vsize, out)
+	}
+	if _, _, err := b.ParseRawTransactionSize(bytes.NewBufferString(`{"vout": []}`)); !errors.Is(err, btc.ErrFailedToDecode) {
+		t.Errorf("got %v but want ErrFailedToDecode", err)
+	}
+}
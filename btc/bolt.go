@@ -0,0 +1,143 @@
+package btc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ebiiim/btcgw/util"
+
+	"go.etcd.io/bbolt"
+)
+
+var _ Wallet = (*BoltWallet)(nil)
+
+// BoltWallet is a Wallet that uses an embedded bbolt database file (see util.OpenBolt).
+// UTXOs are kept in a bucket named by the address, in JSON with a sequence number as the key,
+// so that each call writes only the changed UTXOs.
+type BoltWallet struct {
+	addr []byte
+
+	path string
+	db   *bbolt.DB
+}
+
+// MustNewBoltWallet initializes a BoltWallet,
+// panics if failed to open the database file.
+//
+// Parameters:
+//   - path sets the database file.
+//   - addr sets bucket name (uses Bitcoin addresses).
+func MustNewBoltWallet(path string, addr string) *BoltWallet {
+	w := &BoltWallet{
+		addr: []byte(addr),
+		path: path,
+		db:   nil,
+	}
+	if err := w.open(); err != nil {
+		panic(fmt.Sprintf("%v path=%s", err, path))
+	}
+	return w
+}
+
+func (w *BoltWallet) open() error {
+	db, err := util.OpenBolt(w.path, w.addr)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenWalletStore, err)
+	}
+	w.db = db
+	return nil
+}
+
+func (w *BoltWallet) Close() error {
+	if err := w.db.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseWalletStore, err)
+	}
+	return nil
+}
+
+// first returns the key and the value of the next UTXO.
+func (w *BoltWallet) first(tx *bbolt.Tx) ([]byte, *utxo, error) {
+	k, v := tx.Bucket(w.addr).Cursor().First()
+	if k == nil {
+		return nil, nil, errEmptyQueue
+	}
+	var u utxo
+	if err := json.Unmarshal(v, &u); err != nil {
+		return nil, nil, err
+	}
+	return k, &u, nil
+}
+
+// add appends a UTXO with the next sequence number.
+func (w *BoltWallet) add(tx *bbolt.Tx, txid []byte, addr string) error {
+	b := tx.Bucket(w.addr)
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	v, err := json.Marshal(utxo{TXID: txid, Addr: addr})
+	if err != nil {
+		return err
+	}
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, seq)
+	return b.Put(k, v)
+}
+
+func (w *BoltWallet) NextUTXO() (txid []byte, addr string, err error) {
+	var u *utxo
+	err = w.db.Update(func(tx *bbolt.Tx) error {
+		k, v, err := w.first(tx)
+		if err != nil {
+			return err
+		}
+		u = v
+		return tx.Bucket(w.addr).Delete(k)
+	})
+	if err != nil {
+		return nil, "", util.Wrap(ErrCouldNotGetNextUTXO, err)
+	}
+	return u.TXID, u.Addr, nil
+}
+
+func (w *BoltWallet) PeekNextUTXO() (txid []byte, addr string, err error) {
+	var u *utxo
+	err = w.db.View(func(tx *bbolt.Tx) error {
+		_, v, err := w.first(tx)
+		u = v
+		return err
+	})
+	if err != nil {
+		return nil, "", util.Wrap(ErrCouldNotGetNextUTXO, err)
+	}
+	return u.TXID, u.Addr, nil
+}
+
+func (w *BoltWallet) AddUTXO(txid []byte, addr string) error {
+	err := w.db.Update(func(tx *bbolt.Tx) error {
+		return w.add(tx, txid, addr)
+	})
+	if err != nil {
+		return util.Wrap(ErrCouldNotAddUTXO, err)
+	}
+	return nil
+}
+
+func (w *BoltWallet) ReplaceNextUTXO(txid []byte, addr string) error {
+	// Both in a transaction.
+	err := w.db.Update(func(tx *bbolt.Tx) error {
+		k, _, err := w.first(tx)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(w.addr).Delete(k); err != nil {
+			return err
+		}
+		return w.add(tx, txid, addr)
+	})
+	if err != nil {
+		return util.Wrap(ErrCouldNotReplaceUTXO, err)
+	}
+	return nil
+}
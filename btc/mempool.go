@@ -0,0 +1,66 @@
+package btc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mempool rejections.
+// These are returned when bitcoind refuses a transaction by its policy,
+// so they are not caused by the unavailability of bitcoind.
+var (
+	ErrTxRejected            = errors.New("ErrTxRejected")
+	ErrTxFeeTooLow           = errors.New("ErrTxFeeTooLow")
+	ErrTxDust                = errors.New("ErrTxDust")
+	ErrTxNonStandard         = errors.New("ErrTxNonStandard")
+	ErrTxMissingInputs       = errors.New("ErrTxMissingInputs")
+	ErrTxTooLongMempoolChain = errors.New("ErrTxTooLongMempoolChain")
+)
+
+// IsMempoolRejection returns whether err is one of the mempool rejections.
+func IsMempoolRejection(err error) bool {
+	for _, e := range []error{ErrTxRejected, ErrTxFeeTooLow, ErrTxDust, ErrTxNonStandard, ErrTxMissingInputs, ErrTxTooLongMempoolChain} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reject reasons reported by bitcoind, matched by prefix.
+// Old versions of bitcoind prepend the reject code, e.g. "66: min relay fee not met".
+// Reasons meaning the transaction has already been sent are converted to ErrTxAlreadyExists,
+// that is not a mempool rejection.
+var rejectReasons = []struct {
+	prefixes []string
+	err      error
+}{
+	{[]string{"txn-already-in-mempool", "txn-already-known", "txn-same-nonwitness-data-in-mempool"}, ErrTxAlreadyExists},
+	{[]string{"too-long-mempool-chain"}, ErrTxTooLongMempoolChain},
+	{[]string{"missing-inputs", "bad-txns-inputs-missingorspent"}, ErrTxMissingInputs},
+	{[]string{"min relay fee not met", "mempool min fee not met", "insufficient fee", "mempool full"}, ErrTxFeeTooLow},
+	{[]string{"dust"}, ErrTxDust},
+	{[]string{
+		"scriptpubkey", "bare-multisig", "multi-op-return", "tx-size", "version",
+		"scriptsig-size", "scriptsig-not-pushonly", "bad-txns-nonstandard-inputs",
+		"bad-witness-nonstandard", "non-mandatory-script-verify-flag", "non-final", "non-BIP68-final",
+	}, ErrTxNonStandard},
+}
+
+var reRejectCode = regexp.MustCompile(`^\d+: `)
+
+// rejectReasonError converts the reject reason into one of the mempool rejections or ErrTxAlreadyExists.
+// Returns ErrTxRejected if the reason is unknown.
+func rejectReasonError(reason string) error {
+	r := reRejectCode.ReplaceAllString(strings.TrimSpace(reason), "")
+	for _, rr := range rejectReasons {
+		for _, p := range rr.prefixes {
+			if strings.HasPrefix(r, p) {
+				return fmt.Errorf("%w (%s)", rr.err, reason)
+			}
+		}
+	}
+	return fmt.Errorf("%w (%s)", ErrTxRejected, reason)
+}
@@ -0,0 +1,121 @@
+package btc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CoreVersion represents a Bitcoin Core version.
+//
+// Bitcoin Core changed its versioning scheme in v22.0.0,
+// so v0.21.0 is {0, 21, 0} and v22.0.0 is {22, 0, 0}.
+// Both of them can be compared by Compare as they are.
+type CoreVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// MinSupportedCoreVersion is the oldest bitcoin-cli version that BitcoinCLI works with.
+var MinSupportedCoreVersion = CoreVersion{0, 20, 0}
+
+// String returns the version in the form of "v0.21.0".
+func (v CoreVersion) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1 if v < w, 0 if v == w, and +1 if v > w.
+func (v CoreVersion) Compare(w CoreVersion) int {
+	a := [3]int{v.Major, v.Minor, v.Patch}
+	b := [3]int{w.Major, w.Minor, w.Patch}
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast returns v >= w.
+func (v CoreVersion) AtLeast(w CoreVersion) bool {
+	return v.Compare(w) >= 0
+}
+
+// Supported returns whether v is equal to or newer than MinSupportedCoreVersion.
+func (v CoreVersion) Supported() bool {
+	return v.AtLeast(MinSupportedCoreVersion)
+}
+
+// CoreFeatures contains version dependent behaviors of Bitcoin Core that BitcoinCLI cares about.
+type CoreFeatures struct {
+	// GetBalances is true if `getbalances` is available.
+	// It returns a JSON object instead of a number like `getbalance`.
+	GetBalances bool
+	// DescriptorWallets is true if descriptor wallets are supported.
+	// Not used by BitcoinCLI yet, but reported in /info.
+	DescriptorWallets bool
+	// DecodedTransaction is true if `gettransaction` accepts the verbose argument
+	// and returns the decoded transaction in the "decoded" field.
+	DecodedTransaction bool
+}
+
+// Features returns CoreFeatures available in v.
+func (v CoreVersion) Features() CoreFeatures {
+	return CoreFeatures{
+		GetBalances:        v.AtLeast(CoreVersion{0, 19, 0}),
+		DescriptorWallets:  v.AtLeast(CoreVersion{0, 21, 0}),
+		DecodedTransaction: v.AtLeast(CoreVersion{0, 20, 0}),
+	}
+}
+
+// Names returns names of the available features.
+func (f CoreFeatures) Names() []string {
+	var s []string
+	if f.GetBalances {
+		s = append(s, "getbalances")
+	}
+	if f.DescriptorWallets {
+		s = append(s, "descriptor_wallets")
+	}
+	if f.DecodedTransaction {
+		s = append(s, "decoded_transaction")
+	}
+	return s
+}
+
+const cliVersionPrefix = "Bitcoin Core RPC client version "
+
+// e.g. v0.21.0, v0.17.0.1, v22.0.0, v0.21.0rc2, v25.99.0-7c1d3e0b9a2f
+var reCoreVersion = regexp.MustCompile(`^v(\d+)\.(\d+)(?:\.(\d+))?(?:\.\d+)?(?:[^\d.].*)?$`)
+
+// ParseCoreVersion parses the output of `bitcoin-cli --version`.
+// Only the first line is used, so copyright notices printed by newer versions are ignored.
+//
+// Possible errors: ErrUnsupportedVersion
+func ParseCoreVersion(s string) (CoreVersion, error) {
+	line := strings.TrimSpace(strings.SplitN(s, "\n", 2)[0])
+	if !strings.HasPrefix(line, cliVersionPrefix) {
+		return CoreVersion{}, fmt.Errorf("%w (%s)", ErrUnsupportedVersion, line)
+	}
+	m := reCoreVersion.FindStringSubmatch(strings.TrimPrefix(line, cliVersionPrefix))
+	if m == nil {
+		return CoreVersion{}, fmt.Errorf("%w (%s)", ErrUnsupportedVersion, line)
+	}
+	var v [3]int
+	for i := range v {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return CoreVersion{}, fmt.Errorf("%w (%s)", ErrUnsupportedVersion, line)
+		}
+		v[i] = n
+	}
+	return CoreVersion{v[0], v[1], v[2]}, nil
+}
@@ -0,0 +1,135 @@
+package btc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ebiiim/btcgw/btc"
+)
+
+func TestParseCoreVersion(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name  string
+		input string
+		want  btc.CoreVersion
+	}{
+		{"v0.20.1", "Bitcoin Core RPC client version v0.20.1\n", btc.CoreVersion{0, 20, 1}},
+		{"v0.21.0", "Bitcoin Core RPC client version v0.21.0", btc.CoreVersion{0, 21, 0}},
+		{"v0.17.0.1", "Bitcoin Core RPC client version v0.17.0.1", btc.CoreVersion{0, 17, 0}},
+		{"v0.21.0rc2", "Bitcoin Core RPC client version v0.21.0rc2", btc.CoreVersion{0, 21, 0}},
+		{"v22.0", "Bitcoin Core RPC client version v22.0", btc.CoreVersion{22, 0, 0}},
+		{"v25.1.0_copyright", "Bitcoin Core RPC client version v25.1.0\nCopyright (C) 2009-2023 The Bitcoin Core developers\n\nPlease contribute if you find Bitcoin Core useful.\n", btc.CoreVersion{25, 1, 0}},
+		{"v26.99.0_dev", "Bitcoin Core RPC client version v26.99.0-7c1d3e0b9a2f\r\n", btc.CoreVersion{26, 99, 0}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := btc.ParseCoreVersion(c.input)
+			if err != nil {
+				t.Error(err)
+				t.Skip()
+			}
+			if got != c.want {
+				t.Errorf("got %+v but want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseCoreVersion_Error(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"no_prefix", "v0.21.0"},
+		{"daemon", "Bitcoin Core version v0.21.0"},
+		{"no_v", "Bitcoin Core RPC client version 0.21.0"},
+		{"garbage", "Bitcoin Core RPC client version vX.Y.Z"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := btc.ParseCoreVersion(c.input)
+			if !errors.Is(err, btc.ErrUnsupportedVersion) {
+				t.Errorf("got %+v but want %+v", err, btc.ErrUnsupportedVersion)
+			}
+		})
+	}
+}
+
+func TestCoreVersion_Compare(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		v    btc.CoreVersion
+		w    btc.CoreVersion
+		want int
+	}{
+		{"equal", btc.CoreVersion{0, 21, 0}, btc.CoreVersion{0, 21, 0}, 0},
+		{"patch", btc.CoreVersion{0, 20, 0}, btc.CoreVersion{0, 20, 1}, -1},
+		{"minor", btc.CoreVersion{0, 21, 0}, btc.CoreVersion{0, 20, 1}, 1},
+		{"new_scheme", btc.CoreVersion{0, 21, 1}, btc.CoreVersion{22, 0, 0}, -1},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := c.v.Compare(c.w); got != c.want {
+				t.Errorf("got %+v but want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCoreVersion_Supported(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		v    btc.CoreVersion
+		want bool
+	}{
+		{"v0.19.1", btc.CoreVersion{0, 19, 1}, false},
+		{"v0.20.0", btc.CoreVersion{0, 20, 0}, true},
+		{"v0.21.0", btc.CoreVersion{0, 21, 0}, true},
+		{"v27.0.0", btc.CoreVersion{27, 0, 0}, true},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := c.v.Supported(); got != c.want {
+				t.Errorf("got %+v but want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCoreVersion_Features(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		v    btc.CoreVersion
+		want []string
+	}{
+		{"v0.18.1", btc.CoreVersion{0, 18, 1}, nil},
+		{"v0.19.0", btc.CoreVersion{0, 19, 0}, []string{"getbalances"}},
+		{"v0.20.1", btc.CoreVersion{0, 20, 1}, []string{"getbalances", "decoded_transaction"}},
+		{"v0.21.0", btc.CoreVersion{0, 21, 0}, []string{"getbalances", "descriptor_wallets", "decoded_transaction"}},
+		{"v23.0.0", btc.CoreVersion{23, 0, 0}, []string{"getbalances", "descriptor_wallets", "decoded_transaction"}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := c.v.Features().Names(); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %+v but want %+v", got, c.want)
+			}
+		})
+	}
+}
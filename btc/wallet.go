@@ -9,6 +9,8 @@ import (
 	"log"
 	"time"
 
+	"github.com/ebiiim/btcgw/util"
+
 	"gocloud.dev/docstore"
 )
 
@@ -27,17 +29,24 @@ var (
 	ErrCouldNotSaveWallet       = errors.New("ErrCouldNotSaveWallet")
 	ErrCouldNotGetNextUTXO      = errors.New("ErrCouldNotGetNextUTXO")
 	ErrCouldNotAddUTXO          = errors.New("ErrCouldNotAddUTXO")
+	ErrCouldNotReplaceUTXO      = errors.New("ErrCouldNotReplaceUTXO")
 )
 
 type Wallet interface {
 	NextUTXO() (txid []byte, addr string, err error)
 	PeekNextUTXO() (txid []byte, addr string, err error)
 	AddUTXO(txid []byte, addr string) error
+	// ReplaceNextUTXO removes the next UTXO and adds the given one atomically,
+	// so that no UTXO is lost even if the process stops in between.
+	ReplaceNextUTXO(txid []byte, addr string) error
 	io.Closer
 }
 
 var _ Wallet = (*DocstoreWallet)(nil)
 
+// errEmptyQueue is returned if there are no UTXOs.
+var errEmptyQueue = fmt.Errorf("%w (empty queue)", util.ErrNotFound)
+
 type utxo struct {
 	TXID []byte
 	Addr string // This is always same with w.Addr for now.
@@ -62,7 +71,7 @@ func (w *DocstoreWallet) enqueue(v utxo) {
 
 func (w *DocstoreWallet) dequeue() (utxo, error) {
 	if len(w.q) == 0 {
-		return utxo{}, errors.New("empty queue")
+		return utxo{}, errEmptyQueue
 	}
 	v := w.q[0]
 	w.q = w.q[1:]
@@ -71,7 +80,7 @@ func (w *DocstoreWallet) dequeue() (utxo, error) {
 
 func (w *DocstoreWallet) peek() (utxo, error) {
 	if len(w.q) == 0 {
-		return utxo{}, errors.New("empty queue")
+		return utxo{}, errEmptyQueue
 	}
 	v := w.q[0]
 	return v, nil
@@ -84,7 +93,7 @@ func (w *DocstoreWallet) load() error {
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelFunc()
 	if err := w.coll.Get(ctx, doc); err != nil {
-		return fmt.Errorf("%w (%v)", ErrCouldNotLoadWallet, err)
+		return util.Wrap(ErrCouldNotLoadWallet, util.DocstoreError(err))
 	}
 	w.q = doc.UTXOs
 	return nil
@@ -98,7 +107,7 @@ func (w *DocstoreWallet) save() error {
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelFunc()
 	if err := w.coll.Put(ctx, doc); err != nil {
-		return fmt.Errorf("%w (%v)", ErrCouldNotSaveWallet, err)
+		return util.Wrap(ErrCouldNotSaveWallet, util.DocstoreError(err))
 	}
 	return nil
 }
@@ -132,6 +141,10 @@ func MustNewDocstoreWallet(conn string, addr string) *DocstoreWallet {
 		panic(fmt.Sprintf("%v conn=%s", err, conn))
 	}
 	if err := w.load(); err != nil {
+		// Do not start with an empty wallet if the datastore is unavailable, as it overwrites the stored UTXOs.
+		if !errors.Is(err, util.ErrNotFound) {
+			panic(fmt.Sprintf("%v conn=%s", err, conn))
+		}
 		log.Printf("MustNewDocstoreWallet: addr=%s not found, create a new doc\n", addr)
 	}
 	return w
@@ -150,11 +163,11 @@ func (w *DocstoreWallet) Close() error {
 func (w *DocstoreWallet) NextUTXO() (txid []byte, addr string, err error) {
 	v, err := w.dequeue()
 	if err != nil {
-		return nil, "", fmt.Errorf("%w, %v", ErrCouldNotGetNextUTXO, err)
+		return nil, "", util.Wrap(ErrCouldNotGetNextUTXO, err)
 	}
 	// Save on every NextUTXO call.
 	if err := w.save(); err != nil {
-		return nil, "", fmt.Errorf("%w, %v", ErrCouldNotGetNextUTXO, err)
+		return nil, "", util.Wrap(ErrCouldNotGetNextUTXO, err)
 	}
 	return v.TXID, v.Addr, nil
 }
@@ -162,7 +175,7 @@ func (w *DocstoreWallet) NextUTXO() (txid []byte, addr string, err error) {
 func (w *DocstoreWallet) PeekNextUTXO() (txid []byte, addr string, err error) {
 	v, err := w.peek()
 	if err != nil {
-		return nil, "", fmt.Errorf("%w, %v", ErrCouldNotGetNextUTXO, err)
+		return nil, "", util.Wrap(ErrCouldNotGetNextUTXO, err)
 	}
 	return v.TXID, v.Addr, nil
 }
@@ -174,7 +187,24 @@ func (w *DocstoreWallet) AddUTXO(txid []byte, addr string) error {
 	})
 	// Save on every AddUTXO call.
 	if err := w.save(); err != nil {
-		return fmt.Errorf("%w, %v", ErrCouldNotAddUTXO, err)
+		return util.Wrap(ErrCouldNotAddUTXO, err)
+	}
+	return nil
+}
+
+func (w *DocstoreWallet) ReplaceNextUTXO(txid []byte, addr string) error {
+	old := w.q
+	if _, err := w.dequeue(); err != nil {
+		return util.Wrap(ErrCouldNotReplaceUTXO, err)
+	}
+	w.enqueue(utxo{
+		TXID: txid,
+		Addr: addr,
+	})
+	// Save once for both dequeue and enqueue.
+	if err := w.save(); err != nil {
+		w.q = old
+		return util.Wrap(ErrCouldNotReplaceUTXO, err)
 	}
 	return nil
 }
@@ -34,7 +34,7 @@ func TestDocstoreWallet_UTXOs(t *testing.T) {
 
 	// peek and dequeue from [] -> [] err
 	txid, addr, err := utxos1.PeekNextUTXO()
-	if txid != nil || addr != "" || !errors.Is(err, btc.ErrCouldNotGetNextUTXO) {
+	if txid != nil || addr != "" || !errors.Is(err, btc.ErrCouldNotGetNextUTXO) || !errors.Is(err, util.ErrNotFound) {
 		t.Errorf("want empty but got txid=%v, addr=%v", txid, addr)
 		t.Skip()
 	}
@@ -146,3 +146,82 @@ func TestNewDocstoreWallet(t *testing.T) {
 		t.Skip()
 	}
 }
+
+func TestDocstoreWallet_ReplaceNextUTXO(t *testing.T) {
+	utxos1 := btc.MustNewDocstoreWallet(conn2, waddr1)
+
+	// replace in [] -> [] err
+	if err := utxos1.ReplaceNextUTXO(wtx1, waddr1); !errors.Is(err, btc.ErrCouldNotReplaceUTXO) {
+		t.Errorf("want %v but got %v", btc.ErrCouldNotReplaceUTXO, err)
+		t.Skip()
+	}
+	// enqueue utxo1 to [] -> [utxo1]
+	// enqueue utxo2 to [utxo1] -> [utxo1, utxo2]
+	err1 := utxos1.AddUTXO(wtx1, waddr1)
+	err2 := utxos1.AddUTXO(wtx2, waddr1)
+	if err1 != nil || err2 != nil {
+		t.Error("1")
+		t.Skip()
+	}
+	// replace utxo1 with utxo3 in [utxo1, utxo2] -> [utxo2, utxo3]
+	if err := utxos1.ReplaceNextUTXO(wtx3, waddr1); err != nil {
+		t.Error(err)
+		t.Skip()
+	}
+	txid, addr, err := utxos1.NextUTXO()
+	if bytes.Compare(txid, wtx2) != 0 || addr != waddr1 || err != nil {
+		t.Error("2")
+		t.Skip()
+	}
+	txid, addr, err = utxos1.NextUTXO()
+	if bytes.Compare(txid, wtx3) != 0 || addr != waddr1 || err != nil {
+		t.Error("3")
+		t.Skip()
+	}
+	if err := utxos1.Close(); err != nil {
+		t.Error(err)
+		t.Skip()
+	}
+}
+
+func TestBoltWallet(t *testing.T) {
+	path := t.TempDir() + "/wallet.db"
+	utxos1 := btc.MustNewBoltWallet(path, waddr1)
+
+	// dequeue from [] -> [] err
+	if txid, addr, err := utxos1.NextUTXO(); txid != nil || addr != "" || !errors.Is(err, btc.ErrCouldNotGetNextUTXO) || !errors.Is(err, util.ErrNotFound) {
+		t.Errorf("want empty but got txid=%v, addr=%v", txid, addr)
+	}
+	// replace in [] -> [] err
+	if err := utxos1.ReplaceNextUTXO(wtx1, waddr1); !errors.Is(err, btc.ErrCouldNotReplaceUTXO) {
+		t.Errorf("want %v but got %v", btc.ErrCouldNotReplaceUTXO, err)
+	}
+	// enqueue utxo1 and utxo2 to [] -> [utxo1, utxo2]
+	// replace utxo1 with utxo3 in [utxo1, utxo2] -> [utxo2, utxo3]
+	err1 := utxos1.AddUTXO(wtx1, waddr1)
+	err2 := utxos1.AddUTXO(wtx2, waddr1)
+	err3 := utxos1.ReplaceNextUTXO(wtx3, waddr1)
+	if err1 != nil || err2 != nil || err3 != nil {
+		t.Fatal(err1, err2, err3)
+	}
+	if err := utxos1.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// UTXOs are kept after reopening.
+	utxos2 := btc.MustNewBoltWallet(path, waddr1)
+	defer utxos2.Close()
+	txid, addr, err := utxos2.PeekNextUTXO()
+	if bytes.Compare(txid, wtx2) != 0 || addr != waddr1 || err != nil {
+		t.Errorf("1 txid=%v, addr=%v, err=%v", txid, addr, err)
+	}
+	for i, want := range [][]byte{wtx2, wtx3} {
+		txid, addr, err := utxos2.NextUTXO()
+		if bytes.Compare(txid, want) != 0 || addr != waddr1 || err != nil {
+			t.Errorf("%d txid=%v, addr=%v, err=%v", i+2, txid, addr, err)
+		}
+	}
+	if txid, addr, err := utxos2.NextUTXO(); txid != nil || addr != "" || !errors.Is(err, btc.ErrCouldNotGetNextUTXO) {
+		t.Errorf("want empty but got txid=%v, addr=%v", txid, addr)
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/ebiiim/btcgw/auth"
+	"github.com/ebiiim/btcgw/util"
 
 	_ "gocloud.dev/docstore/mongodocstore"
 )
@@ -16,9 +19,14 @@ import (
 const (
 	dbName    = "btcgw"
 	authTable = "apikeys"
-	authKey   = "key"
+	authKey   = "id"
+	authFile  = "apikeys.db" // in DATA_DIR
 )
 
+// dataDir is the same as btcgw.
+// Please stop btcgw first as the file is locked while opened (btcgw also serves the API key service in this mode).
+var dataDir = util.GetEnvOr("DATA_DIR", "")
+
 func useMongoDBAtlas() {
 	// Please set environment variables first.
 	// e.g. `set -a; source .env; set +a;`
@@ -50,27 +58,77 @@ var usageFmt3 = fmt.Sprintf(`cmd:
   %s
 args:
 `, cmdDelete)
+var usageFmt4 = fmt.Sprintf(`cmd:
+  %s
+    Hashes API keys stored in plaintext by old versions. The keys are still valid.
+`, cmdMigrate)
+var usageFmt5 = fmt.Sprintf(`cmd:
+  %s
+    Issues the successor of the API key. The API key stays valid for the grace period.
+args:
+`, cmdRotate)
+var usageFmt6 = fmt.Sprintf(`cmd:
+  %s
+    Lists API keys that have expired or not been used, in TSV: id domain admin scopes created lastused expires successor note
+args:
+`, cmdStale)
+var usageFmt7 = fmt.Sprintf(`cmd:
+  %s
+    Deletes the API key by ID, e.g. one listed by %s.
+args:
+`, cmdRevoke, cmdStale)
 
 const (
-	cmdCreate = "create"
-	cmdDelete = "delete"
+	cmdCreate  = "create"
+	cmdDelete  = "delete"
+	cmdMigrate = "migrate"
+	cmdRotate  = "rotate"
+	cmdStale   = "stale"
+	cmdRevoke  = "revoke"
 )
 
+// formatTime returns "-" if t is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
 func do() int {
 	createCmd := flag.NewFlagSet(cmdCreate, flag.ExitOnError)
 	domID := createCmd.String("domain", "", `BBc-1 Domain ID in hex string (required if admin=false)`)
 	gAdmin := createCmd.Bool("admin", false, "Global Administrator (default: false)")
 	note := createCmd.String("note", "", `Note (optional)`)
+	ttl := createCmd.Duration("ttl", 0, `Lifetime, e.g. 2160h (default: never expires)`)
+	scopes := createCmd.String("scopes", "", `Comma separated scopes, e.g. anchors:read,anchors:register (default: all if admin=true, otherwise anchors:read,anchors:register,anchors:refresh)`)
 
 	deleteCmd := flag.NewFlagSet(cmdDelete, flag.ExitOnError)
 	apiKey := deleteCmd.String("apikey", "", "API Key (required)")
 
+	rotateCmd := flag.NewFlagSet(cmdRotate, flag.ExitOnError)
+	rotateKey := rotateCmd.String("apikey", "", "API Key (required)")
+	grace := rotateCmd.Duration("grace", 24*time.Hour, "Grace period of the API Key")
+
+	staleCmd := flag.NewFlagSet(cmdStale, flag.ExitOnError)
+	unused := staleCmd.Duration("unused", 90*24*time.Hour, "Lists keys not used for this period")
+
+	revokeCmd := flag.NewFlagSet(cmdRevoke, flag.ExitOnError)
+	revokeID := revokeCmd.String("id", "", "API Key ID (required)")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, usageFmt1, os.Args[0])
 		fmt.Fprintf(os.Stderr, usageFmt2)
 		createCmd.PrintDefaults()
 		fmt.Fprintf(os.Stderr, usageFmt3)
 		deleteCmd.PrintDefaults()
+		fmt.Fprintf(os.Stderr, usageFmt4)
+		fmt.Fprintf(os.Stderr, usageFmt5)
+		rotateCmd.PrintDefaults()
+		fmt.Fprintf(os.Stderr, usageFmt6)
+		staleCmd.PrintDefaults()
+		fmt.Fprintf(os.Stderr, usageFmt7)
+		revokeCmd.PrintDefaults()
 	}
 
 	if len(os.Args) < 2 {
@@ -78,8 +136,21 @@ func do() int {
 		return 1
 	}
 
-	useMongoDBAtlas()
-	a := auth.MustNewDocstoreAuth(mongoAuthenticator())
+	var a interface {
+		auth.Authenticator
+		Generate(ctx context.Context, domID string, isGlobalAdmin bool, note string, ttl time.Duration, scopes []auth.Scope) (*auth.APIKey, error)
+		Delete(ctx context.Context, apiKey string) error
+		Migrate(ctx context.Context) (int, error)
+		Rotate(ctx context.Context, apiKey string, grace time.Duration) (*auth.APIKey, error)
+		ListStale(ctx context.Context, since time.Time) ([]*auth.APIKey, error)
+		Revoke(ctx context.Context, id string) error
+	}
+	if dataDir != "" {
+		a = auth.MustNewBoltAuth(filepath.Join(dataDir, authFile))
+	} else {
+		useMongoDBAtlas()
+		a = auth.MustNewDocstoreAuth(mongoAuthenticator())
+	}
 	defer a.Close()
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelFunc()
@@ -94,7 +165,12 @@ func do() int {
 			flag.Usage()
 			return 3
 		}
-		a, err := a.Generate(ctx, *domID, *gAdmin, *note)
+		ss, err := auth.ParseScopes(*scopes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			return 3
+		}
+		a, err := a.Generate(ctx, *domID, *gAdmin, *note, *ttl, ss)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%v", err)
 			return 4
@@ -107,6 +183,50 @@ func do() int {
 			fmt.Fprintf(os.Stderr, "%v", err)
 			return 5
 		}
+	case cmdMigrate:
+		n, err := a.Migrate(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			return 6
+		}
+		fmt.Printf("%d keys migrated\n", n)
+	case cmdRotate:
+		rotateCmd.Parse(os.Args[2:])
+		if *rotateKey == "" {
+			flag.Usage()
+			return 3
+		}
+		k, err := a.Rotate(ctx, *rotateKey, *grace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			return 7
+		}
+		fmt.Println(k.Key)
+	case cmdStale:
+		staleCmd.Parse(os.Args[2:])
+		ks, err := a.ListStale(ctx, time.Now().Add(-*unused))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			return 8
+		}
+		for _, k := range ks {
+			scopes := make([]string, len(k.AllowedScopes()))
+			for i, s := range k.AllowedScopes() {
+				scopes[i] = string(s)
+			}
+			fmt.Printf("%s\t%s\t%v\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.DomainID, k.ScopeRegisterAll, strings.Join(scopes, ","),
+				formatTime(k.CreatedAt), formatTime(k.LastUsed), formatTime(k.ExpiresAt), k.SuccessorID, k.Note)
+		}
+	case cmdRevoke:
+		revokeCmd.Parse(os.Args[2:])
+		if *revokeID == "" {
+			flag.Usage()
+			return 3
+		}
+		if err := a.Revoke(ctx, *revokeID); err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			return 9
+		}
 	}
 	return 0
 }
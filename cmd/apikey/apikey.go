@@ -13,6 +13,7 @@ import (
 
 	"github.com/ebiiim/btcgw/api"
 	"github.com/ebiiim/btcgw/auth"
+	"github.com/ebiiim/btcgw/gw"
 	"github.com/ebiiim/btcgw/util"
 
 	"github.com/go-chi/chi"
@@ -24,12 +25,19 @@ import (
 
 var (
 	port = util.GetEnvIntOr("PORT", 8081)
+	// bootstrapFile contains a token accepted as a global admin key, to create the first admin keys.
+	// Remove it once an admin key is created.
+	bootstrapFile = util.GetEnvOr("BOOTSTRAP_TOKEN_FILE", "")
 )
 
 const (
 	dbName    = "btcgw"
 	authTable = "apikeys"
-	authKey   = "key"
+	authKey   = "id"
+	chalTable = "challenges"
+	chalKey   = "nonce"
+	domTable  = "domains"
+	domKey    = "domid"
 )
 
 func useMongoDBAtlas() {
@@ -54,6 +62,14 @@ func mongoAuthenticator() string {
 	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, authTable, authKey)
 }
 
+func mongoChallenges() string {
+	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, chalTable, chalKey)
+}
+
+func mongoDomains() string {
+	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, domTable, domKey)
+}
+
 func main() {
 
 	fmt.Println("")
@@ -68,14 +84,41 @@ func main() {
 
 	// Setup APIKeyService.
 	// It's allowed to open the same DocstoreAuth twice, and it makes APIKeyService.Close() successful.
+	var bootstrap auth.BootstrapToken
+	if bootstrapFile != "" {
+		bootstrap, err = auth.ReadBootstrapToken(bootstrapFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Bootstrap token is enabled. Please remove %s once an admin key is created.", bootstrapFile)
+	}
 	docAuth := auth.MustNewDocstoreAuth(mongoAuthenticator())
-	akService := api.NewAPIKeyService(docAuth)
+	akService := api.NewAPIKeyService(docAuth, bootstrap)
 	defer func() {
 		if cErr := akService.Close(); cErr != nil {
 			log.Printf("%v (captured err: %v)", cErr, err)
 		}
 	}()
 
+	// Owners of BBc-1 domains registered with public keys by btcgw can get API keys by proving the ownership.
+	challenges := auth.MustNewDocstoreChallenges(mongoChallenges())
+	defer func() {
+		if cErr := challenges.Close(); cErr != nil {
+			log.Printf("%v (captured err: %v)", cErr, err)
+		}
+	}()
+	domains := gw.NewDocstoreDomains(mongoDomains())
+	if err = domains.Open(); err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if cErr := domains.Close(); cErr != nil {
+			log.Printf("%v (captured err: %v)", cErr, err)
+		}
+	}()
+	akService.Challenges = challenges
+	akService.Domains = domains
+
 	// Setup Chi.
 	r := chi.NewRouter()
 	r.Use(middleware.RealIP)                     // use this only if you have a trusted reverse proxy
@@ -85,7 +128,7 @@ func main() {
 	r.Use(middleware.Heartbeat("/healthz"))
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"POST"},
+		AllowedMethods:   []string{"GET", "POST"},
 		AllowedHeaders:   []string{"*"},
 		ExposedHeaders:   []string{"*"},
 		AllowCredentials: false,
@@ -102,7 +145,7 @@ func main() {
 		Addr:    addr,
 	}
 
-	sigCh := make(chan os.Signal)
+	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		log.Println(s.ListenAndServe())
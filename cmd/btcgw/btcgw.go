@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -23,7 +24,9 @@ import (
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/httprate"
+	_ "github.com/lib/pq"
 	_ "gocloud.dev/docstore/mongodocstore"
+	_ "modernc.org/sqlite"
 )
 
 var (
@@ -41,6 +44,22 @@ var (
 	dev        = util.GetEnvBoolOr("DEV", false)
 	port       = util.GetEnvIntOr("PORT", 8080)
 	walletAddr = util.GetEnvOr("BITCOIN_WALLET_ADDR", "")
+
+	// DATA_DIR enables the self-contained mode that keeps everything in embedded database files in the directory.
+	dataDir     = util.GetEnvOr("DATA_DIR", "")
+	storeDriver = util.GetEnvOr("STORE_DRIVER", "") // mongo, bolt, sqlite, or postgres (default: bolt if DATA_DIR is set, otherwise mongo)
+	storeDSN    = util.GetEnvOr("STORE_DSN", "")    // for sqlite and postgres
+
+	pendingInterval = util.GetEnvIntOr("PENDING_CHECK_INTERVAL", 600) // seconds, 0 disables tracking
+	finalConfs      = util.GetEnvIntOr("FINAL_CONFIRMATIONS", 6)
+	queueWorkers    = util.GetEnvIntOr("QUEUE_WORKERS", 1)
+	queueInterval   = util.GetEnvIntOr("QUEUE_RETRY_INTERVAL", 60) // seconds
+
+	// Budget of all domains, 0 means unlimited.
+	dailyAnchors   = util.GetEnvIntOr("DAILY_ANCHOR_QUOTA", 0)
+	monthlyAnchors = util.GetEnvIntOr("MONTHLY_ANCHOR_QUOTA", 0)
+	dailySpend     = util.GetEnvIntOr("DAILY_FEE_BUDGET", 0)   // satoshis
+	monthlySpend   = util.GetEnvIntOr("MONTHLY_FEE_BUDGET", 0) // satoshis
 )
 
 const (
@@ -48,9 +67,31 @@ const (
 	anchorTable = "anchors"
 	anchorKey   = "cid"
 	authTable   = "apikeys"
-	authKey     = "key"
+	authKey     = "id"
 	utxoTable   = "utxos"
 	utxoKey     = "addr"
+	pendTable   = "pendings"
+	pendKey     = "btctx"
+	jrnlTable   = "journal"
+	jrnlKey     = "cid"
+	histTable   = "history"
+	histKey     = "id"
+	domTable    = "domains"
+	domKey      = "domid"
+	ledgTable   = "ledger"
+	ledgKey     = "btctx"
+)
+
+// Files in dataDir.
+const (
+	anchorFile = "anchors.db"
+	authFile   = "apikeys.db"
+	utxoFile   = "wallet.db"
+	pendFile   = "pendings.db"
+	jrnlFile   = "journal.db"
+	histFile   = "history.db"
+	domFile    = "domains.db"
+	ledgFile   = "ledger.db"
 )
 
 func useMongoDBAtlas() {
@@ -83,6 +124,44 @@ func mongoWallet() string {
 	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, utxoTable, utxoKey)
 }
 
+func mongoTracker() string {
+	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, pendTable, pendKey)
+}
+
+func mongoJournal() string {
+	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, jrnlTable, jrnlKey)
+}
+
+func mongoHistory() string {
+	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, histTable, histKey)
+}
+
+func mongoDomains() string {
+	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, domTable, domKey)
+}
+
+func mongoLedger() string {
+	return fmt.Sprintf("mongo://%s/%s?id_field=%s", dbName, ledgTable, ledgKey)
+}
+
+// checkPendingAnchors calls g.CheckPendingAnchors every interval until ctx is done.
+func checkPendingAnchors(ctx context.Context, g *gw.GatewayImpl, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			cctx, cancelFunc := context.WithTimeout(ctx, interval)
+			if err := g.CheckPendingAnchors(cctx); err != nil {
+				log.Println(err)
+			}
+			cancelFunc()
+		}
+	}
+}
+
 func main() {
 	// flag.IntVar(&port, "port", 8080, "HTTP port")
 	// flag.BoolVar(&dev, "dev", false, "Use AnchorVersion 255 and prettify HTTP response body")
@@ -95,6 +174,9 @@ func main() {
 		log.Println("please set wallet")
 		return
 	}
+	if finalConfs > 0 {
+		model.FinalConfirmations = uint(finalConfs)
+	}
 	if dev {
 		fmt.Println("Development Environment")
 		// Set AnchorVersion to test.
@@ -108,7 +190,18 @@ func main() {
 		fmt.Println("")
 	}
 
-	useMongoDBAtlas()
+	selfContained := dataDir != ""
+	if selfContained {
+		if err := os.MkdirAll(dataDir, 0700); err != nil {
+			log.Println(err)
+			return
+		}
+		if storeDriver == "" {
+			storeDriver = "bolt"
+		}
+	} else {
+		useMongoDBAtlas()
+	}
 	// Setup Gateway.
 	var err error
 	var btcCLI *btc.BitcoinCLI
@@ -117,17 +210,116 @@ func main() {
 	} else {
 		btcCLI = btc.NewBitcoinCLI(cliPath, btcNet, rpcAddr, rpcPort, rpcUser, rpcPW)
 	}
-	docStore := store.NewDocstore(mongoStore())
-	if err = docStore.Open(); err != nil {
+	var anchorStore interface {
+		store.Store
+		Open() error
+	}
+	switch storeDriver {
+	case store.DriverSQLite, store.DriverPostgres:
+		anchorStore = store.NewSQL(storeDriver, storeDSN)
+	case "bolt":
+		anchorStore = store.NewBolt(filepath.Join(dataDir, anchorFile))
+	default:
+		anchorStore = store.NewDocstore(mongoStore())
+	}
+	if err = anchorStore.Open(); err != nil {
 		log.Println(err)
 		return
 	}
-	wallet := btc.MustNewDocstoreWallet(mongoWallet(), walletAddr)
-	gwImpl := gw.NewGatewayImpl(model.BTCTestnet3, btcCLI, wallet, docStore)
+	var wallet btc.Wallet
+	var journal interface {
+		gw.Journal
+		Open() error
+	}
+	var history interface {
+		gw.History
+		Open() error
+	}
+	var domains interface {
+		gw.Domains
+		Open() error
+	}
+	var ledger interface {
+		gw.Ledger
+		Open() error
+	}
+	if selfContained {
+		wallet = btc.MustNewBoltWallet(filepath.Join(dataDir, utxoFile), walletAddr)
+		journal = gw.NewBoltJournal(filepath.Join(dataDir, jrnlFile))
+		history = gw.NewBoltHistory(filepath.Join(dataDir, histFile))
+		domains = gw.NewBoltDomains(filepath.Join(dataDir, domFile))
+		ledger = gw.NewBoltLedger(filepath.Join(dataDir, ledgFile))
+	} else {
+		wallet = btc.MustNewDocstoreWallet(mongoWallet(), walletAddr)
+		journal = gw.NewDocstoreJournal(mongoJournal())
+		history = gw.NewDocstoreHistory(mongoHistory())
+		domains = gw.NewDocstoreDomains(mongoDomains())
+		ledger = gw.NewDocstoreLedger(mongoLedger())
+	}
+	gwImpl := gw.NewGatewayImpl(model.BTCTestnet3, btcCLI, wallet, anchorStore)
+	if err = journal.Open(); err != nil {
+		log.Println(err)
+		return
+	}
+	gwImpl.Journal = journal
+	if err = history.Open(); err != nil {
+		log.Println(err)
+		return
+	}
+	gwImpl.History = history
+	if err = domains.Open(); err != nil {
+		log.Println(err)
+		return
+	}
+	gwImpl.Domains = domains
+	if err = ledger.Open(); err != nil {
+		log.Println(err)
+		return
+	}
+	gwImpl.Ledger = ledger
+	gwImpl.Budget = model.Budget{
+		DailyAnchors:   uint(dailyAnchors),
+		MonthlyAnchors: uint(monthlyAnchors),
+		DailySpend:     model.Amount(dailySpend),
+		MonthlySpend:   model.Amount(monthlySpend),
+	}
+	if pendingInterval > 0 {
+		var tracker interface {
+			gw.Tracker
+			Open() error
+		}
+		if selfContained {
+			tracker = gw.NewBoltTracker(filepath.Join(dataDir, pendFile))
+		} else {
+			tracker = gw.NewDocstoreTracker(mongoTracker())
+		}
+		if err = tracker.Open(); err != nil {
+			log.Println(err)
+			return
+		}
+		gwImpl.Tracker = tracker
+		trackerCtx, stopTracker := context.WithCancel(context.Background())
+		defer stopTracker()
+		go checkPendingAnchors(trackerCtx, gwImpl, time.Duration(pendingInterval)*time.Second)
+	}
+	// Finish or roll back registrations interrupted by the last shutdown.
+	if err := gwImpl.Recover(context.Background()); err != nil {
+		log.Println(err)
+	}
+	// Start workers for asynchronous registrations.
+	queueCtx, stopQueue := context.WithCancel(context.Background())
+	defer stopQueue()
+	gwImpl.StartWorkers(queueCtx, queueWorkers, time.Duration(queueInterval)*time.Second)
 
 	// Setup Authenticator.
 	var a auth.Authenticator
-	a = auth.MustNewDocstoreAuth(mongoAuthenticator())
+	var keys auth.KeyManager
+	if selfContained {
+		boltAuth := auth.MustNewBoltAuth(filepath.Join(dataDir, authFile))
+		a, keys = boltAuth, boltAuth
+	} else {
+		a = auth.MustNewDocstoreAuth(mongoAuthenticator())
+	}
 	// a = &auth.SpecialAuth{}
 
 	// Setup GatewayService.
@@ -138,6 +330,14 @@ func main() {
 		}
 	}()
 
+	// Setup APIKeyService in the self-contained mode, as cmd/apikey cannot open the file of BoltAuth while btcgw is running.
+	// It shares BoltAuth with GatewayService, which closes it. Ownership proofs are not available.
+	// Otherwise cmd/apikey serves it with MongoDB.
+	var akService *api.APIKeyService
+	if keys != nil {
+		akService = api.NewAPIKeyService(keys, "")
+	}
+
 	// Setup Chi.
 	r := chi.NewRouter()
 	r.Use(middleware.RealIP)                     // use this only if you have a trusted reverse proxy
@@ -153,8 +353,16 @@ func main() {
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
-	r.Use(gwService.OAPIValidator())
-	api.AnchorHandlerFromMux(gwService, r)
+	r.Group(func(r chi.Router) {
+		r.Use(gwService.OAPIValidator())
+		api.AnchorHandlerFromMux(gwService, r)
+	})
+	if akService != nil {
+		r.Group(func(r chi.Router) {
+			r.Use(akService.OAPIValidator())
+			api.APIKeyHandlerFromMux(akService, r)
+		})
+	}
 
 	// Serve.
 	addr := fmt.Sprintf("0.0.0.0:%d", port)
@@ -164,7 +372,7 @@ func main() {
 		Addr:    addr,
 	}
 
-	sigCh := make(chan os.Signal)
+	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		log.Println(s.ListenAndServe())
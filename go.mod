@@ -3,6 +3,7 @@ module github.com/ebiiim/btcgw
 go 1.15
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/deepmap/oapi-codegen v1.5.0
 	github.com/ebiiim/cmdproxy v0.1.0
 	github.com/getkin/kin-openapi v0.37.0
@@ -10,6 +11,11 @@ require (
 	github.com/go-chi/cors v1.1.1
 	github.com/go-chi/httprate v0.4.0
 	github.com/google/uuid v1.1.2
+	github.com/lib/pq v1.10.0
+	github.com/pkg/errors v0.9.1
+	go.etcd.io/bbolt v1.3.5
 	gocloud.dev v0.22.0
 	gocloud.dev/docstore/mongodocstore v0.22.0
+	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
+	modernc.org/sqlite v1.10.0
 )
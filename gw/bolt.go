@@ -0,0 +1,592 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	// boltJournal contains journalDocs in JSON, with journalCID as the key.
+	boltJournal = []byte("journal")
+	// boltPendings contains pendingDocs in JSON, with the Bitcoin transaction ID as the key.
+	boltPendings = []byte("pendings")
+	// boltHistory contains historyDocs in JSON, with historyID as the key.
+	boltHistory = []byte("history")
+	// boltDomains contains domainDocs in JSON, with the BBc-1 domain ID in hexadecimal string as the key.
+	boltDomains = []byte("domains")
+	// boltLedger contains spendDocs in JSON, with the Bitcoin transaction ID in hexadecimal string as the key.
+	boltLedger = []byte("ledger")
+)
+
+var _ Journal = (*BoltJournal)(nil)
+var _ Tracker = (*BoltTracker)(nil)
+var _ History = (*BoltHistory)(nil)
+var _ Domains = (*BoltDomains)(nil)
+var _ Ledger = (*BoltLedger)(nil)
+
+// BoltJournal is a Journal that uses an embedded bbolt database file (see util.OpenBolt).
+type BoltJournal struct {
+	path string
+	db   *bbolt.DB
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewBoltJournal(path string) *BoltJournal {
+	j := &BoltJournal{
+		path: path,
+		db:   nil,
+	}
+	return j
+}
+
+func (j *BoltJournal) open() error {
+	db, err := util.OpenBolt(j.path, boltJournal)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenJournal, err)
+	}
+	j.db = db
+	return nil
+}
+
+// Open opens j.db once.
+func (j *BoltJournal) Open() error {
+	var oErr error
+	j.once.Do(func() { oErr = j.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the BoltJournal.
+func (j *BoltJournal) Close() error {
+	if err := j.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseJournal, err)
+	}
+	if err := j.db.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseJournal, err)
+	}
+	return nil
+}
+
+func (j *BoltJournal) Put(ctx context.Context, e *JournalEntry) error {
+	if err := j.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	d := newJournalDoc(e)
+	v, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	err = j.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltJournal).Put([]byte(d.CID), v)
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	return nil
+}
+
+func (j *BoltJournal) Get(ctx context.Context, domID, txID []byte) (*JournalEntry, error) {
+	if err := j.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadJournal, err)
+	}
+	cid := journalCID(domID, txID)
+	var d *journalDoc
+	err := j.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(boltJournal).Get([]byte(cid))
+		if v == nil {
+			return nil
+		}
+		d = &journalDoc{}
+		return json.Unmarshal(v, d)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadJournal, err)
+	}
+	if d == nil {
+		return nil, wrap(ErrJournalEntryNotFound, fmt.Errorf("%w (cid=%s)", util.ErrNotFound, cid))
+	}
+	return d.journalEntry(), nil
+}
+
+func (j *BoltJournal) Delete(ctx context.Context, domID, txID []byte) error {
+	if err := j.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	err := j.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltJournal).Delete([]byte(journalCID(domID, txID)))
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	return nil
+}
+
+func (j *BoltJournal) List(ctx context.Context) ([]*JournalEntry, error) {
+	if err := j.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadJournal, err)
+	}
+	var es []*JournalEntry
+	err := j.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltJournal).ForEach(func(_, v []byte) error {
+			var d journalDoc
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			es = append(es, d.journalEntry())
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadJournal, err)
+	}
+	sort.SliceStable(es, func(i, k int) bool { return es[i].CreatedAt.Before(es[k].CreatedAt) })
+	return es, nil
+}
+
+// BoltTracker is a Tracker that uses an embedded bbolt database file (see util.OpenBolt).
+type BoltTracker struct {
+	path string
+	db   *bbolt.DB
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewBoltTracker(path string) *BoltTracker {
+	t := &BoltTracker{
+		path: path,
+		db:   nil,
+	}
+	return t
+}
+
+func (t *BoltTracker) open() error {
+	db, err := util.OpenBolt(t.path, boltPendings)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenTracker, err)
+	}
+	t.db = db
+	return nil
+}
+
+// Open opens t.db once.
+func (t *BoltTracker) Open() error {
+	var oErr error
+	t.once.Do(func() { oErr = t.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the BoltTracker.
+func (t *BoltTracker) Close() error {
+	if err := t.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseTracker, err)
+	}
+	if err := t.db.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseTracker, err)
+	}
+	return nil
+}
+
+func (t *BoltTracker) Put(ctx context.Context, p *PendingAnchor) error {
+	if err := t.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotTrack, err)
+	}
+	d := newPendingDoc(p)
+	v, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotTrack, err)
+	}
+	err = t.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltPendings).Put([]byte(d.BTCTx), v)
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotTrack, err)
+	}
+	return nil
+}
+
+func (t *BoltTracker) Delete(ctx context.Context, btcTXID []byte) error {
+	if err := t.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotUntrack, err)
+	}
+	err := t.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltPendings).Delete([]byte(hex.EncodeToString(btcTXID)))
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotUntrack, err)
+	}
+	return nil
+}
+
+func (t *BoltTracker) List(ctx context.Context) ([]*PendingAnchor, error) {
+	if err := t.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotListPending, err)
+	}
+	var ps []*PendingAnchor
+	err := t.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltPendings).ForEach(func(_, v []byte) error {
+			var d pendingDoc
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			p, err := d.pendingAnchor()
+			if err != nil {
+				return err
+			}
+			ps = append(ps, p)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotListPending, err)
+	}
+	sort.SliceStable(ps, func(i, j int) bool { return ps[i].BroadcastTime.Before(ps[j].BroadcastTime) })
+	return ps, nil
+}
+
+// BoltHistory is a History that uses an embedded bbolt database file (see util.OpenBolt).
+type BoltHistory struct {
+	path string
+	db   *bbolt.DB
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewBoltHistory(path string) *BoltHistory {
+	h := &BoltHistory{
+		path: path,
+		db:   nil,
+	}
+	return h
+}
+
+func (h *BoltHistory) open() error {
+	db, err := util.OpenBolt(h.path, boltHistory)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenHistory, err)
+	}
+	h.db = db
+	return nil
+}
+
+// Open opens h.db once.
+func (h *BoltHistory) Open() error {
+	var oErr error
+	h.once.Do(func() { oErr = h.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the BoltHistory.
+func (h *BoltHistory) Close() error {
+	if err := h.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseHistory, err)
+	}
+	if err := h.db.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseHistory, err)
+	}
+	return nil
+}
+
+// Append puts HistoryEntries in es in a transaction.
+// Returns util.ErrAlreadyExists if one of them exists, so existing ones are never overwritten.
+func (h *BoltHistory) Append(ctx context.Context, es []*HistoryEntry) error {
+	if err := h.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteHistory, err)
+	}
+	seq := atomic.AddUint32(&historySeq, 1)
+	err := h.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(boltHistory)
+		for i, e := range es {
+			d := newHistoryDoc(e, seq, i)
+			if b.Get([]byte(d.ID)) != nil {
+				return fmt.Errorf("%w (id=%s)", util.ErrAlreadyExists, d.ID)
+			}
+			v, err := json.Marshal(d)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(d.ID), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return wrap(ErrCouldNotWriteHistory, err)
+	}
+	return nil
+}
+
+// List scans the keys that start with the journalCID in order.
+func (h *BoltHistory) List(ctx context.Context, domID, txID []byte) ([]*HistoryEntry, error) {
+	if err := h.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadHistory, err)
+	}
+	prefix := []byte(journalCID(domID, txID) + "/")
+	es := []*HistoryEntry{}
+	err := h.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(boltHistory).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var d historyDoc
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			es = append(es, d.historyEntry())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadHistory, err)
+	}
+	return es, nil
+}
+
+// BoltDomains is a Domains that uses an embedded bbolt database file (see util.OpenBolt).
+type BoltDomains struct {
+	path string
+	db   *bbolt.DB
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewBoltDomains(path string) *BoltDomains {
+	s := &BoltDomains{
+		path: path,
+		db:   nil,
+	}
+	return s
+}
+
+func (s *BoltDomains) open() error {
+	db, err := util.OpenBolt(s.path, boltDomains)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenDomains, err)
+	}
+	s.db = db
+	return nil
+}
+
+// Open opens s.db once.
+func (s *BoltDomains) Open() error {
+	var oErr error
+	s.once.Do(func() { oErr = s.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the BoltDomains.
+func (s *BoltDomains) Close() error {
+	if err := s.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseDomains, err)
+	}
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseDomains, err)
+	}
+	return nil
+}
+
+func (s *BoltDomains) Get(ctx context.Context, domID []byte) (*model.Domain, error) {
+	if err := s.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+	}
+	key := hex.EncodeToString(domID)
+	var doc *domainDoc
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(boltDomains).Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+		doc = &domainDoc{}
+		return json.Unmarshal(v, doc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+	}
+	if doc == nil {
+		return nil, wrap(ErrDomainNotFound, fmt.Errorf("%w (domid=%s)", util.ErrNotFound, key))
+	}
+	d, err := doc.domain()
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+	}
+	return d, nil
+}
+
+func (s *BoltDomains) Put(ctx context.Context, d *model.Domain) error {
+	if err := s.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteDomain, err)
+	}
+	doc := newDomainDoc(d)
+	v, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteDomain, err)
+	}
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltDomains).Put([]byte(doc.DomID), v)
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteDomain, err)
+	}
+	return nil
+}
+
+func (s *BoltDomains) Delete(ctx context.Context, domID []byte) error {
+	if err := s.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteDomain, err)
+	}
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltDomains).Delete([]byte(hex.EncodeToString(domID)))
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteDomain, err)
+	}
+	return nil
+}
+
+// List scans the keys in order.
+func (s *BoltDomains) List(ctx context.Context) ([]*model.Domain, error) {
+	if err := s.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+	}
+	ds := []*model.Domain{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltDomains).ForEach(func(_, v []byte) error {
+			var doc domainDoc
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return err
+			}
+			d, err := doc.domain()
+			if err != nil {
+				return err
+			}
+			ds = append(ds, d)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+	}
+	return ds, nil
+}
+
+// BoltLedger is a Ledger that uses an embedded bbolt database file (see util.OpenBolt).
+type BoltLedger struct {
+	path string
+	db   *bbolt.DB
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewBoltLedger(path string) *BoltLedger {
+	l := &BoltLedger{
+		path: path,
+		db:   nil,
+	}
+	return l
+}
+
+func (l *BoltLedger) open() error {
+	db, err := util.OpenBolt(l.path, boltLedger)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenLedger, err)
+	}
+	l.db = db
+	return nil
+}
+
+// Open opens l.db once.
+func (l *BoltLedger) Open() error {
+	var oErr error
+	l.once.Do(func() { oErr = l.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the BoltLedger.
+func (l *BoltLedger) Close() error {
+	if err := l.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseLedger, err)
+	}
+	if err := l.db.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseLedger, err)
+	}
+	return nil
+}
+
+func (l *BoltLedger) Put(ctx context.Context, s *Spend) error {
+	if err := l.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteLedger, err)
+	}
+	doc := newSpendDoc(s)
+	v, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteLedger, err)
+	}
+	err = l.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltLedger).Put([]byte(doc.BTCTx), v)
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteLedger, err)
+	}
+	return nil
+}
+
+// Total scans all Spends.
+func (l *BoltLedger) Total(ctx context.Context, domID []byte, since time.Time) (*SpendTotal, error) {
+	if err := l.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadLedger, err)
+	}
+	var key string
+	if domID != nil {
+		key = hex.EncodeToString(domID)
+	}
+	t := &SpendTotal{}
+	err := l.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltLedger).ForEach(func(_, v []byte) error {
+			var doc spendDoc
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return err
+			}
+			t.add(&doc, key, since)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadLedger, err)
+	}
+	return t, nil
+}
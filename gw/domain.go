@@ -0,0 +1,392 @@
+package gw
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+	"github.com/ebiiim/btcgw/util"
+
+	"gocloud.dev/docstore"
+)
+
+// Domains stores Domains, the settings of BBc-1 domains.
+type Domains interface {
+	// Get returns the Domain of domID.
+	// Returns ErrDomainNotFound (and util.ErrNotFound) if not found.
+	Get(ctx context.Context, domID []byte) (*model.Domain, error)
+	// Put adds or replaces the Domain.
+	Put(ctx context.Context, d *model.Domain) error
+	// Delete removes the Domain of domID. Does nothing if not found.
+	Delete(ctx context.Context, domID []byte) error
+	// List returns all Domains ordered by BBc-1 domain ID.
+	List(ctx context.Context) ([]*model.Domain, error)
+
+	io.Closer
+}
+
+var _ Domains = (*DocstoreDomains)(nil)
+
+// Errors
+var (
+	ErrCouldNotOpenDomains  = errors.New("ErrCouldNotOpenDomains")
+	ErrCouldNotCloseDomains = errors.New("ErrCouldNotCloseDomains")
+	ErrCouldNotWriteDomain  = errors.New("ErrCouldNotWriteDomain")
+	ErrCouldNotReadDomain   = errors.New("ErrCouldNotReadDomain")
+	ErrDomainNotFound       = errors.New("ErrDomainNotFound")
+	ErrCouldNotGetDomain    = errors.New("ErrCouldNotGetDomain")
+	ErrCouldNotPutDomain    = errors.New("ErrCouldNotPutDomain")
+	ErrCouldNotDeleteDomain = errors.New("ErrCouldNotDeleteDomain")
+	ErrCouldNotListDomains  = errors.New("ErrCouldNotListDomains")
+
+	// Policies of Domains applied by RegisterTransaction.
+	ErrNetworkNotAllowed  = errors.New("ErrNetworkNotAllowed")
+	ErrDailyQuotaExceeded = errors.New("ErrDailyQuotaExceeded")
+)
+
+type domainDoc struct {
+	DomID         string    `docstore:"domid"`
+	Name          string    `docstore:"name"`
+	Owner         string    `docstore:"owner"`
+	Networks      []string  `docstore:"networks"`
+	DailyQuota    uint      `docstore:"dailyquota"`
+	MonthlyQuota  uint      `docstore:"monthlyquota"`
+	DailyBudget   int64     `docstore:"dailybudget"`
+	MonthlyBudget int64     `docstore:"monthlybudget"`
+	FeeTier       string    `docstore:"feetier"`
+	PublicKey     []byte    `docstore:"pubkey"`
+	KeyType       string    `docstore:"keytype"`
+	CreatedAt     time.Time `docstore:"createdat"`
+	UpdatedAt     time.Time `docstore:"updatedat"`
+}
+
+func newDomainDoc(d *model.Domain) *domainDoc {
+	doc := &domainDoc{
+		DomID:         hex.EncodeToString(d.BBc1DomainID),
+		Name:          d.Name,
+		Owner:         d.Owner,
+		DailyQuota:    d.DailyQuota,
+		MonthlyQuota:  d.MonthlyQuota,
+		DailyBudget:   int64(d.DailyBudget),
+		MonthlyBudget: int64(d.MonthlyBudget),
+		FeeTier:       string(d.FeeTier),
+		PublicKey:     d.PublicKey,
+		KeyType:       string(d.KeyType),
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}
+	for _, n := range d.AllowedNetworks {
+		doc.Networks = append(doc.Networks, n.String())
+	}
+	return doc
+}
+
+func (doc *domainDoc) domain() (*model.Domain, error) {
+	domID, err := hex.DecodeString(doc.DomID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain ID %q", doc.DomID)
+	}
+	d := &model.Domain{
+		BBc1DomainID:  domID,
+		Name:          doc.Name,
+		Owner:         doc.Owner,
+		DailyQuota:    doc.DailyQuota,
+		MonthlyQuota:  doc.MonthlyQuota,
+		DailyBudget:   model.Amount(doc.DailyBudget),
+		MonthlyBudget: model.Amount(doc.MonthlyBudget),
+		FeeTier:       model.FeeTier(doc.FeeTier),
+		PublicKey:     doc.PublicKey,
+		KeyType:       model.KeyType(doc.KeyType),
+		CreatedAt:     doc.CreatedAt,
+		UpdatedAt:     doc.UpdatedAt,
+	}
+	for _, s := range doc.Networks {
+		n, err := model.ParseBTCNet(s)
+		if err != nil {
+			return nil, err
+		}
+		d.AllowedNetworks = append(d.AllowedNetworks, n)
+	}
+	return d, nil
+}
+
+// DocstoreDomains is a Domains that uses gocloud.dev/docstore.
+// The collection must use "domid" as the ID field.
+type DocstoreDomains struct {
+	conn string
+	coll *docstore.Collection
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewDocstoreDomains(conn string) *DocstoreDomains {
+	s := &DocstoreDomains{
+		conn: conn,
+		coll: nil,
+	}
+	return s
+}
+
+func (s *DocstoreDomains) open() error {
+	coll, err := docstore.OpenCollection(context.Background(), s.conn)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenDomains, err)
+	}
+	s.coll = coll
+	return nil
+}
+
+// Open opens s.coll once.
+func (s *DocstoreDomains) Open() error {
+	var oErr error
+	s.once.Do(func() { oErr = s.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the DocstoreDomains.
+func (s *DocstoreDomains) Close() error {
+	if err := s.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseDomains, err)
+	}
+	if err := s.coll.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseDomains, err)
+	}
+	return nil
+}
+
+func (s *DocstoreDomains) Get(ctx context.Context, domID []byte) (*model.Domain, error) {
+	if err := s.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+	}
+	doc := &domainDoc{DomID: hex.EncodeToString(domID)}
+	if err := s.coll.Get(ctx, doc); err != nil {
+		err = util.DocstoreError(err)
+		if errors.Is(err, util.ErrNotFound) {
+			return nil, wrap(ErrDomainNotFound, err)
+		}
+		return nil, wrap(ErrCouldNotReadDomain, err)
+	}
+	d, err := doc.domain()
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+	}
+	return d, nil
+}
+
+func (s *DocstoreDomains) Put(ctx context.Context, d *model.Domain) error {
+	if err := s.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteDomain, err)
+	}
+	if err := s.coll.Put(ctx, newDomainDoc(d)); err != nil {
+		return wrap(ErrCouldNotWriteDomain, util.DocstoreError(err))
+	}
+	return nil
+}
+
+func (s *DocstoreDomains) Delete(ctx context.Context, domID []byte) error {
+	if err := s.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteDomain, err)
+	}
+	if err := s.coll.Delete(ctx, &domainDoc{DomID: hex.EncodeToString(domID)}); err != nil {
+		return wrap(ErrCouldNotWriteDomain, util.DocstoreError(err))
+	}
+	return nil
+}
+
+func (s *DocstoreDomains) List(ctx context.Context) ([]*model.Domain, error) {
+	if err := s.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+	}
+	iter := s.coll.Query().Get(ctx)
+	defer iter.Stop()
+	ds := []*model.Domain{}
+	for {
+		var doc domainDoc
+		err := iter.Next(ctx, &doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, wrap(ErrCouldNotReadDomain, util.DocstoreError(err))
+		}
+		d, err := doc.domain()
+		if err != nil {
+			return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadDomain, err)
+		}
+		ds = append(ds, d)
+	}
+	sort.Slice(ds, func(i, j int) bool {
+		return hex.EncodeToString(ds[i].BBc1DomainID) < hex.EncodeToString(ds[j].BBc1DomainID)
+	})
+	return ds, nil
+}
+
+// GetDomain returns the Domain of domID. Needs g.Domains.
+func (g *GatewayImpl) GetDomain(ctx context.Context, domID []byte) (*model.Domain, error) {
+	if g.Domains == nil {
+		return nil, fmt.Errorf("%w (Domains is not set)", ErrCouldNotGetDomain)
+	}
+	d, err := g.Domains.Get(ctx, domID)
+	if err != nil {
+		return nil, wrap(ErrCouldNotGetDomain, err)
+	}
+	return d, nil
+}
+
+// ListDomains returns all Domains. Needs g.Domains.
+func (g *GatewayImpl) ListDomains(ctx context.Context) ([]*model.Domain, error) {
+	if g.Domains == nil {
+		return nil, fmt.Errorf("%w (Domains is not set)", ErrCouldNotListDomains)
+	}
+	ds, err := g.Domains.List(ctx)
+	if err != nil {
+		return nil, wrap(ErrCouldNotListDomains, err)
+	}
+	return ds, nil
+}
+
+// PutDomain validates d and adds or replaces it. Needs g.Domains.
+// d.CreatedAt is kept if the Domain exists, and d.UpdatedAt is set to the current time.
+func (g *GatewayImpl) PutDomain(ctx context.Context, d *model.Domain) error {
+	if g.Domains == nil {
+		return fmt.Errorf("%w (Domains is not set)", ErrCouldNotPutDomain)
+	}
+	if err := d.Validate(); err != nil {
+		return wrap(ErrCouldNotPutDomain, err)
+	}
+	now := timeNow()
+	d.CreatedAt = now
+	old, err := g.Domains.Get(ctx, d.BBc1DomainID)
+	switch {
+	case err == nil:
+		d.CreatedAt = old.CreatedAt
+	case !errors.Is(err, ErrDomainNotFound):
+		return wrap(ErrCouldNotPutDomain, err)
+	}
+	d.UpdatedAt = now
+	if err := g.Domains.Put(ctx, d); err != nil {
+		return wrap(ErrCouldNotPutDomain, err)
+	}
+	return nil
+}
+
+// DeleteDomain removes the Domain of domID. Needs g.Domains.
+// Returns ErrDomainNotFound if not found.
+func (g *GatewayImpl) DeleteDomain(ctx context.Context, domID []byte) error {
+	if g.Domains == nil {
+		return fmt.Errorf("%w (Domains is not set)", ErrCouldNotDeleteDomain)
+	}
+	if _, err := g.Domains.Get(ctx, domID); err != nil {
+		return wrap(ErrCouldNotDeleteDomain, err)
+	}
+	if err := g.Domains.Delete(ctx, domID); err != nil {
+		return wrap(ErrCouldNotDeleteDomain, err)
+	}
+	return nil
+}
+
+// applyDomain applies the policies of the Domain of domID and the budgets to a new anchor of txID,
+// and returns the fee of the anchor transaction and whether it is a new anchor, i.e. the digest is not stored yet.
+// Domains not in g.Domains (or g.Domains is nil) have no policies and use the default fee.
+// The budgets need g.Ledger, but DailyQuota of the Domain is counted from g.Store without it.
+// reanchor is true if re-anchoring a failed anchor. It is not charged to the domain,
+// so only the fee budget of g.Budget is applied.
+// Whether it is a new anchor is checked only if g.Ledger or a budget of the domain is set, and is false otherwise.
+func (g *GatewayImpl) applyDomain(ctx context.Context, domID, txID []byte, reanchor bool) (model.Amount, bool, error) {
+	var d *model.Domain
+	if g.Domains != nil {
+		var err error
+		d, err = g.Domains.Get(ctx, domID)
+		switch {
+		case errors.Is(err, ErrDomainNotFound):
+			d = nil
+		case err != nil:
+			return 0, false, err
+		}
+	}
+	fee := btc.FeeOf(model.FeeDefault)
+	var b model.Budget
+	if d != nil {
+		if !d.AllowsNetwork(g.BTCNet) {
+			return 0, false, fmt.Errorf("%w (%s)", ErrNetworkNotAllowed, g.BTCNet)
+		}
+		fee = btc.FeeOf(d.FeeTier)
+		b = d.Budget()
+	}
+	if b.IsZero() && g.Ledger == nil {
+		return fee, false, nil
+	}
+
+	// Anchoring a stored digest again is not a new anchor, but the fee is spent.
+	newAnchor := false
+	if !reanchor {
+		_, err := g.Store.Get(ctx, domID, txID)
+		newAnchor = errors.Is(err, util.ErrNotFound)
+		if err != nil && !newAnchor {
+			return 0, false, err
+		}
+	}
+	if g.Ledger == nil {
+		if b.DailyAnchors > 0 && newAnchor {
+			n, err := g.countAnchors(ctx, domID, model.StartOfDay(timeNow()), b.DailyAnchors)
+			if err != nil {
+				return 0, false, err
+			}
+			if n >= b.DailyAnchors {
+				return 0, false, fmt.Errorf("%w (%d anchors today, quota %d)", ErrDailyQuotaExceeded, n, b.DailyAnchors)
+			}
+		}
+		return fee, newAnchor, nil
+	}
+	if b.IsZero() && g.Budget.IsZero() {
+		return fee, newAnchor, nil
+	}
+	if err := g.applyBudget(ctx, nil, g.Budget, fee, newAnchor); err != nil {
+		switch {
+		case errors.Is(err, ErrBudgetExceeded):
+			return 0, false, fmt.Errorf("%w (%v)", ErrGatewayBudgetExceeded, err)
+		case errors.Is(err, ErrDailyQuotaExceeded), errors.Is(err, ErrMonthlyQuotaExceeded):
+			return 0, false, fmt.Errorf("%w (%v)", ErrGatewayQuotaExceeded, err)
+		}
+		return 0, false, err
+	}
+	if reanchor {
+		return fee, newAnchor, nil
+	}
+	if err := g.applyBudget(ctx, domID, b, fee, newAnchor); err != nil {
+		return 0, false, err
+	}
+	return fee, newAnchor, nil
+}
+
+// countAnchors returns the number of AnchorRecords of domID anchored since the given time,
+// but stops counting at max.
+func (g *GatewayImpl) countAnchors(ctx context.Context, domID []byte, since time.Time, max uint) (uint, error) {
+	var n uint
+	opts := &store.ListOptions{Limit: store.MaxListLimit, Since: since}
+	for n < max {
+		ars, cursor, err := g.Store.List(ctx, domID, opts)
+		if err != nil {
+			return 0, err
+		}
+		n += uint(len(ars))
+		if cursor == "" {
+			break
+		}
+		opts.Cursor = cursor
+	}
+	return n, nil
+}
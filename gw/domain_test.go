@@ -0,0 +1,90 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+)
+
+// withDomain sets Domains to g and puts d with a random ID.
+func withDomain(t *testing.T, g *GatewayImpl, d *model.Domain) []byte {
+	t.Helper()
+	if g.Domains == nil {
+		g.Domains = NewBoltDomains(t.TempDir() + "/domains.db")
+	}
+	d.BBc1DomainID = randBytes(t)
+	if err := g.PutDomain(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+	return d.BBc1DomainID
+}
+
+func TestGatewayImpl_applyDomain(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, _ := newTestGateway(t)
+	cases := []struct {
+		name    string
+		domain  *model.Domain
+		wantFee model.Amount
+		wantErr error
+	}{
+		{"no_domain", nil, btc.FeeOf(model.FeeDefault), nil},
+		{"fee_tier", &model.Domain{FeeTier: model.FeeLarge}, btc.FeeOf(model.FeeLarge), nil},
+		{"network_allowed", &model.Domain{AllowedNetworks: []model.BTCNet{model.BTCTestnet3}}, btc.FeeOf(model.FeeDefault), nil},
+		{"network_not_allowed", &model.Domain{AllowedNetworks: []model.BTCNet{model.BTCMainnet}}, 0, ErrNetworkNotAllowed},
+	}
+	for _, c := range cases {
+		domID := randBytes(t)
+		if c.domain != nil {
+			domID = withDomain(t, g, c.domain)
+		}
+		fee, _, err := g.applyDomain(ctx, domID, randBytes(t), false)
+		if !errors.Is(err, c.wantErr) || fee != c.wantFee {
+			t.Errorf("%s: got %v, %v want %v, %v", c.name, fee, err, c.wantFee, c.wantErr)
+		}
+	}
+}
+
+func TestGatewayImpl_applyDomain_DailyQuota(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	domID := withDomain(t, g, &model.Domain{DailyQuota: 2})
+
+	// Counted from the Store as Ledger is not set.
+	var ars []*model.AnchorRecord
+	for i := 0; i < 2; i++ {
+		ar, err := g.Register(ctx, domID, randBytes(t), "", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ars = append(ars, ar)
+	}
+	if _, err := g.Register(ctx, domID, randBytes(t), "", "", nil); !errors.Is(err, ErrDailyQuotaExceeded) {
+		t.Fatalf("got %v want %v", err, ErrDailyQuotaExceeded)
+	}
+	if got, err := g.countAnchors(ctx, domID, model.StartOfDay(timeNow()), 10); err != nil || got != 2 {
+		t.Errorf("countAnchors: got %d, %v want 2", got, err)
+	}
+	if got, err := g.countAnchors(ctx, domID, timeNow().Add(time.Hour), 10); err != nil || got != 0 {
+		t.Errorf("countAnchors: got %d, %v want 0", got, err)
+	}
+
+	// Anchoring a stored digest again is not a new anchor.
+	if _, err := g.RegisterTransaction(ctx, domID, ars[0].Anchor.BBc1TransactionID[:]); err != nil {
+		t.Errorf("anchor again: %v", err)
+	}
+	// Neither is re-anchoring.
+	n.confirm(ars[1].BTCTransactionID, -1)
+	if err := g.Wallet.ReplaceNextUTXO(randBytes(t), "addr1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Errorf("re-anchor: %v", err)
+	}
+}
@@ -6,24 +6,47 @@ and Store (package store) from applications.
 package gw
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"sync"
 	"time"
 
 	"github.com/ebiiim/btcgw/btc"
 	"github.com/ebiiim/btcgw/model"
 	"github.com/ebiiim/btcgw/store"
+	"github.com/ebiiim/btcgw/util"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // Gateway provides features to register and verify BBc-1 transactions.
 type Gateway interface {
 	// RegisterTransaction inserts an anchor into Bitcoin block chain
 	// by sending a transaction, and returns its Bitcoin transaction ID.
+	// Use IsMempoolRejection and IsNodeUnavailable to check the cause of errors.
+	// The policies of the Domain of domID are applied if it exists:
+	// returns ErrNetworkNotAllowed or ErrDailyQuotaExceeded, and uses the fee of Domain.FeeTier.
 	RegisterTransaction(ctx context.Context, domID, txID []byte) (btcTXID []byte, err error)
 
+	// Register anchors the given digest and stores its AnchorRecord like RegisterTransaction and StoreRecord,
+	// but it is idempotent. If the same idemKey is given, the original AnchorRecord is returned
+	// instead of anchoring again. idemKey can be "".
+	// keyID identifies the API key of the caller for spend reports, and can be "".
+	// meta is set to the AnchorRecord and can be nil. Returns model.ErrInvalidMetadata if it is invalid.
+	Register(ctx context.Context, domID, txID []byte, idemKey, keyID string, meta model.Metadata) (*model.AnchorRecord, error)
+
+	// Enqueue accepts a registration like Register, and returns immediately without anchoring.
+	// The registration is done in background and the progress can be checked by GetRegistration.
+	Enqueue(ctx context.Context, domID, txID []byte, idemKey, keyID string, meta model.Metadata) (*Registration, error)
+
+	// GetRegistration returns the progress of the registration of the given digest.
+	GetRegistration(ctx context.Context, domID, txID []byte) (*Registration, error)
+
 	// StoreRecord retrieves a Bitcoin transaction,
 	// and saves its AnchorRecord embedded in OP_RETURN in the datastore.
 	StoreRecord(ctx context.Context, btcTXID []byte) error
@@ -32,15 +55,60 @@ type Gateway interface {
 	// specified by the given information from the datastore.
 	GetRecord(ctx context.Context, domID, txID []byte) (*model.AnchorRecord, error)
 
+	// GetRecordsByBTCTransaction returns AnchorRecords embedded in the Bitcoin transaction btcTXID.
+	// If none is found in the datastore, decodes OP_RETURN of the Bitcoin transaction.
+	GetRecordsByBTCTransaction(ctx context.Context, btcTXID []byte) ([]*model.AnchorRecord, error)
+
+	// ListRecords returns AnchorRecords of the domain domID that match opts, and the cursor to get the next page.
+	// See store.Store.List for the details.
+	ListRecords(ctx context.Context, domID []byte, opts *store.ListOptions) ([]*model.AnchorRecord, string, error)
+
+	// SpendReport totals the cost of anchors per month, BBc-1 domain and API key as opts selects.
+	// See store.Report for the details.
+	SpendReport(ctx context.Context, opts *store.ReportOptions) ([]*store.SpendRow, error)
+
 	// RefreshRecord update AnchorRecord specified by domID and txID.
-	// Get it from datastore, update AnchorRecord.Confirmations by
+	// Get it from datastore, update AnchorRecord.Confirmations and AnchorRecord.Status by
 	// retrieving and checking the Bitcoin transaction, and then put it into datastore.
-	// In addition, changes AnchorRecord.BBc1DomainName or AnchorRecord.Note or both, if the given value is not nil.
-	RefreshRecord(ctx context.Context, domID, txID []byte, pBBc1domName, pNote *string) error
+	// In addition, changes AnchorRecord.BBc1DomainName, AnchorRecord.Note, and AnchorRecord.Metadata if the given value is not nil.
+	// meta replaces the whole Metadata, and an empty one removes it. Returns model.ErrInvalidMetadata if it is invalid.
+	RefreshRecord(ctx context.Context, domID, txID []byte, pBBc1domName, pNote *string, meta model.Metadata) error
+
+	// GetHistory returns the changes of the AnchorRecord specified by domID and txID, oldest first.
+	// Changes are recorded with the actor set by WithActor.
+	GetHistory(ctx context.Context, domID, txID []byte) ([]*HistoryEntry, error)
+
+	// GetDomain returns the Domain (settings of the BBc-1 domain) of domID.
+	GetDomain(ctx context.Context, domID []byte) (*model.Domain, error)
+
+	// ListDomains returns all Domains ordered by BBc-1 domain ID.
+	ListDomains(ctx context.Context) ([]*model.Domain, error)
+
+	// PutDomain adds or replaces the Domain. Returns model.ErrInvalidDomain if it is invalid.
+	// Its policies are applied by RegisterTransaction.
+	PutDomain(ctx context.Context, d *model.Domain) error
+
+	// DeleteDomain removes the Domain of domID, so the BBc-1 domain has no policies.
+	DeleteDomain(ctx context.Context, domID []byte) error
+
+	// Info pings the Bitcoin node and returns information about the Gateway.
+	Info(ctx context.Context) (*Info, error)
 
 	io.Closer
 }
 
+// Info contains information about the Gateway and the Bitcoin node it uses.
+type Info struct {
+	BTCNet model.BTCNet
+
+	// CoreVersion is the detected bitcoin-cli version.
+	CoreVersion btc.CoreVersion
+	// MinCoreVersion is the oldest supported bitcoin-cli version.
+	MinCoreVersion btc.CoreVersion
+	// CoreFeatures contains version dependent features of CoreVersion.
+	CoreFeatures btc.CoreFeatures
+}
+
 var _ Gateway = (*GatewayImpl)(nil)
 
 // Errors
@@ -50,19 +118,71 @@ var (
 	ErrCouldNotPutAnchor     = errors.New("ErrCouldNotPutAnchor")
 	ErrCouldNotStoreRecord   = errors.New("ErrCouldNotStoreRecord")
 	ErrCouldNotGetRecord     = errors.New("ErrCouldNotGetRecord")
+	ErrCouldNotListRecords   = errors.New("ErrCouldNotListRecords")
+	ErrCouldNotReport        = errors.New("ErrCouldNotReport")
+	ErrCouldNotFindRecords   = errors.New("ErrCouldNotFindRecords")
 	ErrCouldNotRefreshRecord = errors.New("ErrCouldNotRefreshRecord")
 	ErrCouldNotCloseStore    = errors.New("ErrCouldNotCloseStore")
+	ErrCouldNotGetInfo       = errors.New("ErrCouldNotGetInfo")
+	ErrCouldNotCheckPending  = errors.New("ErrCouldNotCheckPending")
 )
 
+// IsMempoolRejection returns whether err is caused by the mempool policy of the Bitcoin node,
+// e.g. the fee is too low. Retrying with the same transaction does not help.
+// See btc.IsMempoolRejection for the details.
+func IsMempoolRejection(err error) bool {
+	return btc.IsMempoolRejection(err)
+}
+
+// IsNodeUnavailable returns whether err is caused by the Bitcoin node being unavailable.
+// Retrying later may help.
+func IsNodeUnavailable(err error) bool {
+	return errors.Is(err, btc.ErrPingFailed) || errors.Is(err, btc.ErrFailedToExec) || errors.Is(err, btc.ErrWalletNotLoaded)
+}
+
+// wrap wraps err with one of the errors above without losing err,
+// so both of them can be checked by errors.Is.
+func wrap(gwErr, err error) error {
+	return util.Wrap(gwErr, err)
+}
+
 type GatewayImpl struct {
 	BTCNet model.BTCNet
 	BTC    btc.BTC
 	Wallet btc.Wallet
 	Store  store.Store
 
-	xBTCImpl *btc.BitcoinCLI
+	// Journal records each step of registrations so that half-done ones can be recovered.
+	// Set this to enable Recover. nil disables journaling.
+	Journal Journal
+
+	// Tracker tracks broadcast anchor transactions until they are confirmed.
+	// Set this to enable CheckPendingAnchors. nil disables tracking.
+	Tracker Tracker
+
+	// History records changes of AnchorRecords.
+	// Set this to enable GetHistory. nil disables recording.
+	History History
+
+	// Domains stores the settings of BBc-1 domains, that are applied by RegisterTransaction.
+	// Set this to enable *Domain methods. nil means no domains have policies.
+	Domains Domains
+
+	// Ledger records the fee spent by anchor transactions, and is used to enforce Budget
+	// and the budgets of Domains. nil disables them except DailyQuota of Domains.
+	Ledger Ledger
+	// Budget caps anchoring of all domains. Needs Ledger. Zero means unlimited.
+	// Re-anchoring by CheckPendingAnchors counts toward the fee only.
+	Budget model.Budget
+
+	xBTCImpl bitcoinNode
 
 	mu sync.Mutex
+	sf singleflight.Group
+
+	// For Enqueue and workers.
+	qmu    sync.Mutex
+	queued chan struct{}
 }
 
 // NewGatewayImpl initializes a GatewayImpl.
@@ -86,40 +206,200 @@ func NewGatewayImpl(bn model.BTCNet, b btc.BTC, w btc.Wallet, s store.Store) *Ga
 		Wallet:   w,
 		Store:    s,
 		xBTCImpl: bImpl,
+		queued:   make(chan struct{}, 1),
 	}
 	return g
 }
 
+// bitcoinNode is the part of *btc.BitcoinCLI used by GatewayImpl in addition to btc.BTC.
+type bitcoinNode interface {
+	XSetUTXO(txid []byte, btcAddr string)
+	XGetUTXO() (txid []byte, btcAddr string)
+	CreateAnchorTransactionWithFee(ctx context.Context, a *model.Anchor, fee model.Amount) ([]byte, error)
+	BroadcastTransaction(ctx context.Context, signedTx []byte) ([]byte, error)
+	GetTransaction(ctx context.Context, txid []byte) (*bytes.Buffer, error)
+	ParseWalletTransactionDepth(txJSON *bytes.Buffer) (int, error)
+	GetMempoolEntry(ctx context.Context, txid []byte) (*bytes.Buffer, error)
+	DecodeRawTransaction(ctx context.Context, txdata []byte) (*bytes.Buffer, error)
+	ParseRawTransactionID(rawTxJSON *bytes.Buffer) ([]byte, error)
+	Ping(ctx context.Context) error
+	CoreVersion() (btc.CoreVersion, bool)
+}
+
+var _ bitcoinNode = (*btc.BitcoinCLI)(nil)
+
 var timeNow = time.Now
 
 func (g *GatewayImpl) RegisterTransaction(ctx context.Context, domID, txID []byte) (btcTXID []byte, err error) {
-	a := model.NewAnchor(g.BTCNet, timeNow(), domID, txID)
-
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	return g.registerTransaction(ctx, domID, txID, "", "", nil, nil)
+}
+
+// registerTransaction is RegisterTransaction without locking g.mu.
+// idemKey, keyID and meta are saved in the JournalEntry.
+// failedTxid is the failed anchor transaction of the digest when re-anchoring, otherwise nil.
+func (g *GatewayImpl) registerTransaction(ctx context.Context, domID, txID []byte, idemKey, keyID string, meta model.Metadata, failedTxid []byte) ([]byte, error) {
+	// A half-done registration of the same digest is finished or rolled back first if Journal is set.
+	var e, orig *JournalEntry
+	if g.Journal != nil {
+		old, err := g.Journal.Get(ctx, domID, txID)
+		switch {
+		case err == nil && (old.expired() || old.State == JournalFailed):
+			g.rollbackJournal(ctx, old)
+		case err == nil && failedTxid != nil && bytes.Equal(old.BTCTransactionID, failedTxid):
+			// Re-anchoring, the entry is of the failed transaction. Start it again keeping the request.
+			o := *old
+			orig, e = &o, old
+			e.FromTransactionID, e.FromAddr, e.RawTransaction, e.Fee, e.BTCTransactionID = nil, "", nil, 0, nil
+			e.Reanchor = true
+			e.FailReason = ""
+		case err == nil && old.State == JournalStored && (idemKey == "" || idemKey != old.IdempotencyKey):
+			// Completed. The stored transaction is only returned to retried requests with the same key.
+			g.rollbackJournal(ctx, old)
+		case err == nil && old.State == JournalQueued:
+			// Queued by Enqueue, start it.
+			e = old
+			e.FailReason = ""
+		case err == nil:
+			txid, err := g.recoverEntry(ctx, old)
+			if err != nil {
+				return nil, wrap(ErrCouldNotPutAnchor, err)
+			}
+			if txid != nil {
+				return txid, nil
+			}
+		case !errors.Is(err, ErrJournalEntryNotFound):
+			return nil, wrap(ErrCouldNotPutAnchor, err)
+		}
+	}
+
+	// rollback removes e, or restores the entry of the failed transaction if re-anchoring,
+	// so that retried requests with the same key still get the AnchorRecord.
+	rollback := func() {
+		if orig == nil {
+			g.rollbackJournal(ctx, e)
+			return
+		}
+		if err := g.Journal.Put(ctx, orig); err != nil {
+			log.Printf("RegisterTransaction: %v (cid=%s)", err, journalCID(domID, txID))
+		}
+	}
+
+	// Apply the policies of the domain. Half-done registrations finished above are not limited.
+	reanchor := failedTxid != nil
+	fee, newAnchor, err := g.applyDomain(ctx, domID, txID, reanchor)
+	if err != nil {
+		return nil, wrap(ErrCouldNotPutAnchor, err)
+	}
+
+	// Write the intent to Journal if it is set.
+	if g.Journal != nil {
+		if e == nil {
+			e = &JournalEntry{
+				BBc1DomainID:      domID,
+				BBc1TransactionID: txID,
+				IdempotencyKey:    idemKey,
+				APIKeyID:          keyID,
+				Metadata:          meta,
+				Reanchor:          reanchor,
+				CreatedAt:         timeNow(),
+			}
+		}
+		e.NewAnchor = newAnchor
+		if err := g.writeJournal(ctx, e, JournalIntent); err != nil {
+			return nil, wrap(ErrCouldNotPutAnchor, err)
+		}
+	}
+
+	// A signed transaction of another digest may have spent the next UTXO.
+	if e != nil {
+		if err := g.settleSigned(ctx, e); err != nil {
+			rollback()
+			return nil, wrap(ErrCouldNotPutAnchor, err)
+		}
+	}
+
+	a := model.NewAnchor(g.BTCNet, timeNow(), domID, txID)
 
 	// Set UTXO if Wallet is set.
 	if g.Wallet != nil {
 		tx, addr, err := g.Wallet.PeekNextUTXO()
 		if err != nil {
-			return nil, fmt.Errorf("%w (%v)", ErrCouldNotPutAnchor, err)
+			rollback()
+			return nil, wrap(ErrCouldNotPutAnchor, err)
 		}
 		g.xBTCImpl.XSetUTXO(tx, addr)
 	}
-	txid, err := g.BTC.PutAnchor(ctx, a)
+	fromTxid, addr := g.xBTCImpl.XGetUTXO()
+	signedTx, err := g.xBTCImpl.CreateAnchorTransactionWithFee(ctx, a, fee)
+	if err != nil {
+		rollback()
+		return nil, wrap(ErrCouldNotPutAnchor, err)
+	}
+	if e != nil {
+		e.FromTransactionID = fromTxid
+		e.FromAddr = addr
+		e.RawTransaction = signedTx
+		e.Fee = fee
+		if err := g.writeJournal(ctx, e, JournalSigned); err != nil {
+			rollback()
+			return nil, wrap(ErrCouldNotPutAnchor, err)
+		}
+	}
+	txid, err := g.xBTCImpl.BroadcastTransaction(ctx, signedTx)
 	if err != nil {
-		return nil, fmt.Errorf("%w (%v)", ErrCouldNotPutAnchor, err)
+		// Rejected transactions are never sent.
+		// Otherwise it may have been sent, so keep the journal to check it later.
+		if btc.IsMempoolRejection(err) {
+			rollback()
+		}
+		return nil, wrap(ErrCouldNotPutAnchor, err)
+	}
+	if e != nil {
+		// The transaction has been sent so failing to write the journal is not an error.
+		e.BTCTransactionID = txid
+		if err := g.writeJournal(ctx, e, JournalBroadcast); err != nil {
+			log.Printf("RegisterTransaction: %v (btctx=%s)", err, hex.EncodeToString(txid))
+		}
+	}
+	if err := g.finishBroadcast(ctx, domID, txID, txid, fromTxid, addr, signedTx, fee, reanchor, newAnchor); err != nil {
+		return nil, wrap(ErrCouldNotPutAnchor, err)
 	}
-	// Update Wallet if it is set.
+	return txid, nil
+}
+
+// finishBroadcast updates Wallet, Tracker and Ledger after the anchor transaction txid paying fee has been sent.
+// reanchor is true if txid re-anchors a failed anchor, and newAnchor is true if the digest is not stored yet.
+// It can be called again for the same transaction, e.g. on recovery.
+func (g *GatewayImpl) finishBroadcast(ctx context.Context, domID, txID, txid, fromTxid []byte, addr string, signedTx []byte, fee model.Amount, reanchor, newAnchor bool) error {
+	g.xBTCImpl.XSetUTXO(txid, addr)
+	// Update Wallet if it is set and not updated yet.
 	if g.Wallet != nil {
-		if _, _, err := g.Wallet.NextUTXO(); err != nil {
-			return nil, fmt.Errorf("%w (%v)", ErrCouldNotPutAnchor, err)
+		next, _, err := g.Wallet.PeekNextUTXO()
+		if err == nil && bytes.Equal(next, fromTxid) {
+			if err := g.Wallet.ReplaceNextUTXO(txid, addr); err != nil {
+				return err
+			}
+		}
+	}
+	// Track the transaction if Tracker is set.
+	// The transaction has been sent so failing to track it is not an error.
+	if g.Tracker != nil {
+		p := &PendingAnchor{
+			BTCTransactionID:  txid,
+			BBc1DomainID:      domID,
+			BBc1TransactionID: txID,
+			RawTransaction:    signedTx,
+			FromTransactionID: fromTxid,
+			BroadcastTime:     timeNow(),
 		}
-		if err := g.Wallet.AddUTXO(g.xBTCImpl.XGetUTXO()); err != nil {
-			return nil, fmt.Errorf("%w (%v)", ErrCouldNotPutAnchor, err)
+		if err := g.Tracker.Put(ctx, p); err != nil {
+			log.Printf("RegisterTransaction: %v (btctx=%s)", err, hex.EncodeToString(txid))
 		}
 	}
-	return txid, err
+	g.recordSpend(ctx, domID, txID, txid, fee, reanchor, newAnchor)
+	return nil
 }
 
 func (g *GatewayImpl) StoreRecord(ctx context.Context, btcTXID []byte) error {
@@ -127,55 +407,149 @@ func (g *GatewayImpl) StoreRecord(ctx context.Context, btcTXID []byte) error {
 	ar, err := g.BTC.GetAnchor(ctx, btcTXID)
 	g.mu.Unlock()
 	if err != nil {
-		return fmt.Errorf("%w (%v)", ErrCouldNotStoreRecord, err)
+		return wrap(ErrCouldNotStoreRecord, err)
 	}
+	ar.LastChecked = timeNow()
+	oldAR, _ := g.Store.Get(ctx, ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:])
 	if err := g.Store.Put(ctx, ar); err != nil {
-		return fmt.Errorf("%w (%v)", ErrCouldNotStoreRecord, err)
+		return wrap(ErrCouldNotStoreRecord, err)
+	}
+	if oldAR != nil {
+		g.appendHistory(ctx, oldAR, ar)
 	}
+	// The registration has been completed.
+	g.markStored(ctx, ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:], btcTXID)
 	return nil
 }
 
 func (g *GatewayImpl) GetRecord(ctx context.Context, domID, txID []byte) (*model.AnchorRecord, error) {
 	ar, err := g.Store.Get(ctx, domID, txID)
 	if err != nil {
-		return nil, fmt.Errorf("%w (%v)", ErrCouldNotGetRecord, err)
+		return nil, wrap(ErrCouldNotGetRecord, err)
 	}
 	return ar, nil
 }
 
-func (g *GatewayImpl) RefreshRecord(ctx context.Context, domID, txID []byte, pBBc1domName, pNote *string) error {
+func (g *GatewayImpl) GetRecordsByBTCTransaction(ctx context.Context, btcTXID []byte) ([]*model.AnchorRecord, error) {
+	ars, err := g.Store.GetByBTCTransaction(ctx, btcTXID)
+	if err != nil {
+		return nil, wrap(ErrCouldNotFindRecords, err)
+	}
+	if len(ars) != 0 {
+		return ars, nil
+	}
+	// Not in the datastore or stored by older versions, so check the Bitcoin transaction.
+	g.mu.Lock()
+	ar, err := g.BTC.GetAnchor(ctx, btcTXID)
+	g.mu.Unlock()
+	if err != nil {
+		return nil, wrap(ErrCouldNotFindRecords, err)
+	}
+	// Prefer the stored one as it has optional data.
+	if stored, err := g.Store.Get(ctx, ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]); err == nil && bytes.Equal(stored.BTCTransactionID, btcTXID) {
+		ar = stored
+	}
+	return []*model.AnchorRecord{ar}, nil
+}
+
+func (g *GatewayImpl) ListRecords(ctx context.Context, domID []byte, opts *store.ListOptions) ([]*model.AnchorRecord, string, error) {
+	ars, cursor, err := g.Store.List(ctx, domID, opts)
+	if err != nil {
+		return nil, "", wrap(ErrCouldNotListRecords, err)
+	}
+	return ars, cursor, nil
+}
+
+func (g *GatewayImpl) SpendReport(ctx context.Context, opts *store.ReportOptions) ([]*store.SpendRow, error) {
+	rows, err := store.Report(ctx, g.Store, opts)
+	if err != nil {
+		return nil, wrap(ErrCouldNotReport, err)
+	}
+	return rows, nil
+}
+
+func (g *GatewayImpl) RefreshRecord(ctx context.Context, domID, txID []byte, pBBc1domName, pNote *string, meta model.Metadata) error {
+	if err := meta.Validate(); err != nil {
+		return wrap(ErrCouldNotRefreshRecord, err)
+	}
 	oldAR, err := g.GetRecord(ctx, domID, txID)
 	if err != nil {
-		return fmt.Errorf("%w (%v)", ErrCouldNotRefreshRecord, err)
+		return wrap(ErrCouldNotRefreshRecord, err)
 	}
 	g.mu.Lock()
 	newAR, err := g.BTC.GetAnchor(ctx, oldAR.BTCTransactionID)
 	g.mu.Unlock()
 	if err != nil {
-		return fmt.Errorf("%w (%v)", ErrCouldNotRefreshRecord, err)
-	}
-	if err := g.Store.UpdateConfirmations(ctx, domID, txID, newAR.Confirmations); err != nil {
-		return fmt.Errorf("%w (%v)", ErrCouldNotRefreshRecord, err)
+		return wrap(ErrCouldNotRefreshRecord, err)
 	}
-	if pBBc1domName != nil {
-		if err := g.Store.UpdateBBc1DomainName(ctx, domID, txID, *pBBc1domName); err != nil {
-			return fmt.Errorf("%w (%v)", ErrCouldNotRefreshRecord, err)
-		}
+	ar := *oldAR
+	ar.Refresh(newAR.Confirmations, timeNow())
+	u := &store.RecordUpdate{
+		Confirmations:  &ar.Confirmations,
+		Status:         &ar.Status,
+		StatusReason:   ar.StatusReason,
+		LastChecked:    ar.LastChecked,
+		BBc1DomainName: pBBc1domName,
+		Note:           pNote,
+		Metadata:       meta,
 	}
-	if pNote != nil {
-		if err := g.Store.UpdateNote(ctx, domID, txID, *pNote); err != nil {
-			return fmt.Errorf("%w (%v)", ErrCouldNotRefreshRecord, err)
-		}
+	if err := g.updateRecord(ctx, oldAR, u); err != nil {
+		return wrap(ErrCouldNotRefreshRecord, err)
 	}
 	return nil
 }
 
-// Close closes g.Store.
+func (g *GatewayImpl) Info(ctx context.Context) (*Info, error) {
+	g.mu.Lock()
+	err := g.xBTCImpl.Ping(ctx)
+	g.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotGetInfo, err)
+	}
+	v, ok := g.xBTCImpl.CoreVersion()
+	if !ok {
+		return nil, fmt.Errorf("%w (version not detected)", ErrCouldNotGetInfo)
+	}
+	info := &Info{
+		BTCNet:         g.BTCNet,
+		CoreVersion:    v,
+		MinCoreVersion: btc.MinSupportedCoreVersion,
+		CoreFeatures:   v.Features(),
+	}
+	return info, nil
+}
+
+// Close closes g.Store, and g.Tracker, g.Journal, g.History and g.Domains if set.
 // No need to close *btc.BitcoinCLI
 func (g *GatewayImpl) Close() error {
 	err := g.Store.Close()
 	if err != nil {
 		return fmt.Errorf("%w (%v)", ErrCouldNotCloseStore, err)
 	}
+	if g.Tracker != nil {
+		if err := g.Tracker.Close(); err != nil {
+			return fmt.Errorf("%w (%v)", ErrCouldNotCloseStore, err)
+		}
+	}
+	if g.Journal != nil {
+		if err := g.Journal.Close(); err != nil {
+			return fmt.Errorf("%w (%v)", ErrCouldNotCloseStore, err)
+		}
+	}
+	if g.History != nil {
+		if err := g.History.Close(); err != nil {
+			return fmt.Errorf("%w (%v)", ErrCouldNotCloseStore, err)
+		}
+	}
+	if g.Domains != nil {
+		if err := g.Domains.Close(); err != nil {
+			return fmt.Errorf("%w (%v)", ErrCouldNotCloseStore, err)
+		}
+	}
+	if g.Ledger != nil {
+		if err := g.Ledger.Close(); err != nil {
+			return fmt.Errorf("%w (%v)", ErrCouldNotCloseStore, err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,389 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+)
+
+// fakeTx is a transaction known to fakeNode.
+type fakeTx struct {
+	raw    []byte
+	from   []byte
+	anchor *model.Anchor
+	fee    model.Amount
+	// sent is true if the transaction has been sent. The wallet knows it unless forgotten.
+	sent      bool
+	forgotten bool
+	// depth is the confirmations reported by the wallet, negative if conflicted.
+	depth     int
+	inMempool bool
+	// broadcastErr is returned by BroadcastTransaction instead of sending if set.
+	broadcastErr error
+}
+
+// fakeNode is an in-memory Bitcoin node that implements btc.BTC and bitcoinNode.
+// Transaction IDs are SHA-256 of raw transactions.
+type fakeNode struct {
+	mu   sync.Mutex
+	txs  map[string]*fakeTx
+	utxo []byte
+	addr string
+	n    int
+
+	// createErr is returned by CreateAnchorTransactionWithFee if set.
+	createErr error
+	// broadcastErr is returned by BroadcastTransaction instead of sending if set.
+	broadcastErr error
+	// broadcasts is the number of transactions sent by BroadcastTransaction.
+	broadcasts int
+}
+
+var _ btc.BTC = (*fakeNode)(nil)
+var _ bitcoinNode = (*fakeNode)(nil)
+
+func newFakeNode() *fakeNode {
+	return &fakeNode{txs: make(map[string]*fakeTx)}
+}
+
+func fakeTxID(raw []byte) []byte {
+	h := sha256.Sum256(raw)
+	return h[:]
+}
+
+func (n *fakeNode) tx(txid []byte) *fakeTx {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.txs[hex.EncodeToString(txid)]
+}
+
+// confirm sets the confirmations of txid.
+func (n *fakeNode) confirm(txid []byte, depth int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	tx := n.txs[hex.EncodeToString(txid)]
+	tx.depth = depth
+	tx.inMempool = depth == 0
+}
+
+// drop evicts txid from the mempool. The wallet still knows it.
+func (n *fakeNode) drop(txid []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.txs[hex.EncodeToString(txid)].inMempool = false
+}
+
+// forget makes the wallet not know txid, e.g. the wallet is not synced yet.
+func (n *fakeNode) forget(txid []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.txs[hex.EncodeToString(txid)].forgotten = true
+}
+
+// failBroadcast makes BroadcastTransaction of txid return err. nil clears it.
+func (n *fakeNode) failBroadcast(txid []byte, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.txs[hex.EncodeToString(txid)].broadcastErr = err
+}
+
+func (n *fakeNode) setBroadcastErr(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.broadcastErr = err
+}
+
+func (n *fakeNode) setCreateErr(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.createErr = err
+}
+
+func (n *fakeNode) XSetUTXO(txid []byte, btcAddr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.utxo, n.addr = txid, btcAddr
+}
+
+func (n *fakeNode) XGetUTXO() ([]byte, string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.utxo, n.addr
+}
+
+func (n *fakeNode) CreateAnchorTransactionWithFee(ctx context.Context, a *model.Anchor, fee model.Amount) ([]byte, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.createErr != nil {
+		return nil, n.createErr
+	}
+	n.n++
+	raw := []byte(fmt.Sprintf("%x:%x:%x:%d", n.utxo, a.BBc1DomainID, a.BBc1TransactionID, n.n))
+	n.txs[hex.EncodeToString(fakeTxID(raw))] = &fakeTx{raw: raw, from: n.utxo, anchor: a, fee: fee}
+	return raw, nil
+}
+
+func (n *fakeNode) BroadcastTransaction(ctx context.Context, signedTx []byte) ([]byte, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	// Like bitcoin-cli, which is killed when ctx is done.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if n.broadcastErr != nil {
+		return nil, n.broadcastErr
+	}
+	txid := fakeTxID(signedTx)
+	tx, ok := n.txs[hex.EncodeToString(txid)]
+	switch {
+	case !ok:
+		return nil, btc.ErrTxDecodeFailed
+	case tx.broadcastErr != nil:
+		return nil, tx.broadcastErr
+	case tx.inMempool || tx.depth > 0:
+		return nil, fmt.Errorf("%w (txn-already-in-mempool)", btc.ErrTxAlreadyExists)
+	case tx.depth < 0:
+		return nil, btc.ErrTxAlreadySpent
+	}
+	// The parent must be in the mempool or confirmed.
+	if p, ok := n.txs[hex.EncodeToString(tx.from)]; ok && !p.inMempool && p.depth <= 0 {
+		return nil, fmt.Errorf("%w (missing-inputs)", btc.ErrTxMissingInputs)
+	}
+	tx.sent, tx.inMempool = true, true
+	n.broadcasts++
+	return txid, nil
+}
+
+func (n *fakeNode) GetTransaction(ctx context.Context, txid []byte) (*bytes.Buffer, error) {
+	tx := n.tx(txid)
+	if tx == nil || !tx.sent || tx.forgotten {
+		return nil, btc.ErrInvalidTransactionID
+	}
+	return bytes.NewBufferString(fmt.Sprintf(`{"confirmations": %d}`, tx.depth)), nil
+}
+
+func (n *fakeNode) ParseWalletTransactionDepth(txJSON *bytes.Buffer) (int, error) {
+	return new(btc.BitcoinCLI).ParseWalletTransactionDepth(txJSON)
+}
+
+func (n *fakeNode) GetMempoolEntry(ctx context.Context, txid []byte) (*bytes.Buffer, error) {
+	if tx := n.tx(txid); tx == nil || !tx.inMempool {
+		return nil, btc.ErrTxNotInMempool
+	}
+	return bytes.NewBufferString("{}"), nil
+}
+
+func (n *fakeNode) DecodeRawTransaction(ctx context.Context, txdata []byte) (*bytes.Buffer, error) {
+	return bytes.NewBufferString(hex.EncodeToString(txdata)), nil
+}
+
+func (n *fakeNode) ParseRawTransactionID(rawTxJSON *bytes.Buffer) ([]byte, error) {
+	raw, err := hex.DecodeString(rawTxJSON.String())
+	if err != nil {
+		return nil, err
+	}
+	return fakeTxID(raw), nil
+}
+
+func (n *fakeNode) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (n *fakeNode) CoreVersion() (btc.CoreVersion, bool) {
+	return btc.MinSupportedCoreVersion, true
+}
+
+func (n *fakeNode) PutAnchor(ctx context.Context, a *model.Anchor) ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+func (n *fakeNode) GetAnchor(ctx context.Context, btctx []byte) (*model.AnchorRecord, error) {
+	tx := n.tx(btctx)
+	if tx == nil || !tx.sent {
+		return nil, btc.ErrInvalidTransactionID
+	}
+	var conf uint
+	if tx.depth > 0 {
+		conf = uint(tx.depth)
+	}
+	ar := model.NewAnchorRecord(tx.anchor, btctx, tx.anchor.Timestamp, conf, "", "")
+	ar.Fee = tx.fee
+	return ar, nil
+}
+
+func (n *fakeNode) Close() error {
+	return nil
+}
+
+// newTestGateway returns a GatewayImpl with a fakeNode, a Wallet with a UTXO,
+// and Store, Journal and Tracker in a temporary directory.
+func newTestGateway(t *testing.T) (*GatewayImpl, *fakeNode) {
+	t.Helper()
+	dir := t.TempDir()
+	n := newFakeNode()
+	w := btc.MustNewBoltWallet(dir+"/wallet.db", "addr1")
+	if err := w.AddUTXO(randBytes(t), "addr1"); err != nil {
+		t.Fatal(err)
+	}
+	g := &GatewayImpl{
+		BTCNet:   model.BTCTestnet3,
+		BTC:      n,
+		Wallet:   w,
+		Store:    store.NewBolt(dir + "/anchors.db"),
+		Journal:  NewBoltJournal(dir + "/journal.db"),
+		Tracker:  NewBoltTracker(dir + "/tracker.db"),
+		xBTCImpl: n,
+		queued:   make(chan struct{}, 1),
+	}
+	t.Cleanup(func() {
+		if err := g.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return g, n
+}
+
+func randBytes(t *testing.T) []byte {
+	t.Helper()
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// mustRegister registers a random digest with idemKey and returns its AnchorRecord.
+func mustRegister(t *testing.T, g *GatewayImpl, idemKey string) *model.AnchorRecord {
+	t.Helper()
+	ar, err := g.Register(context.Background(), randBytes(t), randBytes(t), idemKey, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ar
+}
+
+func mustGetJournal(t *testing.T, g *GatewayImpl, domID, txID []byte) *JournalEntry {
+	t.Helper()
+	e, err := g.Journal.Get(context.Background(), domID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestGatewayImpl_RegisterTransaction_Stored(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, _ := newTestGateway(t)
+	ar := mustRegister(t, g, "key1")
+	domID, txID := ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]
+
+	// Not a retry, so the stored digest is anchored again.
+	txid, err := g.RegisterTransaction(ctx, domID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(txid, ar.BTCTransactionID) {
+		t.Errorf("got the stored transaction %x", txid)
+	}
+}
+
+func TestGatewayImpl_reanchor_Stored(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	ar := mustRegister(t, g, "key1")
+	domID, txID := ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]
+	if e := mustGetJournal(t, g, domID, txID); e.State != JournalStored {
+		t.Fatalf("state: got %v want %v", e.State, JournalStored)
+	}
+
+	// Conflicted while the JournalEntry is kept for JournalRetention.
+	// The change does not exist, so a new UTXO is added.
+	n.confirm(ar.BTCTransactionID, -1)
+	if err := g.Wallet.ReplaceNextUTXO(randBytes(t), "addr1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := g.GetRecord(ctx, domID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got.BTCTransactionID, ar.BTCTransactionID) {
+		t.Fatalf("the record still has the conflicted transaction %x", got.BTCTransactionID)
+	}
+	if tx := n.tx(got.BTCTransactionID); tx == nil || !tx.inMempool {
+		t.Errorf("the new transaction %x is not sent", got.BTCTransactionID)
+	}
+	ps, err := g.Tracker.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 1 || !bytes.Equal(ps[0].BTCTransactionID, got.BTCTransactionID) || ps[0].Failed {
+		t.Errorf("tracker: got %d anchors, want only the new one", len(ps))
+	}
+	e := mustGetJournal(t, g, domID, txID)
+	if e.State != JournalStored || !bytes.Equal(e.BTCTransactionID, got.BTCTransactionID) || e.IdempotencyKey != "key1" {
+		t.Errorf("journal: got state=%v btctx=%x key=%q", e.State, e.BTCTransactionID, e.IdempotencyKey)
+	}
+
+	// The retried request gets the new transaction.
+	retried, err := g.Register(ctx, domID, txID, "key1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(retried.BTCTransactionID, got.BTCTransactionID) {
+		t.Errorf("retry: got %x want %x", retried.BTCTransactionID, got.BTCTransactionID)
+	}
+
+	// Nothing to do on the next check.
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := g.GetRecord(ctx, domID, txID); !bytes.Equal(again.BTCTransactionID, got.BTCTransactionID) {
+		t.Errorf("anchored again: got %x want %x", again.BTCTransactionID, got.BTCTransactionID)
+	}
+}
+
+func TestGatewayImpl_reanchor_Rejected(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	ar := mustRegister(t, g, "key1")
+	domID, txID := ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]
+
+	// Re-anchoring is rejected as the next UTXO is the change of the conflicted transaction.
+	n.confirm(ar.BTCTransactionID, -1)
+	if err := g.CheckPendingAnchors(ctx); !errors.Is(err, ErrCouldNotCheckPending) {
+		t.Fatalf("got %v want %v", err, ErrCouldNotCheckPending)
+	}
+
+	// The JournalEntry is restored, so the retried request still gets the AnchorRecord.
+	e := mustGetJournal(t, g, domID, txID)
+	if e.State != JournalStored || !bytes.Equal(e.BTCTransactionID, ar.BTCTransactionID) || e.IdempotencyKey != "key1" {
+		t.Errorf("journal: got state=%v btctx=%x key=%q", e.State, e.BTCTransactionID, e.IdempotencyKey)
+	}
+	retried, err := g.Register(ctx, domID, txID, "key1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != model.AnchorFailed {
+		t.Errorf("status: got %v want %v", retried.Status, model.AnchorFailed)
+	}
+}
@@ -0,0 +1,338 @@
+package gw
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+	"github.com/ebiiim/btcgw/util"
+
+	"gocloud.dev/docstore"
+)
+
+// HistoryEntry is a change of a field of an AnchorRecord.
+// Values are in the same format as the API, e.g. IDs in hexadecimal string and times in RFC 3339.
+type HistoryEntry struct {
+	BBc1DomainID      []byte
+	BBc1TransactionID []byte
+	Time              time.Time
+	// Actor is who made the change, see WithActor.
+	Actor string
+	Field string
+	Old   string
+	New   string
+}
+
+// Fields of AnchorRecords in HistoryEntries.
+const (
+	FieldBTCTransaction = "btctx"
+	FieldConfirmations  = "confirmations"
+	FieldStatus         = "status"
+	FieldStatusReason   = "status_reason"
+	FieldLastChecked    = "last_checked"
+	FieldBBc1DomainName = "bbc1name"
+	FieldNote           = "note"
+	FieldMetadata       = "metadata"
+)
+
+// History stores HistoryEntries. It is append-only.
+type History interface {
+	// Append adds HistoryEntries of a change.
+	Append(ctx context.Context, es []*HistoryEntry) error
+	// List returns HistoryEntries of the AnchorRecord specified by domID and txID in the order they were appended.
+	// Returns an empty slice if not found.
+	List(ctx context.Context, domID, txID []byte) ([]*HistoryEntry, error)
+
+	io.Closer
+}
+
+var _ History = (*DocstoreHistory)(nil)
+
+// Errors
+var (
+	ErrCouldNotOpenHistory  = errors.New("ErrCouldNotOpenHistory")
+	ErrCouldNotCloseHistory = errors.New("ErrCouldNotCloseHistory")
+	ErrCouldNotWriteHistory = errors.New("ErrCouldNotWriteHistory")
+	ErrCouldNotReadHistory  = errors.New("ErrCouldNotReadHistory")
+	ErrCouldNotGetHistory   = errors.New("ErrCouldNotGetHistory")
+)
+
+// Actors
+const (
+	// ActorSystem is the default actor, e.g. workers and the tracker.
+	ActorSystem = "system"
+)
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that records actor as who made changes with it.
+// ActorSystem is used if not set.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorOf(ctx context.Context) string {
+	if a, ok := ctx.Value(actorKey{}).(string); ok && a != "" {
+		return a
+	}
+	return ActorSystem
+}
+
+// historySeq is the sequence number of the last change appended by this process.
+var historySeq uint32
+
+// historyID returns the ID of the i-th HistoryEntry of the change seq, that is ordered by the time.
+// seq keeps changes at the same time from overwriting each other.
+func historyID(e *HistoryEntry, seq uint32, i int) string {
+	return fmt.Sprintf("%s/%020d/%010d/%02d", journalCID(e.BBc1DomainID, e.BBc1TransactionID), e.Time.UnixNano(), seq, i)
+}
+
+type historyDoc struct {
+	ID           string    `docstore:"id"`
+	CID          string    `docstore:"cid"`
+	BBc1DomainID []byte    `docstore:"bbc1domid"`
+	BBc1TxID     []byte    `docstore:"bbc1txid"`
+	Time         time.Time `docstore:"time"`
+	Actor        string    `docstore:"actor"`
+	Field        string    `docstore:"field"`
+	Old          string    `docstore:"old"`
+	New          string    `docstore:"new"`
+}
+
+func newHistoryDoc(e *HistoryEntry, seq uint32, i int) *historyDoc {
+	return &historyDoc{
+		ID:           historyID(e, seq, i),
+		CID:          journalCID(e.BBc1DomainID, e.BBc1TransactionID),
+		BBc1DomainID: e.BBc1DomainID,
+		BBc1TxID:     e.BBc1TransactionID,
+		Time:         e.Time,
+		Actor:        e.Actor,
+		Field:        e.Field,
+		Old:          e.Old,
+		New:          e.New,
+	}
+}
+
+func (d *historyDoc) historyEntry() *HistoryEntry {
+	return &HistoryEntry{
+		BBc1DomainID:      d.BBc1DomainID,
+		BBc1TransactionID: d.BBc1TxID,
+		Time:              d.Time,
+		Actor:             d.Actor,
+		Field:             d.Field,
+		Old:               d.Old,
+		New:               d.New,
+	}
+}
+
+// DocstoreHistory is a History that uses gocloud.dev/docstore.
+// The collection must use "id" as the ID field.
+type DocstoreHistory struct {
+	conn string
+	coll *docstore.Collection
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewDocstoreHistory(conn string) *DocstoreHistory {
+	h := &DocstoreHistory{
+		conn: conn,
+		coll: nil,
+	}
+	return h
+}
+
+func (h *DocstoreHistory) open() error {
+	coll, err := docstore.OpenCollection(context.Background(), h.conn)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenHistory, err)
+	}
+	h.coll = coll
+	return nil
+}
+
+// Open opens h.coll once.
+func (h *DocstoreHistory) Open() error {
+	var oErr error
+	h.once.Do(func() { oErr = h.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the DocstoreHistory.
+func (h *DocstoreHistory) Close() error {
+	if err := h.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseHistory, err)
+	}
+	if err := h.coll.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseHistory, err)
+	}
+	return nil
+}
+
+// Append creates HistoryEntries in es, so existing ones are never overwritten.
+func (h *DocstoreHistory) Append(ctx context.Context, es []*HistoryEntry) error {
+	if err := h.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteHistory, err)
+	}
+	seq := atomic.AddUint32(&historySeq, 1)
+	al := h.coll.Actions()
+	for i, e := range es {
+		al.Create(newHistoryDoc(e, seq, i))
+	}
+	if err := al.Do(ctx); err != nil {
+		return wrap(ErrCouldNotWriteHistory, util.DocstoreError(err))
+	}
+	return nil
+}
+
+func (h *DocstoreHistory) List(ctx context.Context, domID, txID []byte) ([]*HistoryEntry, error) {
+	if err := h.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadHistory, err)
+	}
+	iter := h.coll.Query().Where("cid", "=", journalCID(domID, txID)).Get(ctx)
+	defer iter.Stop()
+	var ds []*historyDoc
+	for {
+		var d historyDoc
+		err := iter.Next(ctx, &d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, wrap(ErrCouldNotReadHistory, util.DocstoreError(err))
+		}
+		ds = append(ds, &d)
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].ID < ds[j].ID })
+	es := make([]*HistoryEntry, len(ds))
+	for i, d := range ds {
+		es[i] = d.historyEntry()
+	}
+	return es, nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// formatMetadata returns m in JSON with sorted keys, or "" if empty.
+func formatMetadata(m model.Metadata) string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(m) // never fails
+	return string(b)
+}
+
+// recordChanges returns HistoryEntries of the fields changed from old to ar.
+func recordChanges(ctx context.Context, old, ar *model.AnchorRecord) []*HistoryEntry {
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{FieldBTCTransaction, hex.EncodeToString(old.BTCTransactionID), hex.EncodeToString(ar.BTCTransactionID)},
+		{FieldConfirmations, strconv.FormatUint(uint64(old.Confirmations), 10), strconv.FormatUint(uint64(ar.Confirmations), 10)},
+		{FieldStatus, string(old.Status), string(ar.Status)},
+		{FieldStatusReason, old.StatusReason, ar.StatusReason},
+		{FieldLastChecked, formatTime(old.LastChecked), formatTime(ar.LastChecked)},
+		{FieldBBc1DomainName, old.BBc1DomainName, ar.BBc1DomainName},
+		{FieldNote, old.Note, ar.Note},
+		{FieldMetadata, formatMetadata(old.Metadata), formatMetadata(ar.Metadata)},
+	}
+	now, actor := timeNow(), actorOf(ctx)
+	var es []*HistoryEntry
+	for _, f := range fields {
+		if f.old == f.new {
+			continue
+		}
+		es = append(es, &HistoryEntry{
+			BBc1DomainID:      ar.Anchor.BBc1DomainID[:],
+			BBc1TransactionID: ar.Anchor.BBc1TransactionID[:],
+			Time:              now,
+			Actor:             actor,
+			Field:             f.name,
+			Old:               f.old,
+			New:               f.new,
+		})
+	}
+	return es
+}
+
+// appendHistory appends the changes from old to ar to g.History if it is set.
+// The AnchorRecord has been changed so failing to write the history is not an error.
+func (g *GatewayImpl) appendHistory(ctx context.Context, old, ar *model.AnchorRecord) {
+	if g.History == nil {
+		return
+	}
+	es := recordChanges(ctx, old, ar)
+	if len(es) == 0 {
+		return
+	}
+	if err := g.History.Append(ctx, es); err != nil {
+		log.Printf("appendHistory: %v (cid=%s)", err, journalCID(ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]))
+	}
+}
+
+// updateRecord updates the AnchorRecord old in g.Store by u, and appends the changes to g.History.
+func (g *GatewayImpl) updateRecord(ctx context.Context, old *model.AnchorRecord, u *store.RecordUpdate) error {
+	domID, txID := old.Anchor.BBc1DomainID[:], old.Anchor.BBc1TransactionID[:]
+	if err := g.Store.Update(ctx, domID, txID, u); err != nil {
+		return err
+	}
+	ar := *old
+	if u.Confirmations != nil {
+		ar.Confirmations = *u.Confirmations
+	}
+	if u.Status != nil {
+		ar.Status = *u.Status
+		ar.StatusReason = u.StatusReason
+		ar.LastChecked = u.LastChecked
+	}
+	if u.BBc1DomainName != nil {
+		ar.BBc1DomainName = *u.BBc1DomainName
+	}
+	if u.Note != nil {
+		ar.Note = *u.Note
+	}
+	if u.Metadata != nil {
+		ar.Metadata = nil
+		if len(u.Metadata) != 0 {
+			ar.Metadata = u.Metadata
+		}
+	}
+	g.appendHistory(ctx, old, &ar)
+	return nil
+}
+
+func (g *GatewayImpl) GetHistory(ctx context.Context, domID, txID []byte) ([]*HistoryEntry, error) {
+	if g.History == nil {
+		return nil, fmt.Errorf("%w (History is not set)", ErrCouldNotGetHistory)
+	}
+	if _, err := g.Store.Get(ctx, domID, txID); err != nil {
+		return nil, wrap(ErrCouldNotGetHistory, err)
+	}
+	es, err := g.History.List(ctx, domID, txID)
+	if err != nil {
+		return nil, wrap(ErrCouldNotGetHistory, err)
+	}
+	return es, nil
+}
@@ -0,0 +1,159 @@
+package gw
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+
+	_ "gocloud.dev/docstore/memdocstore"
+)
+
+// withHistory sets History to g.
+func withHistory(t *testing.T, g *GatewayImpl) {
+	t.Helper()
+	g.History = NewBoltHistory(t.TempDir() + "/history.db")
+}
+
+// mustListHistory returns HistoryEntries of ar by the field.
+func mustListHistory(t *testing.T, g *GatewayImpl, ar *model.AnchorRecord) map[string]*HistoryEntry {
+	t.Helper()
+	es, err := g.GetHistory(context.Background(), ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[string]*HistoryEntry)
+	for _, e := range es {
+		if m[e.Field] != nil {
+			t.Fatalf("%s is recorded twice", e.Field)
+		}
+		m[e.Field] = e
+	}
+	return m
+}
+
+func TestGatewayImpl_RefreshRecord_History(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	withHistory(t, g)
+	ar := mustRegister(t, g, "")
+	domID, txID := ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]
+	if es := mustListHistory(t, g, ar); len(es) != 0 {
+		t.Fatalf("got %d entries want 0", len(es))
+	}
+
+	n.confirm(ar.BTCTransactionID, 1)
+	name := "name1"
+	if err := g.RefreshRecord(WithActor(ctx, "apikey:key1"), domID, txID, &name, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := g.GetRecord(ctx, domID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := mustListHistory(t, g, ar)
+	want := map[string][2]string{
+		FieldConfirmations:  {"0", "1"},
+		FieldStatus:         {string(model.AnchorBroadcast), string(model.AnchorConfirmed)},
+		FieldLastChecked:    {formatTime(ar.LastChecked), formatTime(got.LastChecked)},
+		FieldBBc1DomainName: {"", name},
+	}
+	if len(es) != len(want) {
+		t.Errorf("got %d entries want %d", len(es), len(want))
+	}
+	for f, w := range want {
+		e := es[f]
+		if e == nil || e.Old != w[0] || e.New != w[1] || e.Actor != "apikey:key1" {
+			t.Errorf("%s: got %+v want %v", f, e, w)
+		}
+	}
+	// The refresh time is recorded in the history instead of the note.
+	if got.Note != "" || es[FieldNote] != nil {
+		t.Errorf("note: got %q, %+v", got.Note, es[FieldNote])
+	}
+
+	// Nothing is changed.
+	if err := g.updateRecord(ctx, got, &store.RecordUpdate{Note: &got.Note}); err != nil {
+		t.Fatal(err)
+	}
+	if es2 := mustListHistory(t, g, ar); len(es2) != len(es) {
+		t.Errorf("got %d entries want %d", len(es2), len(es))
+	}
+
+	// Changed by the system.
+	note := "note1"
+	if err := g.updateRecord(ctx, got, &store.RecordUpdate{Note: &note}); err != nil {
+		t.Fatal(err)
+	}
+	if e := mustListHistory(t, g, ar)[FieldNote]; e == nil || e.Old != "" || e.New != note || e.Actor != ActorSystem {
+		t.Errorf("got %+v", e)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		new  func(t *testing.T) History
+	}{
+		{"bolt", func(t *testing.T) History { return NewBoltHistory(t.TempDir() + "/history.db") }},
+		{"docstore", func(t *testing.T) History { return NewDocstoreHistory("mem://history_test/id") }},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			h := c.new(t)
+			t.Cleanup(func() {
+				if err := h.Close(); err != nil {
+					t.Error(err)
+				}
+			})
+			domID, txID := randBytes(t), randBytes(t)
+			now := time.Now()
+			change := func(tm time.Time, vs ...string) []*HistoryEntry {
+				var es []*HistoryEntry
+				for _, v := range vs {
+					es = append(es, &HistoryEntry{BBc1DomainID: domID, BBc1TransactionID: txID, Time: tm, Actor: ActorSystem, Field: FieldNote, New: v})
+				}
+				return es
+			}
+			// Appended out of order, and two changes at the same time.
+			for _, es := range [][]*HistoryEntry{
+				change(now.Add(time.Second), "3"),
+				change(now, "0", "1"),
+				change(now, "2"),
+				change(now.Add(2*time.Second), "4"),
+			} {
+				if err := h.Append(ctx, es); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Another record.
+			if err := h.Append(ctx, []*HistoryEntry{{BBc1DomainID: domID, BBc1TransactionID: randBytes(t), Time: now}}); err != nil {
+				t.Fatal(err)
+			}
+
+			es, err := h.List(ctx, domID, txID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(es) != 5 {
+				t.Fatalf("got %d entries want 5", len(es))
+			}
+			for i, e := range es {
+				if e.New != strconv.Itoa(i) {
+					t.Errorf("%d: got %q", i, e.New)
+				}
+			}
+			if es, err := h.List(ctx, randBytes(t), txID); err != nil || len(es) != 0 {
+				t.Errorf("got %d entries, %v want 0", len(es), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package gw
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
+
+	"gocloud.dev/docstore"
+)
+
+// JournalState is the progress of a registration.
+type JournalState string
+
+// JournalStates in order.
+const (
+	// JournalQueued means the registration is accepted by Enqueue and waiting for a worker.
+	JournalQueued JournalState = "queued"
+	// JournalIntent means the registration has started. Nothing has been sent.
+	JournalIntent JournalState = "intent"
+	// JournalSigned means the anchor transaction has been signed. It may or may not have been sent.
+	JournalSigned JournalState = "signed"
+	// JournalBroadcast means the anchor transaction has been sent. The wallet may not be updated.
+	JournalBroadcast JournalState = "broadcast"
+	// JournalStored means the AnchorRecord has been stored.
+	// The entry is kept for JournalRetention to answer retried requests with the same IdempotencyKey.
+	JournalStored JournalState = "stored"
+	// JournalFailed means a worker has given up the registration. See JournalEntry.FailReason.
+	// The entry is kept for JournalRetention like JournalStored.
+	JournalFailed JournalState = "failed"
+)
+
+// JournalRetention is how long entries in JournalStored and JournalFailed are kept.
+var JournalRetention = 24 * time.Hour
+
+// JournalEntry is a write-ahead log of a registration
+// that is written before each step of RegisterTransaction and StoreRecord.
+// An entry before JournalStored is also used as the pending record of the registration.
+type JournalEntry struct {
+	BBc1DomainID      []byte
+	BBc1TransactionID []byte
+	State             JournalState
+	// IdempotencyKey is the key given by the client, or "" if not given.
+	IdempotencyKey string
+	// APIKeyID and Metadata are set to the AnchorRecord when it is stored.
+	APIKeyID string
+	Metadata model.Metadata
+	// Set in JournalSigned.
+	FromTransactionID []byte
+	FromAddr          string
+	RawTransaction    []byte
+	Fee               model.Amount
+	// Set in JournalBroadcast.
+	BTCTransactionID []byte
+	// Reanchor is true if the registration re-anchors a failed anchor (see CheckPendingAnchors).
+	Reanchor bool
+	// NewAnchor is true if the digest is not stored yet when the registration started (see Spend.NewAnchor).
+	NewAnchor bool
+	// Set in JournalFailed.
+	FailReason string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Journal stores JournalEntries.
+// An entry is specified by BBc1DomainID and BBc1TransactionID.
+type Journal interface {
+	// Put adds or replaces a JournalEntry.
+	Put(ctx context.Context, e *JournalEntry) error
+	// Get returns the JournalEntry specified by domID and txID.
+	// Returns ErrJournalEntryNotFound if not found.
+	Get(ctx context.Context, domID, txID []byte) (*JournalEntry, error)
+	// Delete removes the JournalEntry specified by domID and txID.
+	Delete(ctx context.Context, domID, txID []byte) error
+	// List returns all JournalEntries ordered by CreatedAt (oldest first).
+	List(ctx context.Context) ([]*JournalEntry, error)
+
+	io.Closer
+}
+
+var _ Journal = (*DocstoreJournal)(nil)
+
+// Errors
+var (
+	ErrCouldNotOpenJournal  = errors.New("ErrCouldNotOpenJournal")
+	ErrCouldNotCloseJournal = errors.New("ErrCouldNotCloseJournal")
+	ErrCouldNotWriteJournal = errors.New("ErrCouldNotWriteJournal")
+	ErrCouldNotReadJournal  = errors.New("ErrCouldNotReadJournal")
+	ErrJournalEntryNotFound = errors.New("ErrJournalEntryNotFound")
+	ErrCouldNotRecover      = errors.New("ErrCouldNotRecover")
+)
+
+func journalCID(domID, txID []byte) string {
+	return hex.EncodeToString(domID) + hex.EncodeToString(txID)
+}
+
+type journalDoc struct {
+	CID          string            `docstore:"cid"`
+	BBc1DomainID []byte            `docstore:"bbc1domid"`
+	BBc1TxID     []byte            `docstore:"bbc1txid"`
+	State        string            `docstore:"state"`
+	IdemKey      string            `docstore:"idemkey"`
+	APIKeyID     string            `docstore:"apikey"`
+	Metadata     map[string]string `docstore:"metadata"`
+	FromTxID     []byte            `docstore:"fromtxid"`
+	FromAddr     string            `docstore:"fromaddr"`
+	RawTx        []byte            `docstore:"rawtx"`
+	Fee          int64             `docstore:"fee"`
+	BTCTxID      []byte            `docstore:"btctxid"`
+	Reanchor     bool              `docstore:"reanchor"`
+	NewAnchor    bool              `docstore:"newanchor"`
+	FailReason   string            `docstore:"failreason"`
+	CreatedAt    time.Time         `docstore:"createdat"`
+	UpdatedAt    time.Time         `docstore:"updatedat"`
+}
+
+func newJournalDoc(e *JournalEntry) *journalDoc {
+	return &journalDoc{
+		CID:          journalCID(e.BBc1DomainID, e.BBc1TransactionID),
+		BBc1DomainID: e.BBc1DomainID,
+		BBc1TxID:     e.BBc1TransactionID,
+		State:        string(e.State),
+		IdemKey:      e.IdempotencyKey,
+		APIKeyID:     e.APIKeyID,
+		Metadata:     e.Metadata,
+		FromTxID:     e.FromTransactionID,
+		FromAddr:     e.FromAddr,
+		RawTx:        e.RawTransaction,
+		Fee:          int64(e.Fee),
+		BTCTxID:      e.BTCTransactionID,
+		Reanchor:     e.Reanchor,
+		NewAnchor:    e.NewAnchor,
+		FailReason:   e.FailReason,
+		CreatedAt:    e.CreatedAt,
+		UpdatedAt:    e.UpdatedAt,
+	}
+}
+
+func (d *journalDoc) journalEntry() *JournalEntry {
+	return &JournalEntry{
+		BBc1DomainID:      d.BBc1DomainID,
+		BBc1TransactionID: d.BBc1TxID,
+		State:             JournalState(d.State),
+		IdempotencyKey:    d.IdemKey,
+		APIKeyID:          d.APIKeyID,
+		Metadata:          d.Metadata,
+		FromTransactionID: d.FromTxID,
+		FromAddr:          d.FromAddr,
+		RawTransaction:    d.RawTx,
+		Fee:               model.Amount(d.Fee),
+		BTCTransactionID:  d.BTCTxID,
+		Reanchor:          d.Reanchor,
+		NewAnchor:         d.NewAnchor,
+		FailReason:        d.FailReason,
+		CreatedAt:         d.CreatedAt,
+		UpdatedAt:         d.UpdatedAt,
+	}
+}
+
+// DocstoreJournal is a Journal that uses gocloud.dev/docstore.
+// The collection must use "cid" as the ID field.
+type DocstoreJournal struct {
+	conn string
+	coll *docstore.Collection
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewDocstoreJournal(conn string) *DocstoreJournal {
+	j := &DocstoreJournal{
+		conn: conn,
+		coll: nil,
+	}
+	return j
+}
+
+func (j *DocstoreJournal) open() error {
+	coll, err := docstore.OpenCollection(context.Background(), j.conn)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenJournal, err)
+	}
+	j.coll = coll
+	return nil
+}
+
+// Open opens j.coll once.
+func (j *DocstoreJournal) Open() error {
+	var oErr error
+	j.once.Do(func() { oErr = j.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the DocstoreJournal.
+func (j *DocstoreJournal) Close() error {
+	if err := j.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseJournal, err)
+	}
+	if err := j.coll.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseJournal, err)
+	}
+	return nil
+}
+
+func (j *DocstoreJournal) Put(ctx context.Context, e *JournalEntry) error {
+	if err := j.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	if err := j.coll.Put(ctx, newJournalDoc(e)); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	return nil
+}
+
+func (j *DocstoreJournal) Get(ctx context.Context, domID, txID []byte) (*JournalEntry, error) {
+	if err := j.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadJournal, err)
+	}
+	d := &journalDoc{CID: journalCID(domID, txID)}
+	if err := j.coll.Get(ctx, d); err != nil {
+		err = util.DocstoreError(err)
+		if errors.Is(err, util.ErrNotFound) {
+			return nil, wrap(ErrJournalEntryNotFound, err)
+		}
+		return nil, wrap(ErrCouldNotReadJournal, err)
+	}
+	return d.journalEntry(), nil
+}
+
+func (j *DocstoreJournal) Delete(ctx context.Context, domID, txID []byte) error {
+	if err := j.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	if err := j.coll.Delete(ctx, &journalDoc{CID: journalCID(domID, txID)}); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteJournal, err)
+	}
+	return nil
+}
+
+func (j *DocstoreJournal) List(ctx context.Context) ([]*JournalEntry, error) {
+	if err := j.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadJournal, err)
+	}
+	iter := j.coll.Query().Get(ctx)
+	defer iter.Stop()
+	var es []*JournalEntry
+	for {
+		var d journalDoc
+		err := iter.Next(ctx, &d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadJournal, err)
+		}
+		es = append(es, d.journalEntry())
+	}
+	sort.SliceStable(es, func(i, k int) bool { return es[i].CreatedAt.Before(es[k].CreatedAt) })
+	return es, nil
+}
+
+// writeJournal sets e.State to state and writes e to g.Journal.
+func (g *GatewayImpl) writeJournal(ctx context.Context, e *JournalEntry, state JournalState) error {
+	e.State = state
+	e.UpdatedAt = timeNow()
+	return g.Journal.Put(ctx, e)
+}
+
+// rollbackJournal removes e from g.Journal. Does nothing if e is nil.
+func (g *GatewayImpl) rollbackJournal(ctx context.Context, e *JournalEntry) {
+	if e == nil {
+		return
+	}
+	if err := g.Journal.Delete(ctx, e.BBc1DomainID, e.BBc1TransactionID); err != nil {
+		log.Printf("RegisterTransaction: %v (cid=%s)", err, journalCID(e.BBc1DomainID, e.BBc1TransactionID))
+	}
+}
+
+// Recover finishes or rolls back every half-done registration in g.Journal.
+// Call this on startup before accepting requests.
+//
+//   - JournalIntent: rolled back as nothing has been sent.
+//   - JournalSigned: finished if the transaction has been sent (known to the wallet, the mempool
+//     or the block chain) or can be sent now, otherwise (e.g. the input has already been spent) rolled back.
+//   - JournalBroadcast: finished by updating Wallet and Tracker, and storing the AnchorRecord.
+//   - JournalStored, JournalFailed: removed if older than JournalRetention.
+//   - JournalQueued: left for workers.
+//
+// Does nothing if g.Journal is nil.
+func (g *GatewayImpl) Recover(ctx context.Context) error {
+	if g.Journal == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	es, err := g.Journal.List(ctx)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotRecover, err)
+	}
+	var lastErr error
+	for _, e := range es {
+		cid := journalCID(e.BBc1DomainID, e.BBc1TransactionID)
+		switch e.State {
+		case JournalQueued:
+			continue
+		case JournalStored, JournalFailed:
+			if e.expired() {
+				g.rollbackJournal(ctx, e)
+			}
+			continue
+		}
+		txid, err := g.recoverEntry(ctx, e)
+		if err != nil {
+			log.Printf("Recover: %v (cid=%s, state=%s)", err, cid, e.State)
+			lastErr = err
+			continue
+		}
+		if txid == nil {
+			log.Printf("Recover: rolled back (cid=%s, state=%s)", cid, e.State)
+			continue
+		}
+		if _, err := g.storeAnchorRecord(ctx, txid, e.APIKeyID, e.Metadata); err != nil {
+			log.Printf("Recover: %v (cid=%s, state=%s)", err, cid, e.State)
+			lastErr = err
+			continue
+		}
+		log.Printf("Recover: finished (cid=%s, state=%s, btctx=%s)", cid, e.State, hex.EncodeToString(txid))
+	}
+	if lastErr != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotRecover, lastErr)
+	}
+	return nil
+}
+
+// recoverEntry finishes or rolls back the registration of e without storing the AnchorRecord.
+// Returns the transaction ID if finished, or nil if rolled back.
+// g.mu must be locked.
+func (g *GatewayImpl) recoverEntry(ctx context.Context, e *JournalEntry) ([]byte, error) {
+	switch e.State {
+	case JournalSigned:
+		txid, err := g.resendSigned(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		if txid == nil {
+			g.rollbackJournal(ctx, e)
+			return nil, nil
+		}
+		e.BTCTransactionID = txid
+		if err := g.writeJournal(ctx, e, JournalBroadcast); err != nil {
+			return nil, err
+		}
+		fallthrough
+	case JournalBroadcast:
+		if err := g.finishBroadcast(ctx, e.BBc1DomainID, e.BBc1TransactionID, e.BTCTransactionID, e.FromTransactionID, e.FromAddr, e.RawTransaction, e.Fee, e.Reanchor, e.NewAnchor); err != nil {
+			return nil, err
+		}
+		return e.BTCTransactionID, nil
+	case JournalStored:
+		return e.BTCTransactionID, nil
+	default:
+		// JournalIntent, JournalFailed, and unknown states.
+		g.rollbackJournal(ctx, e)
+		return nil, nil
+	}
+}
+
+// expired returns whether e is in JournalStored or JournalFailed, and older than JournalRetention.
+func (e *JournalEntry) expired() bool {
+	done := e.State == JournalStored || e.State == JournalFailed
+	return done && timeNow().After(e.UpdatedAt.Add(JournalRetention))
+}
+
+// resendSigned returns the transaction ID of e.RawTransaction if it has been sent or is sent now.
+// Returns nil if it has never been sent and cannot be sent.
+// The transaction may have been sent before the wallet knows it, so the mempool is checked too.
+func (g *GatewayImpl) resendSigned(ctx context.Context, e *JournalEntry) ([]byte, error) {
+	decoded, err := g.xBTCImpl.DecodeRawTransaction(ctx, e.RawTransaction)
+	if err != nil {
+		return nil, err
+	}
+	txid, err := g.xBTCImpl.ParseRawTransactionID(decoded)
+	if err != nil {
+		return nil, err
+	}
+	// The wallet knows the transaction if it has been sent.
+	if txJSON, err := g.xBTCImpl.GetTransaction(ctx, txid); err == nil {
+		if depth, err := g.xBTCImpl.ParseWalletTransactionDepth(txJSON); err == nil && depth >= 0 {
+			return txid, nil
+		}
+	}
+	if _, err := g.xBTCImpl.GetMempoolEntry(ctx, txid); err == nil {
+		return txid, nil
+	}
+	// Already in the mempool or the block chain is reported as ErrTxAlreadyExists.
+	_, err = g.xBTCImpl.BroadcastTransaction(ctx, e.RawTransaction)
+	switch {
+	case err == nil, errors.Is(err, btc.ErrTxAlreadyExists):
+		return txid, nil
+	case btc.IsMempoolRejection(err), errors.Is(err, btc.ErrTxAlreadySpent):
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+// settleSigned finishes or rolls back JournalEntries in JournalSigned except e,
+// as their transactions may have spent the next UTXO of g.Wallet.
+// Returns an error if any of them cannot be settled, so that the UTXO is not spent twice.
+// g.mu must be locked.
+func (g *GatewayImpl) settleSigned(ctx context.Context, e *JournalEntry) error {
+	es, err := g.Journal.List(ctx)
+	if err != nil {
+		return err
+	}
+	cid := journalCID(e.BBc1DomainID, e.BBc1TransactionID)
+	for _, s := range es {
+		if s.State != JournalSigned || journalCID(s.BBc1DomainID, s.BBc1TransactionID) == cid {
+			continue
+		}
+		txid, err := g.recoverEntry(ctx, s)
+		if err != nil {
+			return err
+		}
+		if txid == nil {
+			continue
+		}
+		// The transaction has been sent, so the AnchorRecord can be stored later by Recover or retries.
+		if _, err := g.storeAnchorRecord(ctx, txid, s.APIKeyID, s.Metadata); err != nil {
+			log.Printf("RegisterTransaction: %v (btctx=%s)", err, hex.EncodeToString(txid))
+		}
+	}
+	return nil
+}
+
+// storeAnchorRecord puts the AnchorRecord of txid into g.Store, and marks its JournalEntry stored.
+// BBc1DomainName, Note, Metadata and APIKeyID are kept if the AnchorRecord already exists, and the changes are appended to g.History.
+// Otherwise keyID and meta are set.
+// g.mu must be locked.
+func (g *GatewayImpl) storeAnchorRecord(ctx context.Context, txid []byte, keyID string, meta model.Metadata) (*model.AnchorRecord, error) {
+	ar, err := g.BTC.GetAnchor(ctx, txid)
+	if err != nil {
+		return nil, err
+	}
+	domID, txID := ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]
+	ar.APIKeyID = keyID
+	ar.Metadata = meta
+	oldAR, err := g.Store.Get(ctx, domID, txID)
+	if err == nil {
+		ar.BBc1DomainName = oldAR.BBc1DomainName
+		ar.Note = oldAR.Note
+		ar.Metadata = oldAR.Metadata
+		ar.APIKeyID = oldAR.APIKeyID
+	}
+	ar.LastChecked = timeNow()
+	if err := g.Store.Put(ctx, ar); err != nil {
+		return nil, err
+	}
+	if oldAR != nil {
+		g.appendHistory(ctx, oldAR, ar)
+	}
+	g.markStored(ctx, domID, txID, txid)
+	return ar, nil
+}
+
+// markStored marks the JournalEntry specified by domID and txID stored if it exists.
+// The AnchorRecord has been stored so failing to write the journal is not an error.
+func (g *GatewayImpl) markStored(ctx context.Context, domID, txID, txid []byte) {
+	if g.Journal == nil {
+		return
+	}
+	e, err := g.Journal.Get(ctx, domID, txID)
+	if err != nil {
+		if !errors.Is(err, ErrJournalEntryNotFound) {
+			log.Printf("StoreRecord: %v (btctx=%s)", err, hex.EncodeToString(txid))
+		}
+		return
+	}
+	e.BTCTransactionID = txid
+	if err := g.writeJournal(ctx, e, JournalStored); err != nil {
+		log.Printf("StoreRecord: %v (btctx=%s)", err, hex.EncodeToString(txid))
+	}
+}
@@ -0,0 +1,256 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
+)
+
+// putSignedEntry signs an anchor transaction of a random digest spending the next UTXO of g.Wallet,
+// and writes its JournalEntry in JournalSigned without sending it.
+func putSignedEntry(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+	t.Helper()
+	ctx := context.Background()
+	domID, txID := randBytes(t), randBytes(t)
+	from, addr, err := g.Wallet.PeekNextUTXO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.XSetUTXO(from, addr)
+	fee := btc.FeeOf(model.FeeDefault)
+	raw, err := n.CreateAnchorTransactionWithFee(ctx, model.NewAnchor(g.BTCNet, timeNow(), domID, txID), fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &JournalEntry{
+		BBc1DomainID:      domID,
+		BBc1TransactionID: txID,
+		IdempotencyKey:    "key1",
+		FromTransactionID: from,
+		FromAddr:          addr,
+		RawTransaction:    raw,
+		Fee:               fee,
+		CreatedAt:         timeNow(),
+	}
+	if err := g.writeJournal(ctx, e, JournalSigned); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// mustSend sends the transaction of e like a crash after broadcasting, and returns the transaction ID.
+func mustSend(t *testing.T, n *fakeNode, e *JournalEntry) []byte {
+	t.Helper()
+	txid, err := n.BroadcastTransaction(context.Background(), e.RawTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txid
+}
+
+func TestGatewayImpl_Recover(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	expire := func(t *testing.T, g *GatewayImpl, e *JournalEntry) {
+		t.Helper()
+		e.UpdatedAt = timeNow().Add(-JournalRetention - 1)
+		if err := g.Journal.Put(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		name  string
+		setup func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry
+		// wantState is "" if the entry is removed.
+		wantState JournalState
+		// wantSent is true if the registration is finished with the transaction.
+		wantSent bool
+		wantErr  bool
+	}{
+		{"queued", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := &JournalEntry{BBc1DomainID: randBytes(t), BBc1TransactionID: randBytes(t), CreatedAt: timeNow()}
+			if err := g.writeJournal(ctx, e, JournalQueued); err != nil {
+				t.Fatal(err)
+			}
+			return e
+		}, JournalQueued, false, false},
+		{"intent", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := &JournalEntry{BBc1DomainID: randBytes(t), BBc1TransactionID: randBytes(t), CreatedAt: timeNow()}
+			if err := g.writeJournal(ctx, e, JournalIntent); err != nil {
+				t.Fatal(err)
+			}
+			return e
+		}, "", false, false},
+		{"signed_not_sent", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			return putSignedEntry(t, g, n)
+		}, JournalStored, true, false},
+		{"signed_known_to_wallet", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := putSignedEntry(t, g, n)
+			mustSend(t, n, e)
+			return e
+		}, JournalStored, true, false},
+		{"signed_in_mempool", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			// Sent but unknown to the wallet, and testmempoolaccept would reject it.
+			e := putSignedEntry(t, g, n)
+			txid := mustSend(t, n, e)
+			n.forget(txid)
+			n.failBroadcast(txid, fmt.Errorf("%w (txn-already-in-mempool)", btc.ErrTxRejected))
+			return e
+		}, JournalStored, true, false},
+		{"signed_in_chain", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := putSignedEntry(t, g, n)
+			txid := mustSend(t, n, e)
+			n.confirm(txid, 1)
+			n.forget(txid)
+			return e
+		}, JournalStored, true, false},
+		{"signed_rejected", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := putSignedEntry(t, g, n)
+			n.failBroadcast(fakeTxID(e.RawTransaction), btc.ErrTxFeeTooLow)
+			return e
+		}, "", false, false},
+		{"signed_node_unavailable", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := putSignedEntry(t, g, n)
+			n.failBroadcast(fakeTxID(e.RawTransaction), btc.ErrFailedToExec)
+			return e
+		}, JournalSigned, false, true},
+		{"broadcast", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := putSignedEntry(t, g, n)
+			e.BTCTransactionID = mustSend(t, n, e)
+			if err := g.writeJournal(ctx, e, JournalBroadcast); err != nil {
+				t.Fatal(err)
+			}
+			return e
+		}, JournalStored, true, false},
+		{"stored", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := &JournalEntry{BBc1DomainID: randBytes(t), BBc1TransactionID: randBytes(t), CreatedAt: timeNow()}
+			if err := g.writeJournal(ctx, e, JournalStored); err != nil {
+				t.Fatal(err)
+			}
+			return e
+		}, JournalStored, false, false},
+		{"stored_expired", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := &JournalEntry{BBc1DomainID: randBytes(t), BBc1TransactionID: randBytes(t), State: JournalStored, CreatedAt: timeNow()}
+			expire(t, g, e)
+			return e
+		}, "", false, false},
+		{"failed", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := &JournalEntry{BBc1DomainID: randBytes(t), BBc1TransactionID: randBytes(t), FailReason: "ErrTxFeeTooLow", CreatedAt: timeNow()}
+			if err := g.writeJournal(ctx, e, JournalFailed); err != nil {
+				t.Fatal(err)
+			}
+			return e
+		}, JournalFailed, false, false},
+		{"failed_expired", func(t *testing.T, g *GatewayImpl, n *fakeNode) *JournalEntry {
+			e := &JournalEntry{BBc1DomainID: randBytes(t), BBc1TransactionID: randBytes(t), State: JournalFailed, CreatedAt: timeNow()}
+			expire(t, g, e)
+			return e
+		}, "", false, false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			g, n := newTestGateway(t)
+			e := c.setup(t, g, n)
+			domID, txID := e.BBc1DomainID, e.BBc1TransactionID
+
+			err := g.Recover(ctx)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got %v want error %v", err, c.wantErr)
+			}
+
+			got, err := g.Journal.Get(ctx, domID, txID)
+			switch {
+			case c.wantState == "" && !errors.Is(err, ErrJournalEntryNotFound):
+				t.Fatalf("got %v want removed", err)
+			case c.wantState != "" && err != nil:
+				t.Fatal(err)
+			case c.wantState != "" && got.State != c.wantState:
+				t.Fatalf("state: got %v want %v", got.State, c.wantState)
+			}
+
+			ar, err := g.Store.Get(ctx, domID, txID)
+			if !c.wantSent {
+				if !errors.Is(err, util.ErrNotFound) {
+					t.Errorf("got %v want %v", err, util.ErrNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			txid := fakeTxID(e.RawTransaction)
+			if !bytes.Equal(ar.BTCTransactionID, txid) || !bytes.Equal(got.BTCTransactionID, txid) {
+				t.Errorf("got record=%x journal=%x want %x", ar.BTCTransactionID, got.BTCTransactionID, txid)
+			}
+			if tx := n.tx(txid); !tx.inMempool && tx.depth <= 0 {
+				t.Error("not sent")
+			}
+			// The next registration spends the change.
+			if next, _, err := g.Wallet.PeekNextUTXO(); err != nil || !bytes.Equal(next, txid) {
+				t.Errorf("next UTXO: got %x (%v) want %x", next, err, txid)
+			}
+			if p := mustListPending(t, g)[string(txid)]; p == nil {
+				t.Error("not tracked")
+			}
+		})
+	}
+}
+
+func TestGatewayImpl_RegisterTransaction_Signed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("sent", func(t *testing.T) {
+		t.Parallel()
+		g, n := newTestGateway(t)
+		e := putSignedEntry(t, g, n)
+
+		// The signed transaction is sent first, and its change is spent.
+		txid, err := g.RegisterTransaction(ctx, randBytes(t), randBytes(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed := fakeTxID(e.RawTransaction)
+		if from := n.tx(txid).from; !bytes.Equal(from, signed) {
+			t.Errorf("spent %x want %x", from, signed)
+		}
+		ar, err := g.Store.Get(ctx, e.BBc1DomainID, e.BBc1TransactionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ar.BTCTransactionID, signed) {
+			t.Errorf("got %x want %x", ar.BTCTransactionID, signed)
+		}
+	})
+
+	t.Run("node_unavailable", func(t *testing.T) {
+		t.Parallel()
+		g, n := newTestGateway(t)
+		e := putSignedEntry(t, g, n)
+		n.failBroadcast(fakeTxID(e.RawTransaction), btc.ErrFailedToExec)
+
+		// The same UTXO is not spent until the signed transaction is settled.
+		domID, txID := randBytes(t), randBytes(t)
+		_, err := g.RegisterTransaction(ctx, domID, txID)
+		if !IsNodeUnavailable(err) {
+			t.Fatalf("got %v want node unavailable", err)
+		}
+		if n.broadcasts != 0 {
+			t.Errorf("broadcasts: got %d want 0", n.broadcasts)
+		}
+		if _, err := g.Journal.Get(ctx, domID, txID); !errors.Is(err, ErrJournalEntryNotFound) {
+			t.Errorf("got %v want rolled back", err)
+		}
+		if got := mustGetJournal(t, g, e.BBc1DomainID, e.BBc1TransactionID); got.State != JournalSigned {
+			t.Errorf("state: got %v want %v", got.State, JournalSigned)
+		}
+	})
+}
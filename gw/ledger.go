@@ -0,0 +1,248 @@
+package gw
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
+
+	"gocloud.dev/docstore"
+)
+
+// Spend is the fee spent by an anchor transaction.
+type Spend struct {
+	BTCTransactionID  []byte
+	BBc1DomainID      []byte
+	BBc1TransactionID []byte
+	Fee               model.Amount
+	SpentAt           time.Time
+	// Reanchor is true if the transaction re-anchors a failed anchor (see CheckPendingAnchors).
+	// It is not charged to the domain, and is counted only in the fee of the total of all domains.
+	Reanchor bool
+	// NewAnchor is true if the digest is not stored yet. Only new anchors are counted in SpendTotal.Anchors,
+	// so anchoring a stored digest again spends the fee but not the quota.
+	NewAnchor bool
+}
+
+// SpendTotal is the total of Spends.
+type SpendTotal struct {
+	Anchors uint
+	Fee     model.Amount
+}
+
+// Ledger stores Spends, and is used to enforce Budgets.
+type Ledger interface {
+	// Put adds a Spend. The Spend of the same BTCTransactionID is replaced.
+	Put(ctx context.Context, s *Spend) error
+	// Total returns the total of Spends of domID since the given time.
+	// nil domID totals all domains. See Spend.Reanchor for re-anchoring.
+	Total(ctx context.Context, domID []byte, since time.Time) (*SpendTotal, error)
+
+	io.Closer
+}
+
+var _ Ledger = (*DocstoreLedger)(nil)
+
+// Errors
+var (
+	ErrCouldNotOpenLedger  = errors.New("ErrCouldNotOpenLedger")
+	ErrCouldNotCloseLedger = errors.New("ErrCouldNotCloseLedger")
+	ErrCouldNotWriteLedger = errors.New("ErrCouldNotWriteLedger")
+	ErrCouldNotReadLedger  = errors.New("ErrCouldNotReadLedger")
+
+	// Budgets applied by RegisterTransaction.
+	// The quotas limit the number of anchors, and the budgets limit the fee spent.
+	ErrMonthlyQuotaExceeded  = errors.New("ErrMonthlyQuotaExceeded")
+	ErrBudgetExceeded        = errors.New("ErrBudgetExceeded")
+	ErrGatewayQuotaExceeded  = errors.New("ErrGatewayQuotaExceeded")
+	ErrGatewayBudgetExceeded = errors.New("ErrGatewayBudgetExceeded")
+)
+
+type spendDoc struct {
+	BTCTx     string    `docstore:"btctx"`
+	DomID     string    `docstore:"domid"`
+	BBc1TxID  []byte    `docstore:"bbc1txid"`
+	Fee       int64     `docstore:"fee"`
+	SpentAt   time.Time `docstore:"spentat"`
+	Reanchor  bool      `docstore:"reanchor"`
+	NewAnchor bool      `docstore:"newanchor"`
+}
+
+func newSpendDoc(s *Spend) *spendDoc {
+	return &spendDoc{
+		BTCTx:     hex.EncodeToString(s.BTCTransactionID),
+		DomID:     hex.EncodeToString(s.BBc1DomainID),
+		BBc1TxID:  s.BBc1TransactionID,
+		Fee:       int64(s.Fee),
+		SpentAt:   s.SpentAt,
+		Reanchor:  s.Reanchor,
+		NewAnchor: s.NewAnchor,
+	}
+}
+
+// add adds doc to t if doc is of domID (or domID is nil) and spent since the given time.
+// Re-anchoring is added to the fee of all domains only, and only new anchors are counted in Anchors.
+func (t *SpendTotal) add(doc *spendDoc, domID string, since time.Time) {
+	if (domID != "" && doc.DomID != domID) || doc.SpentAt.Before(since) {
+		return
+	}
+	if doc.Reanchor {
+		if domID == "" {
+			t.Fee += model.Amount(doc.Fee)
+		}
+		return
+	}
+	if doc.NewAnchor {
+		t.Anchors++
+	}
+	t.Fee += model.Amount(doc.Fee)
+}
+
+// DocstoreLedger is a Ledger that uses gocloud.dev/docstore.
+// The collection must use "btctx" as the ID field.
+type DocstoreLedger struct {
+	conn string
+	coll *docstore.Collection
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewDocstoreLedger(conn string) *DocstoreLedger {
+	l := &DocstoreLedger{
+		conn: conn,
+		coll: nil,
+	}
+	return l
+}
+
+func (l *DocstoreLedger) open() error {
+	coll, err := docstore.OpenCollection(context.Background(), l.conn)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenLedger, err)
+	}
+	l.coll = coll
+	return nil
+}
+
+// Open opens l.coll once.
+func (l *DocstoreLedger) Open() error {
+	var oErr error
+	l.once.Do(func() { oErr = l.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the DocstoreLedger.
+func (l *DocstoreLedger) Close() error {
+	if err := l.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseLedger, err)
+	}
+	if err := l.coll.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseLedger, err)
+	}
+	return nil
+}
+
+func (l *DocstoreLedger) Put(ctx context.Context, s *Spend) error {
+	if err := l.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotWriteLedger, err)
+	}
+	if err := l.coll.Put(ctx, newSpendDoc(s)); err != nil {
+		return wrap(ErrCouldNotWriteLedger, util.DocstoreError(err))
+	}
+	return nil
+}
+
+func (l *DocstoreLedger) Total(ctx context.Context, domID []byte, since time.Time) (*SpendTotal, error) {
+	if err := l.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotReadLedger, err)
+	}
+	var key string
+	q := l.coll.Query().Where("spentat", ">=", since)
+	if domID != nil {
+		key = hex.EncodeToString(domID)
+		q = q.Where("domid", "=", key)
+	}
+	iter := q.Get(ctx)
+	defer iter.Stop()
+	t := &SpendTotal{}
+	for {
+		var doc spendDoc
+		err := iter.Next(ctx, &doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, wrap(ErrCouldNotReadLedger, util.DocstoreError(err))
+		}
+		t.add(&doc, key, since)
+	}
+	return t, nil
+}
+
+// applyBudget checks whether an anchor transaction of domID paying fee fits in b.
+// newAnchor is false if the anchor is not counted as a new one, e.g. re-anchoring.
+// Returns ErrDailyQuotaExceeded, ErrMonthlyQuotaExceeded or ErrBudgetExceeded if not.
+// nil domID checks the total of all domains.
+func (g *GatewayImpl) applyBudget(ctx context.Context, domID []byte, b model.Budget, fee model.Amount, newAnchor bool) error {
+	now := timeNow()
+	periods := []struct {
+		name     string
+		since    time.Time
+		anchors  uint
+		spend    model.Amount
+		quotaErr error
+	}{
+		{"today", model.StartOfDay(now), b.DailyAnchors, b.DailySpend, ErrDailyQuotaExceeded},
+		{"this month", model.StartOfMonth(now), b.MonthlyAnchors, b.MonthlySpend, ErrMonthlyQuotaExceeded},
+	}
+	for _, p := range periods {
+		if (p.anchors == 0 || !newAnchor) && p.spend == 0 {
+			continue
+		}
+		t, err := g.Ledger.Total(ctx, domID, p.since)
+		if err != nil {
+			return err
+		}
+		if p.anchors > 0 && newAnchor && t.Anchors >= p.anchors {
+			return fmt.Errorf("%w (%d anchors %s, quota %d)", p.quotaErr, t.Anchors, p.name, p.anchors)
+		}
+		if p.spend > 0 && t.Fee+fee > p.spend {
+			return fmt.Errorf("%w (%d satoshis spent %s, budget %d)", ErrBudgetExceeded, t.Fee, p.name, p.spend)
+		}
+	}
+	return nil
+}
+
+// recordSpend adds the Spend of the anchor transaction txid to g.Ledger if it is set.
+// reanchor is true if txid re-anchors a failed anchor, and newAnchor is true if the digest is not stored yet
+// (see applyDomain). Rebroadcasting does not add Spends as the transaction is same.
+// The transaction has been sent so failing to record it is not an error.
+func (g *GatewayImpl) recordSpend(ctx context.Context, domID, txID, txid []byte, fee model.Amount, reanchor, newAnchor bool) {
+	if g.Ledger == nil {
+		return
+	}
+	s := &Spend{
+		BTCTransactionID:  txid,
+		BBc1DomainID:      domID,
+		BBc1TransactionID: txID,
+		Fee:               fee,
+		SpentAt:           timeNow(),
+		Reanchor:          reanchor,
+		NewAnchor:         newAnchor,
+	}
+	if err := g.Ledger.Put(ctx, s); err != nil {
+		log.Printf("RegisterTransaction: %v (btctx=%s)", err, hex.EncodeToString(txid))
+	}
+}
@@ -0,0 +1,149 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+)
+
+// withLedger sets Ledger and Budget to g.
+func withLedger(t *testing.T, g *GatewayImpl, b model.Budget) {
+	t.Helper()
+	g.Ledger = NewBoltLedger(t.TempDir() + "/ledger.db")
+	g.Budget = b
+}
+
+func mustTotal(t *testing.T, g *GatewayImpl, domID []byte) *SpendTotal {
+	t.Helper()
+	st, err := g.Ledger.Total(context.Background(), domID, model.StartOfDay(timeNow()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestGatewayImpl_applyBudget(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fee := btc.FeeOf(model.FeeDefault)
+	cases := []struct {
+		name    string
+		domain  *model.Domain
+		budget  model.Budget
+		wantErr error
+	}{
+		{"daily_quota", &model.Domain{DailyQuota: 1}, model.Budget{}, ErrDailyQuotaExceeded},
+		{"monthly_quota", &model.Domain{MonthlyQuota: 1}, model.Budget{}, ErrMonthlyQuotaExceeded},
+		{"daily_budget", &model.Domain{DailyBudget: fee + fee/2}, model.Budget{}, ErrBudgetExceeded},
+		{"monthly_budget", &model.Domain{MonthlyBudget: fee + fee/2}, model.Budget{}, ErrBudgetExceeded},
+		{"gateway_quota", &model.Domain{}, model.Budget{DailyAnchors: 1}, ErrGatewayQuotaExceeded},
+		{"gateway_budget", &model.Domain{}, model.Budget{MonthlySpend: fee}, ErrGatewayBudgetExceeded},
+		{"within", &model.Domain{DailyQuota: 2, DailyBudget: 2 * fee}, model.Budget{DailyAnchors: 2, DailySpend: 2 * fee}, nil},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			g, _ := newTestGateway(t)
+			withLedger(t, g, c.budget)
+			domID := withDomain(t, g, c.domain)
+			if _, err := g.Register(ctx, domID, randBytes(t), "", "", nil); err != nil {
+				t.Fatal(err)
+			}
+			if st := mustTotal(t, g, domID); st.Anchors != 1 || st.Fee != fee {
+				t.Errorf("total: got %+v", st)
+			}
+			_, err := g.Register(ctx, domID, randBytes(t), "", "", nil)
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("got %v want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestGatewayImpl_recordSpend_Stored(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fee := btc.FeeOf(model.FeeDefault)
+	g, _ := newTestGateway(t)
+	withLedger(t, g, model.Budget{})
+	domID := withDomain(t, g, &model.Domain{DailyQuota: 2, MonthlyQuota: 2})
+	ar, err := g.Register(ctx, domID, randBytes(t), "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Anchoring a stored digest again spends the fee, but is not counted against the quotas.
+	if _, err := g.RegisterTransaction(ctx, domID, ar.Anchor.BBc1TransactionID[:]); err != nil {
+		t.Fatalf("anchor again: %v", err)
+	}
+	if st := mustTotal(t, g, domID); st.Anchors != 1 || st.Fee != 2*fee {
+		t.Errorf("domain: got %+v", st)
+	}
+	if _, err := g.Register(ctx, domID, randBytes(t), "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Register(ctx, domID, randBytes(t), "", "", nil); !errors.Is(err, ErrDailyQuotaExceeded) {
+		t.Errorf("got %v want %v", err, ErrDailyQuotaExceeded)
+	}
+}
+
+func TestGatewayImpl_recordSpend_Reanchor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fee := btc.FeeOf(model.FeeDefault)
+	g, n := newTestGateway(t)
+	withLedger(t, g, model.Budget{DailyAnchors: 1, DailySpend: 2 * fee})
+	domID := withDomain(t, g, &model.Domain{DailyQuota: 1, DailyBudget: fee})
+	ar, err := g.Register(ctx, domID, randBytes(t), "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-anchoring is allowed even though the domain has used up the quota and the budget.
+	n.confirm(ar.BTCTransactionID, -1)
+	if err := g.Wallet.ReplaceNextUTXO(randBytes(t), "addr1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Not charged to the domain, but the fee is counted for the Gateway.
+	if st := mustTotal(t, g, domID); st.Anchors != 1 || st.Fee != fee {
+		t.Errorf("domain: got %+v", st)
+	}
+	if st := mustTotal(t, g, nil); st.Anchors != 1 || st.Fee != 2*fee {
+		t.Errorf("gateway: got %+v", st)
+	}
+
+	// Rebroadcasting sends the same transaction, so nothing is added.
+	re, err := g.GetRecord(ctx, ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.drop(re.BTCTransactionID)
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if p := mustListPending(t, g)[string(re.BTCTransactionID)]; p == nil || p.Rebroadcasts != 1 {
+		t.Fatalf("got %+v", p)
+	}
+	if st := mustTotal(t, g, nil); st.Anchors != 1 || st.Fee != 2*fee {
+		t.Errorf("gateway: got %+v", st)
+	}
+
+	// The fee budget of the Gateway is applied to re-anchoring.
+	n.confirm(re.BTCTransactionID, -1)
+	if err := g.Wallet.ReplaceNextUTXO(randBytes(t), "addr1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CheckPendingAnchors(ctx); !errors.Is(err, ErrCouldNotCheckPending) {
+		t.Errorf("got %v want %v", err, ErrCouldNotCheckPending)
+	}
+	if p := mustListPending(t, g)[string(re.BTCTransactionID)]; p == nil || !p.Failed {
+		t.Errorf("got %+v", p)
+	}
+}
@@ -0,0 +1,216 @@
+package gw
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
+)
+
+// Errors
+var (
+	ErrCouldNotEnqueue         = errors.New("ErrCouldNotEnqueue")
+	ErrCouldNotGetRegistration = errors.New("ErrCouldNotGetRegistration")
+)
+
+// Registration is the progress of a registration of a digest.
+type Registration struct {
+	BBc1DomainID      []byte
+	BBc1TransactionID []byte
+	Status            model.AnchorStatus
+	// BTCTransactionID is set after the anchor transaction is sent.
+	BTCTransactionID []byte
+	// FailReason is set if Status is model.AnchorFailed.
+	FailReason string
+	// Record is set if the AnchorRecord has been stored.
+	Record *model.AnchorRecord
+}
+
+// journalStatus returns the AnchorStatus of the registration of e before it is stored.
+func journalStatus(s JournalState) model.AnchorStatus {
+	switch s {
+	case JournalQueued:
+		return model.AnchorQueued
+	case JournalBroadcast:
+		return model.AnchorBroadcast
+	case JournalFailed:
+		return model.AnchorFailed
+	default:
+		return model.AnchorPending
+	}
+}
+
+func newRegistration(e *JournalEntry) *Registration {
+	return &Registration{
+		BBc1DomainID:      e.BBc1DomainID,
+		BBc1TransactionID: e.BBc1TransactionID,
+		Status:            journalStatus(e.State),
+		BTCTransactionID:  e.BTCTransactionID,
+		FailReason:        e.FailReason,
+	}
+}
+
+func newRegistrationFromRecord(ar *model.AnchorRecord) *Registration {
+	return &Registration{
+		BBc1DomainID:      ar.Anchor.BBc1DomainID[:],
+		BBc1TransactionID: ar.Anchor.BBc1TransactionID[:],
+		Status:            ar.Status,
+		BTCTransactionID:  ar.BTCTransactionID,
+		Record:            ar,
+	}
+}
+
+// Enqueue accepts a registration of the given digest and returns immediately.
+// Workers started by StartWorkers do the registration like Register.
+// The progress can be checked by GetRegistration.
+//
+// Idempotency is same as Register: if the same idemKey is given,
+// the current progress is returned instead of registering again.
+// This needs g.Journal as it is used as the queue.
+func (g *GatewayImpl) Enqueue(ctx context.Context, domID, txID []byte, idemKey, keyID string, meta model.Metadata) (*Registration, error) {
+	if g.Journal == nil {
+		return nil, fmt.Errorf("%w (Journal is not set)", ErrCouldNotEnqueue)
+	}
+	if err := meta.Validate(); err != nil {
+		return nil, err
+	}
+	// Do not lock g.mu as it is held during registrations.
+	g.qmu.Lock()
+	defer g.qmu.Unlock()
+
+	e, err := g.Journal.Get(ctx, domID, txID)
+	switch {
+	case err == nil && e.expired(), err == nil && e.State == JournalFailed && (idemKey == "" || idemKey != e.IdempotencyKey):
+		// Register again.
+	case err == nil:
+		if idemKey != "" && e.IdempotencyKey != "" && idemKey != e.IdempotencyKey {
+			return nil, ErrIdempotencyKeyMismatch
+		}
+		if e.State == JournalStored {
+			return g.GetRegistration(ctx, domID, txID)
+		}
+		return newRegistration(e), nil
+	case !errors.Is(err, ErrJournalEntryNotFound):
+		return nil, wrap(ErrCouldNotEnqueue, err)
+	}
+	if _, err := g.Store.Get(ctx, domID, txID); err == nil {
+		return nil, ErrRecordAlreadyExists
+	} else if !errors.Is(err, util.ErrNotFound) {
+		return nil, wrap(ErrCouldNotEnqueue, err)
+	}
+	e = &JournalEntry{
+		BBc1DomainID:      domID,
+		BBc1TransactionID: txID,
+		IdempotencyKey:    idemKey,
+		APIKeyID:          keyID,
+		Metadata:          meta,
+		CreatedAt:         timeNow(),
+	}
+	if err := g.writeJournal(ctx, e, JournalQueued); err != nil {
+		return nil, wrap(ErrCouldNotEnqueue, err)
+	}
+	// Wake up a worker.
+	select {
+	case g.queued <- struct{}{}:
+	default:
+	}
+	return newRegistration(e), nil
+}
+
+// GetRegistration returns the progress of the registration of the given digest.
+// Registration.Record is set if the AnchorRecord has been stored.
+func (g *GatewayImpl) GetRegistration(ctx context.Context, domID, txID []byte) (*Registration, error) {
+	if g.Journal != nil {
+		e, err := g.Journal.Get(ctx, domID, txID)
+		if err == nil && !e.expired() && e.State != JournalStored {
+			return newRegistration(e), nil
+		}
+	}
+	ar, err := g.GetRecord(ctx, domID, txID)
+	if err != nil {
+		return nil, wrap(ErrCouldNotGetRegistration, err)
+	}
+	return newRegistrationFromRecord(ar), nil
+}
+
+// StartWorkers starts n workers that process registrations queued by Enqueue, until ctx is done.
+// Workers check the queue when a registration is queued, and every interval for retries.
+// Note that anchor transactions are sent one by one as they spend the change of the previous one,
+// so n > 1 only helps when some registrations are waiting for the node.
+func (g *GatewayImpl) StartWorkers(ctx context.Context, n int, interval time.Duration) {
+	for i := 0; i < n; i++ {
+		go func() {
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-g.queued:
+				case <-t.C:
+				}
+				g.processQueue(ctx)
+			}
+		}()
+	}
+}
+
+// processQueue registers queued digests in order.
+func (g *GatewayImpl) processQueue(ctx context.Context) {
+	es, err := g.Journal.List(ctx)
+	if err != nil {
+		log.Printf("processQueue: %v", err)
+		return
+	}
+	for _, e := range es {
+		if ctx.Err() != nil {
+			return
+		}
+		if e.State != JournalQueued {
+			continue
+		}
+		cid := journalCID(e.BBc1DomainID, e.BBc1TransactionID)
+		ar, err := g.Register(ctx, e.BBc1DomainID, e.BBc1TransactionID, e.IdempotencyKey, e.APIKeyID, e.Metadata)
+		if err == nil {
+			log.Printf("processQueue: registered (cid=%s, btctx=%s)", cid, hex.EncodeToString(ar.BTCTransactionID))
+			continue
+		}
+		log.Printf("processQueue: %v (cid=%s)", err, cid)
+		g.failQueued(ctx, e, err)
+	}
+}
+
+// failQueued updates the JournalEntry of the failed registration e.
+// Registrations failed by unavailability of the node are queued again.
+func (g *GatewayImpl) failQueued(ctx context.Context, e *JournalEntry, err error) {
+	g.qmu.Lock()
+	defer g.qmu.Unlock()
+
+	cur, gErr := g.Journal.Get(ctx, e.BBc1DomainID, e.BBc1TransactionID)
+	switch {
+	case gErr == nil && cur.State != JournalQueued && cur.State != JournalIntent:
+		// It may have been sent (JournalSigned) or completed by another request, leave it.
+		return
+	case gErr != nil && !errors.Is(gErr, ErrJournalEntryNotFound):
+		log.Printf("processQueue: %v", gErr)
+		return
+	}
+	// Rolled back or not started.
+	if errors.Is(err, ErrRecordAlreadyExists) {
+		g.rollbackJournal(ctx, e)
+		return
+	}
+	state := JournalFailed
+	if IsNodeUnavailable(err) {
+		state = JournalQueued
+	}
+	e.FailReason = err.Error()
+	if wErr := g.writeJournal(ctx, e, state); wErr != nil {
+		log.Printf("processQueue: %v", wErr)
+	}
+}
@@ -0,0 +1,152 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+)
+
+func mustEnqueue(t *testing.T, g *GatewayImpl, domID, txID []byte, idemKey string) *Registration {
+	t.Helper()
+	r, err := g.Enqueue(context.Background(), domID, txID, idemKey, "keyid1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestGatewayImpl_Enqueue(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	domID, txID := randBytes(t), randBytes(t)
+
+	if r := mustEnqueue(t, g, domID, txID, "key1"); r.Status != model.AnchorQueued {
+		t.Fatalf("status: got %v want %v", r.Status, model.AnchorQueued)
+	}
+	// Queued again by the retried request.
+	if r := mustEnqueue(t, g, domID, txID, "key1"); r.Status != model.AnchorQueued {
+		t.Errorf("status: got %v want %v", r.Status, model.AnchorQueued)
+	}
+	if _, err := g.Enqueue(ctx, domID, txID, "key2", "", nil); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Errorf("got %v want %v", err, ErrIdempotencyKeyMismatch)
+	}
+
+	g.processQueue(ctx)
+	if n.broadcasts != 1 {
+		t.Errorf("broadcasts: got %d want 1", n.broadcasts)
+	}
+	r, err := g.GetRegistration(ctx, domID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != model.AnchorBroadcast || r.Record == nil || r.Record.APIKeyID != "keyid1" {
+		t.Fatalf("got %+v", r)
+	}
+	// The retried request gets the AnchorRecord.
+	if got := mustEnqueue(t, g, domID, txID, "key1"); got.Record == nil || !bytes.Equal(got.BTCTransactionID, r.BTCTransactionID) {
+		t.Errorf("got %+v", got)
+	}
+	if _, err := g.Enqueue(ctx, randBytes(t), randBytes(t), "", "", model.Metadata{"": "a"}); !errors.Is(err, model.ErrInvalidMetadata) {
+		t.Errorf("got %v want %v", err, model.ErrInvalidMetadata)
+	}
+}
+
+func TestGatewayImpl_Enqueue_RecordExists(t *testing.T) {
+	t.Parallel()
+	g, _ := newTestGateway(t)
+	ar := mustRegister(t, g, "")
+	domID, txID := ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]
+	e := mustGetJournal(t, g, domID, txID)
+	e.UpdatedAt = timeNow().Add(-JournalRetention - 1)
+	if err := g.Journal.Put(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Enqueue(context.Background(), domID, txID, "key1", "", nil); !errors.Is(err, ErrRecordAlreadyExists) {
+		t.Errorf("got %v want %v", err, ErrRecordAlreadyExists)
+	}
+}
+
+func TestGatewayImpl_Enqueue_NoJournal(t *testing.T) {
+	t.Parallel()
+	g, _ := newTestGateway(t)
+	if err := g.Journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g.Journal = nil
+	if _, err := g.Enqueue(context.Background(), randBytes(t), randBytes(t), "", "", nil); !errors.Is(err, ErrCouldNotEnqueue) {
+		t.Errorf("got %v want %v", err, ErrCouldNotEnqueue)
+	}
+}
+
+func TestGatewayImpl_processQueue_Fail(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cases := []struct {
+		name string
+		fail func(n *fakeNode)
+		// wantState is the JournalState after the worker failed.
+		wantState  JournalState
+		wantStatus model.AnchorStatus
+	}{
+		// Not started, so queued again.
+		{"node_unavailable", func(n *fakeNode) { n.setCreateErr(btc.ErrFailedToExec) }, JournalQueued, model.AnchorQueued},
+		// Never sent, so failed.
+		{"rejected", func(n *fakeNode) { n.setBroadcastErr(btc.ErrTxFeeTooLow) }, JournalFailed, model.AnchorFailed},
+		// May have been sent, so left for Recover and retries.
+		{"may_be_sent", func(n *fakeNode) { n.setBroadcastErr(btc.ErrFailedToExec) }, JournalSigned, model.AnchorPending},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			g, n := newTestGateway(t)
+			domID, txID := randBytes(t), randBytes(t)
+			mustEnqueue(t, g, domID, txID, "key1")
+
+			c.fail(n)
+			g.processQueue(ctx)
+			e := mustGetJournal(t, g, domID, txID)
+			if e.State != c.wantState {
+				t.Fatalf("state: got %v want %v", e.State, c.wantState)
+			}
+			if c.wantState != JournalSigned && e.FailReason == "" {
+				t.Error("no FailReason")
+			}
+			r, err := g.GetRegistration(ctx, domID, txID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Status != c.wantStatus {
+				t.Errorf("status: got %v want %v", r.Status, c.wantStatus)
+			}
+
+			// The retried request gets the progress, and another request registers again if failed.
+			if got := mustEnqueue(t, g, domID, txID, "key1"); got.Status != c.wantStatus {
+				t.Errorf("retried: got %v want %v", got.Status, c.wantStatus)
+			}
+			if c.wantState == JournalFailed {
+				if got := mustEnqueue(t, g, domID, txID, "key2"); got.Status != model.AnchorQueued {
+					t.Errorf("another: got %v want %v", got.Status, model.AnchorQueued)
+				}
+			}
+
+			// Finished by the next run.
+			n.setCreateErr(nil)
+			n.setBroadcastErr(nil)
+			g.processQueue(ctx)
+			if c.wantState == JournalSigned {
+				if err := g.Recover(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if e := mustGetJournal(t, g, domID, txID); e.State != JournalStored {
+				t.Errorf("state: got %v want %v", e.State, JournalStored)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package gw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// RegisterTimeout limits the registration shared by concurrent Register calls.
+// It does not use ctx of the callers, so that a caller giving up does not fail the others.
+var RegisterTimeout = 1 * time.Minute
+
+// Errors
+var (
+	ErrRecordAlreadyExists     = errors.New("ErrRecordAlreadyExists")
+	ErrIdempotencyKeyMismatch  = errors.New("ErrIdempotencyKeyMismatch")
+	ErrCouldNotRegisterPending = errors.New("ErrCouldNotRegisterPending")
+	ErrRegistrationFailed      = errors.New("ErrRegistrationFailed")
+)
+
+type registerResult struct {
+	ar      *model.AnchorRecord
+	idemKey string
+}
+
+// Register anchors the given digest and stores its AnchorRecord, and returns the AnchorRecord.
+// This is same as calling RegisterTransaction and StoreRecord, but idempotent:
+//   - Concurrent calls for the same domID and txID are deduplicated, and share the result.
+//   - If idemKey is same as the one given to the first call, the original AnchorRecord is returned
+//     instead of anchoring again. Keys are kept for JournalRetention after completion.
+//   - If idemKey differs from the one given to the first call, returns ErrIdempotencyKeyMismatch.
+//   - If the AnchorRecord already exists and the key cannot be checked, returns ErrRecordAlreadyExists.
+//   - If the registration with the same idemKey has failed in a worker, returns ErrRegistrationFailed.
+//
+// A pending record (JournalEntry) is written before broadcasting, so idemKey needs g.Journal.
+// Errors from RegisterTransaction are returned as they are (e.g. ErrCouldNotPutAnchor).
+//
+// keyID and meta are set to the new AnchorRecord. Calls sharing the result use them of the first call.
+// The registration runs on a context detached from ctx (see RegisterTimeout) with the actor of the first call,
+// and the call returns ctx.Err() if ctx is done before the registration finishes.
+func (g *GatewayImpl) Register(ctx context.Context, domID, txID []byte, idemKey, keyID string, meta model.Metadata) (*model.AnchorRecord, error) {
+	if err := meta.Validate(); err != nil {
+		return nil, err
+	}
+	ch := g.sf.DoChan(journalCID(domID, txID), func() (interface{}, error) {
+		sctx, cancel := context.WithTimeout(WithActor(context.Background(), actorOf(ctx)), RegisterTimeout)
+		defer cancel()
+		return g.register(sctx, domID, txID, idemKey, keyID, meta)
+	})
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	// Calls sharing the result may have different keys.
+	r := res.Val.(*registerResult)
+	if idemKey != "" && r.idemKey != "" && idemKey != r.idemKey {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	return r.ar, nil
+}
+
+func (g *GatewayImpl) register(ctx context.Context, domID, txID []byte, idemKey, keyID string, meta model.Metadata) (*registerResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Check the pending record first, as it is written before the AnchorRecord.
+	if g.Journal != nil {
+		e, err := g.Journal.Get(ctx, domID, txID)
+		switch {
+		case err == nil && e.State == JournalFailed && !e.expired() && idemKey != "" && idemKey == e.IdempotencyKey:
+			// Retried request of a failed registration.
+			return nil, fmt.Errorf("%w (%s)", ErrRegistrationFailed, e.FailReason)
+		case err == nil && !e.expired() && e.State != JournalFailed:
+			if idemKey != "" && e.IdempotencyKey != "" && idemKey != e.IdempotencyKey {
+				return nil, ErrIdempotencyKeyMismatch
+			}
+			if e.State == JournalStored {
+				ar, err := g.Store.Get(ctx, domID, txID)
+				if err != nil {
+					return nil, wrap(ErrCouldNotGetRecord, err)
+				}
+				return &registerResult{ar, e.IdempotencyKey}, nil
+			}
+			// Half-done, registerTransaction finishes it with keyID and meta of the first call.
+			if e.APIKeyID != "" {
+				keyID = e.APIKeyID
+			}
+			if e.Metadata != nil {
+				meta = e.Metadata
+			}
+			txid, err := g.registerTransaction(ctx, domID, txID, idemKey, keyID, meta, nil)
+			if err != nil {
+				return nil, err
+			}
+			ar, err := g.storeAnchorRecord(ctx, txid, keyID, meta)
+			if err != nil {
+				return nil, wrap(ErrCouldNotStoreRecord, err)
+			}
+			if e.IdempotencyKey != "" {
+				idemKey = e.IdempotencyKey
+			}
+			return &registerResult{ar, idemKey}, nil
+		case err != nil && !errors.Is(err, ErrJournalEntryNotFound):
+			return nil, wrap(ErrCouldNotRegisterPending, err)
+		}
+	}
+	if _, err := g.Store.Get(ctx, domID, txID); err == nil {
+		return nil, ErrRecordAlreadyExists
+	} else if !errors.Is(err, util.ErrNotFound) {
+		return nil, wrap(ErrCouldNotGetRecord, err)
+	}
+	txid, err := g.registerTransaction(ctx, domID, txID, idemKey, keyID, meta, nil)
+	if err != nil {
+		return nil, err
+	}
+	ar, err := g.storeAnchorRecord(ctx, txid, keyID, meta)
+	if err != nil {
+		return nil, wrap(ErrCouldNotStoreRecord, err)
+	}
+	return &registerResult{ar, idemKey}, nil
+}
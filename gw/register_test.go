@@ -0,0 +1,141 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ebiiim/btcgw/btc"
+)
+
+func TestGatewayImpl_Register_Idempotency(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	ar := mustRegister(t, g, "key1")
+	domID, txID := ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]
+
+	cases := []struct {
+		name    string
+		idemKey string
+		wantErr error
+	}{
+		{"same_key", "key1", nil},
+		{"no_key", "", nil},
+		{"another_key", "key2", ErrIdempotencyKeyMismatch},
+	}
+	for _, c := range cases {
+		got, err := g.Register(ctx, domID, txID, c.idemKey, "", nil)
+		if !errors.Is(err, c.wantErr) {
+			t.Errorf("%s: got %v want %v", c.name, err, c.wantErr)
+			continue
+		}
+		if err == nil && !bytes.Equal(got.BTCTransactionID, ar.BTCTransactionID) {
+			t.Errorf("%s: got %x want %x", c.name, got.BTCTransactionID, ar.BTCTransactionID)
+		}
+	}
+	if n.broadcasts != 1 {
+		t.Errorf("broadcasts: got %d want 1", n.broadcasts)
+	}
+
+	// The key cannot be checked after JournalRetention.
+	e := mustGetJournal(t, g, domID, txID)
+	e.UpdatedAt = timeNow().Add(-JournalRetention - 1)
+	if err := g.Journal.Put(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Register(ctx, domID, txID, "key1", "", nil); !errors.Is(err, ErrRecordAlreadyExists) {
+		t.Errorf("expired: got %v want %v", err, ErrRecordAlreadyExists)
+	}
+}
+
+func TestGatewayImpl_Register_Canceled(t *testing.T) {
+	t.Parallel()
+	g, n := newTestGateway(t)
+	domID, txID := randBytes(t), randBytes(t)
+
+	// The registration is blocked until g.mu is unlocked.
+	g.mu.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := g.Register(ctx, domID, txID, "key1", "", nil)
+		first <- err
+	}()
+	// The first call starts the registration, and gives up.
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("first: got %v want %v", err, context.Canceled)
+	}
+
+	// The second call shares the registration, which is not canceled.
+	second := make(chan error)
+	go func() {
+		_, err := g.Register(context.Background(), domID, txID, "key1", "", nil)
+		second <- err
+	}()
+	g.mu.Unlock()
+	if err := <-second; err != nil {
+		t.Fatalf("second: %v", err)
+	}
+	if n.broadcasts != 1 {
+		t.Errorf("broadcasts: got %d want 1", n.broadcasts)
+	}
+}
+
+func TestGatewayImpl_Register_HalfDone(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	e := putSignedEntry(t, g, n)
+
+	if _, err := g.Register(ctx, e.BBc1DomainID, e.BBc1TransactionID, "key2", "", nil); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Errorf("got %v want %v", err, ErrIdempotencyKeyMismatch)
+	}
+	// The retried request finishes the registration.
+	ar, err := g.Register(ctx, e.BBc1DomainID, e.BBc1TransactionID, "key1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fakeTxID(e.RawTransaction); !bytes.Equal(ar.BTCTransactionID, want) {
+		t.Errorf("got %x want %x", ar.BTCTransactionID, want)
+	}
+	if got := mustGetJournal(t, g, e.BBc1DomainID, e.BBc1TransactionID); got.State != JournalStored {
+		t.Errorf("state: got %v want %v", got.State, JournalStored)
+	}
+}
+
+func TestGatewayImpl_Register_Failed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	domID, txID := randBytes(t), randBytes(t)
+	e := &JournalEntry{BBc1DomainID: domID, BBc1TransactionID: txID, IdempotencyKey: "key1", FailReason: "ErrTxFeeTooLow", CreatedAt: timeNow()}
+	if err := g.writeJournal(ctx, e, JournalFailed); err != nil {
+		t.Fatal(err)
+	}
+
+	// The retried request gets the failure.
+	if _, err := g.Register(ctx, domID, txID, "key1", "", nil); !errors.Is(err, ErrRegistrationFailed) {
+		t.Errorf("got %v want %v", err, ErrRegistrationFailed)
+	}
+	if n.broadcasts != 0 {
+		t.Fatalf("broadcasts: got %d want 0", n.broadcasts)
+	}
+
+	// Another request registers again.
+	n.setBroadcastErr(btc.ErrTxFeeTooLow)
+	if _, err := g.Register(ctx, domID, txID, "key2", "", nil); !IsMempoolRejection(err) {
+		t.Errorf("got %v want a mempool rejection", err)
+	}
+	n.setBroadcastErr(nil)
+	ar, err := g.Register(ctx, domID, txID, "key2", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := mustGetJournal(t, g, domID, txID)
+	if got.State != JournalStored || got.IdempotencyKey != "key2" || !bytes.Equal(got.BTCTransactionID, ar.BTCTransactionID) {
+		t.Errorf("journal: got state=%v key=%q btctx=%x", got.State, got.IdempotencyKey, got.BTCTransactionID)
+	}
+}
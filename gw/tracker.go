@@ -0,0 +1,361 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+
+	"gocloud.dev/docstore"
+)
+
+// PendingAnchor is an anchor transaction that has been broadcast but not confirmed yet.
+// The signed raw transaction is kept so that it can be rebroadcast if it is dropped from mempools.
+type PendingAnchor struct {
+	BTCTransactionID  []byte
+	BBc1DomainID      []byte
+	BBc1TransactionID []byte
+	// RawTransaction is the signed raw transaction.
+	RawTransaction []byte
+	// FromTransactionID is the transaction ID of the UTXO spent by RawTransaction.
+	FromTransactionID []byte
+	BroadcastTime     time.Time
+	LastChecked       time.Time
+	Rebroadcasts      int
+	// Failed is true if the transaction can never be confirmed,
+	// e.g. the input has been spent by a conflicting transaction.
+	// Failed anchors are waiting for re-anchoring.
+	Failed     bool
+	FailReason string
+}
+
+// Tracker stores PendingAnchors.
+type Tracker interface {
+	// Put adds or replaces a PendingAnchor.
+	Put(ctx context.Context, p *PendingAnchor) error
+	// Delete removes the PendingAnchor specified by btcTXID.
+	Delete(ctx context.Context, btcTXID []byte) error
+	// List returns all PendingAnchors ordered by BroadcastTime (oldest first).
+	List(ctx context.Context) ([]*PendingAnchor, error)
+
+	io.Closer
+}
+
+var _ Tracker = (*DocstoreTracker)(nil)
+
+// Errors
+var (
+	ErrCouldNotOpenTracker  = errors.New("ErrCouldNotOpenTracker")
+	ErrCouldNotCloseTracker = errors.New("ErrCouldNotCloseTracker")
+	ErrCouldNotTrack        = errors.New("ErrCouldNotTrack")
+	ErrCouldNotUntrack      = errors.New("ErrCouldNotUntrack")
+	ErrCouldNotListPending  = errors.New("ErrCouldNotListPending")
+)
+
+type pendingDoc struct {
+	BTCTx        string    `docstore:"btctx"`
+	BBc1DomainID []byte    `docstore:"bbc1domid"`
+	BBc1TxID     []byte    `docstore:"bbc1txid"`
+	RawTx        []byte    `docstore:"rawtx"`
+	FromTxID     []byte    `docstore:"fromtxid"`
+	BroadcastAt  time.Time `docstore:"broadcastat"`
+	LastChecked  time.Time `docstore:"lastchecked"`
+	Rebroadcasts int       `docstore:"rebroadcasts"`
+	Failed       bool      `docstore:"failed"`
+	FailReason   string    `docstore:"failreason"`
+}
+
+func newPendingDoc(p *PendingAnchor) *pendingDoc {
+	return &pendingDoc{
+		BTCTx:        hex.EncodeToString(p.BTCTransactionID),
+		BBc1DomainID: p.BBc1DomainID,
+		BBc1TxID:     p.BBc1TransactionID,
+		RawTx:        p.RawTransaction,
+		FromTxID:     p.FromTransactionID,
+		BroadcastAt:  p.BroadcastTime,
+		LastChecked:  p.LastChecked,
+		Rebroadcasts: p.Rebroadcasts,
+		Failed:       p.Failed,
+		FailReason:   p.FailReason,
+	}
+}
+
+func (d *pendingDoc) pendingAnchor() (*PendingAnchor, error) {
+	btctx, err := hex.DecodeString(d.BTCTx)
+	if err != nil {
+		return nil, err
+	}
+	p := &PendingAnchor{
+		BTCTransactionID:  btctx,
+		BBc1DomainID:      d.BBc1DomainID,
+		BBc1TransactionID: d.BBc1TxID,
+		RawTransaction:    d.RawTx,
+		FromTransactionID: d.FromTxID,
+		BroadcastTime:     d.BroadcastAt,
+		LastChecked:       d.LastChecked,
+		Rebroadcasts:      d.Rebroadcasts,
+		Failed:            d.Failed,
+		FailReason:        d.FailReason,
+	}
+	return p, nil
+}
+
+// DocstoreTracker is a Tracker that uses gocloud.dev/docstore.
+// The collection must use "btctx" as the ID field.
+type DocstoreTracker struct {
+	conn string
+	coll *docstore.Collection
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+func NewDocstoreTracker(conn string) *DocstoreTracker {
+	t := &DocstoreTracker{
+		conn: conn,
+		coll: nil,
+	}
+	return t
+}
+
+func (t *DocstoreTracker) open() error {
+	coll, err := docstore.OpenCollection(context.Background(), t.conn)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotOpenTracker, err)
+	}
+	t.coll = coll
+	return nil
+}
+
+// Open opens t.coll once.
+func (t *DocstoreTracker) Open() error {
+	var oErr error
+	t.once.Do(func() { oErr = t.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the DocstoreTracker.
+func (t *DocstoreTracker) Close() error {
+	if err := t.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseTracker, err)
+	}
+	if err := t.coll.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCloseTracker, err)
+	}
+	return nil
+}
+
+func (t *DocstoreTracker) Put(ctx context.Context, p *PendingAnchor) error {
+	if err := t.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotTrack, err)
+	}
+	if err := t.coll.Put(ctx, newPendingDoc(p)); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotTrack, err)
+	}
+	return nil
+}
+
+func (t *DocstoreTracker) Delete(ctx context.Context, btcTXID []byte) error {
+	if err := t.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotUntrack, err)
+	}
+	if err := t.coll.Delete(ctx, &pendingDoc{BTCTx: hex.EncodeToString(btcTXID)}); err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotUntrack, err)
+	}
+	return nil
+}
+
+func (t *DocstoreTracker) List(ctx context.Context) ([]*PendingAnchor, error) {
+	if err := t.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrCouldNotListPending, err)
+	}
+	iter := t.coll.Query().Get(ctx)
+	defer iter.Stop()
+	var ps []*PendingAnchor
+	for {
+		var d pendingDoc
+		err := iter.Next(ctx, &d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w (%v)", ErrCouldNotListPending, err)
+		}
+		p, err := d.pendingAnchor()
+		if err != nil {
+			return nil, fmt.Errorf("%w (%v)", ErrCouldNotListPending, err)
+		}
+		ps = append(ps, p)
+	}
+	// Sort in memory as not all drivers support OrderBy without an index.
+	sort.SliceStable(ps, func(i, j int) bool { return ps[i].BroadcastTime.Before(ps[j].BroadcastTime) })
+	return ps, nil
+}
+
+// pendingConfirmations is the number of confirmations to stop tracking.
+const pendingConfirmations = 1
+
+// CheckPendingAnchors checks every PendingAnchor in g.Tracker and does the following:
+//   - Stops tracking the anchor if the transaction is confirmed.
+//   - Rebroadcasts the transaction if the node no longer knows it
+//     (e.g. evicted from the mempool, node restarted, expired).
+//   - Marks the anchor failed if the input has been spent by a conflicting transaction,
+//     and then re-anchors the same digest with a new transaction.
+//
+// Failed anchors stay in g.Tracker until re-anchoring succeeds, so they are retried on the next call.
+// Re-anchoring is not charged to the quotas and budgets of the Domain, but its fee counts toward g.Budget.
+// Note that re-anchoring needs a valid UTXO in g.Wallet;
+// if the conflicting transaction spent the last UTXO, please add a new one.
+//
+// Does nothing if g.Tracker is nil. Call this periodically.
+func (g *GatewayImpl) CheckPendingAnchors(ctx context.Context) error {
+	if g.Tracker == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ps, err := g.Tracker.List(ctx)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCheckPending, err)
+	}
+	pending := make(map[string]bool)
+	for _, p := range ps {
+		if !p.Failed {
+			pending[hex.EncodeToString(p.BTCTransactionID)] = true
+		}
+	}
+	// Check oldest first, so that a parent is rebroadcast before its children.
+	var lastErr error
+	var failed []*PendingAnchor
+	for _, p := range ps {
+		if !p.Failed {
+			if err := g.checkPendingAnchor(ctx, p, pending); err != nil {
+				log.Printf("CheckPendingAnchors: %v (btctx=%s)", err, hex.EncodeToString(p.BTCTransactionID))
+				lastErr = err
+				continue
+			}
+		}
+		if p.Failed {
+			failed = append(failed, p)
+		}
+	}
+	for _, p := range failed {
+		if err := g.reanchor(ctx, p); err != nil {
+			log.Printf("CheckPendingAnchors: %v (btctx=%s)", err, hex.EncodeToString(p.BTCTransactionID))
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return fmt.Errorf("%w (%v)", ErrCouldNotCheckPending, lastErr)
+	}
+	return nil
+}
+
+// checkPendingAnchor checks p and updates g.Tracker.
+// pending contains transaction IDs of unfailed PendingAnchors in hex.
+func (g *GatewayImpl) checkPendingAnchor(ctx context.Context, p *PendingAnchor, pending map[string]bool) error {
+	btctx := hex.EncodeToString(p.BTCTransactionID)
+	p.LastChecked = timeNow()
+
+	// Check confirmations by the wallet. The wallet may not know the transaction.
+	txJSON, err := g.xBTCImpl.GetTransaction(ctx, p.BTCTransactionID)
+	if err != nil && !errors.Is(err, btc.ErrInvalidTransactionID) {
+		return err
+	}
+	if err == nil {
+		depth, err := g.xBTCImpl.ParseWalletTransactionDepth(txJSON)
+		if err != nil {
+			return err
+		}
+		if depth >= pendingConfirmations {
+			if err := g.Tracker.Delete(ctx, p.BTCTransactionID); err != nil {
+				return err
+			}
+			delete(pending, btctx)
+			return nil
+		}
+		if depth < 0 {
+			return g.failPendingAnchor(ctx, p, pending, fmt.Sprintf("conflicted (confirmations=%d)", depth))
+		}
+	}
+
+	// Not confirmed yet, so it should be in the mempool.
+	_, err = g.xBTCImpl.GetMempoolEntry(ctx, p.BTCTransactionID)
+	if err == nil {
+		return g.Tracker.Put(ctx, p)
+	}
+	if !errors.Is(err, btc.ErrTxNotInMempool) {
+		return err
+	}
+
+	// Dropped from the mempool, rebroadcast it.
+	_, err = g.xBTCImpl.BroadcastTransaction(ctx, p.RawTransaction)
+	switch {
+	case err == nil, errors.Is(err, btc.ErrTxAlreadyExists):
+		p.Rebroadcasts++
+		log.Printf("CheckPendingAnchors: rebroadcast (btctx=%s, count=%d)", btctx, p.Rebroadcasts)
+		return g.Tracker.Put(ctx, p)
+	case errors.Is(err, btc.ErrTxMissingInputs), errors.Is(err, btc.ErrTxAlreadySpent):
+		// The input is missing because the parent is also dropped, wait for it to be rebroadcast.
+		if pending[hex.EncodeToString(p.FromTransactionID)] {
+			return g.Tracker.Put(ctx, p)
+		}
+		// Otherwise, the input has been spent by a conflicting transaction.
+		return g.failPendingAnchor(ctx, p, pending, err.Error())
+	default:
+		return err
+	}
+}
+
+func (g *GatewayImpl) failPendingAnchor(ctx context.Context, p *PendingAnchor, pending map[string]bool, reason string) error {
+	p.Failed = true
+	p.FailReason = reason
+	delete(pending, hex.EncodeToString(p.BTCTransactionID))
+	log.Printf("CheckPendingAnchors: failed (btctx=%s, reason=%s)", hex.EncodeToString(p.BTCTransactionID), reason)
+	// Let clients know until re-anchoring replaces the AnchorRecord.
+	status := model.AnchorFailed
+	u := &store.RecordUpdate{Status: &status, StatusReason: reason, LastChecked: p.LastChecked}
+	ar, err := g.Store.Get(ctx, p.BBc1DomainID, p.BBc1TransactionID)
+	if err == nil {
+		err = g.updateRecord(ctx, ar, u)
+	}
+	if err != nil {
+		log.Printf("CheckPendingAnchors: %v (btctx=%s)", err, hex.EncodeToString(p.BTCTransactionID))
+	}
+	return g.Tracker.Put(ctx, p)
+}
+
+// reanchor anchors the digest of the failed p again,
+// replaces the AnchorRecord in g.Store, and stops tracking p.
+// The JournalEntry of p is started again, so the new transaction is never p itself.
+func (g *GatewayImpl) reanchor(ctx context.Context, p *PendingAnchor) error {
+	// Already re-anchored if the AnchorRecord has another transaction, e.g. failed to stop tracking p.
+	if ar, err := g.Store.Get(ctx, p.BBc1DomainID, p.BBc1TransactionID); err == nil && !bytes.Equal(ar.BTCTransactionID, p.BTCTransactionID) {
+		return g.Tracker.Delete(ctx, p.BTCTransactionID)
+	}
+	txid, err := g.registerTransaction(ctx, p.BBc1DomainID, p.BBc1TransactionID, "", "", nil, p.BTCTransactionID)
+	if err != nil {
+		return err
+	}
+	log.Printf("CheckPendingAnchors: re-anchored (btctx=%s, new=%s)", hex.EncodeToString(p.BTCTransactionID), hex.EncodeToString(txid))
+	if err := g.Tracker.Delete(ctx, p.BTCTransactionID); err != nil {
+		return err
+	}
+	_, err = g.storeAnchorRecord(ctx, txid, "", nil)
+	return err
+}
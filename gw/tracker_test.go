@@ -0,0 +1,156 @@
+package gw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ebiiim/btcgw/btc"
+	"github.com/ebiiim/btcgw/model"
+)
+
+// mustListPending returns PendingAnchors in g.Tracker by the transaction ID.
+func mustListPending(t *testing.T, g *GatewayImpl) map[string]*PendingAnchor {
+	t.Helper()
+	ps, err := g.Tracker.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[string]*PendingAnchor)
+	for _, p := range ps {
+		m[string(p.BTCTransactionID)] = p
+	}
+	return m
+}
+
+func TestGatewayImpl_CheckPendingAnchors_Confirmed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	ar := mustRegister(t, g, "")
+
+	// Still in the mempool.
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	p := mustListPending(t, g)[string(ar.BTCTransactionID)]
+	if p == nil || p.Failed || p.Rebroadcasts != 0 || p.LastChecked.IsZero() {
+		t.Fatalf("got %+v", p)
+	}
+
+	n.confirm(ar.BTCTransactionID, pendingConfirmations)
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ps := mustListPending(t, g); len(ps) != 0 {
+		t.Errorf("got %d pending anchors want 0", len(ps))
+	}
+}
+
+func TestGatewayImpl_CheckPendingAnchors_Rebroadcast(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	ar := mustRegister(t, g, "")
+
+	n.drop(ar.BTCTransactionID)
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	p := mustListPending(t, g)[string(ar.BTCTransactionID)]
+	if p == nil || p.Failed || p.Rebroadcasts != 1 {
+		t.Fatalf("got %+v", p)
+	}
+	if !n.tx(ar.BTCTransactionID).inMempool {
+		t.Error("not rebroadcast")
+	}
+	if n.broadcasts != 2 {
+		t.Errorf("broadcasts: got %d want 2", n.broadcasts)
+	}
+}
+
+func TestGatewayImpl_CheckPendingAnchors_MissingParent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	parent := mustRegister(t, g, "")
+	child := mustRegister(t, g, "")
+	if !bytes.Equal(n.tx(child.BTCTransactionID).from, parent.BTCTransactionID) {
+		t.Fatal("the child does not spend the change of the parent")
+	}
+
+	// Both are dropped, and the parent cannot be rebroadcast for now.
+	n.drop(parent.BTCTransactionID)
+	n.drop(child.BTCTransactionID)
+	n.failBroadcast(parent.BTCTransactionID, btc.ErrFailedToExec)
+	if err := g.CheckPendingAnchors(ctx); !errors.Is(err, ErrCouldNotCheckPending) {
+		t.Fatalf("got %v want %v", err, ErrCouldNotCheckPending)
+	}
+	// The child waits for the parent instead of failing.
+	ps := mustListPending(t, g)
+	for _, ar := range []*model.AnchorRecord{parent, child} {
+		if p := ps[string(ar.BTCTransactionID)]; p == nil || p.Failed || p.Rebroadcasts != 0 {
+			t.Fatalf("got %+v", p)
+		}
+	}
+
+	n.failBroadcast(parent.BTCTransactionID, nil)
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ps = mustListPending(t, g)
+	for _, ar := range []*model.AnchorRecord{parent, child} {
+		if p := ps[string(ar.BTCTransactionID)]; p == nil || p.Failed || p.Rebroadcasts != 1 {
+			t.Errorf("got %+v", p)
+		}
+		if !n.tx(ar.BTCTransactionID).inMempool {
+			t.Errorf("not rebroadcast %x", ar.BTCTransactionID)
+		}
+	}
+}
+
+func TestGatewayImpl_CheckPendingAnchors_Reanchor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	g, n := newTestGateway(t)
+	ar := mustRegister(t, g, "")
+	domID, txID := ar.Anchor.BBc1DomainID[:], ar.Anchor.BBc1TransactionID[:]
+
+	// Conflicted. Re-anchoring fails as the next UTXO is the change of the conflicted transaction.
+	n.confirm(ar.BTCTransactionID, -1)
+	if err := g.CheckPendingAnchors(ctx); !errors.Is(err, ErrCouldNotCheckPending) {
+		t.Fatalf("got %v want %v", err, ErrCouldNotCheckPending)
+	}
+	p := mustListPending(t, g)[string(ar.BTCTransactionID)]
+	if p == nil || !p.Failed || !strings.HasPrefix(p.FailReason, "conflicted") {
+		t.Fatalf("got %+v", p)
+	}
+	failed, err := g.GetRecord(ctx, domID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != model.AnchorFailed || failed.StatusReason != p.FailReason {
+		t.Errorf("got status=%v reason=%q", failed.Status, failed.StatusReason)
+	}
+
+	// Re-anchored with a new UTXO, and the AnchorRecord is replaced.
+	if err := g.Wallet.ReplaceNextUTXO(randBytes(t), "addr1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CheckPendingAnchors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err := g.GetRecord(ctx, domID, txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got.BTCTransactionID, ar.BTCTransactionID) || got.Status != model.AnchorBroadcast {
+		t.Errorf("got btctx=%x status=%v", got.BTCTransactionID, got.Status)
+	}
+	ps := mustListPending(t, g)
+	if p := ps[string(got.BTCTransactionID)]; len(ps) != 1 || p == nil || p.Failed {
+		t.Errorf("got %d pending anchors want only the new one", len(ps))
+	}
+}
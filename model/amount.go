@@ -0,0 +1,94 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Errors
+var (
+	ErrInvalidAmount = errors.New("ErrInvalidAmount")
+)
+
+// Amount represents an amount of bitcoin in satoshi.
+// Use this instead of float64 as BTC amounts must be exact.
+type Amount int64
+
+// Units and limits of Amount.
+const (
+	Satoshi   Amount = 1
+	BTC       Amount = 100_000_000
+	MaxAmount Amount = 21_000_000 * BTC
+)
+
+// ParseBTC parses a decimal BTC string such as "0.01158624" into an Amount.
+// Up to 8 decimal places are accepted and the absolute value must not exceed MaxAmount.
+// Exponents (e.g. "1e-8") are not accepted as bitcoin-cli never prints them.
+//
+// Possible errors: ErrInvalidAmount
+func ParseBTC(s string) (Amount, error) {
+	str := s
+	neg := false
+	if strings.HasPrefix(str, "-") {
+		neg = true
+		str = str[1:]
+	}
+	intPart, fracPart := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		intPart, fracPart = str[:i], str[i+1:]
+		if fracPart == "" {
+			return 0, fmt.Errorf("%w (%q)", ErrInvalidAmount, s)
+		}
+	}
+	if intPart == "" || len(fracPart) > 8 || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("%w (%q)", ErrInvalidAmount, s)
+	}
+	// 21,000,000 has 8 digits, so the integer part must fit in 8 digits after trimming zeros.
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(intPart) > 8 {
+		return 0, fmt.Errorf("%w (%q)", ErrInvalidAmount, s)
+	}
+	var btc, sat int64
+	if intPart != "" {
+		btc, _ = strconv.ParseInt(intPart, 10, 64) // always nil
+	}
+	if fracPart != "" {
+		sat, _ = strconv.ParseInt(fracPart+strings.Repeat("0", 8-len(fracPart)), 10, 64) // always nil
+	}
+	a := Amount(btc)*BTC + Amount(sat)
+	if a > MaxAmount {
+		return 0, fmt.Errorf("%w (%q)", ErrInvalidAmount, s)
+	}
+	if neg {
+		a = -a
+	}
+	return a, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the Amount in BTC with 8 decimal places, e.g. "0.01158624".
+// The result can be passed to bitcoin-cli as it is.
+func (a Amount) String() string {
+	sign := ""
+	u := uint64(a)
+	if a < 0 {
+		sign = "-"
+		u = uint64(-a)
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, u/uint64(BTC), u%uint64(BTC))
+}
+
+// Satoshi returns the Amount in satoshi.
+func (a Amount) Satoshi() int64 {
+	return int64(a)
+}
@@ -32,6 +32,16 @@ func (n BTCNet) String() string {
 	}
 }
 
+// ParseBTCNet returns the BTCNet named s, that is the result of BTCNet.String.
+func ParseBTCNet(s string) (BTCNet, error) {
+	for _, n := range []BTCNet{BTCMainnet, BTCTestnet3, BTCTestnet4} {
+		if n.String() == s {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown network %q", s)
+}
+
 // Anchor contains an anchor that can be encoded to OP_RETURN.
 type Anchor struct {
 	Version           uint8
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Errors
+var (
+	ErrInvalidDomain = errors.New("ErrInvalidDomain")
+)
+
+// FeeTier selects the fee of anchor transactions.
+type FeeTier string
+
+// Fee tiers. The amount of each tier is decided by package btc.
+const (
+	// FeeDefault uses the default fee of the Gateway.
+	FeeDefault FeeTier = ""
+	FeeSmall   FeeTier = "small"
+	FeeNormal  FeeTier = "normal"
+	FeeLarge   FeeTier = "large"
+)
+
+// KeyType is the type of the public key of a BBc-1 domain, as KeyType of bbclib.
+type KeyType string
+
+// Key types.
+const (
+	KeyNone      KeyType = ""
+	KeySecp256k1 KeyType = "secp256k1" // ECDSA secp256k1, the default of BBc-1
+	KeyP256      KeyType = "p256"      // ECDSA P-256 (prime256v1)
+)
+
+// Limits of Domain.
+const (
+	MaxDomainNameLen  = 255 // in characters
+	MaxDomainOwnerLen = 255 // in characters
+)
+
+// Domain contains settings of a BBc-1 domain that the Gateway applies to its anchors.
+// Like BBc1DomainName in AnchorRecord, it is NOT included in Bitcoin.
+type Domain struct {
+	BBc1DomainID []byte
+
+	// Name is the display name, e.g. "example.com".
+	Name string
+	// Owner is the contact of the owner, e.g. an email address.
+	Owner string
+
+	// AllowedNetworks limits the Bitcoin networks to anchor. Empty allows all.
+	AllowedNetworks []BTCNet
+	// DailyQuota is the maximum number of anchors per day in UTC. 0 means unlimited.
+	DailyQuota uint
+	// MonthlyQuota is the maximum number of anchors per month in UTC. 0 means unlimited.
+	MonthlyQuota uint
+	// DailyBudget is the maximum fee spent per day in UTC. 0 means unlimited.
+	DailyBudget Amount
+	// MonthlyBudget is the maximum fee spent per month in UTC. 0 means unlimited.
+	MonthlyBudget Amount
+	// FeeTier is the fee of anchor transactions.
+	FeeTier FeeTier
+
+	// PublicKey is the public key of the domain in SEC 1 form, 33 bytes compressed or 65 bytes uncompressed,
+	// and KeyType is its type. The owner of the domain proves the ownership with it to get API keys.
+	PublicKey []byte
+	KeyType   KeyType
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Validate checks the fields of d:
+//   - BBc1DomainID is 32 bytes.
+//   - Name and Owner are UTF-8 without control characters, and within the limits.
+//   - AllowedNetworks and FeeTier are known ones.
+//   - DailyBudget and MonthlyBudget are not negative.
+//   - PublicKey is in SEC 1 form and KeyType is a known one if set, or both are not set.
+func (d *Domain) Validate() error {
+	if len(d.BBc1DomainID) != 32 {
+		return fmt.Errorf("%w (domain ID: %d bytes)", ErrInvalidDomain, len(d.BBc1DomainID))
+	}
+	if !validDomainText(d.Name, MaxDomainNameLen) {
+		return fmt.Errorf("%w (name)", ErrInvalidDomain)
+	}
+	if !validDomainText(d.Owner, MaxDomainOwnerLen) {
+		return fmt.Errorf("%w (owner)", ErrInvalidDomain)
+	}
+	for _, n := range d.AllowedNetworks {
+		if n.String() == "" {
+			return fmt.Errorf("%w (network %d)", ErrInvalidDomain, n)
+		}
+	}
+	switch d.FeeTier {
+	case FeeDefault, FeeSmall, FeeNormal, FeeLarge:
+	default:
+		return fmt.Errorf("%w (fee tier %q)", ErrInvalidDomain, d.FeeTier)
+	}
+	if d.DailyBudget < 0 || d.MonthlyBudget < 0 {
+		return fmt.Errorf("%w (negative budget)", ErrInvalidDomain)
+	}
+	switch d.KeyType {
+	case KeyNone:
+		if len(d.PublicKey) != 0 {
+			return fmt.Errorf("%w (public key without key type)", ErrInvalidDomain)
+		}
+	case KeySecp256k1, KeyP256:
+		if !validSEC1(d.PublicKey) {
+			return fmt.Errorf("%w (public key: %d bytes)", ErrInvalidDomain, len(d.PublicKey))
+		}
+	default:
+		return fmt.Errorf("%w (key type %q)", ErrInvalidDomain, d.KeyType)
+	}
+	return nil
+}
+
+// validSEC1 checks the length and the prefix of a public key in SEC 1 form.
+// Whether it is on the curve is checked when it is used.
+func validSEC1(pub []byte) bool {
+	switch len(pub) {
+	case 33:
+		return pub[0] == 0x02 || pub[0] == 0x03
+	case 65:
+		return pub[0] == 0x04
+	}
+	return false
+}
+
+// Budget returns the caps of d.
+func (d *Domain) Budget() Budget {
+	return Budget{
+		DailyAnchors:   d.DailyQuota,
+		MonthlyAnchors: d.MonthlyQuota,
+		DailySpend:     d.DailyBudget,
+		MonthlySpend:   d.MonthlyBudget,
+	}
+}
+
+func validDomainText(s string, max int) bool {
+	if !utf8.ValidString(s) || utf8.RuneCountInString(s) > max {
+		return false
+	}
+	for _, c := range s {
+		if unicode.IsControl(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsNetwork returns whether d allows anchoring to the Bitcoin network n.
+func (d *Domain) AllowsNetwork(n BTCNet) bool {
+	if len(d.AllowedNetworks) == 0 {
+		return true
+	}
+	for _, an := range d.AllowedNetworks {
+		if an == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Budget caps the number of anchors and the fee spent per day and month in UTC.
+// 0 means unlimited.
+type Budget struct {
+	DailyAnchors   uint
+	MonthlyAnchors uint
+	DailySpend     Amount
+	MonthlySpend   Amount
+}
+
+// IsZero returns whether b has no caps.
+func (b Budget) IsZero() bool {
+	return b == Budget{}
+}
+
+// StartOfDay returns the start of the day in UTC that t belongs to.
+// Daily caps are counted from it.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// StartOfMonth returns the start of the month in UTC that t belongs to.
+// Monthly caps are counted from it.
+func StartOfMonth(t time.Time) time.Time {
+	y, m, _ := t.UTC().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
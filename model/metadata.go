@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Errors
+var (
+	ErrInvalidMetadata = errors.New("ErrInvalidMetadata")
+)
+
+// Limits of Metadata.
+const (
+	MaxMetadataKeys     = 16
+	MaxMetadataKeyLen   = 64  // in bytes
+	MaxMetadataValueLen = 512 // in characters
+)
+
+// Metadata is typed key/value data attached to an AnchorRecord by applications,
+// e.g. {"source": "erp", "doctype": "invoice", "url": "https://example.com/invoices/1"}.
+// Like BBc1DomainName and Note, it is NOT included in Bitcoin.
+type Metadata map[string]string
+
+// Validate checks the limits of m:
+//   - At most MaxMetadataKeys keys.
+//   - Keys are 1 to MaxMetadataKeyLen characters of [A-Za-z0-9_.-].
+//   - Values are at most MaxMetadataValueLen characters of UTF-8 without control characters.
+func (m Metadata) Validate() error {
+	if len(m) > MaxMetadataKeys {
+		return fmt.Errorf("%w (%d keys, max %d)", ErrInvalidMetadata, len(m), MaxMetadataKeys)
+	}
+	for k, v := range m {
+		if !validMetadataKey(k) {
+			return fmt.Errorf("%w (key %q)", ErrInvalidMetadata, k)
+		}
+		if !utf8.ValidString(v) || utf8.RuneCountInString(v) > MaxMetadataValueLen {
+			return fmt.Errorf("%w (value of %q)", ErrInvalidMetadata, k)
+		}
+		for _, c := range v {
+			if unicode.IsControl(c) {
+				return fmt.Errorf("%w (value of %q)", ErrInvalidMetadata, k)
+			}
+		}
+	}
+	return nil
+}
+
+func validMetadataKey(k string) bool {
+	if len(k) == 0 || len(k) > MaxMetadataKeyLen {
+		return false
+	}
+	for _, c := range k {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '.', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Match returns whether m has all keys in filter,
+// and the values are same as in filter unless they are "" (any value).
+func (m Metadata) Match(filter Metadata) bool {
+	for k, v := range filter {
+		mv, ok := m[k]
+		if !ok || (v != "" && v != mv) {
+			return false
+		}
+	}
+	return true
+}
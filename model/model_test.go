@@ -3,6 +3,7 @@ package model_test
 import (
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -165,7 +166,7 @@ func TestNewAnchorRecord(t *testing.T) {
 		note        string
 		want        *model.AnchorRecord
 	}{
-		{"normal", normalAnchor, btctx1, ts1, 1500, domName1, note1, &model.AnchorRecord{normalAnchor, btctx1, ts1, 1500, domName1, note1}},
+		{"normal", normalAnchor, btctx1, ts1, 1500, domName1, note1, &model.AnchorRecord{Anchor: normalAnchor, BTCTransactionID: btctx1, TransactionTime: ts1, Confirmations: 1500, Status: model.AnchorFinal, BBc1DomainName: domName1, Note: note1}},
 	}
 	for _, c := range cases {
 		c := c
@@ -178,3 +179,313 @@ func TestNewAnchorRecord(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBTC(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name  string
+		input string
+		want  model.Amount
+	}{
+		{"zero", "0", 0},
+		{"zero_8", "0.00000000", 0},
+		{"one_satoshi", "0.00000001", 1},
+		{"minus_one_satoshi", "-0.00000001", -1},
+		{"fee", "0.0002", 20_000},
+		{"received", "0.01158624", 1158624},
+		{"one_btc", "1", model.BTC},
+		{"leading_zeros", "0001.10000000", 110_000_000},
+		{"max", "21000000", model.MaxAmount},
+		{"max_8", "21000000.00000000", model.MaxAmount},
+		{"minus_max", "-21000000.00000000", -model.MaxAmount},
+		{"max_minus_one_satoshi", "20999999.99999999", model.MaxAmount - 1},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := model.ParseBTC(c.input)
+			if err != nil {
+				t.Error(err)
+				t.Skip()
+			}
+			if got != c.want {
+				t.Errorf("got %d but want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseBTC_Error(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"minus_only", "-"},
+		{"dot_only", "."},
+		{"no_int", ".5"},
+		{"no_frac", "1."},
+		{"too_precise", "0.000000001"},
+		{"exponent", "21e6"},
+		{"plus", "+1"},
+		{"space", " 1"},
+		{"over_max", "21000000.00000001"},
+		{"over_max_int", "100000000"},
+		{"overflow", "99999999999999999999"},
+		{"not_number", "1a"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := model.ParseBTC(c.input); !errors.Is(err, model.ErrInvalidAmount) {
+				t.Errorf("got %+v but want %+v", err, model.ErrInvalidAmount)
+			}
+		})
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name  string
+		input model.Amount
+		want  string
+	}{
+		{"zero", 0, "0.00000000"},
+		{"one_satoshi", 1, "0.00000001"},
+		{"minus_one_satoshi", -1, "-0.00000001"},
+		{"fee", 20_000 * model.Satoshi, "0.00020000"},
+		{"one_btc", model.BTC, "1.00000000"},
+		{"max", model.MaxAmount, "21000000.00000000"},
+		{"minus_max", -model.MaxAmount, "-21000000.00000000"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			got := c.input.String()
+			if got != c.want {
+				t.Errorf("got %s but want %s", got, c.want)
+			}
+			// round trip
+			if a, err := model.ParseBTC(got); err != nil || a != c.input {
+				t.Errorf("round trip: got %d (%v) but want %d", a, err, c.input)
+			}
+		})
+	}
+}
+
+func TestStatusOfConfirmations(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name  string
+		input uint
+		want  model.AnchorStatus
+	}{
+		{"unconfirmed", 0, model.AnchorBroadcast},
+		{"confirmed", 1, model.AnchorConfirmed},
+		{"almost_final", model.FinalConfirmations - 1, model.AnchorConfirmed},
+		{"final", model.FinalConfirmations, model.AnchorFinal},
+		{"deep", 823, model.AnchorFinal},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := model.StatusOfConfirmations(c.input); got != c.want {
+				t.Errorf("got %s but want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAnchorRecord_Refresh(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1612449916, 0)
+	cases := []struct {
+		name       string
+		conf       uint
+		status     model.AnchorStatus
+		reason     string
+		newConf    uint
+		wantStatus model.AnchorStatus
+		wantReason string
+	}{
+		{"broadcast", 0, model.AnchorBroadcast, "", 0, model.AnchorBroadcast, ""},
+		{"confirmed", 0, model.AnchorBroadcast, "", 1, model.AnchorConfirmed, ""},
+		{"final", 5, model.AnchorConfirmed, "", model.FinalConfirmations, model.AnchorFinal, ""},
+		{"reorged", 3, model.AnchorConfirmed, "", 0, model.AnchorReorged, "confirmations dropped from 3 to 0"},
+		{"still_reorged", 0, model.AnchorReorged, "hoge", 0, model.AnchorReorged, "hoge"},
+		{"reconfirmed", 0, model.AnchorReorged, "hoge", 1, model.AnchorConfirmed, ""},
+		{"still_failed", 0, model.AnchorFailed, "conflicted", 0, model.AnchorFailed, "conflicted"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			r := &model.AnchorRecord{Confirmations: c.conf, Status: c.status, StatusReason: c.reason}
+			r.Refresh(c.newConf, now)
+			if r.Status != c.wantStatus || r.StatusReason != c.wantReason {
+				t.Errorf("got %s (%s) but want %s (%s)", r.Status, r.StatusReason, c.wantStatus, c.wantReason)
+			}
+			if r.Confirmations != c.newConf || !r.LastChecked.Equal(now) {
+				t.Errorf("got %d at %s but want %d at %s", r.Confirmations, r.LastChecked, c.newConf, now)
+			}
+		})
+	}
+}
+
+func TestMetadata_Validate(t *testing.T) {
+	t.Parallel()
+	many := model.Metadata{}
+	for i := 0; i <= model.MaxMetadataKeys; i++ {
+		many[string(rune('a'+i))] = ""
+	}
+	cases := []struct {
+		name  string
+		input model.Metadata
+		want  error
+	}{
+		{"nil", nil, nil},
+		{"normal", model.Metadata{"source": "erp", "doc.type": "invoice", "ref_url": "https://example.com/a?b=c", "ja": "請求書"}, nil},
+		{"empty_value", model.Metadata{"k": ""}, nil},
+		{"empty_key", model.Metadata{"": "v"}, model.ErrInvalidMetadata},
+		{"invalid_key", model.Metadata{"a b": "v"}, model.ErrInvalidMetadata},
+		{"long_key", model.Metadata{strings.Repeat("k", model.MaxMetadataKeyLen+1): "v"}, model.ErrInvalidMetadata},
+		{"long_value", model.Metadata{"k": strings.Repeat("値", model.MaxMetadataValueLen+1)}, model.ErrInvalidMetadata},
+		{"max_value", model.Metadata{"k": strings.Repeat("値", model.MaxMetadataValueLen)}, nil},
+		{"control", model.Metadata{"k": "a\nb"}, model.ErrInvalidMetadata},
+		{"invalid_utf8", model.Metadata{"k": "\xff"}, model.ErrInvalidMetadata},
+		{"too_many", many, model.ErrInvalidMetadata},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := c.input.Validate(); !errors.Is(got, c.want) {
+				t.Errorf("got %v but want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMetadata_Match(t *testing.T) {
+	t.Parallel()
+	m := model.Metadata{"source": "erp", "doctype": "invoice"}
+	cases := []struct {
+		name   string
+		filter model.Metadata
+		want   bool
+	}{
+		{"nil", nil, true},
+		{"value", model.Metadata{"source": "erp"}, true},
+		{"values", model.Metadata{"source": "erp", "doctype": "invoice"}, true},
+		{"key", model.Metadata{"doctype": ""}, true},
+		{"wrong_value", model.Metadata{"source": "crm"}, false},
+		{"no_key", model.Metadata{"url": ""}, false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := m.Match(c.filter); got != c.want {
+				t.Errorf("got %v but want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDomain_Validate(t *testing.T) {
+	t.Parallel()
+	domID := make([]byte, 32)
+	cases := []struct {
+		name  string
+		input model.Domain
+		want  error
+	}{
+		{"minimum", model.Domain{BBc1DomainID: domID}, nil},
+		{"full", model.Domain{BBc1DomainID: domID, Name: "example.com", Owner: "admin@example.com",
+			AllowedNetworks: []model.BTCNet{model.BTCTestnet3}, DailyQuota: 100, FeeTier: model.FeeLarge}, nil},
+		{"short_id", model.Domain{BBc1DomainID: domID[:31]}, model.ErrInvalidDomain},
+		{"long_name", model.Domain{BBc1DomainID: domID, Name: strings.Repeat("名", model.MaxDomainNameLen+1)}, model.ErrInvalidDomain},
+		{"control_owner", model.Domain{BBc1DomainID: domID, Owner: "a\tb"}, model.ErrInvalidDomain},
+		{"unknown_network", model.Domain{BBc1DomainID: domID, AllowedNetworks: []model.BTCNet{2}}, model.ErrInvalidDomain},
+		{"unknown_tier", model.Domain{BBc1DomainID: domID, FeeTier: "huge"}, model.ErrInvalidDomain},
+		{"budget", model.Domain{BBc1DomainID: domID, MonthlyQuota: 1000, DailyBudget: 100000, MonthlyBudget: 1000000}, nil},
+		{"negative_budget", model.Domain{BBc1DomainID: domID, MonthlyBudget: -1}, model.ErrInvalidDomain},
+		{"public_key_bad_prefix", model.Domain{BBc1DomainID: domID, PublicKey: append([]byte{0x04}, domID...), KeyType: model.KeyP256}, model.ErrInvalidDomain},
+		{"public_key_uncompressed", model.Domain{BBc1DomainID: domID, PublicKey: append(append([]byte{0x04}, domID...), domID...), KeyType: model.KeyP256}, nil},
+		{"public_key_compressed", model.Domain{BBc1DomainID: domID, PublicKey: append([]byte{0x02}, domID...), KeyType: model.KeySecp256k1}, nil},
+		{"public_key_no_type", model.Domain{BBc1DomainID: domID, PublicKey: append([]byte{0x02}, domID...)}, model.ErrInvalidDomain},
+		{"unknown_key_type", model.Domain{BBc1DomainID: domID, PublicKey: append([]byte{0x02}, domID...), KeyType: "rsa"}, model.ErrInvalidDomain},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			if got := c.input.Validate(); !errors.Is(got, c.want) {
+				t.Errorf("got %v but want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDomain_AllowsNetwork(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name     string
+		networks []model.BTCNet
+		input    model.BTCNet
+		want     bool
+	}{
+		{"any", nil, model.BTCMainnet, true},
+		{"allowed", []model.BTCNet{model.BTCTestnet3, model.BTCMainnet}, model.BTCMainnet, true},
+		{"not_allowed", []model.BTCNet{model.BTCTestnet3}, model.BTCMainnet, false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			d := &model.Domain{AllowedNetworks: c.networks}
+			if got := d.AllowsNetwork(c.input); got != c.want {
+				t.Errorf("got %v but want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseBTCNet(t *testing.T) {
+	t.Parallel()
+	for _, n := range []model.BTCNet{model.BTCMainnet, model.BTCTestnet3, model.BTCTestnet4} {
+		got, err := model.ParseBTCNet(n.String())
+		if err != nil || got != n {
+			t.Errorf("got %v, %v but want %v", got, err, n)
+		}
+	}
+	if _, err := model.ParseBTCNet("Regtest"); err == nil {
+		t.Error("got nil but want error")
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	t.Parallel()
+	jst := time.FixedZone("JST", 9*60*60)
+	got := model.StartOfDay(time.Date(2021, 2, 5, 8, 0, 0, 0, jst))
+	want := time.Date(2021, 2, 4, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("got %v but want %v", got, want)
+	}
+}
+
+func TestStartOfMonth(t *testing.T) {
+	t.Parallel()
+	jst := time.FixedZone("JST", 9*60*60)
+	got := model.StartOfMonth(time.Date(2021, 3, 1, 8, 0, 0, 0, jst))
+	want := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("got %v but want %v", got, want)
+	}
+}
@@ -16,9 +16,27 @@ type AnchorRecord struct {
 	TransactionTime time.Time
 	Confirmations   uint
 
+	// Cost of the Bitcoin transaction. Fee is InputAmount minus ChangeAmount, and VSize is in vbytes.
+	// They are zero if unknown, e.g. stored by older versions.
+	Fee          Amount
+	InputAmount  Amount
+	ChangeAmount Amount
+	VSize        uint
+
+	// Status is the AnchorStatus derived from Confirmations and the history of checks.
+	// StatusReason describes why, and is set if Status is AnchorFailed or AnchorReorged.
+	// LastChecked is the time Confirmations was checked.
+	Status       AnchorStatus
+	StatusReason string
+	LastChecked  time.Time
+
 	// Optional data NOT included in Bitcoin.
 	BBc1DomainName string
 	Note           string
+	Metadata       Metadata
+	// APIKeyID identifies the API key that registered the anchor, or "" if unknown.
+	// It is not the secret of the key.
+	APIKeyID string
 }
 
 // String returns a human-readable expression for the AnchorRecord.
@@ -30,9 +48,14 @@ func (r *AnchorRecord) String() string {
 	s += fmt.Sprintf("   BTCTransactionID: %x\n", r.BTCTransactionID)
 	s += fmt.Sprintf("    TransactionTime: %d | %s | 0x%016x\n", r.TransactionTime.Unix(), r.TransactionTime, r.TransactionTime.Unix())
 	s += fmt.Sprintf("      Confirmations: %d\n", r.Confirmations)
+	s += fmt.Sprintf("             Status: %s %s\n", r.Status, r.StatusReason)
+	s += fmt.Sprintf("        LastChecked: %s\n", r.LastChecked)
+	s += fmt.Sprintf("                Fee: %s (input %s, change %s, %d vB)\n", r.Fee, r.InputAmount, r.ChangeAmount, r.VSize)
 	s += "------------Optional------------\n"
 	s += fmt.Sprintf("     BBc1DomainName: %s\n", r.BBc1DomainName)
 	s += fmt.Sprintf("               Note: %s\n", r.Note)
+	s += fmt.Sprintf("           Metadata: %v\n", r.Metadata)
+	s += fmt.Sprintf("           APIKeyID: %s\n", r.APIKeyID)
 	s += "================================\n"
 	return s
 }
@@ -43,7 +66,7 @@ func (r *AnchorRecord) String() string {
 //   - anchor sets the Anchor
 //   - btctx sets the Bitcoin transaction ID in which anchor is embedded.
 //   - ts sets the time in Bitcoin transaction.
-//   - conf sets the number of confirmations.
+//   - conf sets the number of confirmations, and Status derived from it.
 //   - bbc1domName sets the BBc-1 domain name.
 //   - note sets a string for note.
 //
@@ -55,8 +78,28 @@ func NewAnchorRecord(anchor *Anchor, btctx []byte, ts time.Time, conf uint, bbc1
 		BTCTransactionID: btctx,
 		TransactionTime:  ts,
 		Confirmations:    conf,
+		Status:           StatusOfConfirmations(conf),
 		BBc1DomainName:   bbc1domName,
 		Note:             note,
 	}
 	return r
 }
+
+// Refresh sets conf confirmations checked at t, and updates Status and StatusReason:
+//   - AnchorReorged if r has been confirmed and conf is 0.
+//   - Unchanged if r is AnchorReorged or AnchorFailed and conf is 0.
+//   - Otherwise derived from conf (see StatusOfConfirmations).
+func (r *AnchorRecord) Refresh(conf uint, t time.Time) {
+	switch {
+	case conf == 0 && r.Confirmations > 0:
+		r.Status = AnchorReorged
+		r.StatusReason = fmt.Sprintf("confirmations dropped from %d to 0", r.Confirmations)
+	case conf == 0 && (r.Status == AnchorReorged || r.Status == AnchorFailed):
+		// Keep the reason.
+	default:
+		r.Status = StatusOfConfirmations(conf)
+		r.StatusReason = ""
+	}
+	r.Confirmations = conf
+	r.LastChecked = t
+}
@@ -0,0 +1,39 @@
+package model
+
+// AnchorStatus represents the progress of an anchor.
+type AnchorStatus string
+
+// Anchor statuses in order.
+const (
+	// AnchorQueued means the registration is accepted and waiting for a worker.
+	AnchorQueued AnchorStatus = "queued"
+	// AnchorPending means the anchor transaction is being created or sent.
+	AnchorPending AnchorStatus = "pending"
+	// AnchorBroadcast means the anchor transaction has been sent but not confirmed yet.
+	AnchorBroadcast AnchorStatus = "broadcast"
+	// AnchorConfirmed means the anchor transaction has been confirmed at least once.
+	AnchorConfirmed AnchorStatus = "confirmed"
+	// AnchorFinal means the anchor transaction has FinalConfirmations or more.
+	AnchorFinal AnchorStatus = "final"
+	// AnchorFailed means the registration has failed,
+	// or the anchor transaction can never be confirmed (e.g. conflicted).
+	AnchorFailed AnchorStatus = "failed"
+	// AnchorReorged means the anchor transaction had been confirmed but is no longer in the best chain.
+	AnchorReorged AnchorStatus = "reorged"
+)
+
+// FinalConfirmations is the number of confirmations to regard an anchor as final.
+// Change this before starting the Gateway to use another depth.
+var FinalConfirmations uint = 6
+
+// StatusOfConfirmations returns the AnchorStatus of an anchor transaction that has conf confirmations.
+func StatusOfConfirmations(conf uint) AnchorStatus {
+	switch {
+	case conf >= FinalConfirmations:
+		return AnchorFinal
+	case conf > 0:
+		return AnchorConfirmed
+	default:
+		return AnchorBroadcast
+	}
+}
@@ -0,0 +1,516 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+)
+
+// Errors
+var (
+	ErrUnsupportedFormat = errors.New("ErrUnsupportedFormat")
+	ErrInvalidArchive    = errors.New("ErrInvalidArchive")
+)
+
+// Archive formats.
+// Both of them have the columns of the SQL Store: binary IDs are hexadecimal strings
+// and times are Unix time in seconds (0 if not set), so archives do not depend on the Store or the time zone.
+const (
+	FormatJSONL = "jsonl" // JSON Lines, an object per line.
+	FormatCSV   = "csv"   // CSV with the header line.
+)
+
+// ArchiveFormatOf returns the archive format of path by the extension (.jsonl, .ndjson, or .csv).
+func ArchiveFormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".csv":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("%w (%s)", ErrUnsupportedFormat, path)
+	}
+}
+
+// archiveColumns is the CSV header, same as the JSON keys of archiveRecord.
+var archiveColumns = strings.Split(strings.ReplaceAll(sqlColumns, " ", ""), ",")
+
+// archiveColumnsV1 and archiveColumnsV2 are archiveColumns that older archives have:
+// V1 has no metadata, and V2 has no cost and API key.
+var (
+	archiveColumnsV1 = archiveColumns[:13]
+	archiveColumnsV2 = archiveColumns[:14]
+)
+
+type archiveRecord struct {
+	Domain         string `json:"domain"`
+	Digest         string `json:"digest"`
+	AnchorVersion  uint8  `json:"anchor_version"`
+	BTCNet         uint8  `json:"btcnet"`
+	AnchorTime     int64  `json:"anchor_time"`
+	BTCTx          string `json:"btctx"`
+	TxTime         int64  `json:"tx_time"`
+	Confirmations  uint   `json:"confirmations"`
+	Status         string `json:"status"`
+	StatusReason   string `json:"status_reason"`
+	LastChecked    int64  `json:"last_checked"`
+	BBc1DomainName string `json:"bbc1dom"`
+	Note           string `json:"note"`
+	// Metadata is omitted if empty, so AnchorRecords without it have the same line as older archives.
+	// It is a JSON object in CSV.
+	Metadata model.Metadata `json:"metadata,omitempty"`
+	// The following are omitted if zero like Metadata.
+	Fee          int64  `json:"fee,omitempty"`
+	InputAmount  int64  `json:"input_amount,omitempty"`
+	ChangeAmount int64  `json:"change_amount,omitempty"`
+	VSize        uint   `json:"vsize,omitempty"`
+	APIKeyID     string `json:"apikey,omitempty"`
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func timeOrZero(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+func newArchiveRecord(r *model.AnchorRecord) *archiveRecord {
+	status := r.Status
+	if status == "" {
+		status = model.StatusOfConfirmations(r.Confirmations)
+	}
+	return &archiveRecord{
+		Domain:         hex.EncodeToString(r.Anchor.BBc1DomainID[:]),
+		Digest:         hex.EncodeToString(r.Anchor.BBc1TransactionID[:]),
+		AnchorVersion:  r.Anchor.Version,
+		BTCNet:         uint8(r.Anchor.BTCNet),
+		AnchorTime:     unixOrZero(r.Anchor.Timestamp),
+		BTCTx:          hex.EncodeToString(r.BTCTransactionID),
+		TxTime:         unixOrZero(r.TransactionTime),
+		Confirmations:  r.Confirmations,
+		Status:         string(status),
+		StatusReason:   r.StatusReason,
+		LastChecked:    unixOrZero(r.LastChecked),
+		BBc1DomainName: r.BBc1DomainName,
+		Note:           r.Note,
+		Metadata:       r.Metadata,
+		Fee:            int64(r.Fee),
+		InputAmount:    int64(r.InputAmount),
+		ChangeAmount:   int64(r.ChangeAmount),
+		VSize:          r.VSize,
+		APIKeyID:       r.APIKeyID,
+	}
+}
+
+func (a *archiveRecord) anchorRecord() (*model.AnchorRecord, error) {
+	dom, err1 := hex.DecodeString(a.Domain)
+	dig, err2 := hex.DecodeString(a.Digest)
+	btctx, err3 := hex.DecodeString(a.BTCTx)
+	if err1 != nil || err2 != nil || err3 != nil || len(dom) != 32 || len(dig) != 32 {
+		return nil, fmt.Errorf("invalid hex (domain: %q, digest: %q, btctx: %q)", a.Domain, a.Digest, a.BTCTx)
+	}
+	if err := a.Metadata.Validate(); err != nil {
+		return nil, err
+	}
+	var did, txid [32]byte
+	copy(did[:], dom)
+	copy(txid[:], dig)
+	r := &model.AnchorRecord{
+		Anchor: &model.Anchor{
+			Version:           a.AnchorVersion,
+			BTCNet:            model.BTCNet(a.BTCNet),
+			Timestamp:         timeOrZero(a.AnchorTime),
+			BBc1DomainID:      did,
+			BBc1TransactionID: txid,
+		},
+		BTCTransactionID: btctx,
+		TransactionTime:  timeOrZero(a.TxTime),
+		Confirmations:    a.Confirmations,
+		Fee:              model.Amount(a.Fee),
+		InputAmount:      model.Amount(a.InputAmount),
+		ChangeAmount:     model.Amount(a.ChangeAmount),
+		VSize:            a.VSize,
+		Status:           model.AnchorStatus(a.Status),
+		StatusReason:     a.StatusReason,
+		LastChecked:      timeOrZero(a.LastChecked),
+		BBc1DomainName:   a.BBc1DomainName,
+		Note:             a.Note,
+		APIKeyID:         a.APIKeyID,
+	}
+	if len(a.Metadata) != 0 {
+		r.Metadata = a.Metadata
+	}
+	if r.Status == "" {
+		r.Status = model.StatusOfConfirmations(r.Confirmations)
+	}
+	return r, nil
+}
+
+// line returns a in JSON Lines.
+func (a *archiveRecord) line() []byte {
+	b, _ := json.Marshal(a) // never fails
+	return append(b, '\n')
+}
+
+func (a *archiveRecord) csv() []string {
+	meta := ""
+	if len(a.Metadata) != 0 {
+		b, _ := json.Marshal(a.Metadata) // never fails
+		meta = string(b)
+	}
+	return []string{
+		a.Domain, a.Digest,
+		strconv.Itoa(int(a.AnchorVersion)), strconv.Itoa(int(a.BTCNet)), strconv.FormatInt(a.AnchorTime, 10),
+		a.BTCTx, strconv.FormatInt(a.TxTime, 10), strconv.FormatUint(uint64(a.Confirmations), 10),
+		a.Status, a.StatusReason, strconv.FormatInt(a.LastChecked, 10),
+		a.BBc1DomainName, a.Note, meta,
+		strconv.FormatInt(a.Fee, 10), strconv.FormatInt(a.InputAmount, 10), strconv.FormatInt(a.ChangeAmount, 10),
+		strconv.FormatUint(uint64(a.VSize), 10), a.APIKeyID,
+	}
+}
+
+// parseCSVRecord parses fs in archiveColumns, archiveColumnsV1 or archiveColumnsV2.
+func parseCSVRecord(fs []string) (*archiveRecord, error) {
+	if len(fs) != len(archiveColumns) && len(fs) != len(archiveColumnsV1) && len(fs) != len(archiveColumnsV2) {
+		return nil, fmt.Errorf("%d fields (want %d)", len(fs), len(archiveColumns))
+	}
+	ver, err1 := strconv.ParseUint(fs[2], 10, 8)
+	net, err2 := strconv.ParseUint(fs[3], 10, 8)
+	ats, err3 := strconv.ParseInt(fs[4], 10, 64)
+	tts, err4 := strconv.ParseInt(fs[6], 10, 64)
+	conf, err5 := strconv.ParseUint(fs[7], 10, 64)
+	lc, err6 := strconv.ParseInt(fs[10], 10, 64)
+	for _, err := range []error{err1, err2, err3, err4, err5, err6} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	a := &archiveRecord{
+		Domain:         fs[0],
+		Digest:         fs[1],
+		AnchorVersion:  uint8(ver),
+		BTCNet:         uint8(net),
+		AnchorTime:     ats,
+		BTCTx:          fs[5],
+		TxTime:         tts,
+		Confirmations:  uint(conf),
+		Status:         fs[8],
+		StatusReason:   fs[9],
+		LastChecked:    lc,
+		BBc1DomainName: fs[11],
+		Note:           fs[12],
+	}
+	if len(fs) > 13 && fs[13] != "" {
+		if err := json.Unmarshal([]byte(fs[13]), &a.Metadata); err != nil {
+			return nil, fmt.Errorf("metadata: %v", err)
+		}
+	}
+	if len(fs) > 14 {
+		fee, err1 := strconv.ParseInt(fs[14], 10, 64)
+		input, err2 := strconv.ParseInt(fs[15], 10, 64)
+		change, err3 := strconv.ParseInt(fs[16], 10, 64)
+		vsize, err4 := strconv.ParseUint(fs[17], 10, 64)
+		for _, err := range []error{err1, err2, err3, err4} {
+			if err != nil {
+				return nil, err
+			}
+		}
+		a.Fee, a.InputAmount, a.ChangeAmount, a.VSize = fee, input, change, uint(vsize)
+		a.APIKeyID = fs[18]
+	}
+	return a, nil
+}
+
+// Checksum is a SHA-256 checksum of AnchorRecords in the order they are added.
+// It hashes each AnchorRecord in the JSON Lines archive format,
+// so it does not depend on the Store or the archive format.
+// e.g. a JSON Lines archive exported by ArchiveWriter has the same checksum as the file itself.
+type Checksum struct {
+	h hash.Hash
+	n int
+}
+
+func NewChecksum() *Checksum {
+	return &Checksum{
+		h: sha256.New(),
+		n: 0,
+	}
+}
+
+func (c *Checksum) Add(r *model.AnchorRecord) {
+	c.h.Write(newArchiveRecord(r).line())
+	c.n++
+}
+
+// Count returns the number of AnchorRecords added.
+func (c *Checksum) Count() int {
+	return c.n
+}
+
+// String returns the checksum in hexadecimal string.
+func (c *Checksum) String() string {
+	return hex.EncodeToString(c.h.Sum(nil))
+}
+
+// ScanChecksum returns the Checksum of all AnchorRecords in s.
+func ScanChecksum(ctx context.Context, s Scanner) (*Checksum, error) {
+	c := NewChecksum()
+	err := s.Scan(ctx, func(r *model.AnchorRecord) error {
+		c.Add(r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ArchiveWriter writes AnchorRecords to an archive.
+type ArchiveWriter struct {
+	format string
+	w      *bufio.Writer
+	csv    *csv.Writer
+	// sum is the checksum of the written bytes.
+	sum hash.Hash
+	c   *Checksum
+}
+
+// NewArchiveWriter initializes an ArchiveWriter that writes to w in the format.
+// Call Flush after writing all AnchorRecords.
+func NewArchiveWriter(w io.Writer, format string) (*ArchiveWriter, error) {
+	a := &ArchiveWriter{
+		format: format,
+		sum:    sha256.New(),
+		c:      NewChecksum(),
+	}
+	a.w = bufio.NewWriter(io.MultiWriter(w, a.sum))
+	switch format {
+	case FormatJSONL:
+	case FormatCSV:
+		a.csv = csv.NewWriter(a.w)
+		if err := a.csv.Write(archiveColumns); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w (%s)", ErrUnsupportedFormat, format)
+	}
+	return a, nil
+}
+
+// Put writes r.
+func (a *ArchiveWriter) Put(ctx context.Context, r *model.AnchorRecord) error {
+	ar := newArchiveRecord(r)
+	var err error
+	if a.csv != nil {
+		err = a.csv.Write(ar.csv())
+	} else {
+		_, err = a.w.Write(ar.line())
+	}
+	if err != nil {
+		return err
+	}
+	a.c.Add(r)
+	return nil
+}
+
+// Flush writes buffered data to the underlying io.Writer.
+func (a *ArchiveWriter) Flush() error {
+	if a.csv != nil {
+		a.csv.Flush()
+		if err := a.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return a.w.Flush()
+}
+
+// FileSum returns the SHA-256 checksum of the flushed bytes in hexadecimal string,
+// that can be verified by `sha256sum`.
+func (a *ArchiveWriter) FileSum() string {
+	return hex.EncodeToString(a.sum.Sum(nil))
+}
+
+// Checksum returns the Checksum of the written AnchorRecords.
+func (a *ArchiveWriter) Checksum() *Checksum {
+	return a.c
+}
+
+// ReadArchive reads the archive in the format from r, and calls f for each AnchorRecord in order.
+// It stops if f returns an error.
+func ReadArchive(r io.Reader, format string, f func(r *model.AnchorRecord) error) error {
+	switch format {
+	case FormatJSONL:
+		return readJSONL(r, f)
+	case FormatCSV:
+		return readCSV(r, f)
+	default:
+		return fmt.Errorf("%w (%s)", ErrUnsupportedFormat, format)
+	}
+}
+
+func readJSONL(r io.Reader, f func(r *model.AnchorRecord) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for n := 1; s.Scan(); n++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		d := json.NewDecoder(bytes.NewReader(line))
+		d.DisallowUnknownFields()
+		var a archiveRecord
+		if err := d.Decode(&a); err != nil {
+			return fmt.Errorf("%w (line %d: %v)", ErrInvalidArchive, n, err)
+		}
+		ar, err := a.anchorRecord()
+		if err != nil {
+			return fmt.Errorf("%w (line %d: %v)", ErrInvalidArchive, n, err)
+		}
+		if err := f(ar); err != nil {
+			return err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrInvalidArchive, err)
+	}
+	return nil
+}
+
+func readCSV(r io.Reader, f func(r *model.AnchorRecord) error) error {
+	c := csv.NewReader(r)
+	header, err := c.Read()
+	if err != nil {
+		return fmt.Errorf("%w (header: %v)", ErrInvalidArchive, err)
+	}
+	if h := strings.Join(header, ","); h != strings.Join(archiveColumns, ",") && h != strings.Join(archiveColumnsV1, ",") && h != strings.Join(archiveColumnsV2, ",") {
+		return fmt.Errorf("%w (header: %v)", ErrInvalidArchive, header)
+	}
+	c.FieldsPerRecord = len(header)
+	for n := 1; ; n++ {
+		fs, err := c.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w (%v)", ErrInvalidArchive, err)
+		}
+		a, err := parseCSVRecord(fs)
+		if err != nil {
+			return fmt.Errorf("%w (record %d: %v)", ErrInvalidArchive, n, err)
+		}
+		ar, err := a.anchorRecord()
+		if err != nil {
+			return fmt.Errorf("%w (record %d: %v)", ErrInvalidArchive, n, err)
+		}
+		if err := f(ar); err != nil {
+			return err
+		}
+	}
+}
+
+// ArchiveFile is a Scanner of the archive file at Path.
+// The format is detected by ArchiveFormatOf.
+type ArchiveFile struct {
+	Path string
+}
+
+var _ Scanner = (*ArchiveFile)(nil)
+
+// Scan reads AnchorRecords in the order in the file.
+func (a *ArchiveFile) Scan(ctx context.Context, f func(r *model.AnchorRecord) error) error {
+	format, err := ArchiveFormatOf(a.Path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return ReadArchive(file, format, f)
+}
+
+// DiffKind is the kind of a difference found by Diff.
+type DiffKind string
+
+// DiffKinds
+const (
+	DiffAdded   DiffKind = "+" // Only in the source.
+	DiffChanged DiffKind = "~" // In both but different.
+	DiffRemoved DiffKind = "-" // Only in the destination.
+)
+
+// Diff compares AnchorRecords in src and dst as in the archive formats,
+// and calls f with the AnchorRecord in src (or dst if DiffRemoved) for each difference.
+// Returns the number of identical AnchorRecords.
+// AnchorRecords in dst are loaded into memory.
+func Diff(ctx context.Context, src, dst Scanner, f func(kind DiffKind, r *model.AnchorRecord)) (int, error) {
+	dsts := make(map[string][]byte)
+	err := dst.Scan(ctx, func(r *model.AnchorRecord) error {
+		a := newArchiveRecord(r)
+		dsts[a.Domain+a.Digest] = a.line()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	same := 0
+	err = src.Scan(ctx, func(r *model.AnchorRecord) error {
+		a := newArchiveRecord(r)
+		cid := a.Domain + a.Digest
+		line, ok := dsts[cid]
+		switch {
+		case !ok:
+			f(DiffAdded, r)
+		case !bytes.Equal(line, a.line()):
+			f(DiffChanged, r)
+		default:
+			same++
+		}
+		delete(dsts, cid)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	cids := make([]string, 0, len(dsts))
+	for cid := range dsts {
+		cids = append(cids, cid)
+	}
+	sort.Strings(cids)
+	for _, cid := range cids {
+		var a archiveRecord
+		if err := json.Unmarshal(dsts[cid], &a); err != nil {
+			return 0, err
+		}
+		r, err := a.anchorRecord()
+		if err != nil {
+			return 0, err
+		}
+		f(DiffRemoved, r)
+	}
+	return same, nil
+}
@@ -0,0 +1,223 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+	"github.com/ebiiim/btcgw/util"
+)
+
+// archiveRecords returns AnchorRecords ordered by BBc-1 transaction ID.
+func archiveRecords() []*model.AnchorRecord {
+	ar2 := *ar1
+	a2 := *ar1.Anchor
+	a2.BBc1TransactionID = util.MustConvert32B(btctx1)
+	ar2.Anchor = &a2
+	ar2.Status = model.AnchorFailed
+	ar2.StatusReason = "conflicted, \"double spent\"\nretrying"
+	ar2.LastChecked = txts1
+	ar2.Note = ""
+	ar2.Metadata = model.Metadata{"source": "erp", "url": "https://example.com/?a=1,b=\"2\""}
+	ar2.Fee, ar2.InputAmount, ar2.ChangeAmount, ar2.VSize = 10000, 1168624, 1158624, 135
+	ar2.APIKeyID = "0123456789abcdef"
+	return []*model.AnchorRecord{ar1, &ar2}
+}
+
+func TestStore_Scan(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func(t *testing.T) testStore) {
+		s := newTestStore(t, newStore)
+		defer s.Close()
+		ctx := context.Background()
+
+		want := archiveRecords()
+		for i := len(want) - 1; i >= 0; i-- {
+			if err := s.Put(ctx, want[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var got []*model.AnchorRecord
+		if err := s.Scan(ctx, func(r *model.AnchorRecord) error {
+			got = append(got, r)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v but want %+v", got, want)
+		}
+
+		// Stops by f.
+		errStop := errors.New("stop")
+		n := 0
+		if err := s.Scan(ctx, func(r *model.AnchorRecord) error {
+			n++
+			return errStop
+		}); !errors.Is(err, errStop) || n != 1 {
+			t.Errorf("got %v (n=%d) but want %v", err, n, errStop)
+		}
+	})
+}
+
+func TestArchive(t *testing.T) {
+	for _, format := range []string{store.FormatJSONL, store.FormatCSV} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			want := archiveRecords()
+			var buf bytes.Buffer
+			w, err := store.NewArchiveWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range want {
+				if err := w.Put(context.Background(), r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(buf.Bytes())
+			if got := w.FileSum(); got != hex.EncodeToString(sum[:]) {
+				t.Errorf("FileSum: got %s but want %x", got, sum)
+			}
+			if format == store.FormatJSONL && w.FileSum() != w.Checksum().String() {
+				t.Errorf("Checksum of JSON Lines should be same with FileSum")
+			}
+
+			c := store.NewChecksum()
+			var got []*model.AnchorRecord
+			if err := store.ReadArchive(&buf, format, func(r *model.AnchorRecord) error {
+				c.Add(r)
+				got = append(got, r)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v but want %+v", got, want)
+			}
+			if c.String() != w.Checksum().String() || c.Count() != len(want) {
+				t.Errorf("Checksum: got %s (%d) but want %s (%d)", c, c.Count(), w.Checksum(), len(want))
+			}
+		})
+	}
+}
+
+func TestReadArchive_CSVWithoutMetadata(t *testing.T) {
+	// Written by older versions.
+	const input = "domain,digest,anchor_version,btcnet,anchor_time,btctx,tx_time,confirmations,status,status_reason,last_checked,bbc1dom,note\n" +
+		"456789abc0ef0123456089abcdef0023456789a0cdef0123406789abcde00123,56789abcd0f0123456709abcdef0103456789ab0def0123450789abcdef01234,255,3,1612449628,00,0,1,confirmed,,0,,\n"
+	n := 0
+	err := store.ReadArchive(strings.NewReader(input), store.FormatCSV, func(r *model.AnchorRecord) error {
+		n++
+		if r.Metadata != nil || r.Confirmations != 1 {
+			t.Errorf("got %+v", r)
+		}
+		return nil
+	})
+	if err != nil || n != 1 {
+		t.Errorf("got %v (n=%d)", err, n)
+	}
+}
+
+func TestReadArchive_Invalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		input  string
+		want   error
+	}{
+		{"jsonl_unknown_field", store.FormatJSONL, `{"foo":1}`, store.ErrInvalidArchive},
+		{"jsonl_invalid_id", store.FormatJSONL, `{"domain":"00","digest":"00"}`, store.ErrInvalidArchive},
+		{"jsonl_invalid_metadata", store.FormatJSONL, `{"domain":"` + strings.Repeat("00", 32) + `","digest":"` + strings.Repeat("00", 32) + `","metadata":{"a b":"c"}}`, store.ErrInvalidArchive},
+		{"csv_header", store.FormatCSV, "a,b\n", store.ErrInvalidArchive},
+		{"csv_empty", store.FormatCSV, "", store.ErrInvalidArchive},
+		{"unsupported", "xml", "", store.ErrUnsupportedFormat},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			err := store.ReadArchive(strings.NewReader(c.input), c.format, func(*model.AnchorRecord) error { return nil })
+			if !errors.Is(err, c.want) {
+				t.Errorf("got %v but want %v", err, c.want)
+			}
+		})
+	}
+}
+
+func TestArchiveFormatOf(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"a.jsonl", store.FormatJSONL},
+		{"dir/a.NDJSON", store.FormatJSONL},
+		{"a.csv", store.FormatCSV},
+		{"a.json", ""},
+	}
+	for _, c := range cases {
+		got, err := store.ArchiveFormatOf(c.path)
+		if got != c.want || (c.want == "") != errors.Is(err, store.ErrUnsupportedFormat) {
+			t.Errorf("%s: got %q, %v but want %q", c.path, got, err, c.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	src := store.NewBolt(t.TempDir() + "/src.db")
+	dst := store.NewBolt(t.TempDir() + "/dst.db")
+	defer src.Close()
+	defer dst.Close()
+
+	rs := archiveRecords()
+	changed := *rs[0]
+	changed.Note = "changed"
+	removed := *rs[0]
+	removed.Anchor = &model.Anchor{BBc1DomainID: util.MustConvert32B(tx1), BBc1TransactionID: util.MustConvert32B(dom1), Timestamp: ts1}
+	for _, r := range rs {
+		if err := src.Put(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range []*model.AnchorRecord{&changed, &removed} {
+		if err := dst.Put(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	same, err := store.Diff(ctx, src, dst, func(kind store.DiffKind, r *model.AnchorRecord) {
+		got = append(got, string(kind)+hex.EncodeToString(r.Anchor.BBc1DomainID[:1]))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"~45", "+45", "-56"}
+	if same != 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v (same=%d) but want %v", got, same, want)
+	}
+
+	// Copy and compare again.
+	if err := src.Scan(ctx, func(r *model.AnchorRecord) error { return dst.Put(ctx, r) }); err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	same, err = store.Diff(ctx, src, dst, func(kind store.DiffKind, r *model.AnchorRecord) {
+		got = append(got, string(kind))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != 2 || !reflect.DeepEqual(got, []string{"-"}) {
+		t.Errorf("got %v (same=%d)", got, same)
+	}
+}
@@ -0,0 +1,299 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	// boltAnchors contains AnchorEntities in JSON, with BBc-1 domain ID + BBc-1 transaction ID as the key.
+	boltAnchors = []byte("anchors")
+	// boltBTCTx is the index for GetByBTCTransaction,
+	// with Bitcoin transaction ID + BBc-1 domain ID + BBc-1 transaction ID as the key, and nil as the value.
+	boltBTCTx = []byte("btctx")
+)
+
+// Bolt is a Store that uses an embedded bbolt database file (see util.OpenBolt).
+type Bolt struct {
+	path string
+	db   *bbolt.DB
+
+	// Checks whether Open is called.
+	// For internal use only.
+	once sync.Once
+}
+
+var _ Store = (*Bolt)(nil)
+
+func NewBolt(path string) *Bolt {
+	b := &Bolt{
+		path: path,
+		db:   nil,
+	}
+	return b
+}
+
+func (b *Bolt) open() error {
+	db, err := util.OpenBolt(b.path, boltAnchors, boltBTCTx)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrFailedToOpen, err)
+	}
+	b.db = db
+	return nil
+}
+
+// Open opens b.db once.
+func (b *Bolt) Open() error {
+	var oErr error
+	b.once.Do(func() { oErr = b.open() })
+	if oErr != nil {
+		return oErr
+	}
+	return nil
+}
+
+// Close closes the Bolt.
+func (b *Bolt) Close() error {
+	if err := b.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrFailedToClose, err)
+	}
+	if err := b.db.Close(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrFailedToClose, err)
+	}
+	return nil
+}
+
+func boltKey(bbc1dom, bbc1tx []byte) []byte {
+	k := make([]byte, 0, len(bbc1dom)+len(bbc1tx))
+	k = append(k, bbc1dom...)
+	return append(k, bbc1tx...)
+}
+
+func boltGetEntity(tx *bbolt.Tx, key []byte) (*AnchorEntity, error) {
+	v := tx.Bucket(boltAnchors).Get(key)
+	if v == nil {
+		return nil, util.ErrNotFound
+	}
+	return boltUnmarshalEntity(v)
+}
+
+// boltUnmarshalEntity decodes v and sets times in local time like time.Unix,
+// as other Stores return AnchorRecords in local time.
+func boltUnmarshalEntity(v []byte) (*AnchorEntity, error) {
+	var e AnchorEntity
+	if err := json.Unmarshal(v, &e); err != nil {
+		return nil, err
+	}
+	for _, t := range []*time.Time{&e.AnchorTime, &e.TransactionTime, &e.LastChecked} {
+		if !t.IsZero() {
+			*t = t.Local()
+		}
+	}
+	return &e, nil
+}
+
+// boltPutEntity puts e and updates the index.
+func boltPutEntity(tx *bbolt.Tx, e *AnchorEntity) error {
+	key := boltKey(e.BBc1DomainID, e.BBc1TransactionID)
+	if old, err := boltGetEntity(tx, key); err == nil {
+		if err := tx.Bucket(boltBTCTx).Delete(append(append([]byte{}, old.BTCTransactionID...), key...)); err != nil {
+			return err
+		}
+	}
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltAnchors).Put(key, v); err != nil {
+		return err
+	}
+	return tx.Bucket(boltBTCTx).Put(append(append([]byte{}, e.BTCTransactionID...), key...), nil)
+}
+
+func (b *Bolt) Put(ctx context.Context, r *model.AnchorRecord) error {
+	if err := b.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrFailedToPut, err)
+	}
+	e := NewAnchorEntity(r)
+	if e.Status == "" {
+		e.Status = string(model.StatusOfConfirmations(e.Confirmations))
+	}
+	if err := b.db.Update(func(tx *bbolt.Tx) error { return boltPutEntity(tx, e) }); err != nil {
+		return util.Wrap(ErrFailedToPut, err)
+	}
+	return nil
+}
+
+func (b *Bolt) Get(ctx context.Context, bbc1dom, bbc1tx []byte) (*model.AnchorRecord, error) {
+	if err := b.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrFailedToGet, err)
+	}
+	var e *AnchorEntity
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		e, err = boltGetEntity(tx, boltKey(bbc1dom, bbc1tx))
+		return err
+	})
+	if err != nil {
+		return nil, util.Wrap(ErrFailedToGet, err)
+	}
+	return e.AnchorRecord(), nil
+}
+
+func (b *Bolt) GetByBTCTransaction(ctx context.Context, btctx []byte) ([]*model.AnchorRecord, error) {
+	if err := b.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrFailedToGet, err)
+	}
+	rs := []*model.AnchorRecord{}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(boltBTCTx).Cursor()
+		for k, _ := c.Seek(btctx); k != nil && bytes.HasPrefix(k, btctx); k, _ = c.Next() {
+			e, err := boltGetEntity(tx, k[len(btctx):])
+			if err != nil {
+				return err
+			}
+			rs = append(rs, e.AnchorRecord())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, util.Wrap(ErrFailedToGet, err)
+	}
+	return rs, nil
+}
+
+// List scans the keys that start with bbc1dom in order.
+// The cursor is the BBc-1 transaction ID of the last AnchorRecord in hexadecimal string.
+func (b *Bolt) List(ctx context.Context, bbc1dom []byte, opts *ListOptions) ([]*model.AnchorRecord, string, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	after, err := hex.DecodeString(opts.Cursor)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w (%v)", ErrInvalidCursor, err)
+	}
+	if err := b.Open(); err != nil {
+		return nil, "", fmt.Errorf("%w (%v)", ErrFailedToList, err)
+	}
+	limit := opts.limit()
+	var rs []*model.AnchorRecord
+	var cursor string
+	err = b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(boltAnchors).Cursor()
+		start := boltKey(bbc1dom, after)
+		for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, bbc1dom); k, v = c.Next() {
+			if bytes.Equal(k, start) && len(after) != 0 {
+				continue
+			}
+			e, err := boltUnmarshalEntity(v)
+			if err != nil {
+				return err
+			}
+			r := e.AnchorRecord()
+			if !opts.match(r) {
+				continue
+			}
+			if len(rs) == limit {
+				// There is at least one more.
+				cursor = hex.EncodeToString(rs[len(rs)-1].Anchor.BBc1TransactionID[:])
+				return nil
+			}
+			rs = append(rs, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", util.Wrap(ErrFailedToList, err)
+	}
+	return rs, cursor, nil
+}
+
+// Scan iterates over the keys in order in a read-only transaction.
+// Errors returned by f are returned as is.
+func (b *Bolt) Scan(ctx context.Context, f func(r *model.AnchorRecord) error) error {
+	if err := b.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrFailedToList, err)
+	}
+	var fErr error
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltAnchors).ForEach(func(_, v []byte) error {
+			e, err := boltUnmarshalEntity(v)
+			if err != nil {
+				return err
+			}
+			fErr = f(e.AnchorRecord())
+			return fErr
+		})
+	})
+	if fErr != nil {
+		return fErr
+	}
+	if err != nil {
+		return util.Wrap(ErrFailedToList, err)
+	}
+	return nil
+}
+
+// Update updates the fields in u in a transaction.
+func (b *Bolt) Update(ctx context.Context, bbc1dom, bbc1tx []byte, u *RecordUpdate) error {
+	if err := b.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrFailedToUpdate, err)
+	}
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		e, err := boltGetEntity(tx, boltKey(bbc1dom, bbc1tx))
+		if err != nil {
+			return err
+		}
+		if u.Confirmations != nil {
+			e.Confirmations = *u.Confirmations
+		}
+		if u.Status != nil {
+			e.Status = string(*u.Status)
+			e.StatusReason = u.StatusReason
+			e.LastChecked = u.LastChecked
+		}
+		if u.BBc1DomainName != nil {
+			e.BBc1DomainName = *u.BBc1DomainName
+		}
+		if u.Note != nil {
+			e.Note = *u.Note
+		}
+		if u.Metadata != nil {
+			e.Metadata = nil
+			if len(u.Metadata) != 0 {
+				e.Metadata = u.Metadata
+			}
+		}
+		return boltPutEntity(tx, e)
+	})
+	if err != nil {
+		return util.Wrap(ErrFailedToUpdate, err)
+	}
+	return nil
+}
+
+func (b *Bolt) UpdateConfirmations(ctx context.Context, bbc1dom, bbc1tx []byte, confirmations uint) error {
+	return b.Update(ctx, bbc1dom, bbc1tx, &RecordUpdate{Confirmations: &confirmations})
+}
+
+func (b *Bolt) UpdateStatus(ctx context.Context, bbc1dom, bbc1tx []byte, status model.AnchorStatus, reason string, lastChecked time.Time) error {
+	return b.Update(ctx, bbc1dom, bbc1tx, &RecordUpdate{Status: &status, StatusReason: reason, LastChecked: lastChecked})
+}
+
+func (b *Bolt) UpdateBBc1DomainName(ctx context.Context, bbc1dom, bbc1tx []byte, bbc1domName string) error {
+	return b.Update(ctx, bbc1dom, bbc1tx, &RecordUpdate{BBc1DomainName: &bbc1domName})
+}
+
+func (b *Bolt) UpdateNote(ctx context.Context, bbc1dom, bbc1tx []byte, note string) error {
+	return b.Update(ctx, bbc1dom, bbc1tx, &RecordUpdate{Note: &note})
+}
@@ -1,15 +1,20 @@
 package store
 
 import (
+	"bytes"
 	"context"
 	"encoding/gob"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/util"
 
 	"gocloud.dev/docstore"
 )
@@ -27,6 +32,8 @@ var (
 	ErrFailedToGet    = errors.New("ErrFailedToGet")
 	ErrFailedToPut    = errors.New("ErrFailedToPut")
 	ErrFailedToUpdate = errors.New("ErrFailedToUpdate")
+	ErrFailedToList   = errors.New("ErrFailedToList")
+	ErrInvalidCursor  = errors.New("ErrInvalidCursor")
 )
 
 type Docstore struct {
@@ -81,7 +88,7 @@ func (d *Docstore) PutEntity(ctx context.Context, e *AnchorEntity) error {
 		return fmt.Errorf("%w (%v)", ErrFailedToPut, err)
 	}
 	if err := d.coll.Put(ctx, e); err != nil {
-		return fmt.Errorf("%w (%v)", ErrFailedToPut, err)
+		return util.Wrap(ErrFailedToPut, util.DocstoreError(err))
 	}
 	return nil
 }
@@ -91,15 +98,15 @@ func (d *Docstore) GetEntity(ctx context.Context, e *AnchorEntity) error {
 		return fmt.Errorf("%w (%v)", ErrFailedToGet, err)
 	}
 	if err := d.coll.Get(ctx, e); err != nil {
-		return fmt.Errorf("%w (%v)", ErrFailedToGet, err)
+		return util.Wrap(ErrFailedToGet, util.DocstoreError(err))
 	}
 	return nil
 }
 
 // UpdateEntity updates the AnchorEntity specified by e.CID.
-// It updates Confirmations, BBc1DomainName, and Note only,
+// It updates Confirmations, status (Status, StatusReason, and LastChecked), BBc1DomainName, Note, and Metadata only,
 // as other data must not be changed.
-func (d *Docstore) UpdateEntity(ctx context.Context, e *AnchorEntity, updateConfirmations, updateBBc1Dom, updateNote bool) error {
+func (d *Docstore) UpdateEntity(ctx context.Context, e *AnchorEntity, updateConfirmations, updateStatus, updateBBc1Dom, updateNote, updateMetadata bool) error {
 	if err := d.Open(); err != nil {
 		return fmt.Errorf("%w (%v)", ErrFailedToUpdate, err)
 	}
@@ -107,14 +114,56 @@ func (d *Docstore) UpdateEntity(ctx context.Context, e *AnchorEntity, updateConf
 	if updateConfirmations {
 		mod["confirmations"] = e.Confirmations
 	}
+	if updateStatus {
+		mod["status"] = e.Status
+		mod["statusreason"] = e.StatusReason
+		mod["lastchecked"] = e.LastChecked
+	}
 	if updateBBc1Dom {
 		mod["bbc1dom"] = e.BBc1DomainName
 	}
 	if updateNote {
 		mod["note"] = e.Note
 	}
+	if updateMetadata {
+		// nil removes the field.
+		mod["metadata"] = nil
+		if len(e.Metadata) != 0 {
+			mod["metadata"] = e.Metadata
+		}
+	}
 	if err := d.coll.Update(ctx, e, mod); err != nil {
-		return fmt.Errorf("%w (%v)", ErrFailedToUpdate, err)
+		return util.Wrap(ErrFailedToUpdate, util.DocstoreError(err))
+	}
+	return nil
+}
+
+// Update updates the AnchorEntity in a single docstore update, so it is atomic.
+func (d *Docstore) Update(ctx context.Context, bbc1dom, bbc1tx []byte, u *RecordUpdate) error {
+	e := &AnchorEntity{
+		CID:          hex.EncodeToString(bbc1dom) + hex.EncodeToString(bbc1tx),
+		StatusReason: u.StatusReason,
+		LastChecked:  u.LastChecked,
+	}
+	if u.Confirmations != nil {
+		e.Confirmations = *u.Confirmations
+	}
+	if u.Status != nil {
+		e.Status = string(*u.Status)
+	}
+	if u.BBc1DomainName != nil {
+		e.BBc1DomainName = *u.BBc1DomainName
+	}
+	if u.Note != nil {
+		e.Note = *u.Note
+	}
+	e.Metadata = u.Metadata
+	upConf, upStatus, upDom, upNote, upMeta := u.Confirmations != nil, u.Status != nil, u.BBc1DomainName != nil, u.Note != nil, u.Metadata != nil
+	if !upConf && !upStatus && !upDom && !upNote && !upMeta {
+		return nil
+	}
+	if err := d.UpdateEntity(ctx, e, upConf, upStatus, upDom, upNote, upMeta); err != nil {
+		return err
 	}
 	return nil
 }
@@ -137,12 +186,122 @@ func (d *Docstore) Get(ctx context.Context, bbc1dom, bbc1tx []byte) (*model.Anch
 	return e.AnchorRecord(), nil
 }
 
+// GetByBTCTransaction queries AnchorEntities by BTCTx.
+// Note that AnchorEntities put by older versions do not have BTCTx, so they are not found.
+func (d *Docstore) GetByBTCTransaction(ctx context.Context, btctx []byte) ([]*model.AnchorRecord, error) {
+	if err := d.Open(); err != nil {
+		return nil, fmt.Errorf("%w (%v)", ErrFailedToGet, err)
+	}
+	iter := d.coll.Query().Where("btctx", "=", hex.EncodeToString(btctx)).Get(ctx)
+	defer iter.Stop()
+
+	rs := []*model.AnchorRecord{}
+	for {
+		var e AnchorEntity
+		err := iter.Next(ctx, &e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, util.Wrap(ErrFailedToGet, util.DocstoreError(err))
+		}
+		rs = append(rs, e.AnchorRecord())
+	}
+	// Sort in memory as not all drivers support OrderBy without an index.
+	sort.SliceStable(rs, func(i, j int) bool {
+		return bytes.Compare(rs[i].Anchor.BBc1DomainID[:], rs[j].Anchor.BBc1DomainID[:]) < 0 ||
+			rs[i].Anchor.BBc1DomainID == rs[j].Anchor.BBc1DomainID && bytes.Compare(rs[i].Anchor.BBc1TransactionID[:], rs[j].Anchor.BBc1TransactionID[:]) < 0
+	})
+	return rs, nil
+}
+
+// List queries AnchorEntities by the range of CID, as CID starts with the BBc-1 domain ID.
+// Other filters are applied in memory as not all drivers support multiple range filters.
+// The cursor is the BBc-1 transaction ID of the last AnchorRecord in hexadecimal string.
+func (d *Docstore) List(ctx context.Context, bbc1dom []byte, opts *ListOptions) ([]*model.AnchorRecord, string, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	if _, err := hex.DecodeString(opts.Cursor); err != nil {
+		return nil, "", fmt.Errorf("%w (%v)", ErrInvalidCursor, err)
+	}
+	if err := d.Open(); err != nil {
+		return nil, "", fmt.Errorf("%w (%v)", ErrFailedToList, err)
+	}
+	dom := hex.EncodeToString(bbc1dom)
+	// "g" is larger than any hexadecimal digit.
+	iter := d.coll.Query().
+		Where("cid", ">", dom+strings.ToLower(opts.Cursor)).
+		Where("cid", "<", dom+"g").
+		OrderBy("cid", docstore.Ascending).
+		Get(ctx)
+	defer iter.Stop()
+
+	limit := opts.limit()
+	var rs []*model.AnchorRecord
+	for {
+		var e AnchorEntity
+		err := iter.Next(ctx, &e)
+		if err == io.EOF {
+			return rs, "", nil
+		}
+		if err != nil {
+			return nil, "", util.Wrap(ErrFailedToList, util.DocstoreError(err))
+		}
+		r := e.AnchorRecord()
+		if !opts.match(r) {
+			continue
+		}
+		if len(rs) == limit {
+			// There is at least one more.
+			last := rs[len(rs)-1]
+			return rs, hex.EncodeToString(last.Anchor.BBc1TransactionID[:]), nil
+		}
+		rs = append(rs, r)
+	}
+}
+
+// Scan iterates over all AnchorEntities ordered by CID.
+func (d *Docstore) Scan(ctx context.Context, f func(r *model.AnchorRecord) error) error {
+	if err := d.Open(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrFailedToList, err)
+	}
+	iter := d.coll.Query().OrderBy("cid", docstore.Ascending).Get(ctx)
+	defer iter.Stop()
+	for {
+		var e AnchorEntity
+		err := iter.Next(ctx, &e)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return util.Wrap(ErrFailedToList, util.DocstoreError(err))
+		}
+		if err := f(e.AnchorRecord()); err != nil {
+			return err
+		}
+	}
+}
+
 func (d *Docstore) UpdateConfirmations(ctx context.Context, bbc1dom, bbc1tx []byte, confirmations uint) error {
 	e := &AnchorEntity{
 		CID:           hex.EncodeToString(bbc1dom) + hex.EncodeToString(bbc1tx),
 		Confirmations: confirmations,
 	}
-	if err := d.UpdateEntity(ctx, e, true, false, false); err != nil {
+	if err := d.UpdateEntity(ctx, e, true, false, false, false, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *Docstore) UpdateStatus(ctx context.Context, bbc1dom, bbc1tx []byte, status model.AnchorStatus, reason string, lastChecked time.Time) error {
+	e := &AnchorEntity{
+		CID:          hex.EncodeToString(bbc1dom) + hex.EncodeToString(bbc1tx),
+		Status:       string(status),
+		StatusReason: reason,
+		LastChecked:  lastChecked,
+	}
+	if err := d.UpdateEntity(ctx, e, false, true, false, false, false); err != nil {
 		return err
 	}
 	return nil
@@ -153,7 +312,7 @@ func (d *Docstore) UpdateBBc1DomainName(ctx context.Context, bbc1dom, bbc1tx []b
 		CID:            hex.EncodeToString(bbc1dom) + hex.EncodeToString(bbc1tx),
 		BBc1DomainName: bbc1domName,
 	}
-	if err := d.UpdateEntity(ctx, e, false, true, false); err != nil {
+	if err := d.UpdateEntity(ctx, e, false, false, true, false, false); err != nil {
 		return err
 	}
 	return nil
@@ -164,7 +323,7 @@ func (d *Docstore) UpdateNote(ctx context.Context, bbc1dom, bbc1tx []byte, note
 		CID:  hex.EncodeToString(bbc1dom) + hex.EncodeToString(bbc1tx),
 		Note: note,
 	}
-	if err := d.UpdateEntity(ctx, e, false, false, true); err != nil {
+	if err := d.UpdateEntity(ctx, e, false, false, false, true, false); err != nil {
 		return err
 	}
 	return nil
@@ -2,6 +2,8 @@ package store_test
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"os"
 	"reflect"
 	"testing"
@@ -34,10 +36,27 @@ var (
 		BTCTransactionID: btctx1,
 		TransactionTime:  txts1,
 		Confirmations:    confirm1,
+		Status:           model.AnchorFinal,
 		BBc1DomainName:   "testDom",
 		Note:             "hello world",
 	}
 	ae1 = &store.AnchorEntity{
+		CID:               cid1,
+		BBc1DomainID:      dom1,
+		BBc1TransactionID: tx1,
+		AnchorVersion:     255,
+		BTCNet:            model.BTCTestnet3,
+		AnchorTime:        ts1,
+		BTCTransactionID:  btctx1,
+		BTCTx:             "6928e1c6478d1f55ed1a5d86e1ab24669a14f777b879bbb25c746543810bf916",
+		TransactionTime:   txts1,
+		Confirmations:     confirm1,
+		Status:            "final",
+		BBc1DomainName:    "testDom",
+		Note:              "hello world",
+	}
+	// ae1Legacy is ae1 stored by older versions without status.
+	ae1Legacy = &store.AnchorEntity{
 		CID:               cid1,
 		BBc1DomainID:      dom1,
 		BBc1TransactionID: tx1,
@@ -66,7 +85,7 @@ func TestNewAnchorEntity(t *testing.T) {
 		c := c
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
-			got := store.NewAnchorEntity(ar1)
+			got := store.NewAnchorEntity(c.input)
 			if !reflect.DeepEqual(got, c.want) {
 				t.Errorf("got %+v but want %+v", got, c.want)
 			}
@@ -83,12 +102,13 @@ func TestAnchorEntity_AnchorRecord(t *testing.T) {
 		want  *model.AnchorRecord
 	}{
 		{"normal", ae1, ar1},
+		{"legacy", ae1Legacy, ar1},
 	}
 	for _, c := range cases {
 		c := c
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
-			got := ae1.AnchorRecord()
+			got := c.input.AnchorRecord()
 			if !reflect.DeepEqual(got, c.want) {
 				t.Errorf("got %+v but want %+v", got, c.want)
 			}
@@ -115,7 +135,7 @@ func TestDocstore_GetEntity(t *testing.T) {
 		cid  string
 		want *store.AnchorEntity
 	}{
-		{"normal", conn1, cid1, ae1},
+		{"normal", conn1, cid1, ae1Legacy},
 	}
 	for _, c := range cases {
 		c := c
@@ -193,16 +213,19 @@ func TestDocstore_UpdateEntity(t *testing.T) {
 		original            *store.AnchorEntity
 		updateConfirmations bool
 		confirmations       uint
+		updateStatus        bool
+		status              string
 		updateBBc1Dom       bool
 		bbc1Dom             string
 		updateNote          bool
 		note                string
 	}{
-		{"all", testdb2, conn2, cid1, ae1, true, 123, true, "my-domain", true, "yo"},
-		{"confs_only", testdb2, conn2, cid1, ae1, true, 123, false, "", false, ""},
-		{"dom_only", testdb2, conn2, cid1, ae1, false, 0, true, "my-domain", false, ""},
-		{"note_only", testdb2, conn2, cid1, ae1, false, 0, false, "", true, "yo"},
-		{"empty_dom_note", testdb2, conn2, cid1, ae1, false, 0, true, "", true, ""},
+		{"all", testdb2, conn2, cid1, ae1, true, 123, true, "reorged", true, "my-domain", true, "yo"},
+		{"confs_only", testdb2, conn2, cid1, ae1, true, 123, false, "", false, "", false, ""},
+		{"status_only", testdb2, conn2, cid1, ae1, false, 0, true, "failed", false, "", false, ""},
+		{"dom_only", testdb2, conn2, cid1, ae1, false, 0, false, "", true, "my-domain", false, ""},
+		{"note_only", testdb2, conn2, cid1, ae1, false, 0, false, "", false, "", true, "yo"},
+		{"empty_dom_note", testdb2, conn2, cid1, ae1, false, 0, false, "", true, "", true, ""},
 	}
 	for _, c := range cases {
 		c := c
@@ -220,9 +243,10 @@ func TestDocstore_UpdateEntity(t *testing.T) {
 			// update
 			ue := *c.original
 			ue.Confirmations = c.confirmations
+			ue.Status = c.status
 			ue.BBc1DomainName = c.bbc1Dom
 			ue.Note = c.note
-			if err := docs.UpdateEntity(ctx, &ue, c.updateConfirmations, c.updateBBc1Dom, c.updateNote); err != nil {
+			if err := docs.UpdateEntity(ctx, &ue, c.updateConfirmations, c.updateStatus, c.updateBBc1Dom, c.updateNote, false); err != nil {
 				t.Error(err)
 			}
 			// get
@@ -237,6 +261,12 @@ func TestDocstore_UpdateEntity(t *testing.T) {
 			if !c.updateConfirmations && (got.Confirmations != c.original.Confirmations) {
 				t.Errorf("!updateConfirmations: got %+v but want %+v", got.Confirmations, c.original.Confirmations)
 			}
+			if c.updateStatus && (got.Status != c.status) {
+				t.Errorf("updateStatus: got %+v but want %+v", got.Status, c.status)
+			}
+			if !c.updateStatus && (got.Status != c.original.Status) {
+				t.Errorf("!updateStatus: got %+v but want %+v", got.Status, c.original.Status)
+			}
 			if c.updateBBc1Dom && (got.BBc1DomainName != c.bbc1Dom) {
 				t.Errorf("updateBBc1Dom: got %+v but want %+v", got.BBc1DomainName, c.bbc1Dom)
 			}
@@ -289,6 +319,16 @@ func TestDocstore_Get(t *testing.T) {
 
 }
 
+func TestDocstore_Get_NotFound(t *testing.T) {
+	docs := store.NewDocstore(conn1)
+	defer docs.Close() // Ignores error as no write access.
+
+	_, err := docs.Get(context.Background(), dom1, dom1)
+	if !errors.Is(err, store.ErrFailedToGet) || !errors.Is(err, util.ErrNotFound) {
+		t.Errorf("got %v but want %v", err, util.ErrNotFound)
+	}
+}
+
 func TestDocstore_Put(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -466,3 +506,130 @@ func TestDocstore_UpdateNote(t *testing.T) {
 		})
 	}
 }
+
+func TestDocstore_List(t *testing.T) {
+	docs := store.NewDocstore("mem://store_test_list/cid")
+	defer docs.Close()
+
+	ctx := context.Background()
+	ctx, cancelFunc := context.WithTimeout(ctx, 30*time.Second)
+	defer cancelFunc()
+
+	// dom1 has 5 anchors (tx[0] to tx[4], 1 minute apart), and dom2 has 1 anchor.
+	dom2 := util.MustDecodeHexString("0000000000000000000000000000000000000000000000000000000000000002")
+	var txs [][]byte
+	put := func(dom []byte, i int, conf uint) {
+		var tx [32]byte
+		tx[31] = byte(i)
+		txs = append(txs, tx[:])
+		r := model.NewAnchorRecord(&model.Anchor{
+			Version:           255,
+			BTCNet:            model.BTCTestnet3,
+			Timestamp:         ts1.Add(time.Duration(i) * time.Minute),
+			BBc1DomainID:      util.MustConvert32B(dom),
+			BBc1TransactionID: tx,
+		}, btctx1, txts1, conf, "", "")
+		if err := docs.Put(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, conf := range []uint{0, 1, 6, 6, 0} {
+		put(dom1, i, conf)
+	}
+	put(dom2, 5, 6)
+
+	cases := []struct {
+		name       string
+		dom        []byte
+		opts       *store.ListOptions
+		want       [][]byte
+		wantCursor bool
+	}{
+		{"all", dom1, nil, txs[0:5], false},
+		{"other_domain", dom2, nil, txs[5:6], false},
+		{"first_page", dom1, &store.ListOptions{Limit: 2}, txs[0:2], true},
+		{"last_page", dom1, &store.ListOptions{Limit: 3, Cursor: hex.EncodeToString(txs[1])}, txs[2:5], false},
+		{"since_until", dom1, &store.ListOptions{Since: ts1.Add(time.Minute), Until: ts1.Add(3 * time.Minute)}, txs[1:3], false},
+		{"status", dom1, &store.ListOptions{Statuses: []model.AnchorStatus{model.AnchorFinal}}, txs[2:4], false},
+		{"status_page", dom1, &store.ListOptions{Limit: 1, Statuses: []model.AnchorStatus{model.AnchorBroadcast}}, txs[0:1], true},
+		{"no_domain", tx1, nil, nil, false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got, cursor, err := docs.List(ctx, c.dom, c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotTxs [][]byte
+			for _, r := range got {
+				gotTxs = append(gotTxs, r.Anchor.BBc1TransactionID[:])
+			}
+			if !reflect.DeepEqual(gotTxs, c.want) {
+				t.Errorf("got %x but want %x", gotTxs, c.want)
+			}
+			if (cursor != "") != c.wantCursor {
+				t.Errorf("got cursor %q but want cursor: %v", cursor, c.wantCursor)
+			}
+		})
+	}
+}
+
+func TestDocstore_List_InvalidCursor(t *testing.T) {
+	docs := store.NewDocstore("mem://store_test_list_err/cid")
+	defer docs.Close()
+
+	_, _, err := docs.List(context.Background(), dom1, &store.ListOptions{Cursor: "zz"})
+	if !errors.Is(err, store.ErrInvalidCursor) {
+		t.Errorf("got %v but want %v", err, store.ErrInvalidCursor)
+	}
+}
+
+func TestDocstore_GetByBTCTransaction(t *testing.T) {
+	docs := store.NewDocstore("mem://store_test_btctx/cid")
+	defer docs.Close()
+
+	ctx := context.Background()
+	ctx, cancelFunc := context.WithTimeout(ctx, 30*time.Second)
+	defer cancelFunc()
+
+	// tx[0] and tx[1] share btctx1, and tx[2] is in btctx2.
+	btctx2 := util.MustDecodeHexString("0000000000000000000000000000000000000000000000000000000000000002")
+	var txs [][]byte
+	for i, btctx := range [][]byte{btctx1, btctx1, btctx2} {
+		var tx [32]byte
+		tx[31] = byte(i)
+		txs = append(txs, tx[:])
+		a := *a1
+		a.BBc1TransactionID = tx
+		if err := docs.Put(ctx, model.NewAnchorRecord(&a, btctx, txts1, confirm1, "", "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		btctx []byte
+		want  [][]byte
+	}{
+		{"shared", btctx1, txs[0:2]},
+		{"single", btctx2, txs[2:3]},
+		{"not_found", tx1, nil},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got, err := docs.GetByBTCTransaction(ctx, c.btctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotTxs [][]byte
+			for _, r := range got {
+				gotTxs = append(gotTxs, r.Anchor.BBc1TransactionID[:])
+			}
+			if !reflect.DeepEqual(gotTxs, c.want) {
+				t.Errorf("got %x but want %x", gotTxs, c.want)
+			}
+		})
+	}
+}
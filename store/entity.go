@@ -12,18 +12,29 @@ import (
 // In particular, CID is a key field that contains
 // a combined string that starts with BBc-1 domain ID,
 // followed by transaction ID.
+// BTCTx is BTCTransactionID in hexadecimal string for queries.
 type AnchorEntity struct {
-	CID               string    `docstore:"cid"`
-	BBc1DomainID      []byte    `docstore:"bbc1domid"`
-	BBc1TransactionID []byte    `docstore:"bbc1txid"`
-	AnchorVersion     uint8     `docstore:"anchorver"`
-	BTCNet            uint8     `docstore:"btcnet"`
-	AnchorTime        time.Time `docstore:"anchortime"`
-	BTCTransactionID  []byte    `docstore:"btctxid"`
-	TransactionTime   time.Time `docstore:"txtime"`
-	Confirmations     uint      `docstore:"confirmations"`
-	BBc1DomainName    string    `docstore:"bbc1dom"`
-	Note              string    `docstore:"note"`
+	CID               string            `docstore:"cid"`
+	BBc1DomainID      []byte            `docstore:"bbc1domid"`
+	BBc1TransactionID []byte            `docstore:"bbc1txid"`
+	AnchorVersion     uint8             `docstore:"anchorver"`
+	BTCNet            uint8             `docstore:"btcnet"`
+	AnchorTime        time.Time         `docstore:"anchortime"`
+	BTCTransactionID  []byte            `docstore:"btctxid"`
+	BTCTx             string            `docstore:"btctx"`
+	TransactionTime   time.Time         `docstore:"txtime"`
+	Confirmations     uint              `docstore:"confirmations"`
+	Fee               int64             `docstore:"fee"`
+	InputAmount       int64             `docstore:"input"`
+	ChangeAmount      int64             `docstore:"change"`
+	VSize             uint              `docstore:"vsize"`
+	Status            string            `docstore:"status"`
+	StatusReason      string            `docstore:"statusreason"`
+	LastChecked       time.Time         `docstore:"lastchecked"`
+	BBc1DomainName    string            `docstore:"bbc1dom"`
+	Note              string            `docstore:"note"`
+	Metadata          map[string]string `docstore:"metadata"`
+	APIKeyID          string            `docstore:"apikey"`
 }
 
 // NewAnchorEntity initializes an AnchorEntity from the given AnchorRecord.
@@ -37,15 +48,28 @@ func NewAnchorEntity(r *model.AnchorRecord) *AnchorEntity {
 		BTCNet:            uint8(r.Anchor.BTCNet),
 		AnchorTime:        r.Anchor.Timestamp,
 		BTCTransactionID:  r.BTCTransactionID,
+		BTCTx:             hex.EncodeToString(r.BTCTransactionID),
 		TransactionTime:   r.TransactionTime,
 		Confirmations:     r.Confirmations,
+		Fee:               int64(r.Fee),
+		InputAmount:       int64(r.InputAmount),
+		ChangeAmount:      int64(r.ChangeAmount),
+		VSize:             r.VSize,
+		Status:            string(r.Status),
+		StatusReason:      r.StatusReason,
+		LastChecked:       r.LastChecked,
 		BBc1DomainName:    r.BBc1DomainName,
 		Note:              r.Note,
+		APIKeyID:          r.APIKeyID,
+	}
+	if len(r.Metadata) != 0 {
+		e.Metadata = r.Metadata
 	}
 	return e
 }
 
 // AnchorRecord returns an AnchorRecord from the AnchorEntity.
+// Status is derived from Confirmations if the AnchorEntity has no status (i.e. stored by older versions).
 func (e *AnchorEntity) AnchorRecord() *model.AnchorRecord {
 	var did, txid [32]byte
 	copy(did[:], e.BBc1DomainID[0:len(e.BBc1DomainID)])
@@ -62,8 +86,22 @@ func (e *AnchorEntity) AnchorRecord() *model.AnchorRecord {
 		BTCTransactionID: e.BTCTransactionID,
 		TransactionTime:  e.TransactionTime,
 		Confirmations:    e.Confirmations,
+		Fee:              model.Amount(e.Fee),
+		InputAmount:      model.Amount(e.InputAmount),
+		ChangeAmount:     model.Amount(e.ChangeAmount),
+		VSize:            e.VSize,
+		Status:           model.AnchorStatus(e.Status),
+		StatusReason:     e.StatusReason,
+		LastChecked:      e.LastChecked,
 		BBc1DomainName:   e.BBc1DomainName,
 		Note:             e.Note,
+		APIKeyID:         e.APIKeyID,
+	}
+	if len(e.Metadata) != 0 {
+		r.Metadata = e.Metadata
+	}
+	if r.Status == "" {
+		r.Status = model.StatusOfConfirmations(e.Confirmations)
 	}
 	return r
 }
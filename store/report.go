@@ -0,0 +1,138 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+)
+
+// ReportOptions selects AnchorRecords and how to group them in a spend report.
+type ReportOptions struct {
+	// ByMonth, ByDomain and ByAPIKey group the totals by the month of the anchor time in UTC,
+	// the BBc-1 domain ID, and the API key. All AnchorRecords are totaled into one row if none of them is set.
+	ByMonth  bool
+	ByDomain bool
+	ByAPIKey bool
+
+	// BBc1DomainID selects AnchorRecords of the domain if not nil.
+	BBc1DomainID []byte
+	// Since and Until select AnchorRecords anchored in [Since, Until). Zero means no limit.
+	Since time.Time
+	Until time.Time
+}
+
+// SpendRow is a row of a spend report.
+// Month ("2021-02"), BBc1DomainID and APIKeyID are set if grouped by them.
+type SpendRow struct {
+	Month        string
+	BBc1DomainID []byte
+	APIKeyID     string
+
+	// Anchors is the number of AnchorRecords, and Unpriced is the number of them with unknown cost.
+	Anchors  uint
+	Unpriced uint
+	Fee      model.Amount
+	VSize    uint
+}
+
+// reportColumns is the CSV header of a spend report. Amounts are in satoshis.
+var reportColumns = []string{"month", "domain", "apikey", "anchors", "unpriced", "fee", "vsize"}
+
+func (o *ReportOptions) selects(r *model.AnchorRecord) bool {
+	if o.BBc1DomainID != nil && !bytes.Equal(o.BBc1DomainID, r.Anchor.BBc1DomainID[:]) {
+		return false
+	}
+	if !o.Since.IsZero() && r.Anchor.Timestamp.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && !r.Anchor.Timestamp.Before(o.Until) {
+		return false
+	}
+	return true
+}
+
+// row returns the empty SpendRow that r is totaled into.
+func (o *ReportOptions) row(r *model.AnchorRecord) *SpendRow {
+	row := &SpendRow{}
+	if o.ByMonth {
+		row.Month = r.Anchor.Timestamp.UTC().Format("2006-01")
+	}
+	if o.ByDomain {
+		row.BBc1DomainID = r.Anchor.BBc1DomainID[:]
+	}
+	if o.ByAPIKey {
+		row.APIKeyID = r.APIKeyID
+	}
+	return row
+}
+
+func (row *SpendRow) key() string {
+	return row.Month + "/" + hex.EncodeToString(row.BBc1DomainID) + "/" + row.APIKeyID
+}
+
+// Report totals the cost of AnchorRecords in s selected by opts.
+// Rows are ordered by Month, BBc1DomainID and APIKeyID.
+func Report(ctx context.Context, s Scanner, opts *ReportOptions) ([]*SpendRow, error) {
+	if opts == nil {
+		opts = &ReportOptions{}
+	}
+	rows := make(map[string]*SpendRow)
+	err := s.Scan(ctx, func(r *model.AnchorRecord) error {
+		if !opts.selects(r) {
+			return nil
+		}
+		row := opts.row(r)
+		if old, ok := rows[row.key()]; ok {
+			row = old
+		} else {
+			rows[row.key()] = row
+		}
+		row.Anchors++
+		if r.Fee == 0 {
+			row.Unpriced++
+		}
+		row.Fee += r.Fee
+		row.VSize += r.VSize
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(rows))
+	for k := range rows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rs := make([]*SpendRow, len(keys))
+	for i, k := range keys {
+		rs[i] = rows[k]
+	}
+	return rs, nil
+}
+
+// WriteReportCSV writes rows in CSV with the header line.
+func WriteReportCSV(w io.Writer, rows []*SpendRow) error {
+	c := csv.NewWriter(w)
+	if err := c.Write(reportColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		err := c.Write([]string{
+			row.Month, hex.EncodeToString(row.BBc1DomainID), row.APIKeyID,
+			strconv.FormatUint(uint64(row.Anchors), 10), strconv.FormatUint(uint64(row.Unpriced), 10),
+			strconv.FormatInt(int64(row.Fee), 10), strconv.FormatUint(uint64(row.VSize), 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
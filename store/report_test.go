@@ -0,0 +1,87 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+	"github.com/ebiiim/btcgw/util"
+)
+
+type sliceScanner []*model.AnchorRecord
+
+func (s sliceScanner) Scan(ctx context.Context, f func(r *model.AnchorRecord) error) error {
+	for _, r := range s {
+		if err := f(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+	rs := archiveRecords() // ar1 has no cost and ar2 has.
+	ar3 := *rs[1]
+	a3 := *ar3.Anchor
+	a3.BBc1DomainID = util.MustConvert32B(tx1)
+	a3.Timestamp = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	ar3.Anchor = &a3
+	s := sliceScanner{rs[0], rs[1], &ar3}
+
+	cases := []struct {
+		name string
+		opts *store.ReportOptions
+		want []*store.SpendRow
+	}{
+		{"total", nil, []*store.SpendRow{
+			{Anchors: 3, Unpriced: 1, Fee: 20000, VSize: 270},
+		}},
+		{"by_month", &store.ReportOptions{ByMonth: true}, []*store.SpendRow{
+			{Month: "2021-02", Anchors: 2, Unpriced: 1, Fee: 10000, VSize: 135},
+			{Month: "2021-03", Anchors: 1, Fee: 10000, VSize: 135},
+		}},
+		{"by_domain_apikey", &store.ReportOptions{ByDomain: true, ByAPIKey: true}, []*store.SpendRow{
+			{BBc1DomainID: dom1, Anchors: 1, Unpriced: 1},
+			{BBc1DomainID: dom1, APIKeyID: "0123456789abcdef", Anchors: 1, Fee: 10000, VSize: 135},
+			{BBc1DomainID: tx1, APIKeyID: "0123456789abcdef", Anchors: 1, Fee: 10000, VSize: 135},
+		}},
+		{"domain_since", &store.ReportOptions{BBc1DomainID: dom1, Since: ts1.Add(time.Second)}, []*store.SpendRow{}},
+		{"until", &store.ReportOptions{Until: a3.Timestamp}, []*store.SpendRow{
+			{Anchors: 2, Unpriced: 1, Fee: 10000, VSize: 135},
+		}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := store.Report(context.Background(), s, c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %+v but want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	t.Parallel()
+	rows := []*store.SpendRow{
+		{Month: "2021-02", BBc1DomainID: dom1, APIKeyID: "0123456789abcdef", Anchors: 2, Unpriced: 1, Fee: 10000, VSize: 135},
+	}
+	var buf bytes.Buffer
+	if err := store.WriteReportCSV(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := "month,domain,apikey,anchors,unpriced,fee,vsize\n" +
+		"2021-02,456789abc0ef0123456089abcdef0023456789a0cdef0123406789abcde00123,0123456789abcdef,2,1,10000,135\n"
+	if buf.String() != want {
+		t.Errorf("got %q but want %q", buf.String(), want)
+	}
+}
@@ -7,6 +7,7 @@ package store
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/ebiiim/btcgw/model"
 )
@@ -14,6 +15,9 @@ import (
 // Store provides features to store anchor data in a datastore.
 // Anchor data (especially the Bitcoin transaction IDs) should be stored,
 // as finding an Anchor needs walking through all Bitcoin blockchains and is time consuming.
+//
+// Errors wrap util.ErrNotFound if the AnchorRecord does not exist (Get and Update*),
+// and util.ErrUnavailable if the datastore is not available, so that callers can check them by errors.Is.
 type Store interface {
 	// Put adds or replaces an AnchorRecord in O(1) time.
 	Put(ctx context.Context, r *model.AnchorRecord) error
@@ -21,10 +25,29 @@ type Store interface {
 	// Get returns the AnchorRecord specified by bbc1dom and bbc1tx in O(1) time.
 	Get(ctx context.Context, bbc1dom, bbc1tx []byte) (*model.AnchorRecord, error)
 
+	// GetByBTCTransaction returns AnchorRecords whose BTCTransactionID is btctx.
+	// More than one AnchorRecord may be embedded in a Bitcoin transaction.
+	// Returns an empty slice if not found.
+	GetByBTCTransaction(ctx context.Context, btctx []byte) ([]*model.AnchorRecord, error)
+
+	// List returns AnchorRecords of the BBc-1 domain bbc1dom that match opts, ordered by BBc-1 transaction ID,
+	// and the cursor to get the next page. The cursor is "" if there are no more AnchorRecords.
+	// opts can be nil.
+	List(ctx context.Context, bbc1dom []byte, opts *ListOptions) ([]*model.AnchorRecord, string, error)
+
+	// Update updates the fields in u at once
+	// in the AnchorRecord specified by bbc1dom and bbc1tx.
+	// Either all of them are updated or none of them.
+	Update(ctx context.Context, bbc1dom, bbc1tx []byte, u *RecordUpdate) error
+
 	// UpdateConfirmations updates Confirmations
 	//  in the AnchorRecord specified by bbc1dom and bbc1tx.
 	UpdateConfirmations(ctx context.Context, bbc1dom, bbc1tx []byte, confirmations uint) error
 
+	// UpdateStatus updates Status, StatusReason, and LastChecked
+	// in the AnchorRecord specified by bbc1dom and bbc1tx.
+	UpdateStatus(ctx context.Context, bbc1dom, bbc1tx []byte, status model.AnchorStatus, reason string, lastChecked time.Time) error
+
 	// UpdateBBc1DomainName updates BBc1DomainName
 	// in the AnchorRecord specified by bbc1dom and bbc1tx.
 	UpdateBBc1DomainName(ctx context.Context, bbc1dom, bbc1tx []byte, bbc1domName string) error
@@ -33,7 +56,83 @@ type Store interface {
 	// in the AnchorRecord specified by bbc1dom and bbc1tx.
 	UpdateNote(ctx context.Context, bbc1dom, bbc1tx []byte, note string) error
 
+	Scanner
 	io.Closer
 }
 
+// Scanner iterates over all AnchorRecords, e.g. to migrate or export them.
+type Scanner interface {
+	// Scan calls f for each AnchorRecord ordered by BBc-1 domain ID and BBc-1 transaction ID,
+	// and stops if f returns an error.
+	Scan(ctx context.Context, f func(r *model.AnchorRecord) error) error
+}
+
 var _ Store = (*Docstore)(nil)
+
+// RecordUpdate contains the fields of an AnchorRecord to be updated by Update.
+// nil fields are not updated.
+type RecordUpdate struct {
+	Confirmations *uint
+	// StatusReason and LastChecked are updated with Status.
+	Status         *model.AnchorStatus
+	StatusReason   string
+	LastChecked    time.Time
+	BBc1DomainName *string
+	Note           *string
+	// Metadata replaces the whole Metadata if not nil. An empty one removes it.
+	Metadata model.Metadata
+}
+
+// Page sizes of List.
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+// ListOptions filters and paginates AnchorRecords returned by List.
+// Zero values mean no filters.
+type ListOptions struct {
+	// Limit is the page size. DefaultListLimit is used if 0, and MaxListLimit is used if larger than it.
+	Limit int
+	// Cursor is the cursor returned by the previous List.
+	Cursor string
+	// Since and Until filter AnchorRecords by the timestamp in the Anchor, i.e. Since <= Anchor.Timestamp < Until.
+	Since time.Time
+	Until time.Time
+	// Statuses filters AnchorRecords by AnchorRecord.Status.
+	Statuses []model.AnchorStatus
+	// Metadata filters AnchorRecords that have all the keys, and the values unless "" (see model.Metadata.Match).
+	Metadata model.Metadata
+}
+
+func (o *ListOptions) limit() int {
+	switch {
+	case o.Limit <= 0:
+		return DefaultListLimit
+	case o.Limit > MaxListLimit:
+		return MaxListLimit
+	default:
+		return o.Limit
+	}
+}
+
+func (o *ListOptions) match(r *model.AnchorRecord) bool {
+	if !o.Since.IsZero() && r.Anchor.Timestamp.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && !r.Anchor.Timestamp.Before(o.Until) {
+		return false
+	}
+	if !r.Metadata.Match(o.Metadata) {
+		return false
+	}
+	if len(o.Statuses) == 0 {
+		return true
+	}
+	for _, s := range o.Statuses {
+		if r.Status == s {
+			return true
+		}
+	}
+	return false
+}
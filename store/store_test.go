@@ -0,0 +1,360 @@
+package store_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ebiiim/btcgw/model"
+	"github.com/ebiiim/btcgw/store"
+	"github.com/ebiiim/btcgw/util"
+
+	_ "modernc.org/sqlite"
+)
+
+type testStore interface {
+	store.Store
+	Open() error
+}
+
+// testStores returns constructors of empty Stores that support all features.
+func testStores() map[string]func(t *testing.T) testStore {
+	return map[string]func(t *testing.T) testStore{
+		"SQL": func(t *testing.T) testStore {
+			return store.NewSQL(store.DriverSQLite, ":memory:")
+		},
+		"Bolt": func(t *testing.T) testStore {
+			return store.NewBolt(t.TempDir() + "/anchors.db")
+		},
+	}
+}
+
+func newTestStore(t *testing.T, newStore func(t *testing.T) testStore) testStore {
+	t.Helper()
+	s := newStore(t)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// forEachStore runs f for each Store in testStores.
+func forEachStore(t *testing.T, f func(t *testing.T, newStore func(t *testing.T) testStore)) {
+	for name, newStore := range testStores() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			f(t, newStore)
+		})
+	}
+}
+
+func TestSQL_Open(t *testing.T) {
+	t.Parallel()
+
+	// Migrations are applied only once.
+	dsn := "file:" + t.TempDir() + "/anchors.db"
+	for i := 0; i < 2; i++ {
+		s := store.NewSQL(store.DriverSQLite, dsn)
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := store.NewSQL("mysql", "")
+	if err := s.Open(); !errors.Is(err, store.ErrFailedToOpen) {
+		t.Errorf("got %v but want %v", err, store.ErrFailedToOpen)
+	}
+}
+
+func TestStore_PutGet(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func(t *testing.T) testStore) {
+		s := newTestStore(t, newStore)
+		defer s.Close()
+		ctx := context.Background()
+
+		if _, err := s.Get(ctx, dom1, tx1); !errors.Is(err, store.ErrFailedToGet) {
+			t.Errorf("got %v but want %v", err, store.ErrFailedToGet)
+		}
+		if err := s.Put(ctx, ar1); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Get(ctx, dom1, tx1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, ar1) {
+			t.Errorf("got %+v but want %+v", got, ar1)
+		}
+
+		// replace
+		ar := *ar1
+		ar.BTCTransactionID = tx1
+		ar.LastChecked = txts1
+		if err := s.Put(ctx, &ar); err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.Get(ctx, dom1, tx1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, &ar) {
+			t.Errorf("got %+v but want %+v", got, &ar)
+		}
+	})
+}
+
+func TestStore_Update(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func(t *testing.T) testStore) {
+
+		conf := uint(123)
+		status := model.AnchorReorged
+		name := "my-domain"
+		note := "yo"
+		cases := []struct {
+			name   string
+			update *store.RecordUpdate
+			want   func(r *model.AnchorRecord)
+		}{
+			{"all", &store.RecordUpdate{Confirmations: &conf, Status: &status, StatusReason: "hoge", LastChecked: txts1, BBc1DomainName: &name, Note: &note}, func(r *model.AnchorRecord) {
+				r.Confirmations, r.Status, r.StatusReason, r.LastChecked, r.BBc1DomainName, r.Note = conf, status, "hoge", txts1, name, note
+			}},
+			{"confs_only", &store.RecordUpdate{Confirmations: &conf}, func(r *model.AnchorRecord) { r.Confirmations = conf }},
+			{"status_only", &store.RecordUpdate{Status: &status}, func(r *model.AnchorRecord) { r.Status = status }},
+			{"metadata_only", &store.RecordUpdate{Metadata: model.Metadata{"source": "erp"}}, func(r *model.AnchorRecord) { r.Metadata = model.Metadata{"source": "erp"} }},
+			{"nothing", &store.RecordUpdate{}, func(r *model.AnchorRecord) {}},
+		}
+		for _, c := range cases {
+			c := c
+			t.Run(c.name, func(t *testing.T) {
+				t.Parallel()
+				s := newTestStore(t, newStore)
+				defer s.Close()
+				ctx := context.Background()
+
+				if err := s.Put(ctx, ar1); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.Update(ctx, dom1, tx1, c.update); err != nil {
+					t.Fatal(err)
+				}
+				got, err := s.Get(ctx, dom1, tx1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := *ar1
+				c.want(&want)
+				if !reflect.DeepEqual(got, &want) {
+					t.Errorf("got %+v but want %+v", got, &want)
+				}
+			})
+		}
+	})
+}
+
+func TestStore_Metadata(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func(t *testing.T) testStore) {
+		s := newTestStore(t, newStore)
+		defer s.Close()
+		ctx := context.Background()
+
+		want := *ar1
+		want.Metadata = model.Metadata{"source": "erp", "url": "https://example.com/?a=1&b=\"2\""}
+		if err := s.Put(ctx, &want); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Get(ctx, dom1, tx1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("got %+v but want %+v", got, &want)
+		}
+
+		// An empty one removes it.
+		if err := s.Update(ctx, dom1, tx1, &store.RecordUpdate{Metadata: model.Metadata{}}); err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.Get(ctx, dom1, tx1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Metadata != nil {
+			t.Errorf("got %v but want nil", got.Metadata)
+		}
+	})
+}
+
+func TestStore_Update_NotFound(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func(t *testing.T) testStore) {
+		s := newTestStore(t, newStore)
+		defer s.Close()
+
+		if err := s.UpdateNote(context.Background(), dom1, tx1, "yo"); !errors.Is(err, store.ErrFailedToUpdate) || !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("got %v but want %v", err, util.ErrNotFound)
+		}
+		if _, err := s.Get(context.Background(), dom1, tx1); !errors.Is(err, store.ErrFailedToGet) || !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("got %v but want %v", err, util.ErrNotFound)
+		}
+	})
+}
+
+func TestStore_List(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func(t *testing.T) testStore) {
+		s := newTestStore(t, newStore)
+		defer s.Close()
+		ctx := context.Background()
+
+		// dom1 has 5 anchors (tx[0] to tx[4], 1 minute apart), and dom2 has 1 anchor.
+		dom2 := util.MustDecodeHexString("0000000000000000000000000000000000000000000000000000000000000002")
+		var txs [][]byte
+		put := func(dom []byte, i int, conf uint) {
+			var tx [32]byte
+			tx[31] = byte(i)
+			txs = append(txs, tx[:])
+			r := model.NewAnchorRecord(&model.Anchor{
+				Version:           255,
+				BTCNet:            model.BTCTestnet3,
+				Timestamp:         ts1.Add(time.Duration(i) * time.Minute),
+				BBc1DomainID:      util.MustConvert32B(dom),
+				BBc1TransactionID: tx,
+			}, btctx1, txts1, conf, "", "")
+			if i%2 == 0 {
+				r.Metadata = model.Metadata{"doctype": "invoice", "seq": fmt.Sprint(i)}
+			}
+			if err := s.Put(ctx, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i, conf := range []uint{0, 1, 6, 6, 0} {
+			put(dom1, i, conf)
+		}
+		put(dom2, 5, 6)
+
+		cases := []struct {
+			name       string
+			dom        []byte
+			opts       *store.ListOptions
+			want       [][]byte
+			wantCursor bool
+		}{
+			{"all", dom1, nil, txs[0:5], false},
+			{"other_domain", dom2, nil, txs[5:6], false},
+			{"first_page", dom1, &store.ListOptions{Limit: 2}, txs[0:2], true},
+			{"last_page", dom1, &store.ListOptions{Limit: 3, Cursor: hex.EncodeToString(txs[1])}, txs[2:5], false},
+			{"since_until", dom1, &store.ListOptions{Since: ts1.Add(time.Minute), Until: ts1.Add(3 * time.Minute)}, txs[1:3], false},
+			{"status", dom1, &store.ListOptions{Statuses: []model.AnchorStatus{model.AnchorFinal, model.AnchorConfirmed}}, txs[1:4], false},
+			{"status_page", dom1, &store.ListOptions{Limit: 1, Statuses: []model.AnchorStatus{model.AnchorBroadcast}}, txs[0:1], true},
+			{"metadata_key", dom1, &store.ListOptions{Metadata: model.Metadata{"doctype": ""}}, [][]byte{txs[0], txs[2], txs[4]}, false},
+			{"metadata_values", dom1, &store.ListOptions{Metadata: model.Metadata{"doctype": "invoice", "seq": "2"}}, txs[2:3], false},
+			{"metadata_page", dom1, &store.ListOptions{Limit: 1, Cursor: hex.EncodeToString(txs[0]), Metadata: model.Metadata{"seq": ""}}, txs[2:3], true},
+			{"metadata_none", dom1, &store.ListOptions{Metadata: model.Metadata{"doctype": "receipt"}}, nil, false},
+			{"no_domain", tx1, nil, nil, false},
+		}
+		for _, c := range cases {
+			c := c
+			t.Run(c.name, func(t *testing.T) {
+				got, cursor, err := s.List(ctx, c.dom, c.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var gotTxs [][]byte
+				for _, r := range got {
+					gotTxs = append(gotTxs, r.Anchor.BBc1TransactionID[:])
+				}
+				if !reflect.DeepEqual(gotTxs, c.want) {
+					t.Errorf("got %x but want %x", gotTxs, c.want)
+				}
+				if (cursor != "") != c.wantCursor {
+					t.Errorf("got cursor %q but want cursor: %v", cursor, c.wantCursor)
+				}
+			})
+		}
+
+		if _, _, err := s.List(ctx, dom1, &store.ListOptions{Cursor: "zz"}); !errors.Is(err, store.ErrInvalidCursor) {
+			t.Errorf("got %v but want %v", err, store.ErrInvalidCursor)
+		}
+	})
+}
+
+func TestStore_GetByBTCTransaction(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newStore func(t *testing.T) testStore) {
+		s := newTestStore(t, newStore)
+		defer s.Close()
+		ctx := context.Background()
+
+		// tx[0] and tx[1] share btctx1, and tx[2] is in btctx2.
+		btctx2 := util.MustDecodeHexString("0000000000000000000000000000000000000000000000000000000000000002")
+		var txs [][]byte
+		for i, btctx := range [][]byte{btctx1, btctx1, btctx2} {
+			var tx [32]byte
+			tx[31] = byte(i)
+			txs = append(txs, tx[:])
+			a := *a1
+			a.BBc1TransactionID = tx
+			if err := s.Put(ctx, model.NewAnchorRecord(&a, btctx, txts1, confirm1, "", "")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cases := []struct {
+			name  string
+			btctx []byte
+			want  [][]byte
+		}{
+			{"shared", btctx1, txs[0:2]},
+			{"single", btctx2, txs[2:3]},
+			{"not_found", tx1, nil},
+		}
+		for _, c := range cases {
+			c := c
+			t.Run(c.name, func(t *testing.T) {
+				got, err := s.GetByBTCTransaction(ctx, c.btctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var gotTxs [][]byte
+				for _, r := range got {
+					gotTxs = append(gotTxs, r.Anchor.BBc1TransactionID[:])
+				}
+				if !reflect.DeepEqual(gotTxs, c.want) {
+					t.Errorf("got %x but want %x", gotTxs, c.want)
+				}
+			})
+		}
+	})
+}
+
+func TestOpenURL(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		url  string
+		want interface{}
+	}{
+		{"sqlite://" + dir + "/anchors.sqlite", &store.SQL{}},
+		{"bolt://" + dir + "/anchors.db", &store.Bolt{}},
+		{"mem://anchors/cid", &store.Docstore{}},
+	}
+	for _, c := range cases {
+		s, err := store.OpenURL(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.TypeOf(s) != reflect.TypeOf(c.want) {
+			t.Errorf("%s: got %T but want %T", c.url, s, c.want)
+		}
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := store.OpenURL("unknown://anchors"); !errors.Is(err, store.ErrFailedToOpen) {
+		t.Errorf("got %v but want %v", err, store.ErrFailedToOpen)
+	}
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"errors"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// BoltLockTimeout is how long OpenBolt waits for another process to release the file.
+var BoltLockTimeout = 1 * time.Second
+
+// OpenBolt opens the bbolt database file at path, and creates it and the given buckets if they do not exist.
+// The file is locked while opened, so it cannot be shared with other processes:
+// OpenBolt fails with ErrUnavailable after BoltLockTimeout if another process has opened the same file.
+// Every write transaction is synced to the file on commit, so committed data survives crashes.
+func OpenBolt(path string, buckets ...[]byte) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: BoltLockTimeout})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, Wrap(ErrUnavailable, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, b := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
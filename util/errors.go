@@ -0,0 +1,98 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"gocloud.dev/gcerrors"
+)
+
+// Errors shared by stores (store.Store, btc.Wallet, auth, gw.Journal and so on),
+// so that callers can classify errors without knowing the backend.
+// Errors returned by stores wrap one of them if the cause is known.
+var (
+	// ErrNotFound means the requested item does not exist.
+	ErrNotFound = errors.New("ErrNotFound")
+	// ErrAlreadyExists means the item to create already exists.
+	ErrAlreadyExists = errors.New("ErrAlreadyExists")
+	// ErrUnavailable means the backend cannot be reached or is locked. Retrying later may help.
+	ErrUnavailable = errors.New("ErrUnavailable")
+)
+
+// wrapError wraps err with outer without losing err,
+// so both of them can be checked by errors.Is.
+type wrapError struct {
+	outer error
+	err   error
+}
+
+// Wrap returns an error that is formatted as "outer (err)" and matches both outer and err by errors.Is.
+func Wrap(outer, err error) error {
+	return &wrapError{outer, err}
+}
+
+func (e *wrapError) Error() string {
+	return fmt.Sprintf("%v (%v)", e.outer, e.err)
+}
+
+func (e *wrapError) Is(target error) bool {
+	return target == e.outer
+}
+
+func (e *wrapError) Unwrap() error {
+	return e.err
+}
+
+// DocstoreError wraps err returned by gocloud.dev/docstore with
+// ErrNotFound, ErrAlreadyExists or ErrUnavailable according to gcerrors.Code(err).
+// Returns err as is if none of them applies.
+//
+// Drivers report connection failures (e.g. mongo server selection errors) as gcerrors.Unknown,
+// so Unknown is treated as ErrUnavailable only if it is a connection failure. Others, e.g. decode errors, are not.
+func DocstoreError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch gcerrors.Code(err) {
+	case gcerrors.NotFound:
+		return Wrap(ErrNotFound, err)
+	case gcerrors.AlreadyExists:
+		return Wrap(ErrAlreadyExists, err)
+	case gcerrors.DeadlineExceeded, gcerrors.ResourceExhausted:
+		return Wrap(ErrUnavailable, err)
+	case gcerrors.Unknown:
+		if isConnectionError(err) {
+			return Wrap(ErrUnavailable, err)
+		}
+	}
+	return err
+}
+
+// errorLabeler is implemented by errors with labels, e.g. mongo.CommandError.
+type errorLabeler interface {
+	HasErrorLabel(label string) bool
+}
+
+// isConnectionError returns whether err is caused by a network error or a timeout,
+// including the ones of the mongo driver that are not net.Error.
+func isConnectionError(err error) bool {
+	if IsUnavailable(err) {
+		return true
+	}
+	var l errorLabeler
+	if errors.As(err, &l) && l.HasErrorLabel("NetworkError") {
+		return true
+	}
+	// The mongo driver formats server selection errors without wrapping the cause.
+	return strings.Contains(err.Error(), "server selection error")
+}
+
+// IsUnavailable returns whether err is caused by a network error or a timeout.
+// Errors that already wrap ErrUnavailable are also reported.
+func IsUnavailable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
@@ -1,10 +1,17 @@
 package util_test
 
 import (
+	"context"
+	"errors"
+	"net"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/ebiiim/btcgw/util"
+
+	"gocloud.dev/docstore"
+	_ "gocloud.dev/docstore/memdocstore"
 )
 
 func Test(t *testing.T) {
@@ -138,3 +145,93 @@ func TestGetEnvIntOr(t *testing.T) {
 	}
 
 }
+
+func TestOpenBolt(t *testing.T) {
+	path := t.TempDir() + "/test.db"
+	db, err := util.OpenBolt(path, []byte("b1"), []byte("b2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// The file is locked.
+	old := util.BoltLockTimeout
+	util.BoltLockTimeout = 10 * time.Millisecond
+	defer func() { util.BoltLockTimeout = old }()
+	db2, err := util.OpenBolt(path)
+	if err == nil {
+		db2.Close()
+	}
+	if !errors.Is(err, util.ErrUnavailable) {
+		t.Errorf("want %v but got %v", util.ErrUnavailable, err)
+	}
+}
+
+// labeledError is an error with a label like mongo.CommandError.
+type labeledError struct {
+	label string
+}
+
+func (e labeledError) Error() string {
+	return "labeled: " + e.label
+}
+
+func (e labeledError) HasErrorLabel(label string) bool {
+	return label == e.label
+}
+
+func TestDocstoreError(t *testing.T) {
+	type doc struct {
+		Key string `docstore:"key"`
+	}
+	ctx := context.Background()
+	coll, err := docstore.OpenCollection(ctx, "mem://util_test/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coll.Close()
+	if err := coll.Create(ctx, &doc{Key: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"not_found", coll.Get(ctx, &doc{Key: "2"}), util.ErrNotFound},
+		{"already_exists", coll.Create(ctx, &doc{Key: "1"}), util.ErrAlreadyExists},
+		{"invalid", coll.Get(ctx, &doc{Key: ""}), nil},
+		{"unknown", errors.New("cannot decode into the struct"), nil},
+		{"server_selection", errors.New("server selection error: server selection timeout"), util.ErrUnavailable},
+		{"network_label", labeledError{"NetworkError"}, util.ErrUnavailable},
+		{"other_label", labeledError{"TransientTransactionError"}, nil},
+		{"net", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, util.ErrUnavailable},
+		{"deadline", context.DeadlineExceeded, util.ErrUnavailable},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := util.DocstoreError(c.err)
+			if !errors.Is(got, c.err) {
+				t.Errorf("%v should wrap %v", got, c.err)
+			}
+			for _, e := range []error{util.ErrNotFound, util.ErrAlreadyExists, util.ErrUnavailable} {
+				if errors.Is(got, e) != (e == c.want) {
+					t.Errorf("errors.Is(%v, %v) should be %v", got, e, e == c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	outer := errors.New("outer")
+	err := util.Wrap(outer, util.Wrap(util.ErrNotFound, errors.New("inner")))
+	if !errors.Is(err, outer) || !errors.Is(err, util.ErrNotFound) {
+		t.Errorf("%v should match both", err)
+	}
+	if want := "outer (ErrNotFound (inner))"; err.Error() != want {
+		t.Errorf("got %q but want %q", err.Error(), want)
+	}
+}